@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS clients;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS tokens_valid_after,
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS role;
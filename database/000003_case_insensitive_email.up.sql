@@ -0,0 +1,4 @@
+-- Run `auth-service user collisions` first: this fails while two accounts
+-- share an address that only differs by case.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email));
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS device_authorizations (
+    device_code_hash VARCHAR(64) PRIMARY KEY,
+    user_code VARCHAR(16) NOT NULL UNIQUE,
+    client_id VARCHAR(64) NOT NULL REFERENCES clients (id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+    interval_seconds INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    last_polled_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS device_authorizations_expires_at_idx ON device_authorizations (expires_at);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS access_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    claims JSONB NOT NULL,
+    issued_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS access_tokens_user_id_idx ON access_tokens (user_id);
+CREATE INDEX IF NOT EXISTS access_tokens_expires_at_idx ON access_tokens (expires_at);
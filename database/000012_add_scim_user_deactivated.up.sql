@@ -0,0 +1 @@
+ALTER TABLE scim_users ADD COLUMN IF NOT EXISTS deactivated BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS saml_assertions (
+    provider VARCHAR(255) NOT NULL,
+    assertion_id VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (provider, assertion_id)
+);
+
+CREATE INDEX IF NOT EXISTS saml_assertions_expires_at_idx ON saml_assertions (expires_at);
@@ -0,0 +1,8 @@
+// Package database embeds the SQL migrations so the binary can apply them
+// without a separate golang-migrate container.
+package database
+
+import "embed"
+
+//go:embed *.sql
+var Migrations embed.FS
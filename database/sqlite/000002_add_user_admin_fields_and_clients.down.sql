@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS clients;
+
+ALTER TABLE users DROP COLUMN tokens_valid_after;
+ALTER TABLE users DROP COLUMN disabled_at;
+ALTER TABLE users DROP COLUMN role;
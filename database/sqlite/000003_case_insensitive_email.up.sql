@@ -0,0 +1,3 @@
+-- The column-level UNIQUE from 000001 cannot be dropped without rebuilding
+-- the table; it is kept, and this stricter index is what enforces identity.
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email));
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS saml_assertions (
+    provider TEXT NOT NULL,
+    assertion_id TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (provider, assertion_id)
+);
+
+CREATE INDEX IF NOT EXISTS saml_assertions_expires_at_idx ON saml_assertions (expires_at);
@@ -0,0 +1,8 @@
+// Package sqlite embeds the SQLite flavour of the schema. Versions mirror the
+// Postgres migrations one to one so both backends report the same version.
+package sqlite
+
+import "embed"
+
+//go:embed *.sql
+var Migrations embed.FS
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"auth-service/src/domain"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createdAPIKey struct {
+	Key string `json:"key"`
+	*domain.APIKey
+}
+
+// HandleCreateAPIKey mints a key for the service named in the body. The key
+// is only returned here. To rotate, mint a second key with the same name and
+// revoke the old one once the caller has switched.
+func (h *Handler) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string     `json:"name"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "INVALID_REQUEST_BODY", Message: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	key, apiKey, err := h.apiKeys.Create(r.Context(), req.Name, req.ExpiresAt)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "API key minted by administrator", "admin_id", r.Context().Value(userIDKey), "key_id", apiKey.ID, "name", apiKey.Name)
+	WriteJSON(w, http.StatusCreated, createdAPIKey{Key: key, APIKey: apiKey})
+}
+
+func (h *Handler) HandleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeys.List(r.Context())
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
+
+func (h *Handler) HandleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.apiKeys.Revoke(r.Context(), id); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "API key revoked by administrator", "admin_id", r.Context().Value(userIDKey), "key_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
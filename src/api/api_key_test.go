@@ -0,0 +1,176 @@
+package api
+
+import (
+	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/logging"
+	"auth-service/src/service"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyAuthMiddleware_RecordsCaller(t *testing.T) {
+	// Arrange
+	apiKeys := new(service.APIKeyServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithAPIKeys(apiKeys))
+	apiKeys.On("Authenticate", mock.Anything, "ask_orders").Return(&domain.APIKey{ID: "key-1", Name: "orders"}, nil)
+	apiKeys.On("Authenticate", mock.Anything, "ask_revoked").Return(nil, domain.ErrInvalidAPIKey)
+	var caller string
+	protected := handler.APIKeyAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller = logging.Caller(r.Context())
+	}))
+
+	// Act
+	accepted := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/validate", nil)
+	req.Header.Set("X-Internal-Api-Key", "ask_orders")
+	protected.ServeHTTP(accepted, req)
+
+	rejected := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/auth/validate", nil)
+	req.Header.Set("X-Internal-Api-Key", "ask_revoked")
+	protected.ServeHTTP(rejected, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, accepted.Code)
+	assert.Equal(t, "orders", caller)
+	assert.Equal(t, http.StatusForbidden, rejected.Code)
+}
+
+func TestAPIKeyAuthMiddleware_FallsBackToInternalAPIKey(t *testing.T) {
+	// Arrange
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{InternalAPIKey: "a-very-long-internal-api-key"})
+	var caller string
+	protected := handler.APIKeyAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller = logging.Caller(r.Context())
+	}))
+
+	codes := map[string]int{}
+	for _, key := range []string{"a-very-long-internal-api-key", "a-very-long-internal-api-kez", ""} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/validate", nil)
+		req.Header.Set("X-Internal-Api-Key", key)
+
+		// Act
+		protected.ServeHTTP(rr, req)
+		codes[key] = rr.Code
+	}
+
+	// Assert
+	assert.Equal(t, http.StatusOK, codes["a-very-long-internal-api-key"])
+	assert.Equal(t, http.StatusForbidden, codes["a-very-long-internal-api-kez"])
+	assert.Equal(t, http.StatusForbidden, codes[""])
+	assert.Equal(t, domain.LegacyAPIKeyName, caller)
+}
+
+// newAdminRouter mounts the admin routes the way the server does.
+func newAdminRouter(handler *Handler) http.Handler {
+	router := chi.NewRouter()
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(handler.JWTAuthMiddleware)
+		r.Use(handler.RequireAdmin)
+		r.Get("/api-keys", handler.HandleListAPIKeys)
+		r.Post("/api-keys", handler.HandleCreateAPIKey)
+		r.Delete("/api-keys/{id}", handler.HandleRevokeAPIKey)
+		r.Get("/webhooks", handler.HandleListWebhooks)
+		r.Post("/webhooks", handler.HandleCreateWebhook)
+		r.Delete("/webhooks/{id}", handler.HandleDeleteWebhook)
+		r.Get("/webhooks/{id}/deliveries", handler.HandleListWebhookDeliveries)
+		r.Post("/webhooks/{id}/deliveries/{deliveryID}/retry", handler.HandleRedeliverWebhook)
+	})
+	return router
+}
+
+func TestHandleCreateAPIKey_RequiresAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   string
+		status int
+	}{
+		{name: "admin", role: domain.RoleAdmin, status: http.StatusCreated},
+		{name: "regular user", role: domain.RoleUser, status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			users := new(service.UserServiceMock)
+			apiKeys := new(service.APIKeyServiceMock)
+			router := newAdminRouter(NewHandler(users, &config.Config{}, WithAPIKeys(apiKeys)))
+			users.On("ValidateToken", mock.Anything, "session-jwt").Return(map[string]interface{}{"sub": "user-1"}, nil)
+			users.On("GetProfile", mock.Anything, "user-1").Return(&domain.User{ID: "user-1", Role: tt.role}, nil)
+			apiKeys.On("Create", mock.Anything, "orders", (*time.Time)(nil)).
+				Return("ask_secret", &domain.APIKey{ID: "key-1", Name: "orders", KeyHash: "hash"}, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/api-keys", bytes.NewBufferString(`{"name": "orders"}`))
+			req.Header.Set("Authorization", "Bearer session-jwt")
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, tt.status, rr.Code)
+			if tt.status == http.StatusCreated {
+				var body map[string]interface{}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				assert.Equal(t, "ask_secret", body["key"])
+				assert.Equal(t, "orders", body["name"])
+				assert.NotContains(t, body, "keyHash")
+			} else {
+				apiKeys.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestRequireAdmin_RejectsPersonalAccessTokens(t *testing.T) {
+	// Arrange
+	users := new(service.UserServiceMock)
+	tokens := new(service.PersonalAccessTokenServiceMock)
+	router := newAdminRouter(NewHandler(users, &config.Config{}, WithAPIKeys(new(service.APIKeyServiceMock)), WithPersonalAccessTokens(tokens)))
+	tokens.On("Authenticate", mock.Anything, "asp_admin").
+		Return(&domain.User{ID: "user-1", Role: domain.RoleAdmin}, &domain.PersonalAccessToken{Scopes: domain.PersonalAccessTokenScopes}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+	req.Header.Set("Authorization", "Bearer asp_admin")
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	users.AssertNotCalled(t, "GetProfile", mock.Anything, mock.Anything)
+}
+
+func TestHandleRevokeAPIKey_UnknownKey(t *testing.T) {
+	// Arrange
+	users := new(service.UserServiceMock)
+	apiKeys := new(service.APIKeyServiceMock)
+	router := newAdminRouter(NewHandler(users, &config.Config{}, WithAPIKeys(apiKeys)))
+	users.On("ValidateToken", mock.Anything, "session-jwt").Return(map[string]interface{}{"sub": "user-1"}, nil)
+	users.On("GetProfile", mock.Anything, "user-1").Return(&domain.User{ID: "user-1", Role: domain.RoleAdmin}, nil)
+	apiKeys.On("Revoke", mock.Anything, "key-404").Return(domain.ErrAPIKeyNotFound)
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/api-keys/key-404", nil)
+	req.Header.Set("Authorization", "Bearer session-jwt")
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	var body ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &body)
+	assert.Equal(t, "API_KEY_NOT_FOUND", body.Code)
+}
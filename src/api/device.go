@@ -0,0 +1,132 @@
+package api
+
+import (
+	"auth-service/src/domain"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type deviceRequest struct {
+	UserCode  string    `json:"userCode"`
+	ClientID  string    `json:"clientId"`
+	Client    string    `json:"client"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// HandleDeviceAuthorization starts the device authorization grant. The
+// device shows user_code, or a QR code of verification_uri_complete, and
+// polls the token endpoint with device_code.
+func (h *Handler) HandleDeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		WriteJSON(w, http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_request", Description: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	clientID := r.PostForm.Get("client_id")
+	if clientID == "" {
+		h.handleOAuthError(w, r, domain.ErrClientIDRequired)
+		return
+	}
+	deviceCode, auth, err := h.devices.Authorize(r.Context(), clientID)
+	if err != nil {
+		h.handleOAuthError(w, r, err)
+		return
+	}
+
+	userCode := domain.FormatUserCode(auth.UserCode)
+	resp := deviceAuthorizationResponse{
+		DeviceCode:      deviceCode,
+		UserCode:        userCode,
+		VerificationURI: h.cfg.DeviceAuthorization.VerificationURI,
+		ExpiresIn:       int64(time.Until(auth.ExpiresAt).Seconds()),
+		Interval:        int64(auth.Interval.Seconds()),
+	}
+	if u, err := url.Parse(resp.VerificationURI); err == nil {
+		query := u.Query()
+		query.Set("user_code", userCode)
+		u.RawQuery = query.Encode()
+		resp.VerificationURIComplete = u.String()
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// handleDeviceCode serves the device_code grant, answering every poll until
+// the user approves with one of the errors RFC 8628 defines.
+func (h *Handler) handleDeviceCode(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PostForm.Get("client_id")
+	if clientID == "" {
+		h.handleOAuthError(w, r, domain.ErrClientIDRequired)
+		return
+	}
+	token, err := h.devices.Poll(r.Context(), clientID, r.PostForm.Get("device_code"))
+	if err != nil {
+		h.handleOAuthError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, tokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(h.cfg.TokenTTL.Seconds()),
+	})
+}
+
+// HandleDeviceLookup shows the signed-in user which client asked for the
+// code they typed, before they approve it.
+func (h *Handler) HandleDeviceLookup(w http.ResponseWriter, r *http.Request) {
+	auth, client, err := h.devices.Lookup(r.Context(), r.URL.Query().Get("user_code"))
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, deviceRequest{
+		UserCode:  domain.FormatUserCode(auth.UserCode),
+		ClientID:  client.ID,
+		Client:    client.Name,
+		ExpiresAt: auth.ExpiresAt,
+	})
+}
+
+// HandleDeviceApprove signs the device showing the code in as the current
+// user.
+func (h *Handler) HandleDeviceApprove(w http.ResponseWriter, r *http.Request) {
+	h.handleDeviceDecision(w, r, h.devices.Approve)
+}
+
+func (h *Handler) HandleDeviceDeny(w http.ResponseWriter, r *http.Request) {
+	h.handleDeviceDecision(w, r, h.devices.Deny)
+}
+
+func (h *Handler) handleDeviceDecision(w http.ResponseWriter, r *http.Request, decide func(ctx context.Context, userCode, userID string) error) {
+	var req struct {
+		UserCode string `json:"userCode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "INVALID_REQUEST_BODY", Message: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+	userID := r.Context().Value(userIDKey).(string)
+	if err := decide(r.Context(), req.UserCode, userID); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
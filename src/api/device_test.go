@@ -0,0 +1,166 @@
+package api
+
+import (
+	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/service"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newDeviceRouter(handler *Handler) http.Handler {
+	router := chi.NewRouter()
+	router.Post("/oauth/device_authorization", handler.HandleDeviceAuthorization)
+	router.Post("/oauth/token", handler.HandleToken)
+	router.Group(func(r chi.Router) {
+		r.Use(handler.JWTAuthMiddleware)
+		r.With(handler.RequireSession).Get("/device", handler.HandleDeviceLookup)
+		r.With(handler.RequireSession).Post("/device/approve", handler.HandleDeviceApprove)
+	})
+	return router
+}
+
+func TestHandleDeviceAuthorization_ReturnsCodesAndVerificationURI(t *testing.T) {
+	// Arrange
+	devices := new(service.DeviceAuthorizationServiceMock)
+	cfg := &config.Config{DeviceAuthorization: config.DeviceAuthorizationConfig{VerificationURI: "https://tv.example.com/activate"}}
+	router := newDeviceRouter(NewHandler(new(service.UserServiceMock), cfg, WithDeviceAuthorization(devices)))
+	devices.On("Authorize", mock.Anything, "tv-app").
+		Return("adc_device", &domain.DeviceAuthorization{UserCode: "BCDFGHJK", Interval: 5 * time.Second, ExpiresAt: time.Now().Add(10 * time.Minute)}, nil)
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/oauth/device_authorization", strings.NewReader(url.Values{"client_id": {"tv-app"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	var body deviceAuthorizationResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "adc_device", body.DeviceCode)
+	assert.Equal(t, "BCDF-GHJK", body.UserCode)
+	assert.Equal(t, "https://tv.example.com/activate", body.VerificationURI)
+	assert.Equal(t, "https://tv.example.com/activate?user_code=BCDF-GHJK", body.VerificationURIComplete)
+	assert.InDelta(t, 600, body.ExpiresIn, 2)
+	assert.Equal(t, int64(5), body.Interval)
+}
+
+func TestHandleToken_DeviceCodePolling(t *testing.T) {
+	// Arrange
+	devices := new(service.DeviceAuthorizationServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{TokenTTL: time.Hour}, WithDeviceAuthorization(devices))
+	devices.On("Poll", mock.Anything, "tv-app", "adc_approved").Return("session-jwt", nil)
+	devices.On("Poll", mock.Anything, "tv-app", "adc_pending").Return("", domain.ErrAuthorizationPending)
+	devices.On("Poll", mock.Anything, "tv-app", "adc_fast").Return("", domain.ErrSlowDown)
+	devices.On("Poll", mock.Anything, "tv-app", "adc_denied").Return("", domain.ErrAccessDenied)
+	devices.On("Poll", mock.Anything, "tv-app", "adc_expired").Return("", domain.ErrDeviceCodeExpired)
+	devices.On("Poll", mock.Anything, "tv-app", "adc_unknown").Return("", domain.ErrDeviceCodeInvalid)
+	poll := func(clientID, deviceCode string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.HandleToken(rr, newTokenRequest(url.Values{
+			"grant_type":  {domain.GrantTypeDeviceCode},
+			"client_id":   {clientID},
+			"device_code": {deviceCode},
+		}))
+		return rr
+	}
+
+	// Act
+	approved := poll("tv-app", "adc_approved")
+
+	// Assert
+	require.Equal(t, http.StatusOK, approved.Code)
+	var token tokenResponse
+	require.NoError(t, json.Unmarshal(approved.Body.Bytes(), &token))
+	assert.Equal(t, tokenResponse{AccessToken: "session-jwt", TokenType: "Bearer", ExpiresIn: 3600}, token)
+
+	cases := []struct {
+		clientID, deviceCode string
+		code                 string
+	}{
+		{"tv-app", "adc_pending", "authorization_pending"},
+		{"tv-app", "adc_fast", "slow_down"},
+		{"tv-app", "adc_denied", "access_denied"},
+		{"tv-app", "adc_expired", "expired_token"},
+		{"tv-app", "adc_unknown", "invalid_grant"},
+		{"", "adc_pending", "invalid_request"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.code, func(t *testing.T) {
+			rr := poll(tc.clientID, tc.deviceCode)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			var body OAuthErrorResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.code, body.Error)
+		})
+	}
+}
+
+func TestHandleDeviceApprove_RequiresSession(t *testing.T) {
+	// Arrange
+	users := new(service.UserServiceMock)
+	devices := new(service.DeviceAuthorizationServiceMock)
+	router := newDeviceRouter(NewHandler(users, &config.Config{}, WithDeviceAuthorization(devices)))
+	users.On("ValidateToken", mock.Anything, "session").Return(map[string]interface{}{"sub": "user-123"}, nil)
+	users.On("ValidateToken", mock.Anything, "impersonation").
+		Return(map[string]interface{}{"sub": "user-123", "act": map[string]interface{}{"sub": "agent-id", "type": domain.ActorUser}}, nil)
+	devices.On("Approve", mock.Anything, "bcdf-ghjk", "user-123").Return(nil)
+	approve := func(token string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/device/approve", strings.NewReader(`{"userCode":"bcdf-ghjk"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Act
+	session := approve("session")
+	impersonation := approve("impersonation")
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, session.Code)
+	assert.Equal(t, http.StatusForbidden, impersonation.Code)
+	devices.AssertNumberOfCalls(t, "Approve", 1)
+}
+
+func TestHandleDeviceLookup_ShowsRequestingClient(t *testing.T) {
+	// Arrange
+	users := new(service.UserServiceMock)
+	devices := new(service.DeviceAuthorizationServiceMock)
+	router := newDeviceRouter(NewHandler(users, &config.Config{}, WithDeviceAuthorization(devices)))
+	users.On("ValidateToken", mock.Anything, "session").Return(map[string]interface{}{"sub": "user-123"}, nil)
+	expiresAt := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	devices.On("Lookup", mock.Anything, "BCDFGHJK").
+		Return(&domain.DeviceAuthorization{UserCode: "BCDFGHJK", ClientID: "tv-app", ExpiresAt: expiresAt}, &domain.Client{ID: "tv-app", Name: "Smart TV"}, nil)
+	devices.On("Lookup", mock.Anything, "ZZZZZZZZ").Return(nil, nil, domain.ErrUserCodeInvalid)
+
+	// Act
+	found := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/device?user_code=BCDFGHJK", nil)
+	req.Header.Set("Authorization", "Bearer session")
+	router.ServeHTTP(found, req)
+
+	missing := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/device?user_code=ZZZZZZZZ", nil)
+	req.Header.Set("Authorization", "Bearer session")
+	router.ServeHTTP(missing, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, found.Code)
+	var body deviceRequest
+	require.NoError(t, json.Unmarshal(found.Body.Bytes(), &body))
+	assert.Equal(t, deviceRequest{UserCode: "BCDF-GHJK", ClientID: "tv-app", Client: "Smart TV", ExpiresAt: expiresAt}, body)
+	assert.Equal(t, http.StatusBadRequest, missing.Code)
+}
@@ -3,23 +3,122 @@ package api
 import (
 	"auth-service/src/config"
 	"auth-service/src/domain"
+	"auth-service/src/jwt"
 	"auth-service/src/service"
+	"auth-service/src/tracing"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Handler struct {
-	service service.UserService
-	cfg     *config.Config
+	service    service.UserService
+	magicLinks service.MagicLinkService
+	social     service.SocialLoginService
+	scim       service.SCIMService
+	saml       service.SAMLService
+	tokens     service.PersonalAccessTokenService
+	apiKeys    service.APIKeyService
+	webhooks   service.WebhookService
+	exchange   service.TokenExchangeService
+	devices    service.DeviceAuthorizationService
+	cfg        *config.Config
+	logger     *slog.Logger
+	limiter    *ipRateLimiter
+}
+
+type Option func(*Handler)
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(h *Handler) {
+		h.logger = logger
+	}
+}
+
+func WithMagicLinks(svc service.MagicLinkService) Option {
+	return func(h *Handler) {
+		h.magicLinks = svc
+	}
+}
+
+func WithSocialLogin(svc service.SocialLoginService) Option {
+	return func(h *Handler) {
+		h.social = svc
+	}
+}
+
+func WithSCIM(svc service.SCIMService) Option {
+	return func(h *Handler) {
+		h.scim = svc
+	}
+}
+
+func WithSAML(svc service.SAMLService) Option {
+	return func(h *Handler) {
+		h.saml = svc
+	}
+}
+
+// WithPersonalAccessTokens makes JWTAuthMiddleware and HandleAuthValidate
+// accept personal access tokens next to session JWTs.
+func WithPersonalAccessTokens(svc service.PersonalAccessTokenService) Option {
+	return func(h *Handler) {
+		h.tokens = svc
+	}
 }
 
-func NewHandler(svc service.UserService, cfg *config.Config) *Handler {
-	return &Handler{
+// WithAPIKeys authenticates internal callers with named keys instead of
+// comparing against INTERNAL_API_KEY alone, and serves the key management
+// endpoints.
+func WithAPIKeys(svc service.APIKeyService) Option {
+	return func(h *Handler) {
+		h.apiKeys = svc
+	}
+}
+
+// WithWebhooks serves the endpoints administrators use to manage webhook
+// subscriptions.
+func WithWebhooks(svc service.WebhookService) Option {
+	return func(h *Handler) {
+		h.webhooks = svc
+	}
+}
+
+// WithTokenExchange serves the token exchange grant on the token endpoint.
+func WithTokenExchange(svc service.TokenExchangeService) Option {
+	return func(h *Handler) {
+		h.exchange = svc
+	}
+}
+
+// WithDeviceAuthorization serves the device authorization grant: the
+// endpoint devices start from, the device_code grant on the token endpoint
+// and the endpoints a signed-in user approves devices with.
+func WithDeviceAuthorization(svc service.DeviceAuthorizationService) Option {
+	return func(h *Handler) {
+		h.devices = svc
+	}
+}
+
+func NewHandler(svc service.UserService, cfg *config.Config, opts ...Option) *Handler {
+	h := &Handler{
 		service: svc,
 		cfg:     cfg,
+		logger:  slog.Default(),
+	}
+	if cfg.RateLimit.Enabled {
+		h.limiter = newIPRateLimiter(cfg.RateLimit.RequestsPerMinute, cfg.RateLimit.Burst)
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 type ErrorResponse struct {
@@ -27,30 +126,114 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
-func (h *Handler) handleError(w http.ResponseWriter, err error) {
+func (h *Handler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+
+	h.logger.ErrorContext(r.Context(), "request failed", "error", err)
+	tracing.RecordError(trace.SpanFromContext(r.Context()), err)
 
-	log.Printf("ERRO: %v", err)
+	status, resp := errorResponse(err)
+	WriteJSON(w, status, resp)
+}
+
+// checkAudience refuses a login for an audience tokens are not minted for
+// before any credential or link is spent on it.
+func (h *Handler) checkAudience(audience string) error {
+	if audience != "" && !slices.Contains(h.cfg.JWT.Audience, audience) {
+		return fmt.Errorf("Error signing in for %q: %w", audience, domain.ErrInvalidTarget)
+	}
+	return nil
+}
 
+// errorResponse maps a service error to the status and body clients see.
+func errorResponse(err error) (int, ErrorResponse) {
 	if errors.Is(err, domain.ErrEmailAlreadyExists) {
-		WriteJSON(w, http.StatusConflict, ErrorResponse{Code: "EMAIL_ALREADY_EXISTS", Message: domain.ErrEmailAlreadyExists.Error()})
-		return
+		return http.StatusConflict, ErrorResponse{Code: "EMAIL_ALREADY_EXISTS", Message: domain.ErrEmailAlreadyExists.Error()}
 	}
 	if errors.Is(err, domain.ErrInvalidCredentials) {
-		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Code: "INVALID_CREDENTIALS", Message: domain.ErrInvalidCredentials.Error()})
-		return
+		return http.StatusUnauthorized, ErrorResponse{Code: "INVALID_CREDENTIALS", Message: domain.ErrInvalidCredentials.Error()}
+	}
+	if errors.Is(err, domain.ErrAccountDisabled) {
+		return http.StatusForbidden, ErrorResponse{Code: "ACCOUNT_DISABLED", Message: domain.ErrAccountDisabled.Error()}
+	}
+	if errors.Is(err, domain.ErrRateLimited) {
+		return http.StatusTooManyRequests, ErrorResponse{Code: "RATE_LIMITED", Message: domain.ErrRateLimited.Error()}
+	}
+	if errors.Is(err, domain.ErrMagicLinkInvalid) {
+		return http.StatusUnauthorized, ErrorResponse{Code: "INVALID_MAGIC_LINK", Message: domain.ErrMagicLinkInvalid.Error()}
+	}
+	if errors.Is(err, domain.ErrUnknownProvider) {
+		return http.StatusNotFound, ErrorResponse{Code: "UNKNOWN_PROVIDER", Message: domain.ErrUnknownProvider.Error()}
+	}
+	if errors.Is(err, domain.ErrSocialLoginFailed) {
+		return http.StatusUnauthorized, ErrorResponse{Code: "SOCIAL_LOGIN_FAILED", Message: domain.ErrSocialLoginFailed.Error()}
+	}
+	if errors.Is(err, domain.ErrSAMLLoginFailed) {
+		return http.StatusUnauthorized, ErrorResponse{Code: "SAML_LOGIN_FAILED", Message: domain.ErrSAMLLoginFailed.Error()}
+	}
+	if errors.Is(err, domain.ErrEmailNotVerified) {
+		return http.StatusForbidden, ErrorResponse{Code: "EMAIL_NOT_VERIFIED", Message: domain.ErrEmailNotVerified.Error()}
+	}
+	if errors.Is(err, domain.ErrAccountLinkRequired) {
+		return http.StatusConflict, ErrorResponse{Code: "ACCOUNT_EXISTS", Message: domain.ErrAccountLinkRequired.Error()}
+	}
+	if errors.Is(err, domain.ErrTokenNotFound) {
+		return http.StatusNotFound, ErrorResponse{Code: "TOKEN_NOT_FOUND", Message: domain.ErrTokenNotFound.Error()}
+	}
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		return http.StatusNotFound, ErrorResponse{Code: "API_KEY_NOT_FOUND", Message: domain.ErrAPIKeyNotFound.Error()}
+	}
+	if errors.Is(err, domain.ErrWebhookNotFound) {
+		return http.StatusNotFound, ErrorResponse{Code: "WEBHOOK_NOT_FOUND", Message: domain.ErrWebhookNotFound.Error()}
+	}
+	if errors.Is(err, domain.ErrDeliveryNotFound) {
+		return http.StatusNotFound, ErrorResponse{Code: "DELIVERY_NOT_FOUND", Message: domain.ErrDeliveryNotFound.Error()}
+	}
+	if errors.Is(err, domain.ErrAdminRequired) {
+		return http.StatusForbidden, ErrorResponse{Code: "ADMIN_REQUIRED", Message: domain.ErrAdminRequired.Error()}
+	}
+	if errors.Is(err, domain.ErrSessionRequired) {
+		return http.StatusForbidden, ErrorResponse{Code: "SESSION_REQUIRED", Message: domain.ErrSessionRequired.Error()}
+	}
+	if errors.Is(err, domain.ErrInsufficientScope) {
+		return http.StatusForbidden, ErrorResponse{Code: "INSUFFICIENT_SCOPE", Message: domain.ErrInsufficientScope.Error()}
 	}
 	if errors.Is(err, domain.ErrUserNotFound) {
-		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Code: "USER_NOT_FOUND", Message: domain.ErrUserNotFound.Error()})
-		return
+		return http.StatusUnauthorized, ErrorResponse{Code: "USER_NOT_FOUND", Message: domain.ErrUserNotFound.Error()}
 	}
 	if errors.Is(err, domain.ErrParametersMissing) {
-		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "MISSING_PARAMETERS", Message: domain.ErrParametersMissing.Error()})
+		return http.StatusBadRequest, ErrorResponse{Code: "MISSING_PARAMETERS", Message: domain.ErrParametersMissing.Error()}
+	}
+	if errors.Is(err, domain.ErrInvalidEmail) {
+		return http.StatusBadRequest, ErrorResponse{Code: "INVALID_EMAIL", Message: domain.ErrInvalidEmail.Error()}
 	}
 	if errors.Is(err, domain.ErrPasswordTooShort) {
-		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "INVALID_INPUT", Message: domain.ErrPasswordTooShort.Error()})
-		return
+		return http.StatusBadRequest, ErrorResponse{Code: "INVALID_INPUT", Message: domain.ErrPasswordTooShort.Error()}
+	}
+	if errors.Is(err, domain.ErrTokenNameRequired) {
+		return http.StatusBadRequest, ErrorResponse{Code: "MISSING_PARAMETERS", Message: domain.ErrTokenNameRequired.Error()}
+	}
+	if errors.Is(err, domain.ErrAPIKeyNameRequired) {
+		return http.StatusBadRequest, ErrorResponse{Code: "MISSING_PARAMETERS", Message: domain.ErrAPIKeyNameRequired.Error()}
+	}
+	if errors.Is(err, domain.ErrInvalidScope) {
+		return http.StatusBadRequest, ErrorResponse{Code: "INVALID_SCOPE", Message: domain.ErrInvalidScope.Error()}
+	}
+	if errors.Is(err, domain.ErrInvalidExpiry) {
+		return http.StatusBadRequest, ErrorResponse{Code: "INVALID_EXPIRY", Message: domain.ErrInvalidExpiry.Error()}
 	}
-	WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Code: "INTERNAL_SERVER_ERROR", Message: domain.ErrUnexpected.Error()})
+	if errors.Is(err, domain.ErrUserCodeInvalid) {
+		return http.StatusBadRequest, ErrorResponse{Code: "INVALID_USER_CODE", Message: domain.ErrUserCodeInvalid.Error()}
+	}
+	if errors.Is(err, domain.ErrInvalidWebhookURL) {
+		return http.StatusBadRequest, ErrorResponse{Code: "INVALID_WEBHOOK_URL", Message: domain.ErrInvalidWebhookURL.Error()}
+	}
+	if errors.Is(err, domain.ErrInvalidTarget) {
+		return http.StatusBadRequest, ErrorResponse{Code: "INVALID_AUDIENCE", Message: domain.ErrInvalidTarget.Error()}
+	}
+	if errors.Is(err, domain.ErrInvalidEventType) {
+		return http.StatusBadRequest, ErrorResponse{Code: "INVALID_EVENT_TYPE", Message: domain.ErrInvalidEventType.Error()}
+	}
+	return http.StatusInternalServerError, ErrorResponse{Code: "INTERNAL_SERVER_ERROR", Message: domain.ErrUnexpected.Error()}
 }
 
 func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
@@ -66,7 +249,7 @@ func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.service.Register(r.Context(), req.Name, req.Email, req.Password)
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
@@ -78,15 +261,22 @@ func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		// Audience picks which of the configured audiences the token is
+		// for; empty picks the first.
+		Audience string `json:"audience"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "INVALID_REQUEST_BODY", Message: domain.ErrInvalidRequestBody.Error()})
 		return
 	}
+	if err := h.checkAudience(req.Audience); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
 
-	token, err := h.service.Login(r.Context(), req.Email, req.Password)
+	token, err := h.service.Login(r.Context(), req.Email, req.Password, req.Audience)
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
@@ -97,7 +287,7 @@ func (h *Handler) HandleGetProfile(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(userIDKey).(string)
 	user, err := h.service.GetProfile(r.Context(), userID)
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
@@ -108,26 +298,69 @@ func (h *Handler) HandleGetProfile(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) HandleAuthValidate(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Token string `json:"token"`
+		// Audience, when set, also requires the token to name it in aud.
+		Audience string `json:"audience"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "INVALID_REQUEST_BODY", Message: domain.ErrInvalidRequestBody.Error()})
 		return
 	}
 
-	claims, err := h.service.ValidateToken(req.Token)
+	if h.tokens != nil && strings.HasPrefix(req.Token, domain.PersonalAccessTokenPrefix) {
+		// Personal access tokens are not issued for any audience, so a
+		// service that asks for one must not accept them.
+		if req.Audience != "" {
+			WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{"valid": false, "error": tokenErrorCode(domain.ErrTokenAudience)})
+			return
+		}
+		user, pat, err := h.tokens.Authenticate(r.Context(), req.Token)
+		if err != nil {
+			WriteJSON(w, http.StatusUnauthorized, map[string]bool{"valid": false})
+			return
+		}
+		response := map[string]interface{}{"valid": true, "userId": user.ID, "email": user.Email, "tokenType": "personal_access_token", "scopes": pat.Scopes}
+		WriteJSON(w, http.StatusOK, response)
+		return
+	}
+
+	claims, err := h.service.ValidateToken(r.Context(), req.Token)
+	if err == nil && req.Audience != "" && !jwt.HasAudience(claims, req.Audience) {
+		err = domain.ErrTokenAudience
+	}
 	if err != nil {
-		WriteJSON(w, http.StatusUnauthorized, map[string]bool{"valid": false})
+		WriteJSON(w, http.StatusUnauthorized, map[string]interface{}{"valid": false, "error": tokenErrorCode(err)})
 		return
 	}
 
 	response := map[string]interface{}{"valid": true, "userId": claims["sub"], "email": claims["email"]}
+	if act, ok := claims["act"]; ok {
+		// Exchanged tokens: who is really acting, most recent actor first.
+		response["act"] = act
+	}
 	WriteJSON(w, http.StatusOK, response)
 }
 
+// tokenErrorCode tells the calling service why a token was refused.
+func tokenErrorCode(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrTokenExpired):
+		return "TOKEN_EXPIRED"
+	case errors.Is(err, domain.ErrTokenNotYetValid):
+		return "TOKEN_NOT_YET_VALID"
+	case errors.Is(err, domain.ErrTokenAudience):
+		return "INVALID_AUDIENCE"
+	case errors.Is(err, domain.ErrTokenIssuer):
+		return "INVALID_ISSUER"
+	case errors.Is(err, domain.ErrTokenRevoked), errors.Is(err, domain.ErrAccountDisabled):
+		return "TOKEN_REVOKED"
+	}
+	return "INVALID_TOKEN"
+}
+
 func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(v); err != nil {
-		log.Printf("Failed to write JSON response: %v", err)
+		slog.Error("failed to write JSON response", "error", err)
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"auth-service/src/service"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -68,6 +69,51 @@ func TestHandleRegister_EmailAlreadyExists(t *testing.T) {
 	assert.Equal(t, "EMAIL_ALREADY_EXISTS", errorResponse.Code)
 }
 
+func TestHandleRegister_InvalidEmail(t *testing.T) {
+	// Arrange
+	mockService := new(service.UserServiceMock)
+	handler := NewHandler(mockService, &config.Config{})
+
+	requestBody := `{"name": "Test User", "email": "not-an-email", "password": "password123"}`
+	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(requestBody))
+	rr := httptest.NewRecorder()
+
+	mockService.On("Register", mock.Anything, mock.Anything, "not-an-email", mock.Anything).
+		Return(nil, domain.ErrInvalidEmail)
+
+	// Act
+	handler.HandleRegister(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var errorResponse ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	assert.Equal(t, "INVALID_EMAIL", errorResponse.Code)
+}
+
+func TestHandleRegister_MissingParametersWritesSingleResponse(t *testing.T) {
+	// Arrange
+	mockService := new(service.UserServiceMock)
+	handler := NewHandler(mockService, &config.Config{})
+
+	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBufferString(`{"name": "Test User"}`))
+	rr := httptest.NewRecorder()
+
+	mockService.On("Register", mock.Anything, "Test User", "", "").
+		Return(nil, domain.ErrParametersMissing)
+
+	// Act
+	handler.HandleRegister(rr, req)
+
+	// Assert: o corpo deve conter um único objeto JSON
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var errorResponse ErrorResponse
+	decoder := json.NewDecoder(rr.Body)
+	assert.NoError(t, decoder.Decode(&errorResponse))
+	assert.Equal(t, "MISSING_PARAMETERS", errorResponse.Code)
+	assert.False(t, decoder.More())
+}
+
 func TestJWTAuthMiddleware_Success(t *testing.T) {
 	// Arrange
 	mockService := new(service.UserServiceMock)
@@ -81,7 +127,7 @@ func TestJWTAuthMiddleware_Success(t *testing.T) {
 	defer testServer.Close()
 
 	// Programa os mocks
-	mockService.On("ValidateToken", "valid-token").
+	mockService.On("ValidateToken", mock.Anything, "valid-token").
 		Return(map[string]interface{}{"sub": "user-123"}, nil)
 	mockService.On("GetProfile", mock.Anything, "user-123").
 		Return(&domain.User{ID: "user-123", Name: "Profile User"}, nil)
@@ -96,3 +142,76 @@ func TestJWTAuthMiddleware_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, res.StatusCode)
 	mockService.AssertExpectations(t)
 }
+
+func TestRateLimitMiddleware_RejectsAfterBurst(t *testing.T) {
+	// Arrange
+	cfg := &config.Config{RateLimit: config.RateLimitConfig{Enabled: true, RequestsPerMinute: 1, Burst: 2}}
+	handler := NewHandler(new(service.UserServiceMock), cfg)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	limited := handler.RateLimitMiddleware(next)
+
+	// Act
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		limited.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/login", nil))
+		codes = append(codes, rr.Code)
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestHandleAuthValidate_ReportsWhyTokenWasRefused(t *testing.T) {
+	// Arrange
+	mockService := new(service.UserServiceMock)
+	handler := NewHandler(mockService, &config.Config{})
+	mockService.On("ValidateToken", mock.Anything, "tv-token").
+		Return(map[string]interface{}{"sub": "user-123", "aud": []interface{}{"auth-service", "tv-app"}}, nil)
+	mockService.On("ValidateToken", mock.Anything, "expired").Return(nil, fmt.Errorf("Error validating token: %w", domain.ErrTokenExpired))
+	validate := func(body map[string]string) (int, map[string]interface{}) {
+		raw, _ := json.Marshal(body)
+		rr := httptest.NewRecorder()
+		handler.HandleAuthValidate(rr, httptest.NewRequest(http.MethodPost, "/auth/validate", bytes.NewReader(raw)))
+		var resp map[string]interface{}
+		_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr.Code, resp
+	}
+
+	// Act
+	forTV, tvResp := validate(map[string]string{"token": "tv-token", "audience": "tv-app"})
+	forOrders, ordersResp := validate(map[string]string{"token": "tv-token", "audience": "orders"})
+	expired, expiredResp := validate(map[string]string{"token": "expired"})
+
+	// Assert
+	assert.Equal(t, http.StatusOK, forTV)
+	assert.Equal(t, true, tvResp["valid"])
+	assert.Equal(t, http.StatusUnauthorized, forOrders)
+	assert.Equal(t, map[string]interface{}{"valid": false, "error": "INVALID_AUDIENCE"}, ordersResp)
+	assert.Equal(t, http.StatusUnauthorized, expired)
+	assert.Equal(t, "TOKEN_EXPIRED", expiredResp["error"])
+}
+
+func TestHandleLogin_MintsForRequestedAudience(t *testing.T) {
+	// Arrange
+	mockService := new(service.UserServiceMock)
+	cfg := &config.Config{JWT: config.JWTConfig{Audience: []string{"storefront", "tv-app"}}}
+	handler := NewHandler(mockService, cfg)
+	mockService.On("Login", mock.Anything, "test@example.com", "password123", "tv-app").Return("tv-token", nil)
+	login := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.HandleLogin(rr, httptest.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(body)))
+		return rr
+	}
+
+	// Act
+	tv := login(`{"email": "test@example.com", "password": "password123", "audience": "tv-app"}`)
+	orders := login(`{"email": "test@example.com", "password": "password123", "audience": "orders"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, tv.Code)
+	assert.Contains(t, tv.Body.String(), "tv-token")
+	assert.Equal(t, http.StatusBadRequest, orders.Code)
+	assert.Contains(t, orders.Body.String(), "INVALID_AUDIENCE")
+	mockService.AssertNumberOfCalls(t, "Login", 1)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/secret"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	magicLinkCookie     = "magic_link_nonce"
+	magicLinkCookiePath = "/login/magic-link"
+)
+
+// HandleMagicLinkRequest always answers 202 for well-formed emails, whether
+// or not an account exists, so the endpoint cannot be used to list users.
+func (h *Handler) HandleMagicLinkRequest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "INVALID_REQUEST_BODY", Message: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	nonce := ""
+	if h.cfg.MagicLink.BindToBrowser {
+		var err error
+		if nonce, err = secret.Generate("", 0); err != nil {
+			h.handleError(w, r, err)
+			return
+		}
+	}
+	if err := h.magicLinks.Request(r.Context(), req.Email, nonce); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	if nonce != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     magicLinkCookie,
+			Value:    nonce,
+			Path:     magicLinkCookiePath,
+			MaxAge:   int(h.cfg.MagicLink.TTL.Seconds()),
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	WriteJSON(w, http.StatusAccepted, map[string]string{"message": "if the email belongs to an account, a sign-in link has been sent"})
+}
+
+func (h *Handler) HandleMagicLinkVerify(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Audience string `json:"audience"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "INVALID_REQUEST_BODY", Message: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+	if err := h.checkAudience(req.Audience); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	nonce := ""
+	if cookie, err := r.Cookie(magicLinkCookie); err == nil {
+		nonce = cookie.Value
+	}
+	token, err := h.magicLinks.Verify(r.Context(), req.Token, nonce, req.Audience)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: magicLinkCookie, Path: magicLinkCookiePath, MaxAge: -1, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode})
+	WriteJSON(w, http.StatusOK, map[string]string{"token": token})
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/service"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMagicLinkRequest_SetsNonceCookie(t *testing.T) {
+	// Arrange
+	magicLinks := new(service.MagicLinkServiceMock)
+	cfg := &config.Config{MagicLink: config.MagicLinkConfig{TTL: 15 * time.Minute, BindToBrowser: true}}
+	handler := NewHandler(new(service.UserServiceMock), cfg, WithMagicLinks(magicLinks))
+
+	req := httptest.NewRequest(http.MethodPost, "/login/magic-link", bytes.NewBufferString(`{"email": "shopper@example.com"}`))
+	rr := httptest.NewRecorder()
+	magicLinks.On("Request", mock.Anything, "shopper@example.com", mock.AnythingOfType("string")).Return(nil)
+
+	// Act
+	handler.HandleMagicLinkRequest(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, magicLinkCookie, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+	nonce := magicLinks.Calls[0].Arguments.String(2)
+	assert.NotEmpty(t, nonce)
+	assert.Equal(t, nonce, cookies[0].Value)
+}
+
+func TestHandleMagicLinkRequest_QueueFull(t *testing.T) {
+	// Arrange
+	magicLinks := new(service.MagicLinkServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithMagicLinks(magicLinks))
+
+	req := httptest.NewRequest(http.MethodPost, "/login/magic-link", bytes.NewBufferString(`{"email": "shopper@example.com"}`))
+	rr := httptest.NewRecorder()
+	magicLinks.On("Request", mock.Anything, "shopper@example.com", "").Return(fmt.Errorf("Error queueing magic link: %w", domain.ErrRateLimited))
+
+	// Act
+	handler.HandleMagicLinkRequest(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	var errorResponse ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	assert.Equal(t, "RATE_LIMITED", errorResponse.Code)
+}
+
+func TestHandleMagicLinkVerify_PassesCookieNonce(t *testing.T) {
+	// Arrange
+	magicLinks := new(service.MagicLinkServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithMagicLinks(magicLinks))
+
+	req := httptest.NewRequest(http.MethodPost, "/login/magic-link/verify", bytes.NewBufferString(`{"token": "ml_abc"}`))
+	req.AddCookie(&http.Cookie{Name: magicLinkCookie, Value: "nonce-123"})
+	rr := httptest.NewRecorder()
+	magicLinks.On("Verify", mock.Anything, "ml_abc", "nonce-123", "").Return("jwt-token", nil)
+
+	// Act
+	handler.HandleMagicLinkVerify(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &body)
+	assert.Equal(t, "jwt-token", body["token"])
+	magicLinks.AssertExpectations(t)
+}
+
+func TestHandleMagicLinkVerify_InvalidLink(t *testing.T) {
+	// Arrange
+	magicLinks := new(service.MagicLinkServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithMagicLinks(magicLinks))
+
+	req := httptest.NewRequest(http.MethodPost, "/login/magic-link/verify", bytes.NewBufferString(`{"token": "ml_used"}`))
+	rr := httptest.NewRecorder()
+	magicLinks.On("Verify", mock.Anything, "ml_used", "", "").Return("", domain.ErrMagicLinkInvalid)
+
+	// Act
+	handler.HandleMagicLinkVerify(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	var errorResponse ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	assert.Equal(t, "INVALID_MAGIC_LINK", errorResponse.Code)
+}
@@ -1,26 +1,66 @@
 package api
 
 import (
+	"auth-service/src/domain"
+	"auth-service/src/jwt"
+	"auth-service/src/logging"
 	"context"
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey contextKey = "userID"
+	// scopesKey is only set for personal access tokens and exchanged
+	// tokens; session JWTs are not limited by scope.
+	scopesKey contextKey = "scopes"
+)
+
+// exchangedTokenScopes limit tokens issued by token exchange on this
+// service's own routes.
+var exchangedTokenScopes = []string{domain.ScopeProfileRead, domain.ScopeTokensRead}
 
+// APIKeyAuthMiddleware identifies the calling service by its
+// X-Internal-Api-Key and records its name in the request context, so it
+// shows up in every log line of the request.
 func (h *Handler) APIKeyAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		providedKey := r.Header.Get("X-Internal-Api-Key")
-		if providedKey == "" || providedKey != h.cfg.InternalAPIKey {
+		caller, err := h.authenticateAPIKey(r.Context(), r.Header.Get("X-Internal-Api-Key"))
+		if err != nil {
+			if !errors.Is(err, domain.ErrInvalidAPIKey) {
+				h.logger.ErrorContext(r.Context(), "failed to check API key", "error", err)
+			}
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		next.ServeHTTP(w, r)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("auth.caller", caller.Name))
+		ctx := logging.WithCaller(r.Context(), caller.Name)
+		h.logger.InfoContext(ctx, "internal API call", "key_id", caller.ID, "method", r.Method, "path", r.URL.Path)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// authenticateAPIKey falls back to the single INTERNAL_API_KEY, still
+// compared in constant time, when no APIKeyService is configured.
+func (h *Handler) authenticateAPIKey(ctx context.Context, key string) (*domain.APIKey, error) {
+	if h.apiKeys != nil {
+		return h.apiKeys.Authenticate(ctx, key)
+	}
+	if key == "" || h.cfg.InternalAPIKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(h.cfg.InternalAPIKey)) != 1 {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	return &domain.APIKey{ID: domain.LegacyAPIKeyName, Name: domain.LegacyAPIKeyName}, nil
+}
+
 func (h *Handler) JWTAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -34,12 +74,143 @@ func (h *Handler) JWTAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		claims, err := h.service.ValidateToken(tokenString)
+		if h.tokens != nil && strings.HasPrefix(tokenString, domain.PersonalAccessTokenPrefix) {
+			user, pat, err := h.tokens.Authenticate(r.Context(), tokenString)
+			if err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("enduser.id", user.ID))
+			ctx := context.WithValue(r.Context(), userIDKey, user.ID)
+			ctx = context.WithValue(ctx, scopesKey, pat.Scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		claims, err := h.service.ValidateToken(r.Context(), tokenString)
 		if err != nil {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
+		if userID, ok := claims["sub"].(string); ok {
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("enduser.id", userID))
+		}
 		ctx := context.WithValue(r.Context(), userIDKey, claims["sub"])
+		if actor, _ := jwt.Actor(claims); actor != nil {
+			// Someone acting as the user may look but not mint credentials
+			// or administer anything.
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("auth.actor", actor.Subject))
+			ctx = context.WithValue(ctx, scopesKey, exchangedTokenScopes)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireScope rejects personal access tokens that were not granted scope.
+// It must run after JWTAuthMiddleware.
+func (h *Handler) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, ok := r.Context().Value(scopesKey).([]string); ok && !slices.Contains(scopes, scope) {
+				status, resp := errorResponse(domain.ErrInsufficientScope)
+				WriteJSON(w, status, resp)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession refuses personal access tokens and exchanged tokens, for
+// actions that hand out a new session. It must run after JWTAuthMiddleware.
+func (h *Handler) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(scopesKey).([]string); ok {
+			status, resp := errorResponse(domain.ErrSessionRequired)
+			WriteJSON(w, status, resp)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin only lets administrators through. Personal access tokens and
+// exchanged tokens are refused whatever their scopes, so admin actions
+// always need a session. It must run after JWTAuthMiddleware.
+func (h *Handler) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(scopesKey).([]string); ok {
+			status, resp := errorResponse(domain.ErrAdminRequired)
+			WriteJSON(w, status, resp)
+			return
+		}
+		userID, _ := r.Context().Value(userIDKey).(string)
+		user, err := h.service.GetProfile(r.Context(), userID)
+		if err != nil {
+			if errors.Is(err, domain.ErrUserNotFound) {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			h.handleError(w, r, err)
+			return
+		}
+		if user.Role != domain.RoleAdmin {
+			status, resp := errorResponse(domain.ErrAdminRequired)
+			WriteJSON(w, status, resp)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimitMiddleware throttles requests per client IP. It is a no-op when
+// rate limiting is disabled in the configuration.
+func (h *Handler) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.limiter != nil && !h.limiter.allow(clientIP(r)) {
+			w.Header().Set("Retry-After", "60")
+			WriteJSON(w, http.StatusTooManyRequests, ErrorResponse{Code: "RATE_LIMITED", Message: domain.ErrRateLimited.Error()})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) CORSMiddleware(next http.Handler) http.Handler {
+	cors := h.cfg.CORS
+	methods := strings.Join(cors.AllowedMethods, ", ")
+	headers := strings.Join(cors.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cors.MaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !originAllowed(cors.AllowedOrigins, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if cors.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
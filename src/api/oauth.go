@@ -0,0 +1,169 @@
+package api
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OAuthErrorResponse is the error body of the OAuth token endpoint, in the
+// format RFC 6749 requires instead of ErrorResponse.
+type OAuthErrorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+type tokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// HandleToken is the OAuth 2.0 token endpoint. Parameters arrive form
+// encoded, and the grant_type picks what is issued.
+func (h *Handler) HandleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		WriteJSON(w, http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_request", Description: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch grantType := r.PostForm.Get("grant_type"); {
+	case grantType == domain.GrantTypeTokenExchange && h.exchange != nil:
+		h.handleTokenExchange(w, r)
+	case grantType == domain.GrantTypeDeviceCode && h.devices != nil:
+		h.handleDeviceCode(w, r)
+	default:
+		h.handleOAuthError(w, r, domain.ErrUnsupportedGrantType)
+	}
+}
+
+// handleTokenExchange serves RFC 8693 token exchange. An internal service
+// identifies itself with X-Internal-Api-Key to act on behalf of the user in
+// subject_token; a user with the permission sends their session as
+// actor_token and the account to act as in requested_subject. Issued tokens
+// name a single configured audience, the one audience or resource ask for
+// or the first by default, so a request naming any other, or several, is
+// invalid_target.
+func (h *Handler) handleTokenExchange(w http.ResponseWriter, r *http.Request) {
+	audience := ""
+	for _, target := range append(r.PostForm["audience"], r.PostForm["resource"]...) {
+		if !slices.Contains(h.cfg.JWT.Audience, target) || (audience != "" && target != audience) {
+			h.handleOAuthError(w, r, fmt.Errorf("Error exchanging token for %q: %w", target, domain.ErrInvalidTarget))
+			return
+		}
+		audience = target
+	}
+
+	req := domain.TokenExchange{
+		SubjectToken:     r.PostForm.Get("subject_token"),
+		SubjectTokenType: r.PostForm.Get("subject_token_type"),
+		ActorToken:       r.PostForm.Get("actor_token"),
+		ActorTokenType:   r.PostForm.Get("actor_token_type"),
+		RequestedSubject: r.PostForm.Get("requested_subject"),
+		Audience:         audience,
+	}
+	if key := r.Header.Get("X-Internal-Api-Key"); key != "" {
+		caller, err := h.authenticateAPIKey(r.Context(), key)
+		if err != nil {
+			h.handleOAuthError(w, r, err)
+			return
+		}
+		req.Caller = caller
+	}
+
+	token, err := h.exchange.Exchange(r.Context(), req)
+	if err != nil {
+		h.handleOAuthError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, tokenExchangeResponse{
+		AccessToken:     token.AccessToken,
+		IssuedTokenType: domain.TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(time.Until(token.ExpiresAt).Seconds()),
+	})
+}
+
+// HandleRevoke is the RFC 7009 revocation endpoint. Holding the token is
+// enough to revoke it, and token_type_hint is ignored since only access
+// tokens exist. An unknown token still gets 200, as the RFC asks.
+func (h *Handler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		WriteJSON(w, http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_request", Description: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		WriteJSON(w, http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_request", Description: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+	if err := h.service.RevokeAccessToken(r.Context(), token); err != nil {
+		h.handleOAuthError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) handleOAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	status, resp := oauthErrorResponse(err)
+	switch {
+	case status == http.StatusInternalServerError:
+		h.logger.ErrorContext(r.Context(), "request failed", "error", err)
+	case errors.Is(err, domain.ErrAuthorizationPending), errors.Is(err, domain.ErrSlowDown):
+		// Devices poll every few seconds until the user decides.
+		h.logger.DebugContext(r.Context(), "token request rejected", "error", err)
+	default:
+		h.logger.InfoContext(r.Context(), "token request rejected", "error", err)
+	}
+	tracing.RecordError(trace.SpanFromContext(r.Context()), err)
+	WriteJSON(w, status, resp)
+}
+
+// oauthErrorResponse maps a service error to an RFC 6749 error code.
+func oauthErrorResponse(err error) (int, OAuthErrorResponse) {
+	switch {
+	case errors.Is(err, domain.ErrUnsupportedGrantType):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "unsupported_grant_type", Description: domain.ErrUnsupportedGrantType.Error()}
+	case errors.Is(err, domain.ErrInvalidExchange):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_request", Description: domain.ErrInvalidExchange.Error()}
+	case errors.Is(err, domain.ErrInvalidAPIKey):
+		return http.StatusUnauthorized, OAuthErrorResponse{Error: "invalid_client", Description: domain.ErrInvalidAPIKey.Error()}
+	case errors.Is(err, domain.ErrExchangeNotPermitted):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "unauthorized_client", Description: domain.ErrExchangeNotPermitted.Error()}
+	case errors.Is(err, domain.ErrUnsupportedTokenType):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "unsupported_token_type", Description: domain.ErrUnsupportedTokenType.Error()}
+	case errors.Is(err, domain.ErrInvalidTarget):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_target", Description: domain.ErrInvalidTarget.Error()}
+	case errors.Is(err, domain.ErrInvalidGrant):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_grant", Description: domain.ErrInvalidGrant.Error()}
+	case errors.Is(err, domain.ErrUserNotFound):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_grant", Description: domain.ErrUserNotFound.Error()}
+	case errors.Is(err, domain.ErrAccountDisabled):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_grant", Description: domain.ErrAccountDisabled.Error()}
+	case errors.Is(err, domain.ErrDeviceCodeInvalid):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_grant", Description: domain.ErrDeviceCodeInvalid.Error()}
+	case errors.Is(err, domain.ErrClientIDRequired):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_request", Description: domain.ErrClientIDRequired.Error()}
+	case errors.Is(err, domain.ErrClientNotFound):
+		return http.StatusUnauthorized, OAuthErrorResponse{Error: "invalid_client", Description: domain.ErrClientNotFound.Error()}
+	case errors.Is(err, domain.ErrAuthorizationPending):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "authorization_pending", Description: domain.ErrAuthorizationPending.Error()}
+	case errors.Is(err, domain.ErrSlowDown):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "slow_down", Description: domain.ErrSlowDown.Error()}
+	case errors.Is(err, domain.ErrAccessDenied):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "access_denied", Description: domain.ErrAccessDenied.Error()}
+	case errors.Is(err, domain.ErrDeviceCodeExpired):
+		return http.StatusBadRequest, OAuthErrorResponse{Error: "expired_token", Description: domain.ErrDeviceCodeExpired.Error()}
+	}
+	return http.StatusInternalServerError, OAuthErrorResponse{Error: "server_error", Description: domain.ErrUnexpected.Error()}
+}
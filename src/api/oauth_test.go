@@ -0,0 +1,254 @@
+package api
+
+import (
+	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/service"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestHandleToken_Impersonation(t *testing.T) {
+	// Arrange
+	exchange := new(service.TokenExchangeServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithTokenExchange(exchange))
+	expected := domain.TokenExchange{ActorToken: "agent-session", ActorTokenType: domain.TokenTypeAccessToken, RequestedSubject: "customer-id"}
+	exchange.On("Exchange", mock.Anything, expected).
+		Return(&domain.ExchangedToken{AccessToken: "exchanged", ExpiresAt: time.Now().Add(10 * time.Minute), UserID: "customer-id"}, nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleToken(rr, newTokenRequest(url.Values{
+		"grant_type":        {domain.GrantTypeTokenExchange},
+		"actor_token":       {"agent-session"},
+		"actor_token_type":  {domain.TokenTypeAccessToken},
+		"requested_subject": {"customer-id"},
+	}))
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	var body tokenExchangeResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "exchanged", body.AccessToken)
+	assert.Equal(t, domain.TokenTypeAccessToken, body.IssuedTokenType)
+	assert.Equal(t, "Bearer", body.TokenType)
+	assert.InDelta(t, 600, body.ExpiresIn, 2)
+}
+
+func TestHandleToken_ExchangeChecksTarget(t *testing.T) {
+	tests := map[string]struct {
+		form     url.Values
+		code     int
+		audience string
+	}{
+		"no target":           {form: url.Values{}, code: http.StatusOK},
+		"configured audience": {form: url.Values{"audience": {"storefront"}}, code: http.StatusOK, audience: "storefront"},
+		"same resource":       {form: url.Values{"audience": {"storefront"}, "resource": {"storefront"}}, code: http.StatusOK, audience: "storefront"},
+		"other audience":      {form: url.Values{"audience": {"billing"}}, code: http.StatusBadRequest},
+		"several audiences":   {form: url.Values{"audience": {"auth-service", "storefront"}}, code: http.StatusBadRequest},
+		"resource":            {form: url.Values{"resource": {"https://billing.example.com"}}, code: http.StatusBadRequest},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			exchange := new(service.TokenExchangeServiceMock)
+			cfg := &config.Config{JWT: config.JWTConfig{Audience: []string{"auth-service", "storefront"}}}
+			handler := NewHandler(new(service.UserServiceMock), cfg, WithTokenExchange(exchange))
+			exchange.On("Exchange", mock.Anything, mock.MatchedBy(func(req domain.TokenExchange) bool { return req.Audience == tt.audience })).
+				Return(&domain.ExchangedToken{AccessToken: "exchanged", ExpiresAt: time.Now().Add(10 * time.Minute), UserID: "customer-id"}, nil)
+			form := url.Values{
+				"grant_type":        {domain.GrantTypeTokenExchange},
+				"actor_token":       {"agent-session"},
+				"actor_token_type":  {domain.TokenTypeAccessToken},
+				"requested_subject": {"customer-id"},
+			}
+			for key, values := range tt.form {
+				form[key] = values
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.HandleToken(rr, newTokenRequest(form))
+
+			// Assert
+			require.Equal(t, tt.code, rr.Code)
+			if tt.code != http.StatusOK {
+				var body OAuthErrorResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				assert.Equal(t, "invalid_target", body.Error)
+				exchange.AssertNotCalled(t, "Exchange", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandleToken_DelegationIdentifiesCallingService(t *testing.T) {
+	// Arrange
+	exchange := new(service.TokenExchangeServiceMock)
+	apiKeys := new(service.APIKeyServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithTokenExchange(exchange), WithAPIKeys(apiKeys))
+	orders := &domain.APIKey{ID: "key-1", Name: "orders"}
+	apiKeys.On("Authenticate", mock.Anything, "ask_orders").Return(orders, nil)
+	apiKeys.On("Authenticate", mock.Anything, "ask_revoked").Return(nil, domain.ErrInvalidAPIKey)
+	exchange.On("Exchange", mock.Anything, domain.TokenExchange{SubjectToken: "user-session", SubjectTokenType: domain.TokenTypeJWT, Caller: orders}).
+		Return(&domain.ExchangedToken{AccessToken: "delegated", ExpiresAt: time.Now().Add(time.Minute)}, nil)
+	form := url.Values{
+		"grant_type":         {domain.GrantTypeTokenExchange},
+		"subject_token":      {"user-session"},
+		"subject_token_type": {domain.TokenTypeJWT},
+	}
+
+	// Act
+	accepted := httptest.NewRecorder()
+	req := newTokenRequest(form)
+	req.Header.Set("X-Internal-Api-Key", "ask_orders")
+	handler.HandleToken(accepted, req)
+
+	rejected := httptest.NewRecorder()
+	req = newTokenRequest(form)
+	req.Header.Set("X-Internal-Api-Key", "ask_revoked")
+	handler.HandleToken(rejected, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, accepted.Code)
+	assert.Equal(t, http.StatusUnauthorized, rejected.Code)
+	var body OAuthErrorResponse
+	require.NoError(t, json.Unmarshal(rejected.Body.Bytes(), &body))
+	assert.Equal(t, "invalid_client", body.Error)
+	exchange.AssertNumberOfCalls(t, "Exchange", 1)
+}
+
+func TestHandleToken_Errors(t *testing.T) {
+	// Arrange
+	exchange := new(service.TokenExchangeServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithTokenExchange(exchange))
+	exchange.On("Exchange", mock.Anything, mock.MatchedBy(func(req domain.TokenExchange) bool { return req.RequestedSubject == "admin-id" })).
+		Return(nil, domain.ErrExchangeNotPermitted)
+	exchange.On("Exchange", mock.Anything, mock.MatchedBy(func(req domain.TokenExchange) bool { return req.RequestedSubject == "" })).
+		Return(nil, domain.ErrInvalidExchange)
+
+	cases := []struct {
+		name   string
+		form   url.Values
+		status int
+		code   string
+	}{
+		{"unknown grant", url.Values{"grant_type": {"password"}}, http.StatusBadRequest, "unsupported_grant_type"},
+		{"not permitted", url.Values{"grant_type": {domain.GrantTypeTokenExchange}, "actor_token": {"t"}, "requested_subject": {"admin-id"}}, http.StatusBadRequest, "unauthorized_client"},
+		{"incomplete", url.Values{"grant_type": {domain.GrantTypeTokenExchange}, "actor_token": {"t"}}, http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.HandleToken(rr, newTokenRequest(tc.form))
+
+			// Assert
+			assert.Equal(t, tc.status, rr.Code)
+			var body OAuthErrorResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.code, body.Error)
+		})
+	}
+}
+
+func TestHandleRevoke(t *testing.T) {
+	// Arrange
+	users := new(service.UserServiceMock)
+	handler := NewHandler(users, &config.Config{})
+	users.On("RevokeAccessToken", mock.Anything, "at_known").Return(nil)
+	users.On("RevokeAccessToken", mock.Anything, "jwt").Return(fmt.Errorf("Error revoking token: %w", domain.ErrUnsupportedTokenType))
+
+	cases := []struct {
+		name   string
+		form   url.Values
+		status int
+		code   string
+	}{
+		{"revoked", url.Values{"token": {"at_known"}, "token_type_hint": {"access_token"}}, http.StatusOK, ""},
+		{"missing token", url.Values{}, http.StatusBadRequest, "invalid_request"},
+		{"not revocable", url.Values{"token": {"jwt"}}, http.StatusBadRequest, "unsupported_token_type"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/oauth/revoke", strings.NewReader(tc.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			// Act
+			handler.HandleRevoke(rr, req)
+
+			// Assert
+			assert.Equal(t, tc.status, rr.Code)
+			if tc.code != "" {
+				var body OAuthErrorResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				assert.Equal(t, tc.code, body.Error)
+			}
+		})
+	}
+	users.AssertExpectations(t)
+}
+
+func TestHandleAuthValidate_ExposesActorChain(t *testing.T) {
+	// Arrange
+	users := new(service.UserServiceMock)
+	handler := NewHandler(users, &config.Config{})
+	act := map[string]interface{}{"sub": "orders", "type": domain.ActorService, "act": map[string]interface{}{"sub": "agent-id", "type": domain.ActorUser}}
+	users.On("ValidateToken", mock.Anything, "exchanged").Return(map[string]interface{}{"sub": "customer-id", "email": "customer@example.com", "act": act}, nil)
+	body, _ := json.Marshal(map[string]string{"token": "exchanged"})
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleAuthValidate(rr, httptest.NewRequest(http.MethodPost, "/auth/validate", bytes.NewReader(body)))
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "customer-id", resp["userId"])
+	assert.Equal(t, act, resp["act"])
+}
+
+func TestJWTAuthMiddleware_LimitsExchangedTokens(t *testing.T) {
+	// Arrange
+	users := new(service.UserServiceMock)
+	router := newTokenRouter(NewHandler(users, &config.Config{}))
+	users.On("ValidateToken", mock.Anything, "exchanged").
+		Return(map[string]interface{}{"sub": "customer-id", "act": map[string]interface{}{"sub": "agent-id", "type": domain.ActorUser}}, nil)
+	users.On("GetProfile", mock.Anything, "customer-id").Return(&domain.User{ID: "customer-id", Name: "Customer"}, nil)
+
+	// Act
+	profile := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.Header.Set("Authorization", "Bearer exchanged")
+	router.ServeHTTP(profile, req)
+
+	create := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/profile/tokens", strings.NewReader(`{"name":"ci","scopes":["profile:read"]}`))
+	req.Header.Set("Authorization", "Bearer exchanged")
+	router.ServeHTTP(create, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, profile.Code)
+	assert.Equal(t, http.StatusForbidden, create.Code)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"auth-service/src/domain"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createdPersonalAccessToken struct {
+	Token string `json:"token"`
+	*domain.PersonalAccessToken
+}
+
+// HandleCreatePersonalAccessToken returns the token in the response body; it
+// is not stored and cannot be shown again. A caller using a personal access
+// token cannot create one with scopes it does not hold itself.
+func (h *Handler) HandleCreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "INVALID_REQUEST_BODY", Message: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+	if held, ok := r.Context().Value(scopesKey).([]string); ok {
+		for _, scope := range req.Scopes {
+			if !slices.Contains(held, scope) {
+				h.handleError(w, r, domain.ErrInsufficientScope)
+				return
+			}
+		}
+	}
+
+	userID := r.Context().Value(userIDKey).(string)
+	token, pat, err := h.tokens.Create(r.Context(), userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusCreated, createdPersonalAccessToken{Token: token, PersonalAccessToken: pat})
+}
+
+func (h *Handler) HandleListPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(userIDKey).(string)
+	tokens, err := h.tokens.List(r.Context(), userID)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+}
+
+func (h *Handler) HandleRevokePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(userIDKey).(string)
+	if err := h.tokens.Revoke(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
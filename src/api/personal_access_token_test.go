@@ -0,0 +1,197 @@
+package api
+
+import (
+	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/service"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTokenRouter mounts the routes the way the server does.
+func newTokenRouter(handler *Handler) http.Handler {
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(handler.JWTAuthMiddleware)
+		r.With(handler.RequireScope(domain.ScopeProfileRead)).Get("/profile", handler.HandleGetProfile)
+		r.With(handler.RequireScope(domain.ScopeTokensRead)).Get("/profile/tokens", handler.HandleListPersonalAccessTokens)
+		r.With(handler.RequireScope(domain.ScopeTokensWrite)).Post("/profile/tokens", handler.HandleCreatePersonalAccessToken)
+		r.With(handler.RequireScope(domain.ScopeTokensWrite)).Delete("/profile/tokens/{id}", handler.HandleRevokePersonalAccessToken)
+	})
+	return router
+}
+
+func TestJWTAuthMiddleware_AcceptsPersonalAccessTokenWithScope(t *testing.T) {
+	// Arrange
+	users := new(service.UserServiceMock)
+	tokens := new(service.PersonalAccessTokenServiceMock)
+	router := newTokenRouter(NewHandler(users, &config.Config{}, WithPersonalAccessTokens(tokens)))
+	tokens.On("Authenticate", mock.Anything, "asp_read").
+		Return(&domain.User{ID: "user-123"}, &domain.PersonalAccessToken{Scopes: []string{domain.ScopeProfileRead}}, nil)
+	users.On("GetProfile", mock.Anything, "user-123").Return(&domain.User{ID: "user-123", Name: "Dev"}, nil)
+
+	// Act
+	profile := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.Header.Set("Authorization", "Bearer asp_read")
+	router.ServeHTTP(profile, req)
+
+	list := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/profile/tokens", nil)
+	req.Header.Set("Authorization", "Bearer asp_read")
+	router.ServeHTTP(list, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, profile.Code)
+	assert.Equal(t, http.StatusForbidden, list.Code)
+	var body ErrorResponse
+	json.Unmarshal(list.Body.Bytes(), &body)
+	assert.Equal(t, "INSUFFICIENT_SCOPE", body.Code)
+	users.AssertNotCalled(t, "ValidateToken", mock.Anything, mock.Anything)
+}
+
+func TestJWTAuthMiddleware_RejectsInvalidPersonalAccessToken(t *testing.T) {
+	// Arrange
+	tokens := new(service.PersonalAccessTokenServiceMock)
+	router := newTokenRouter(NewHandler(new(service.UserServiceMock), &config.Config{}, WithPersonalAccessTokens(tokens)))
+	tokens.On("Authenticate", mock.Anything, "asp_revoked").Return(nil, nil, domain.ErrInvalidToken)
+
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.Header.Set("Authorization", "Bearer asp_revoked")
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestHandleCreatePersonalAccessToken_ReturnsTokenOnce(t *testing.T) {
+	// Arrange
+	users := new(service.UserServiceMock)
+	tokens := new(service.PersonalAccessTokenServiceMock)
+	router := newTokenRouter(NewHandler(users, &config.Config{}, WithPersonalAccessTokens(tokens)))
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	users.On("ValidateToken", mock.Anything, "session-jwt").Return(map[string]interface{}{"sub": "user-123"}, nil)
+	tokens.On("Create", mock.Anything, "user-123", "ci", []string{domain.ScopeProfileRead}, &expiresAt).
+		Return("asp_secret", &domain.PersonalAccessToken{ID: "pat-1", UserID: "user-123", Name: "ci", TokenHash: "hash", Scopes: []string{domain.ScopeProfileRead}, ExpiresAt: expiresAt}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/profile/tokens", bytes.NewBufferString(`{"name": "ci", "scopes": ["profile:read"], "expiresAt": "2030-01-01T00:00:00Z"}`))
+	req.Header.Set("Authorization", "Bearer session-jwt")
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "asp_secret", body["token"])
+	assert.Equal(t, "pat-1", body["id"])
+	assert.NotContains(t, body, "tokenHash")
+	tokens.AssertExpectations(t)
+}
+
+func TestHandleCreatePersonalAccessToken_CannotWidenScopes(t *testing.T) {
+	// Arrange
+	tokens := new(service.PersonalAccessTokenServiceMock)
+	router := newTokenRouter(NewHandler(new(service.UserServiceMock), &config.Config{}, WithPersonalAccessTokens(tokens)))
+	tokens.On("Authenticate", mock.Anything, "asp_writer").
+		Return(&domain.User{ID: "user-123"}, &domain.PersonalAccessToken{Scopes: []string{domain.ScopeTokensWrite}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/profile/tokens", bytes.NewBufferString(`{"name": "ci", "scopes": ["profile:read"]}`))
+	req.Header.Set("Authorization", "Bearer asp_writer")
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	tokens.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleRevokePersonalAccessToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "revoked", status: http.StatusNoContent},
+		{name: "unknown token", err: domain.ErrTokenNotFound, status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			users := new(service.UserServiceMock)
+			tokens := new(service.PersonalAccessTokenServiceMock)
+			router := newTokenRouter(NewHandler(users, &config.Config{}, WithPersonalAccessTokens(tokens)))
+			users.On("ValidateToken", mock.Anything, "session-jwt").Return(map[string]interface{}{"sub": "user-123"}, nil)
+			tokens.On("Revoke", mock.Anything, "user-123", "pat-1").Return(tt.err)
+
+			req := httptest.NewRequest(http.MethodDelete, "/profile/tokens/pat-1", nil)
+			req.Header.Set("Authorization", "Bearer session-jwt")
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, tt.status, rr.Code)
+		})
+	}
+}
+
+func TestHandleAuthValidate_PersonalAccessToken(t *testing.T) {
+	// Arrange
+	tokens := new(service.PersonalAccessTokenServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithPersonalAccessTokens(tokens))
+	tokens.On("Authenticate", mock.Anything, "asp_read").
+		Return(&domain.User{ID: "user-123", Email: "dev@example.com"}, &domain.PersonalAccessToken{Scopes: []string{domain.ScopeProfileRead}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/validate", bytes.NewBufferString(`{"token": "asp_read"}`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleAuthValidate(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, true, body["valid"])
+	assert.Equal(t, "user-123", body["userId"])
+	assert.Equal(t, "personal_access_token", body["tokenType"])
+	assert.Equal(t, []interface{}{domain.ScopeProfileRead}, body["scopes"])
+}
+
+func TestHandleAuthValidate_PersonalAccessTokenWithAudience(t *testing.T) {
+	// Arrange
+	tokens := new(service.PersonalAccessTokenServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithPersonalAccessTokens(tokens))
+	tokens.On("Authenticate", mock.Anything, "asp_read").
+		Return(&domain.User{ID: "user-123", Email: "dev@example.com"}, &domain.PersonalAccessToken{Scopes: []string{domain.ScopeProfileRead}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/validate", bytes.NewBufferString(`{"token": "asp_read", "audience": "tv-app"}`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleAuthValidate(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, map[string]interface{}{"valid": false, "error": "INVALID_AUDIENCE"}, body)
+}
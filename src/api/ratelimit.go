@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const rateLimiterIdleTTL = 10 * time.Minute
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipRateLimiter keeps one token bucket per client IP and forgets clients
+// that have been idle for a while.
+type ipRateLimiter struct {
+	mu        sync.Mutex
+	visitors  map[string]*visitor
+	limit     rate.Limit
+	burst     int
+	lastSweep time.Time
+}
+
+func newIPRateLimiter(requestsPerMinute, burst int) *ipRateLimiter {
+	return &ipRateLimiter{
+		visitors:  make(map[string]*visitor),
+		limit:     rate.Limit(float64(requestsPerMinute) / 60),
+		burst:     burst,
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *ipRateLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > rateLimiterIdleTTL {
+		for k, v := range l.visitors {
+			if now.Sub(v.lastSeen) > rateLimiterIdleTTL {
+				delete(l.visitors, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	v, ok := l.visitors[key]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.visitors[key] = v
+	}
+	v.lastSeen = now
+	return v.limiter.Allow()
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/service"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	samlLoginCookie     = "saml_login"
+	samlLoginCookiePath = "/saml/"
+	samlLoginCookieTTL  = 10 * time.Minute
+	// samlMaxResponseSize bounds the posted form; real responses stay far
+	// below it even with many group attributes.
+	samlMaxResponseSize = 1 << 20
+)
+
+// HandleSAMLMetadata serves our SP metadata, for the IdP administrator.
+func (h *Handler) HandleSAMLMetadata(w http.ResponseWriter, r *http.Request) {
+	metadata, err := h.saml.Metadata(r.Context(), chi.URLParam(r, "idp"))
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(metadata)
+}
+
+// HandleSAMLLogin redirects the browser to the IdP with an AuthnRequest,
+// keeping its ID and the audience query parameter in a short-lived cookie
+// for the ACS.
+func (h *Handler) HandleSAMLLogin(w http.ResponseWriter, r *http.Request) {
+	audience := r.URL.Query().Get("audience")
+	if err := h.checkAudience(audience); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	authURL, pending, err := h.saml.Start(r.Context(), chi.URLParam(r, "idp"), audience)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	value, err := json.Marshal(pending)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     samlLoginCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     samlLoginCookiePath,
+		MaxAge:   int(samlLoginCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		// None, not Lax: the IdP returns the browser with a cross-site POST,
+		// which Lax cookies are not sent with.
+		SameSite: http.SameSiteNoneMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// HandleSAMLACS is the Assertion Consumer Service. With a success URL
+// configured the browser is sent back to the storefront with the token, or
+// an error code, in the URL fragment; otherwise the result is JSON.
+func (h *Handler) HandleSAMLACS(w http.ResponseWriter, r *http.Request) {
+	idp := chi.URLParam(r, "idp")
+	pending := readPendingSAMLLogin(r)
+	http.SetCookie(w, &http.Cookie{Name: samlLoginCookie, Path: samlLoginCookiePath, MaxAge: -1, HttpOnly: true, Secure: true, SameSite: http.SameSiteNoneMode})
+
+	r.Body = http.MaxBytesReader(w, r.Body, samlMaxResponseSize)
+	if err := r.ParseForm(); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "INVALID_REQUEST_BODY", Message: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+	token, err := h.saml.Consume(r.Context(), idp, r.PostForm.Get("SAMLResponse"), pending)
+	// 303 turns the POST into a GET on the storefront.
+	h.writeLoginResult(w, r, h.cfg.SAML.SuccessURL, http.StatusSeeOther, token, err)
+}
+
+func readPendingSAMLLogin(r *http.Request) *service.PendingSAMLLogin {
+	cookie, err := r.Cookie(samlLoginCookie)
+	if err != nil {
+		return nil
+	}
+	value, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	var pending service.PendingSAMLLogin
+	if err := json.Unmarshal(value, &pending); err != nil {
+		return nil
+	}
+	return &pending
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/service"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func withIdP(req *http.Request, idp string) *http.Request {
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("idp", idp)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+}
+
+func postSAMLResponse(response string) *http.Request {
+	form := url.Values{"SAMLResponse": {response}}
+	req := httptest.NewRequest(http.MethodPost, "/saml/okta/acs", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return withIdP(req, "okta")
+}
+
+func TestHandleSAMLMetadata_ServesXML(t *testing.T) {
+	// Arrange
+	saml := new(service.SAMLServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSAML(saml))
+	saml.On("Metadata", mock.Anything, "okta").Return([]byte("<EntityDescriptor/>"), nil)
+
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSAMLMetadata(rr, withIdP(httptest.NewRequest(http.MethodGet, "/saml/okta/metadata", nil), "okta"))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/samlmetadata+xml", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "<EntityDescriptor/>", rr.Body.String())
+}
+
+func TestHandleSAMLLogin_RedirectsAndSetsCookie(t *testing.T) {
+	// Arrange
+	saml := new(service.SAMLServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSAML(saml))
+	pending := &service.PendingSAMLLogin{Provider: "okta", RequestID: "id-1"}
+	saml.On("Start", mock.Anything, "okta", "").Return("https://acme.okta.com/sso?SAMLRequest=x", pending, nil)
+
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSAMLLogin(rr, withIdP(httptest.NewRequest(http.MethodGet, "/saml/okta/login", nil), "okta"))
+
+	// Assert
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://acme.okta.com/sso?SAMLRequest=x", rr.Header().Get("Location"))
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, samlLoginCookie, cookies[0].Name)
+	assert.Equal(t, http.SameSiteNoneMode, cookies[0].SameSite)
+	assert.True(t, cookies[0].Secure)
+
+	acs := postSAMLResponse("response")
+	acs.AddCookie(cookies[0])
+	assert.Equal(t, pending, readPendingSAMLLogin(acs))
+}
+
+func TestHandleSAMLACS_ReturnsTokenAsJSON(t *testing.T) {
+	// Arrange
+	saml := new(service.SAMLServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSAML(saml))
+	pending := &service.PendingSAMLLogin{Provider: "okta", RequestID: "id-1"}
+	saml.On("Consume", mock.Anything, "okta", "response", pending).Return("jwt-token", nil)
+
+	value, err := json.Marshal(pending)
+	require.NoError(t, err)
+	req := postSAMLResponse("response")
+	req.AddCookie(&http.Cookie{Name: samlLoginCookie, Value: base64.RawURLEncoding.EncodeToString(value)})
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSAMLACS(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &body)
+	assert.Equal(t, "jwt-token", body["token"])
+	saml.AssertExpectations(t)
+}
+
+func TestHandleSAMLACS_RejectedResponse(t *testing.T) {
+	// Arrange
+	saml := new(service.SAMLServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSAML(saml))
+	saml.On("Consume", mock.Anything, "okta", "forged", (*service.PendingSAMLLogin)(nil)).Return("", domain.ErrSAMLLoginFailed)
+
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSAMLACS(rr, postSAMLResponse("forged"))
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	var errorResponse ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	assert.Equal(t, "SAML_LOGIN_FAILED", errorResponse.Code)
+}
+
+func TestHandleSAMLACS_RedirectsToSuccessURL(t *testing.T) {
+	// Arrange
+	saml := new(service.SAMLServiceMock)
+	cfg := &config.Config{SAML: config.SAMLConfig{SuccessURL: "https://shop.example.com/signed-in"}}
+	handler := NewHandler(new(service.UserServiceMock), cfg, WithSAML(saml))
+	saml.On("Consume", mock.Anything, "okta", "response", (*service.PendingSAMLLogin)(nil)).Return("jwt-token", nil)
+
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSAMLACS(rr, postSAMLResponse("response"))
+
+	// Assert
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	location, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "shop.example.com", location.Host)
+	assert.Equal(t, "token=jwt-token", location.Fragment)
+}
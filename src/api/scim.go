@@ -0,0 +1,328 @@
+package api
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/scim"
+	"auth-service/src/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	scimTenantKey contextKey = "scimTenant"
+
+	// SCIMBasePath is where the SCIM endpoints are mounted.
+	SCIMBasePath = "/scim/v2"
+
+	scimMaxResults = 100
+)
+
+// SCIMAuthMiddleware authenticates the tenant's bearer token and stores the
+// tenant ID for the handlers.
+func (h *Handler) SCIMAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeSCIMError(w, http.StatusUnauthorized, "", domain.ErrInvalidToken.Error())
+			return
+		}
+		tenant, err := h.scim.Authenticate(r.Context(), token)
+		if err != nil {
+			if !errors.Is(err, domain.ErrInvalidToken) {
+				h.handleSCIMError(w, r, err)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim", error="invalid_token"`)
+			writeSCIMError(w, http.StatusUnauthorized, "", domain.ErrInvalidToken.Error())
+			return
+		}
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("scim.tenant.id", tenant.ID))
+		ctx := context.WithValue(r.Context(), scimTenantKey, tenant.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (h *Handler) HandleSCIMServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	supported := func(v bool) map[string]bool { return map[string]bool{"supported": v} }
+	writeSCIM(w, http.StatusOK, map[string]any{
+		"schemas":        []string{scim.ServiceProviderConfigSchema},
+		"patch":          supported(true),
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": supported(true),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Per-tenant token issued with 'auth-service scim create-tenant'",
+			"primary":     true,
+		}},
+	})
+}
+
+func (h *Handler) HandleSCIMListUsers(w http.ResponseWriter, r *http.Request) {
+	filter, startIndex, count, err := listParams(r)
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	list, err := h.scim.ListUsers(r.Context(), scimTenant(r), filter, startIndex, count)
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	for _, user := range list.Resources {
+		setUserLocation(user)
+	}
+	writeSCIM(w, http.StatusOK, list)
+}
+
+func (h *Handler) HandleSCIMCreateUser(w http.ResponseWriter, r *http.Request) {
+	var req scim.User
+	if !decodeSCIM(w, r, &req) {
+		return
+	}
+	user, err := h.scim.CreateUser(r.Context(), scimTenant(r), &req)
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	setUserLocation(user)
+	w.Header().Set("Location", user.Meta.Location)
+	writeSCIM(w, http.StatusCreated, user)
+}
+
+func (h *Handler) HandleSCIMGetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.scim.GetUser(r.Context(), scimTenant(r), chi.URLParam(r, "id"))
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	setUserLocation(user)
+	writeSCIM(w, http.StatusOK, user)
+}
+
+func (h *Handler) HandleSCIMReplaceUser(w http.ResponseWriter, r *http.Request) {
+	var req scim.User
+	if !decodeSCIM(w, r, &req) {
+		return
+	}
+	user, err := h.scim.ReplaceUser(r.Context(), scimTenant(r), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	setUserLocation(user)
+	writeSCIM(w, http.StatusOK, user)
+}
+
+func (h *Handler) HandleSCIMPatchUser(w http.ResponseWriter, r *http.Request) {
+	var req scim.PatchRequest
+	if !decodeSCIM(w, r, &req) {
+		return
+	}
+	user, err := h.scim.PatchUser(r.Context(), scimTenant(r), chi.URLParam(r, "id"), req.Operations)
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	setUserLocation(user)
+	writeSCIM(w, http.StatusOK, user)
+}
+
+func (h *Handler) HandleSCIMDeleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.scim.DeleteUser(r.Context(), scimTenant(r), chi.URLParam(r, "id")); err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) HandleSCIMListGroups(w http.ResponseWriter, r *http.Request) {
+	filter, startIndex, count, err := listParams(r)
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	list, err := h.scim.ListGroups(r.Context(), scimTenant(r), filter, startIndex, count)
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	for _, group := range list.Resources {
+		setGroupLocation(group)
+	}
+	writeSCIM(w, http.StatusOK, list)
+}
+
+func (h *Handler) HandleSCIMCreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req scim.Group
+	if !decodeSCIM(w, r, &req) {
+		return
+	}
+	group, err := h.scim.CreateGroup(r.Context(), scimTenant(r), &req)
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	setGroupLocation(group)
+	w.Header().Set("Location", group.Meta.Location)
+	writeSCIM(w, http.StatusCreated, group)
+}
+
+func (h *Handler) HandleSCIMGetGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := h.scim.GetGroup(r.Context(), scimTenant(r), chi.URLParam(r, "id"))
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	setGroupLocation(group)
+	writeSCIM(w, http.StatusOK, group)
+}
+
+func (h *Handler) HandleSCIMReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	var req scim.Group
+	if !decodeSCIM(w, r, &req) {
+		return
+	}
+	group, err := h.scim.ReplaceGroup(r.Context(), scimTenant(r), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	setGroupLocation(group)
+	writeSCIM(w, http.StatusOK, group)
+}
+
+func (h *Handler) HandleSCIMPatchGroup(w http.ResponseWriter, r *http.Request) {
+	var req scim.PatchRequest
+	if !decodeSCIM(w, r, &req) {
+		return
+	}
+	group, err := h.scim.PatchGroup(r.Context(), scimTenant(r), chi.URLParam(r, "id"), req.Operations)
+	if err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	setGroupLocation(group)
+	writeSCIM(w, http.StatusOK, group)
+}
+
+func (h *Handler) HandleSCIMDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.scim.DeleteGroup(r.Context(), scimTenant(r), chi.URLParam(r, "id")); err != nil {
+		h.handleSCIMError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func scimTenant(r *http.Request) string {
+	tenantID, _ := r.Context().Value(scimTenantKey).(string)
+	return tenantID
+}
+
+// listParams reads filter, startIndex and count. Out of range paging values
+// are clamped as RFC 7644 asks, rather than rejected.
+func listParams(r *http.Request) (*scim.Filter, int, int, error) {
+	query := r.URL.Query()
+	filter, err := scim.ParseFilter(query.Get("filter"))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	startIndex, err := strconv.Atoi(query.Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil || count > scimMaxResults {
+		count = scimMaxResults
+	}
+	if count < 0 {
+		count = 0
+	}
+	return filter, startIndex, count, nil
+}
+
+func setUserLocation(user *scim.User) {
+	if user.Meta != nil {
+		user.Meta.Location = SCIMBasePath + "/Users/" + user.ID
+	}
+}
+
+func setGroupLocation(group *scim.Group) {
+	if group.Meta != nil {
+		group.Meta.Location = SCIMBasePath + "/Groups/" + group.ID
+	}
+}
+
+func decodeSCIM(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", domain.ErrInvalidRequestBody.Error())
+		return false
+	}
+	return true
+}
+
+func (h *Handler) handleSCIMError(w http.ResponseWriter, r *http.Request, err error) {
+	h.logger.ErrorContext(r.Context(), "SCIM request failed", "error", err)
+	tracing.RecordError(trace.SpanFromContext(r.Context()), err)
+
+	status, scimType, detail := scimErrorResponse(err)
+	writeSCIMError(w, status, scimType, detail)
+}
+
+// scimErrorResponse maps a service error to the status and scimType of
+// RFC 7644 section 3.12.
+func scimErrorResponse(err error) (int, string, string) {
+	for _, e := range []struct {
+		err      error
+		status   int
+		scimType string
+	}{
+		{domain.ErrUserNotFound, http.StatusNotFound, ""},
+		{domain.ErrSCIMGroupNotFound, http.StatusNotFound, ""},
+		{domain.ErrSCIMUserExists, http.StatusConflict, "uniqueness"},
+		{domain.ErrEmailAlreadyExists, http.StatusConflict, "uniqueness"},
+		{domain.ErrSCIMGroupExists, http.StatusConflict, "uniqueness"},
+		{scim.ErrInvalidFilter, http.StatusBadRequest, "invalidFilter"},
+		{scim.ErrInvalidPath, http.StatusBadRequest, "invalidPath"},
+		{scim.ErrInvalidSyntax, http.StatusBadRequest, "invalidSyntax"},
+		{scim.ErrInvalidValue, http.StatusBadRequest, "invalidValue"},
+		{domain.ErrInvalidEmail, http.StatusBadRequest, "invalidValue"},
+		{domain.ErrParametersMissing, http.StatusBadRequest, "invalidValue"},
+		{domain.ErrPasswordTooShort, http.StatusBadRequest, "invalidValue"},
+	} {
+		if errors.Is(err, e.err) {
+			return e.status, e.scimType, err.Error()
+		}
+	}
+	return http.StatusInternalServerError, "", domain.ErrUnexpected.Error()
+}
+
+func writeSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	writeSCIM(w, status, scim.Error{
+		Schemas:  []string{scim.ErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+func writeSCIM(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to write SCIM response", "error", err)
+	}
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/scim"
+	"auth-service/src/service"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// scimRequest builds a request that already passed SCIMAuthMiddleware.
+func scimRequest(method, target, body, id string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", scim.ContentType)
+	routeCtx := chi.NewRouteContext()
+	if id != "" {
+		routeCtx.URLParams.Add("id", id)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx)
+	ctx = context.WithValue(ctx, scimTenantKey, "tenant-1")
+	return req.WithContext(ctx)
+}
+
+func scimUser(id, userName string) *scim.User {
+	active := true
+	return &scim.User{
+		Schemas:  []string{scim.UserSchema},
+		ID:       id,
+		UserName: userName,
+		Active:   &active,
+		Meta:     &scim.Meta{ResourceType: "User", Created: time.Now(), LastModified: time.Now()},
+	}
+}
+
+func TestSCIMAuthMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		header string
+		status int
+	}{
+		"valid token":   {header: "Bearer scim_good", status: http.StatusOK},
+		"invalid token": {header: "Bearer scim_bad", status: http.StatusUnauthorized},
+		"missing token": {header: "", status: http.StatusUnauthorized},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			svc := new(service.SCIMServiceMock)
+			svc.On("Authenticate", mock.Anything, "scim_good").Return(&domain.SCIMTenant{ID: "tenant-1"}, nil)
+			svc.On("Authenticate", mock.Anything, "scim_bad").Return(nil, fmt.Errorf("wrapped: %w", domain.ErrInvalidToken))
+			handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSCIM(svc))
+
+			var tenantID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { tenantID = scimTenant(r) })
+			req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.SCIMAuthMiddleware(next).ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, tt.status, rr.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, "tenant-1", tenantID)
+				return
+			}
+			assert.Equal(t, scim.ContentType, rr.Header().Get("Content-Type"))
+			assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Bearer")
+			var body scim.Error
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, "401", body.Status)
+		})
+	}
+}
+
+func TestHandleSCIMListUsers_FilterAndPaging(t *testing.T) {
+	// Arrange
+	svc := new(service.SCIMServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSCIM(svc))
+	list := scim.NewListResponse([]*scim.User{scimUser("user-1", "ana@example.com")}, 1, 1)
+	svc.On("ListUsers", mock.Anything, "tenant-1", &scim.Filter{Attribute: "userName", Value: "ana@example.com"}, 1, 100).Return(list, nil)
+
+	req := scimRequest(http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22ana%40example.com%22&startIndex=0&count=500`, "", "")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSCIMListUsers(rr, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body struct {
+		Schemas      []string    `json:"schemas"`
+		TotalResults int         `json:"totalResults"`
+		Resources    []scim.User `json:"Resources"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, []string{scim.ListResponseSchema}, body.Schemas)
+	assert.Equal(t, 1, body.TotalResults)
+	assert.Equal(t, "/scim/v2/Users/user-1", body.Resources[0].Meta.Location)
+}
+
+func TestHandleSCIMListUsers_UnsupportedFilter(t *testing.T) {
+	// Arrange
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSCIM(new(service.SCIMServiceMock)))
+	req := scimRequest(http.MethodGet, `/scim/v2/Users?filter=userName+sw+%22ana%22`, "", "")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSCIMListUsers(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body scim.Error
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "invalidFilter", body.ScimType)
+}
+
+func TestHandleSCIMCreateUser(t *testing.T) {
+	// Arrange
+	svc := new(service.SCIMServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSCIM(svc))
+	svc.On("CreateUser", mock.Anything, "tenant-1", mock.MatchedBy(func(u *scim.User) bool {
+		return u.UserName == "ana@example.com" && u.ExternalID == "ext-1"
+	})).Return(scimUser("user-1", "ana@example.com"), nil)
+
+	req := scimRequest(http.MethodPost, "/scim/v2/Users", `{"schemas":["`+scim.UserSchema+`"],"userName":"ana@example.com","externalId":"ext-1"}`, "")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSCIMCreateUser(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "/scim/v2/Users/user-1", rr.Header().Get("Location"))
+	assert.Equal(t, scim.ContentType, rr.Header().Get("Content-Type"))
+}
+
+func TestHandleSCIMCreateUser_Errors(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		status   int
+		scimType string
+	}{
+		"already provisioned": {err: domain.ErrSCIMUserExists, status: http.StatusConflict, scimType: "uniqueness"},
+		"email taken":         {err: domain.ErrEmailAlreadyExists, status: http.StatusConflict, scimType: "uniqueness"},
+		"invalid email":       {err: domain.ErrInvalidEmail, status: http.StatusBadRequest, scimType: "invalidValue"},
+		"unexpected":          {err: fmt.Errorf("connection refused"), status: http.StatusInternalServerError},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			svc := new(service.SCIMServiceMock)
+			handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSCIM(svc))
+			svc.On("CreateUser", mock.Anything, "tenant-1", mock.Anything).Return(nil, tt.err)
+			req := scimRequest(http.MethodPost, "/scim/v2/Users", `{"userName":"ana@example.com"}`, "")
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.HandleSCIMCreateUser(rr, req)
+
+			// Assert
+			assert.Equal(t, tt.status, rr.Code)
+			var body scim.Error
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tt.scimType, body.ScimType)
+			assert.Equal(t, fmt.Sprint(tt.status), body.Status)
+		})
+	}
+}
+
+func TestHandleSCIMPatchUser(t *testing.T) {
+	// Arrange
+	svc := new(service.SCIMServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSCIM(svc))
+	svc.On("PatchUser", mock.Anything, "tenant-1", "user-1", mock.MatchedBy(func(ops []scim.PatchOperation) bool {
+		return len(ops) == 1 && ops[0].Op == "Replace" && ops[0].Path == "active"
+	})).Return(scimUser("user-1", "ana@example.com"), nil)
+
+	body := `{"schemas":["` + scim.PatchOpSchema + `"],"Operations":[{"op":"Replace","path":"active","value":"False"}]}`
+	req := scimRequest(http.MethodPatch, "/scim/v2/Users/user-1", body, "user-1")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSCIMPatchUser(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	svc.AssertExpectations(t)
+}
+
+func TestHandleSCIMDeleteUser(t *testing.T) {
+	// Arrange
+	svc := new(service.SCIMServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSCIM(svc))
+	svc.On("DeleteUser", mock.Anything, "tenant-1", "user-1").Return(nil)
+	svc.On("DeleteUser", mock.Anything, "tenant-1", "user-2").Return(domain.ErrUserNotFound)
+
+	// Act
+	deleted := httptest.NewRecorder()
+	handler.HandleSCIMDeleteUser(deleted, scimRequest(http.MethodDelete, "/scim/v2/Users/user-1", "", "user-1"))
+	missing := httptest.NewRecorder()
+	handler.HandleSCIMDeleteUser(missing, scimRequest(http.MethodDelete, "/scim/v2/Users/user-2", "", "user-2"))
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, deleted.Code)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+}
+
+func TestHandleSCIMCreateGroup_InvalidBody(t *testing.T) {
+	// Arrange
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSCIM(new(service.SCIMServiceMock)))
+	req := scimRequest(http.MethodPost, "/scim/v2/Groups", `{"displayName":`, "")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSCIMCreateGroup(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body scim.Error
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "invalidSyntax", body.ScimType)
+}
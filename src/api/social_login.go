@@ -0,0 +1,116 @@
+package api
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/service"
+	"auth-service/src/tracing"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	socialLoginCookie     = "social_login"
+	socialLoginCookiePath = "/auth/"
+	socialLoginCookieTTL  = 10 * time.Minute
+)
+
+// HandleSocialLoginStart redirects the browser to the provider, keeping the
+// state, PKCE verifier, nonce and the audience query parameter in a
+// short-lived cookie for the callback.
+func (h *Handler) HandleSocialLoginStart(w http.ResponseWriter, r *http.Request) {
+	audience := r.URL.Query().Get("audience")
+	if err := h.checkAudience(audience); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	authURL, pending, err := h.social.Start(r.Context(), chi.URLParam(r, "provider"), audience)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	value, err := json.Marshal(pending)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     socialLoginCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     socialLoginCookiePath,
+		MaxAge:   int(socialLoginCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		// Lax, not Strict: the callback is a top-level navigation coming
+		// from the provider's site.
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// HandleSocialLoginCallback finishes the login. With a success URL
+// configured the browser is sent back to the storefront with the token, or
+// an error code, in the URL fragment; otherwise the result is JSON.
+func (h *Handler) HandleSocialLoginCallback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	http.SetCookie(w, &http.Cookie{Name: socialLoginCookie, Path: socialLoginCookiePath, MaxAge: -1, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode})
+
+	query := r.URL.Query()
+	var token string
+	var err error
+	if providerErr := query.Get("error"); providerErr != "" {
+		err = fmt.Errorf("Error from %s: %s: %w", provider, providerErr, domain.ErrSocialLoginFailed)
+	} else {
+		token, err = h.social.Callback(r.Context(), provider, query.Get("code"), query.Get("state"), readPendingLogin(r))
+	}
+
+	h.writeLoginResult(w, r, h.cfg.Social.SuccessURL, http.StatusFound, token, err)
+}
+
+// writeLoginResult ends a browser login. Without a success URL the result is
+// JSON; with one the browser is redirected there with the token, or the
+// error code, in the URL fragment, so it never reaches server logs.
+func (h *Handler) writeLoginResult(w http.ResponseWriter, r *http.Request, successURL string, status int, token string, err error) {
+	if successURL == "" {
+		if err != nil {
+			h.handleError(w, r, err)
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]string{"token": token})
+		return
+	}
+
+	fragment := url.Values{}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "request failed", "error", err)
+		tracing.RecordError(trace.SpanFromContext(r.Context()), err)
+		_, resp := errorResponse(err)
+		fragment.Set("error", resp.Code)
+	} else {
+		fragment.Set("token", token)
+	}
+	http.Redirect(w, r, successURL+"#"+fragment.Encode(), status)
+}
+
+func readPendingLogin(r *http.Request) *service.PendingLogin {
+	cookie, err := r.Cookie(socialLoginCookie)
+	if err != nil {
+		return nil
+	}
+	value, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	var pending service.PendingLogin
+	if err := json.Unmarshal(value, &pending); err != nil {
+		return nil
+	}
+	return &pending
+}
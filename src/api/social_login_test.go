@@ -0,0 +1,139 @@
+package api
+
+import (
+	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/service"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func withProvider(req *http.Request, provider string) *http.Request {
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("provider", provider)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+}
+
+func pendingCookie(t *testing.T, pending *service.PendingLogin) *http.Cookie {
+	value, err := json.Marshal(pending)
+	require.NoError(t, err)
+	return &http.Cookie{Name: socialLoginCookie, Value: base64.RawURLEncoding.EncodeToString(value)}
+}
+
+func TestHandleSocialLoginStart_RedirectsAndSetsCookie(t *testing.T) {
+	// Arrange
+	social := new(service.SocialLoginServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSocialLogin(social))
+	pending := &service.PendingLogin{Provider: "google", State: "state-1", Verifier: "verifier-1", Nonce: "nonce-1"}
+	social.On("Start", mock.Anything, "google", "").Return("https://idp.example.com/authorize?state=state-1", pending, nil)
+
+	req := withProvider(httptest.NewRequest(http.MethodGet, "/auth/google/start", nil), "google")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSocialLoginStart(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://idp.example.com/authorize?state=state-1", rr.Header().Get("Location"))
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, socialLoginCookie, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+
+	callback := httptest.NewRequest(http.MethodGet, "/auth/google/callback", nil)
+	callback.AddCookie(cookies[0])
+	assert.Equal(t, pending, readPendingLogin(callback))
+}
+
+func TestHandleSocialLoginStart_UnknownProvider(t *testing.T) {
+	// Arrange
+	social := new(service.SocialLoginServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSocialLogin(social))
+	social.On("Start", mock.Anything, "myspace", "").Return("", nil, domain.ErrUnknownProvider)
+
+	req := withProvider(httptest.NewRequest(http.MethodGet, "/auth/myspace/start", nil), "myspace")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSocialLoginStart(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	var errorResponse ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	assert.Equal(t, "UNKNOWN_PROVIDER", errorResponse.Code)
+}
+
+func TestHandleSocialLoginCallback_ReturnsTokenAsJSON(t *testing.T) {
+	// Arrange
+	social := new(service.SocialLoginServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSocialLogin(social))
+	pending := &service.PendingLogin{Provider: "google", State: "state-1", Verifier: "verifier-1", Nonce: "nonce-1"}
+	social.On("Callback", mock.Anything, "google", "code-1", "state-1", pending).Return("jwt-token", nil)
+
+	req := withProvider(httptest.NewRequest(http.MethodGet, "/auth/google/callback?code=code-1&state=state-1", nil), "google")
+	req.AddCookie(pendingCookie(t, pending))
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSocialLoginCallback(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &body)
+	assert.Equal(t, "jwt-token", body["token"])
+	social.AssertExpectations(t)
+}
+
+func TestHandleSocialLoginCallback_RedirectsToSuccessURL(t *testing.T) {
+	// Arrange
+	social := new(service.SocialLoginServiceMock)
+	cfg := &config.Config{Social: config.SocialConfig{SuccessURL: "https://shop.example.com/signed-in"}}
+	handler := NewHandler(new(service.UserServiceMock), cfg, WithSocialLogin(social))
+	social.On("Callback", mock.Anything, "google", "code-1", "state-1", (*service.PendingLogin)(nil)).Return("", domain.ErrAccountLinkRequired)
+
+	req := withProvider(httptest.NewRequest(http.MethodGet, "/auth/google/callback?code=code-1&state=state-1", nil), "google")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSocialLoginCallback(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusFound, rr.Code)
+	location, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "shop.example.com", location.Host)
+	assert.Equal(t, "error=ACCOUNT_EXISTS", location.Fragment)
+}
+
+func TestHandleSocialLoginCallback_ProviderError(t *testing.T) {
+	// Arrange: o usuário recusou o consentimento no provedor
+	social := new(service.SocialLoginServiceMock)
+	handler := NewHandler(new(service.UserServiceMock), &config.Config{}, WithSocialLogin(social))
+
+	req := withProvider(httptest.NewRequest(http.MethodGet, "/auth/google/callback?error=access_denied", nil), "google")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.HandleSocialLoginCallback(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	var errorResponse ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	assert.Equal(t, "SOCIAL_LOGIN_FAILED", errorResponse.Code)
+	social.AssertNotCalled(t, "Callback", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
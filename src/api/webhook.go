@@ -0,0 +1,77 @@
+package api
+
+import (
+	"auth-service/src/domain"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createdWebhook struct {
+	Secret string `json:"secret"`
+	*domain.WebhookSubscription
+}
+
+// HandleCreateWebhook subscribes a URL to the listed event types. The secret
+// that signs the deliveries is only returned here.
+func (h *Handler) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Code: "INVALID_REQUEST_BODY", Message: domain.ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	signingSecret, sub, err := h.webhooks.Create(r.Context(), req.URL, req.Events)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "webhook subscribed by administrator", "admin_id", r.Context().Value(userIDKey), "webhook_id", sub.ID, "events", sub.Events)
+	WriteJSON(w, http.StatusCreated, createdWebhook{Secret: signingSecret, WebhookSubscription: sub})
+}
+
+func (h *Handler) HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhooks.List(r.Context())
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"webhooks": subs})
+}
+
+func (h *Handler) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.webhooks.Delete(r.Context(), id); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "webhook deleted by administrator", "admin_id", r.Context().Value(userIDKey), "webhook_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListWebhookDeliveries shows the latest deliveries to a subscription;
+// ?status=dead narrows them to the dead letters.
+func (h *Handler) HandleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.webhooks.ListDeliveries(r.Context(), chi.URLParam(r, "id"), r.URL.Query().Get("status"))
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"deliveries": deliveries})
+}
+
+// HandleRedeliverWebhook queues a dead delivery again with a fresh set of
+// attempts, once the receiver has been fixed.
+func (h *Handler) HandleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	id, deliveryID := chi.URLParam(r, "id"), chi.URLParam(r, "deliveryID")
+	if err := h.webhooks.Redeliver(r.Context(), id, deliveryID); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "webhook delivery requeued by administrator", "admin_id", r.Context().Value(userIDKey), "webhook_id", id, "delivery_id", deliveryID)
+	w.WriteHeader(http.StatusAccepted)
+}
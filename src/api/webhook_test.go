@@ -0,0 +1,123 @@
+package api
+
+import (
+	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/service"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newWebhookAdmin returns the admin router with a signed-in administrator.
+func newWebhookAdmin() (http.Handler, *service.WebhookServiceMock) {
+	users := new(service.UserServiceMock)
+	webhooks := new(service.WebhookServiceMock)
+	users.On("ValidateToken", mock.Anything, "session-jwt").Return(map[string]interface{}{"sub": "admin-1"}, nil)
+	users.On("GetProfile", mock.Anything, "admin-1").Return(&domain.User{ID: "admin-1", Role: domain.RoleAdmin}, nil)
+	return newAdminRouter(NewHandler(users, &config.Config{}, WithWebhooks(webhooks))), webhooks
+}
+
+func adminRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer session-jwt")
+	return req
+}
+
+func TestHandleCreateWebhook_ReturnsSecretOnce(t *testing.T) {
+	// Arrange
+	router, webhooks := newWebhookAdmin()
+	events := []string{domain.EventUserRegistered}
+	webhooks.On("Create", mock.Anything, "https://orders.example.com/hooks", events).
+		Return("whsec_secret", &domain.WebhookSubscription{ID: "wh-1", URL: "https://orders.example.com/hooks", Events: events, Secret: "whsec_secret"}, nil)
+	webhooks.On("List", mock.Anything).
+		Return([]*domain.WebhookSubscription{{ID: "wh-1", URL: "https://orders.example.com/hooks", Events: events, Secret: "whsec_secret"}}, nil)
+	created := httptest.NewRecorder()
+	listed := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(created, adminRequest(http.MethodPost, "/admin/webhooks", `{"url": "https://orders.example.com/hooks", "events": ["user.registered"]}`))
+	router.ServeHTTP(listed, adminRequest(http.MethodGet, "/admin/webhooks", ""))
+
+	// Assert
+	require.Equal(t, http.StatusCreated, created.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(created.Body.Bytes(), &body))
+	assert.Equal(t, "whsec_secret", body["secret"])
+	assert.Equal(t, "wh-1", body["id"])
+	assert.Equal(t, http.StatusOK, listed.Code)
+	assert.NotContains(t, listed.Body.String(), "whsec_secret")
+}
+
+func TestHandleCreateWebhook_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{name: "bad url", err: domain.ErrInvalidWebhookURL, code: "INVALID_WEBHOOK_URL"},
+		{name: "unknown event", err: domain.ErrInvalidEventType, code: "INVALID_EVENT_TYPE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			router, webhooks := newWebhookAdmin()
+			webhooks.On("Create", mock.Anything, mock.Anything, mock.Anything).Return("", nil, tt.err)
+			rr := httptest.NewRecorder()
+
+			// Act
+			router.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/webhooks", `{"url": "ftp://x", "events": ["order.placed"]}`))
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			var body ErrorResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body.Code)
+		})
+	}
+}
+
+func TestHandleListWebhookDeliveries_FiltersByStatus(t *testing.T) {
+	// Arrange
+	router, webhooks := newWebhookAdmin()
+	webhooks.On("ListDeliveries", mock.Anything, "wh-1", domain.DeliveryDead).
+		Return([]*domain.WebhookDelivery{{ID: "dl-1", SubscriptionID: "wh-1", Status: domain.DeliveryDead, Attempts: 10, LastError: "receiver returned 500"}}, nil)
+	webhooks.On("ListDeliveries", mock.Anything, "wh-404", "").Return(nil, domain.ErrWebhookNotFound)
+	dead := httptest.NewRecorder()
+	missing := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(dead, adminRequest(http.MethodGet, "/admin/webhooks/wh-1/deliveries?status=dead", ""))
+	router.ServeHTTP(missing, adminRequest(http.MethodGet, "/admin/webhooks/wh-404/deliveries", ""))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, dead.Code)
+	assert.Contains(t, dead.Body.String(), "receiver returned 500")
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+}
+
+func TestHandleRedeliverWebhook(t *testing.T) {
+	// Arrange
+	router, webhooks := newWebhookAdmin()
+	webhooks.On("Redeliver", mock.Anything, "wh-1", "dl-1").Return(nil)
+	webhooks.On("Redeliver", mock.Anything, "wh-1", "dl-2").Return(domain.ErrDeliveryNotFound)
+	requeued := httptest.NewRecorder()
+	notDead := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(requeued, adminRequest(http.MethodPost, "/admin/webhooks/wh-1/deliveries/dl-1/retry", ""))
+	router.ServeHTTP(notDead, adminRequest(http.MethodPost, "/admin/webhooks/wh-1/deliveries/dl-2/retry", ""))
+
+	// Assert
+	assert.Equal(t, http.StatusAccepted, requeued.Code)
+	assert.Equal(t, http.StatusNotFound, notDead.Code)
+	var body ErrorResponse
+	require.NoError(t, json.Unmarshal(notDead.Body.Bytes(), &body))
+	assert.Equal(t, "DELIVERY_NOT_FOUND", body.Code)
+}
@@ -0,0 +1,53 @@
+// Package cache keeps the results of hot lookups, such as the user read on
+// every token validation, for a short time. LRU is the in-process cache; a
+// shared one (Redis, Memcached) implements the same interface so that every
+// replica sees an invalidation at once.
+package cache
+
+import (
+	"auth-service/src/metrics"
+	"context"
+	"time"
+)
+
+// Cache maps keys to opaque values that expire after their TTL. Callers
+// treat an error like a miss and fall back to the source, so a shared cache
+// that is down only costs latency.
+type Cache interface {
+	// Get returns the value stored under key, or false when it is missing
+	// or has expired. An empty value is a tombstone left by Invalidate.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Add stores value only when key holds nothing, not even a tombstone,
+	// and reports whether it did.
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Invalidate replaces key with a tombstone for ttl. Readers fill the cache
+// with Add, so a value they read from the source before the change cannot
+// be stored over the tombstone; ttl must outlast such a read.
+func Invalidate(ctx context.Context, c Cache, key string, ttl time.Duration) error {
+	return c.Set(ctx, key, nil, ttl)
+}
+
+type instrumented struct {
+	Cache
+	name     string
+	recorder metrics.Recorder
+}
+
+// WithMetrics counts the hits and misses of c under name.
+func WithMetrics(c Cache, name string, recorder metrics.Recorder) Cache {
+	return &instrumented{Cache: c, name: name, recorder: recorder}
+}
+
+func (c *instrumented) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok, err := c.Cache.Get(ctx, key)
+	if ok && err == nil && len(value) > 0 {
+		c.recorder.CacheLookup(c.name, metrics.CacheHit)
+	} else {
+		c.recorder.CacheLookup(c.name, metrics.CacheMiss)
+	}
+	return value, ok, err
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"auth-service/src/metrics"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	c := NewLRU(2)
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), time.Minute))
+
+	// Act: reading a makes b the least recently used
+	_, _, _ = c.Get(ctx, "a")
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), time.Minute))
+
+	// Assert
+	_, bOK, _ := c.Get(ctx, "b")
+	a, aOK, _ := c.Get(ctx, "a")
+	assert.False(t, bOK)
+	assert.True(t, aOK)
+	assert.Equal(t, []byte("1"), a)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRU_ExpiresAndDeletes(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+	require.NoError(t, c.Set(ctx, "short", []byte("1"), time.Second))
+	require.NoError(t, c.Set(ctx, "long", []byte("2"), time.Hour))
+	require.NoError(t, c.Set(ctx, "gone", []byte("3"), time.Hour))
+
+	// Act
+	now = now.Add(time.Minute)
+	require.NoError(t, c.Delete(ctx, "gone", "missing"))
+
+	// Assert
+	_, shortOK, _ := c.Get(ctx, "short")
+	_, longOK, _ := c.Get(ctx, "long")
+	_, goneOK, _ := c.Get(ctx, "gone")
+	assert.False(t, shortOK)
+	assert.True(t, longOK)
+	assert.False(t, goneOK)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestLRU_AddKeepsTombstones(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+	require.NoError(t, Invalidate(ctx, c, "user:1", time.Minute))
+
+	// Act
+	staleAdded, err := c.Add(ctx, "user:1", []byte("stale"), time.Hour)
+	require.NoError(t, err)
+	value, ok, _ := c.Get(ctx, "user:1")
+	now = now.Add(2 * time.Minute)
+	freshAdded, err := c.Add(ctx, "user:1", []byte("fresh"), time.Hour)
+	require.NoError(t, err)
+
+	// Assert
+	assert.False(t, staleAdded)
+	assert.True(t, ok)
+	assert.Empty(t, value)
+	assert.True(t, freshAdded)
+	fresh, _, _ := c.Get(ctx, "user:1")
+	assert.Equal(t, []byte("fresh"), fresh)
+}
+
+func TestLRU_CopiesValues(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	c := NewLRU(10)
+	value := []byte("original")
+	require.NoError(t, c.Set(ctx, "k", value, time.Minute))
+
+	// Act
+	value[0] = 'X'
+	got, _, _ := c.Get(ctx, "k")
+	got[1] = 'X'
+
+	// Assert
+	again, _, _ := c.Get(ctx, "k")
+	assert.Equal(t, []byte("original"), again)
+}
+
+func TestWithMetrics_CountsHitsAndMisses(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	recorder := new(metrics.RecorderMock)
+	recorder.On("CacheLookup", "users", metrics.CacheHit).Once()
+	recorder.On("CacheLookup", "users", metrics.CacheMiss).Twice()
+	c := WithMetrics(NewLRU(10), "users", recorder)
+	require.NoError(t, c.Set(ctx, "k", []byte("v"), time.Minute))
+
+	// Act
+	_, _, _ = c.Get(ctx, "k")
+	_, _, _ = c.Get(ctx, "other")
+	_, _, _ = c.Get(ctx, "another")
+
+	// Assert
+	recorder.AssertExpectations(t)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"auth-service/src/cache"
+	"auth-service/src/config"
+	"auth-service/src/jwt"
+	"auth-service/src/ldapauth"
+	"auth-service/src/logging"
+	"auth-service/src/metrics"
+	"auth-service/src/repository"
+	"auth-service/src/service"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// app wires the dependencies shared by the server and the admin commands,
+// so operators go through the same UserService rules as HTTP callers.
+type app struct {
+	*store
+	cfg         *config.Config
+	logger      *slog.Logger
+	keys        jwt.KeyProvider
+	issuer      jwt.TokenIssuer
+	metrics     *metrics.Prometheus
+	userService service.UserService
+	scimService service.SCIMService
+}
+
+// loadConfig reads .env and the layered configuration and builds the
+// logger. Admin commands log to stderr so their stdout stays scriptable.
+func loadConfig(logOutput io.Writer) (*config.Config, *slog.Logger, error) {
+	envErr := godotenv.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration:\n  %s", strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	}
+
+	logger, err := logging.New(logOutput, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.SetDefault(logger)
+	if envErr != nil {
+		logger.Debug(".env file not found, using system environment variables")
+	}
+	return cfg, logger, nil
+}
+
+func loadKeys(cfg *config.Config) (jwt.KeyProvider, error) {
+	if cfg.SigningKeysFile == "" {
+		return jwt.NewStaticKeys(cfg.JWTSecret), nil
+	}
+	return jwt.NewFileKeys(cfg.SigningKeysFile, cfg.JWTSecret)
+}
+
+// newTokenIssuer picks the token format and sets the registered and custom
+// claims every minted token carries and every presented token is checked
+// against. Opaque tokens keep those claims in the store instead.
+func newTokenIssuer(cfg *config.Config, keys jwt.KeyProvider, st *store, logger *slog.Logger, prom *metrics.Prometheus) (jwt.TokenIssuer, error) {
+	opts := []jwt.Option{
+		jwt.WithIssuer(cfg.JWT.Issuer),
+		jwt.WithAudience(cfg.JWT.Audience...),
+		jwt.WithLeeway(cfg.JWT.Leeway),
+	}
+	if len(cfg.JWT.Claims) > 0 {
+		opts = append(opts, jwt.WithClaims(jwt.UserClaims(cfg.JWT.Claims...)))
+	}
+	switch cfg.TokenFormat {
+	case "paseto-v4-public":
+		return jwt.NewPasetoIssuer(jwt.PasetoPublic, cfg.PasetoKey, opts...)
+	case "paseto-v4-local":
+		return jwt.NewPasetoIssuer(jwt.PasetoLocal, cfg.PasetoKey, opts...)
+	case "opaque":
+		return jwt.NewOpaqueIssuer(st.refTokens, cache.WithMetrics(cache.NewLRU(cfg.Cache.Size), "access_tokens", prom), cfg.OpaqueTokenCacheTTL, logger, opts...), nil
+	}
+	return jwt.NewJWTIssuer(keys, opts...), nil
+}
+
+func newApp(ctx context.Context, logOutput io.Writer) (*app, error) {
+	cfg, logger, err := loadConfig(logOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := loadKeys(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	prom := metrics.NewPrometheus()
+	st, err := openStore(ctx, cfg, logger, prom)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := newTokenIssuer(cfg, keys, st, logger, prom)
+	if err != nil {
+		st.close()
+		return nil, fmt.Errorf("failed to load token keys: %w", err)
+	}
+
+	if cfg.Webhooks.Enabled {
+		// Every account change, whichever path makes it, then records its
+		// event for the dispatcher in the same transaction.
+		st.users = repository.NewOutboxUser(st.users, st.tx, st.outbox)
+	}
+	if cfg.Cache.Enabled {
+		st.users = repository.NewCachedUser(st.users, cache.WithMetrics(cache.NewLRU(cfg.Cache.Size), "users", prom), cfg.Cache.TTL, repository.WithLogger(logger))
+	}
+
+	opts := []service.Option{
+		service.WithTokenIssuer(tokens),
+		service.WithMetrics(prom),
+		service.WithLogger(logger),
+		service.WithTokenTTL(cfg.TokenTTL),
+		service.WithBcryptCost(cfg.BcryptCost),
+		service.WithLowercaseEmails(cfg.EmailLowercaseLocalPart),
+	}
+	if cfg.LDAP.URL != "" {
+		opts = append(opts, service.WithAuthProvider(newLDAPProvider(cfg.LDAP), st.identities, service.AuthProviderPolicy{
+			Exclusive:         cfg.LDAP.Mode == "only",
+			Provision:         cfg.LDAP.ProvisionUsers,
+			LinkExistingEmail: cfg.LDAP.LinkExistingEmail,
+		}))
+	}
+	userService := service.NewUserService(st.users, cfg.JWTSecret, opts...)
+	scimService := service.NewSCIMService(userService, st.users, st.scim, st.tx,
+		service.WithSCIMLogger(logger),
+		service.WithSCIMLowercaseEmails(cfg.EmailLowercaseLocalPart),
+	)
+
+	return &app{
+		store:       st,
+		cfg:         cfg,
+		logger:      logger,
+		keys:        keys,
+		issuer:      tokens,
+		metrics:     prom,
+		userService: userService,
+		scimService: scimService,
+	}, nil
+}
+
+func newLDAPProvider(cfg config.LDAPConfig) *ldapauth.Provider {
+	return ldapauth.New(ldapauth.Config{
+		URL:                cfg.URL,
+		StartTLS:           cfg.StartTLS,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		BindDN:             cfg.BindDN,
+		BindPassword:       cfg.BindPassword,
+		BaseDN:             cfg.BaseDN,
+		UserFilter:         cfg.UserFilter,
+		EmailAttribute:     cfg.EmailAttribute,
+		NameAttribute:      cfg.NameAttribute,
+		SubjectAttribute:   cfg.SubjectAttribute,
+		GroupAttribute:     cfg.GroupAttribute,
+		GroupRoles:         cfg.GroupRoles,
+		DefaultRole:        cfg.DefaultRole,
+		Timeout:            cfg.Timeout,
+	})
+}
+
+func (a *app) Close() {
+	a.store.close()
+}
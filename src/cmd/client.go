@@ -0,0 +1,57 @@
+package main
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/secret"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+const clientUsage = `usage: auth-service client create -name NAME
+
+Registers an application and prints its client_id and client_secret.
+The secret is stored hashed and cannot be shown again.`
+
+func runClient(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprintln(os.Stderr, clientUsage)
+		return errors.New("missing or unknown client command")
+	}
+
+	fs := flag.NewFlagSet("client create", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, clientUsage) }
+	name := fs.String("name", "", "application name")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *name == "" {
+		fs.Usage()
+		return errors.New("-name is required")
+	}
+
+	a, err := newApp(ctx, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	id, err := secret.Generate("", 12)
+	if err != nil {
+		return err
+	}
+	clientSecret, err := secret.Generate("acs_", 32)
+	if err != nil {
+		return err
+	}
+	client := &domain.Client{ID: id, Name: *name, SecretHash: secret.Hash(clientSecret), CreatedAt: time.Now().UTC()}
+	if err := a.clients.Create(ctx, client); err != nil {
+		return err
+	}
+
+	fmt.Printf("client_id=%s\nclient_secret=%s\n", client.ID, clientSecret)
+	return nil
+}
@@ -1,33 +1,60 @@
 package main
 
 import (
-	"auth-service/src/config"
-	"auth-service/src/repository"
-	"auth-service/src/server"
-	"auth-service/src/service"
 	"context"
-	"log"
-
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
+	"fmt"
+	"log/slog"
+	"os"
 )
 
+const usage = `usage: auth-service <command> [arguments]
+
+commands:
+  serve                      run the HTTP server (default)
+  migrate up|down|status|version
+                             manage the database schema
+  user create|list|disable|set-password|revoke-tokens|collisions
+                             manage user accounts
+  token issue|inspect        mint or decode access tokens
+  keys rotate|paseto         rotate the JWT signing keys or create a PASETO key
+  client create              register an application that requests tokens
+  scim create-tenant|list-tenants
+                             manage the identity providers allowed to use SCIM
+
+Run "auth-service <command> -h" for the arguments of each command.`
+
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println(".env file not found, using system environment variables.")
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
 	}
-	cfg := config.Load()
 
-	pool, err := pgxpool.New(context.Background(), cfg.DatabaseURL)
+	ctx := context.Background()
+	var err error
+	switch command {
+	case "serve":
+		err = runServe(ctx, args)
+	case "migrate":
+		err = runMigrate(ctx, args)
+	case "user":
+		err = runUser(ctx, args)
+	case "token":
+		err = runToken(ctx, args)
+	case "keys":
+		err = runKeys(ctx, args)
+	case "client":
+		err = runClient(ctx, args)
+	case "scim":
+		err = runSCIM(ctx, args)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		err = fmt.Errorf("unknown command %q", command)
+	}
 	if err != nil {
-		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+		slog.Error(command+" failed", "error", err)
+		os.Exit(1)
 	}
-	defer pool.Close()
-	log.Println("Successfully connected to PostgreSQL.")
-
-	userRepo := repository.NewUser(pool)
-	userService := service.NewUserService(userRepo, cfg.JWTSecret)
-	httpServer := server.NewServer(cfg, userService)
-
-	httpServer.Run()
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"auth-service/src/migration"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = `usage: auth-service migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [N]    revert the last N migrations (default 1)
+  status      list embedded migrations and whether they are applied
+  version     print the current schema version`
+
+func runMigrate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, migrateUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing migrate command")
+	}
+
+	a, err := newApp(ctx, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	if a.migrator == nil {
+		return errors.New("the configured storage backend does not use migrations")
+	}
+	migrator, logger := a.migrator, a.logger
+
+	switch fs.Arg(0) {
+	case "up":
+		err := migrator.Up(ctx)
+		if errors.Is(err, migration.ErrNoChange) {
+			logger.Info("database already up to date", "version", migrator.Latest())
+			return nil
+		}
+		return err
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			n, err := strconv.Atoi(fs.Arg(1))
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", fs.Arg(1))
+			}
+			steps = n
+		}
+		err := migrator.Down(ctx, steps)
+		if errors.Is(err, migration.ErrNoChange) {
+			logger.Info("no migrations to revert")
+			return nil
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			fmt.Fprintf(w, "%d\t%s\t%t\n", s.Version, s.Name, s.Applied)
+		}
+		return w.Flush()
+	case "version":
+		version, dirty, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version=%d dirty=%t latest=%d\n", version, dirty, migrator.Latest())
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown migrate command %q", fs.Arg(0))
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const scimUsage = `usage: auth-service scim <command> [flags]
+
+commands:
+  create-tenant   -name NAME   register an identity provider and print its token
+  list-tenants                 list the registered identity providers
+
+The token is stored hashed and cannot be shown again; create a new tenant
+to replace a lost one.`
+
+func runSCIM(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, scimUsage)
+		return errors.New("missing scim command")
+	}
+	command, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("scim "+command, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, scimUsage) }
+	name := fs.String("name", "", "tenant name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch command {
+	case "create-tenant":
+		if *name == "" {
+			fs.Usage()
+			return errors.New("-name is required")
+		}
+		a, err := newApp(ctx, os.Stderr)
+		if err != nil {
+			return err
+		}
+		defer a.Close()
+
+		tenant, token, err := a.scimService.CreateTenant(ctx, *name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("tenant_id=%s\ntoken=%s\n", tenant.ID, token)
+		return nil
+	case "list-tenants":
+		a, err := newApp(ctx, os.Stderr)
+		if err != nil {
+			return err
+		}
+		defer a.Close()
+
+		tenants, err := a.scimService.ListTenants(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tCREATED")
+		for _, t := range tenants {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", t.ID, t.Name, t.CreatedAt.Format(time.RFC3339))
+		}
+		return w.Flush()
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown scim command %q", command)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/health"
+	"auth-service/src/mailer"
+	"auth-service/src/migration"
+	"auth-service/src/repository"
+	"auth-service/src/samlsp"
+	"auth-service/src/secret"
+	"auth-service/src/server"
+	"auth-service/src/service"
+	"auth-service/src/social"
+	"auth-service/src/tracing"
+	"auth-service/src/webhook"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+func runServe(ctx context.Context, args []string) error {
+	a, err := newApp(ctx, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	shutdownTracing, err := tracing.Setup(ctx, a.cfg.TraceExporter, a.cfg.ServiceName, a.cfg.TraceEmailKey)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			a.logger.Error("failed to flush traces", "error", err)
+		}
+	}()
+
+	if a.cfg.MigrateOnStart && a.migrator != nil {
+		if err := a.migrator.Up(ctx); err != nil && !errors.Is(err, migration.ErrNoChange) {
+			return fmt.Errorf("failed to migrate database on start: %w", err)
+		}
+	}
+
+	checks := a.checks
+	if a.cfg.TokenFormat == "jwt" {
+		checks = append([]health.Check{health.SigningKeysCheck(a.keys)}, checks...)
+	}
+	checker := health.NewChecker(a.cfg.HTTP.HealthCheckTimeout, checks...)
+	opts := []server.Option{server.WithAPIKeys(newAPIKeyService(a))}
+	if a.cfg.MagicLink.URL != "" {
+		magicLinks := service.NewMagicLinkService(a.userService, a.users, a.magicLinks, newMailer(a.cfg.Mailer, a.logger), a.cfg.MagicLink.URL,
+			service.WithMagicLinkTTL(a.cfg.MagicLink.TTL),
+			service.WithMagicLinkRateLimit(a.cfg.MagicLink.MaxPerHour),
+			service.WithMagicLinkWorkers(a.cfg.MagicLink.Workers, a.cfg.MagicLink.QueueSize),
+			service.WithMagicLinkLogger(a.logger),
+		)
+		opts = append(opts, server.WithMagicLinks(magicLinks))
+	}
+	if len(a.cfg.Social.Providers) > 0 {
+		providers, err := newSocialProviders(a.cfg.Social)
+		if err != nil {
+			return err
+		}
+		socialLogin := service.NewSocialLoginService(a.userService, a.users, a.identities, providers,
+			service.WithSocialLoginLogger(a.logger),
+			service.WithSocialLoginMetrics(a.metrics),
+			service.WithSocialLoginLowercaseEmails(a.cfg.EmailLowercaseLocalPart),
+		)
+		opts = append(opts, server.WithSocialLogin(socialLogin))
+	}
+	if len(a.cfg.SAML.Providers) > 0 {
+		connections, err := newSAMLConnections(a.cfg.SAML, a.assertions)
+		if err != nil {
+			return err
+		}
+		saml := service.NewSAMLService(a.userService, a.users, a.identities, connections,
+			service.WithSAMLLogger(a.logger),
+			service.WithSAMLMetrics(a.metrics),
+			service.WithSAMLLowercaseEmails(a.cfg.EmailLowercaseLocalPart),
+		)
+		opts = append(opts, server.WithSAML(saml))
+	}
+
+	if a.cfg.PersonalAccessTokens.Enabled {
+		tokens := service.NewPersonalAccessTokenService(a.users, a.tokens,
+			service.WithPersonalAccessTokenTTL(a.cfg.PersonalAccessTokens.DefaultTTL, a.cfg.PersonalAccessTokens.MaxTTL),
+			service.WithPersonalAccessTokenLogger(a.logger),
+		)
+		opts = append(opts, server.WithPersonalAccessTokens(tokens))
+	}
+
+	if a.cfg.TokenExchange.Enabled {
+		exchange := service.NewTokenExchangeService(a.userService, a.users, a.issuer,
+			service.WithTokenExchangeTTL(a.cfg.TokenExchange.TTL),
+			service.WithTokenExchangeDelegates(a.cfg.TokenExchange.Delegates...),
+			service.WithTokenExchangeLogger(a.logger),
+		)
+		opts = append(opts, server.WithTokenExchange(exchange))
+	}
+
+	if a.cfg.DeviceAuthorization.Enabled {
+		devices := service.NewDeviceAuthorizationService(a.userService, a.clients, a.devices,
+			service.WithDeviceAuthorizationTTL(a.cfg.DeviceAuthorization.TTL),
+			service.WithDeviceAuthorizationInterval(a.cfg.DeviceAuthorization.Interval),
+			service.WithDeviceAuthorizationLogger(a.logger),
+		)
+		opts = append(opts, server.WithDeviceAuthorization(devices))
+	}
+
+	if a.cfg.SCIMEnabled {
+		opts = append(opts, server.WithSCIM(a.scimService))
+	}
+
+	if a.cfg.Webhooks.Enabled {
+		opts = append(opts, server.WithWebhooks(service.NewWebhookService(a.webhooks, service.WithWebhookLogger(a.logger))))
+		dispatcher := webhook.NewDispatcher(a.outbox, a.webhooks,
+			webhook.WithInterval(a.cfg.Webhooks.PollInterval),
+			webhook.WithTimeout(a.cfg.Webhooks.Timeout),
+			webhook.WithMaxAttempts(a.cfg.Webhooks.MaxAttempts),
+			webhook.WithBackoff(a.cfg.Webhooks.BackoffBase, a.cfg.Webhooks.BackoffMax),
+			webhook.WithLogger(a.logger),
+		)
+		dispatchCtx, stopDispatcher := context.WithCancel(ctx)
+		defer stopDispatcher()
+		go dispatcher.Run(dispatchCtx)
+	}
+
+	server.NewServer(a.cfg, a.userService, checker, a.metrics, a.logger, opts...).Run()
+	return nil
+}
+
+// newAPIKeyService accepts the keys minted through /admin/api-keys, the
+// hashed keys from the configuration file and the legacy INTERNAL_API_KEY.
+func newAPIKeyService(a *app) service.APIKeyService {
+	var static []*domain.APIKey
+	if a.cfg.InternalAPIKey != "" {
+		static = append(static, &domain.APIKey{ID: domain.LegacyAPIKeyName, Name: domain.LegacyAPIKeyName, KeyHash: secret.Hash(a.cfg.InternalAPIKey)})
+	}
+	for _, key := range a.cfg.InternalAPIKeys {
+		hash := strings.ToLower(key.KeyHash)
+		static = append(static, &domain.APIKey{ID: "config:" + hash[:8], Name: key.Name, KeyHash: hash, ExpiresAt: key.ExpiresAt})
+	}
+	return service.NewAPIKeyService(a.apiKeys, service.WithStaticAPIKeys(static...), service.WithAPIKeyLogger(a.logger))
+}
+
+func newMailer(cfg config.MailerConfig, logger *slog.Logger) mailer.Mailer {
+	if cfg.Driver == "smtp" {
+		return mailer.NewSMTP(cfg.SMTPAddr, cfg.From, cfg.SMTPUsername, cfg.SMTPPassword)
+	}
+	return mailer.NewLog(logger)
+}
+
+func newSocialProviders(cfg config.SocialConfig) ([]social.Provider, error) {
+	providers := make([]social.Provider, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		provider, err := social.New(social.Config{
+			Name:                  p.Name,
+			Type:                  p.Type,
+			Issuer:                p.Issuer,
+			ClientID:              p.ClientID,
+			ClientSecret:          p.ClientSecret,
+			Scopes:                p.Scopes,
+			RedirectURL:           strings.TrimSuffix(cfg.CallbackBaseURL, "/") + "/auth/" + p.Name + "/callback",
+			AutoLinkVerifiedEmail: p.AutoLinkVerifiedEmail,
+			AuthURL:               p.AuthURL,
+			TokenURL:              p.TokenURL,
+			APIURL:                p.APIURL,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure social provider %s: %w", p.Name, err)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+func newSAMLConnections(cfg config.SAMLConfig, assertions repository.SAMLAssertionRepository) ([]service.SAMLConnection, error) {
+	key, cert, err := samlsp.LoadKeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	connections := make([]service.SAMLConnection, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		var metadata []byte
+		if p.MetadataFile != "" {
+			if metadata, err = os.ReadFile(p.MetadataFile); err != nil {
+				return nil, fmt.Errorf("failed to read IdP metadata for SAML provider %s: %w", p.Name, err)
+			}
+		}
+		provider, err := samlsp.New(samlsp.Config{
+			Name:              p.Name,
+			BaseURL:           cfg.BaseURL,
+			IDPMetadata:       metadata,
+			IDPMetadataURL:    p.MetadataURL,
+			Key:               key,
+			Certificate:       cert,
+			NameIDFormat:      p.NameIDFormat,
+			EmailAttribute:    p.EmailAttribute,
+			NameAttribute:     p.NameAttribute,
+			GroupAttribute:    p.GroupAttribute,
+			SubjectAttribute:  p.SubjectAttribute,
+			GroupRoles:        p.GroupRoles,
+			DefaultRole:       p.DefaultRole,
+			AllowIDPInitiated: p.AllowIDPInitiated,
+			Assertions:        assertions,
+		})
+		if err != nil {
+			return nil, err
+		}
+		connections = append(connections, service.SAMLConnection{
+			Provider: provider,
+			Policy: service.AuthProviderPolicy{
+				Provision:         p.ProvisionUsers,
+				LinkExistingEmail: p.LinkExistingEmail,
+			},
+		})
+	}
+	return connections, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"auth-service/database"
+	sqlitemigrations "auth-service/database/sqlite"
+	"auth-service/src/config"
+	"auth-service/src/health"
+	"auth-service/src/metrics"
+	"auth-service/src/migration"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"auth-service/src/repository/sqlite"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// store bundles the repositories of one storage backend, selected by the
+// scheme of DATABASE_URL, together with the health checks that apply to it.
+type store struct {
+	users      repository.UserRepository
+	clients    repository.ClientRepository
+	magicLinks repository.MagicLinkRepository
+	identities repository.IdentityRepository
+	scim       repository.SCIMRepository
+	tokens     repository.PersonalAccessTokenRepository
+	apiKeys    repository.APIKeyRepository
+	outbox     repository.OutboxRepository
+	webhooks   repository.WebhookRepository
+	devices    repository.DeviceAuthorizationRepository
+	refTokens  repository.AccessTokenRepository
+	assertions repository.SAMLAssertionRepository
+	tx         repository.TxManager
+	migrator   *migration.Migrator
+	checks     []health.Check
+	close      func()
+}
+
+func openStore(ctx context.Context, cfg *config.Config, logger *slog.Logger, prom *metrics.Prometheus) (*store, error) {
+	u, err := url.Parse(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DATABASE_URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "memory":
+		logger.Warn("using in-memory storage, all data is lost on restart")
+		return &store{
+			users:      memory.NewUser(),
+			clients:    memory.NewClient(),
+			magicLinks: memory.NewMagicLink(),
+			identities: memory.NewIdentity(),
+			scim:       memory.NewSCIM(),
+			tokens:     memory.NewPersonalAccessToken(),
+			apiKeys:    memory.NewAPIKey(),
+			outbox:     memory.NewOutbox(),
+			webhooks:   memory.NewWebhook(),
+			devices:    memory.NewDeviceAuthorization(),
+			refTokens:  memory.NewAccessToken(),
+			assertions: memory.NewSAMLAssertion(),
+			tx:         memory.NewTxManager(),
+			close:      func() {},
+		}, nil
+	case "postgres", "postgresql":
+		return openPostgres(ctx, cfg, logger, prom)
+	case "sqlite":
+		return openSQLite(ctx, strings.TrimPrefix(cfg.DatabaseURL, "sqlite://"), logger)
+	default:
+		return nil, fmt.Errorf("unsupported DATABASE_URL scheme %q", u.Scheme)
+	}
+}
+
+func openPostgres(ctx context.Context, cfg *config.Config, logger *slog.Logger, prom *metrics.Prometheus) (*store, error) {
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	logger.Debug("successfully connected to PostgreSQL")
+
+	migrator, err := migration.New(pool, database.Migrations, logger)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to load embedded migrations: %w", err)
+	}
+	prom.RegisterPool(pool)
+
+	return &store{
+		users:      repository.NewUser(pool, repository.WithLogger(logger)),
+		clients:    repository.NewClient(pool, repository.WithLogger(logger)),
+		magicLinks: repository.NewMagicLink(pool, repository.WithLogger(logger)),
+		identities: repository.NewIdentity(pool, repository.WithLogger(logger)),
+		scim:       repository.NewSCIM(pool, repository.WithLogger(logger)),
+		tokens:     repository.NewPersonalAccessToken(pool, repository.WithLogger(logger)),
+		apiKeys:    repository.NewAPIKey(pool, repository.WithLogger(logger)),
+		outbox:     repository.NewOutbox(pool, repository.WithLogger(logger)),
+		webhooks:   repository.NewWebhook(pool, repository.WithLogger(logger)),
+		devices:    repository.NewDeviceAuthorization(pool, repository.WithLogger(logger)),
+		refTokens:  repository.NewAccessToken(pool, repository.WithLogger(logger)),
+		assertions: repository.NewSAMLAssertion(pool, repository.WithLogger(logger)),
+		tx:         repository.NewTxManager(pool),
+		migrator:   migrator,
+		checks: []health.Check{
+			health.DatabaseCheck(pool),
+			health.MigrationCheck(migrator.Version, migrator.Latest()),
+		},
+		close: pool.Close,
+	}, nil
+}
+
+// openSQLite opens the file named after the sqlite:// prefix, so
+// sqlite:///var/lib/auth/auth.db is absolute and sqlite://auth.db relative.
+func openSQLite(ctx context.Context, dsn string, logger *slog.Logger) (*store, error) {
+	db, err := sqlite.Open(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("successfully opened SQLite database", "path", dsn)
+
+	migrator, err := migration.NewSQLite(db, sqlitemigrations.Migrations, logger)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load embedded migrations: %w", err)
+	}
+
+	return &store{
+		users:      sqlite.NewUser(db),
+		clients:    sqlite.NewClient(db),
+		magicLinks: sqlite.NewMagicLink(db),
+		identities: sqlite.NewIdentity(db),
+		scim:       sqlite.NewSCIM(db),
+		tokens:     sqlite.NewPersonalAccessToken(db),
+		apiKeys:    sqlite.NewAPIKey(db),
+		outbox:     sqlite.NewOutbox(db),
+		webhooks:   sqlite.NewWebhook(db),
+		devices:    sqlite.NewDeviceAuthorization(db),
+		refTokens:  sqlite.NewAccessToken(db),
+		assertions: sqlite.NewSAMLAssertion(db),
+		tx:         sqlite.NewTxManager(db),
+		migrator:   migrator,
+		checks: []health.Check{
+			{Name: "database", Run: db.PingContext},
+			health.MigrationCheck(migrator.Version, migrator.Latest()),
+		},
+		close: func() { db.Close() },
+	}, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"auth-service/src/domain"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const userUsage = `usage: auth-service user <command> [flags]
+
+commands:
+  create          -name NAME -email EMAIL [-role user|support|admin]
+  list            [-limit N] [-offset N]
+  disable         -id ID | -email EMAIL
+  set-password    -id ID | -email EMAIL
+  revoke-tokens   -id ID | -email EMAIL
+  collisions      list accounts whose emails only differ by case or encoding
+
+Passwords are read from stdin, never from flags, so they stay out of shell history.`
+
+func runUser(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return errors.New("missing user command")
+	}
+	command, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("user "+command, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, userUsage) }
+	id := fs.String("id", "", "user ID")
+	email := fs.String("email", "", "user email")
+	name := fs.String("name", "", "user name")
+	role := fs.String("role", domain.RoleUser, "user role")
+	limit := fs.Int("limit", 50, "maximum number of users to list")
+	offset := fs.Int("offset", 0, "number of users to skip")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := newApp(ctx, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	switch command {
+	case "create":
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		user, err := a.userService.CreateUser(ctx, *name, *email, password, *role)
+		if err != nil {
+			return err
+		}
+		fmt.Println(user.ID)
+		return nil
+	case "list":
+		users, err := a.userService.ListUsers(ctx, *limit, *offset)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tEMAIL\tROLE\tCREATED\tDISABLED")
+		for _, u := range users {
+			disabled := "-"
+			if u.DisabledAt != nil {
+				disabled = u.DisabledAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", u.ID, u.Name, u.Email, u.Role, u.CreatedAt.Format(time.RFC3339), disabled)
+		}
+		return w.Flush()
+	case "disable":
+		user, err := findUser(ctx, a, *id, *email)
+		if err != nil {
+			return err
+		}
+		return a.userService.DisableUser(ctx, user.ID)
+	case "set-password":
+		user, err := findUser(ctx, a, *id, *email)
+		if err != nil {
+			return err
+		}
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		return a.userService.SetPassword(ctx, user.ID, password)
+	case "revoke-tokens":
+		user, err := findUser(ctx, a, *id, *email)
+		if err != nil {
+			return err
+		}
+		return a.userService.RevokeTokens(ctx, user.ID)
+	case "collisions":
+		return printEmailCollisions(ctx, a)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown user command %q", command)
+	}
+}
+
+// printEmailCollisions groups every account by its fully normalized email.
+// Groups with more than one account block the case-insensitive unique
+// index and have to be merged or renamed by hand before migrating.
+func printEmailCollisions(ctx context.Context, a *app) error {
+	const pageSize = 500
+	groups := make(map[string][]*domain.User)
+	var keys []string
+	for offset := 0; ; offset += pageSize {
+		users, err := a.users.List(ctx, pageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			key, err := domain.NormalizeEmail(u.Email, true)
+			if err != nil {
+				key = strings.ToLower(strings.TrimSpace(u.Email))
+			}
+			if _, seen := groups[key]; !seen {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], u)
+		}
+		if len(users) < pageSize {
+			break
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NORMALIZED\tID\tEMAIL\tCREATED")
+	collisions := 0
+	for _, key := range keys {
+		if len(groups[key]) < 2 {
+			continue
+		}
+		collisions++
+		for _, u := range groups[key] {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", key, u.ID, u.Email, u.CreatedAt.Format(time.RFC3339))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if collisions > 0 {
+		return fmt.Errorf("found %d email addresses shared by more than one account", collisions)
+	}
+	return nil
+}
+
+func findUser(ctx context.Context, a *app, id, email string) (*domain.User, error) {
+	switch {
+	case id != "":
+		return a.users.FindByID(ctx, id)
+	case email != "":
+		if normalized, err := domain.NormalizeEmail(email, a.cfg.EmailLowercaseLocalPart); err == nil {
+			email = normalized
+		}
+		return a.users.FindByEmail(ctx, email)
+	default:
+		return nil, errors.New("either -id or -email is required")
+	}
+}
+
+func readPassword() (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -1,26 +1,850 @@
 package config
 
-import "os"
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+const minSecretLength = 16
+
+// maxJWTLeeway bounds the clock skew tolerated on exp, nbf and iat.
+const maxJWTLeeway = 5 * time.Minute
+
+// pasetoKeyLengths is the key size in bytes of each PASETO token format.
+var pasetoKeyLengths = map[string]int{"paseto-v4-public": 64, "paseto-v4-local": 32}
+
+// jwtUserClaims are the user fields JWT_CLAIMS can copy into tokens.
+var jwtUserClaims = []string{"name", "role"}
+
+// maxTokenExchangeTTL keeps impersonation and delegation tokens short-lived.
+const maxTokenExchangeTTL = time.Hour
+
+var socialProviderName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+var supportedDatabaseSchemes = map[string]bool{"postgres": true, "postgresql": true, "sqlite": true, "memory": true}
+
+// Config holds every tunable of the service. Values are layered: Default(),
+// then the file named by CONFIG_FILE (YAML or TOML), then environment
+// variables. Any variable can instead be read from a file by setting
+// <NAME>_FILE, which is how secrets are mounted in Docker and Kubernetes.
 type Config struct {
-	ListenAddr     string
-	JWTSecret      string
-	InternalAPIKey string
-	DatabaseURL    string
+	ListenAddr string `yaml:"listen_addr" toml:"listen_addr" env:"LISTEN_ADDR"`
+	JWTSecret  string `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET"`
+	// SigningKeysFile holds the rotating key set managed by `auth-service keys rotate`.
+	// When set, JWT_SECRET is only used to verify tokens issued before the first rotation.
+	SigningKeysFile string `yaml:"signing_keys_file" toml:"signing_keys_file" env:"SIGNING_KEYS_FILE"`
+	InternalAPIKey  string `yaml:"internal_api_key" toml:"internal_api_key" env:"INTERNAL_API_KEY"`
+	DatabaseURL     string `yaml:"database_url" toml:"database_url" env:"DATABASE_URL"`
+	ServiceName     string `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME"`
+	TraceExporter   string `yaml:"trace_exporter" toml:"trace_exporter" env:"OTEL_TRACES_EXPORTER"`
+	// TraceEmailKey keys the email hashes recorded on spans; without it spans
+	// carry no email.
+	TraceEmailKey  string `yaml:"trace_email_key" toml:"trace_email_key" env:"TRACE_EMAIL_KEY"`
+	LogLevel       string `yaml:"log_level" toml:"log_level" env:"LOG_LEVEL"`
+	LogFormat      string `yaml:"log_format" toml:"log_format" env:"LOG_FORMAT"`
+	MigrateOnStart bool   `yaml:"migrate_on_start" toml:"migrate_on_start" env:"MIGRATE_ON_START"`
+
+	// TokenFormat is jwt, paseto-v4-public, paseto-v4-local or opaque. The
+	// PASETO formats sign or encrypt with PasetoKey instead of the JWT keys;
+	// opaque tokens are random references looked up in the database.
+	TokenFormat string `yaml:"token_format" toml:"token_format" env:"TOKEN_FORMAT"`
+	// PasetoKey is the hex Ed25519 secret key for paseto-v4-public or the
+	// 32-byte hex key for paseto-v4-local; `auth-service keys paseto` makes one.
+	PasetoKey string `yaml:"paseto_key" toml:"paseto_key" env:"PASETO_KEY"`
+	// OpaqueTokenCacheTTL is how long a validated opaque token is served
+	// from memory before it is looked up again.
+	OpaqueTokenCacheTTL time.Duration `yaml:"opaque_token_cache_ttl" toml:"opaque_token_cache_ttl" env:"OPAQUE_TOKEN_CACHE_TTL"`
+
+	TokenTTL   time.Duration `yaml:"token_ttl" toml:"token_ttl" env:"TOKEN_TTL"`
+	BcryptCost int           `yaml:"bcrypt_cost" toml:"bcrypt_cost" env:"BCRYPT_COST"`
+	// EmailLowercaseLocalPart stores addresses fully lowercased. Lookups are
+	// case-insensitive regardless; this only changes the stored form.
+	EmailLowercaseLocalPart bool `yaml:"email_lowercase_local_part" toml:"email_lowercase_local_part" env:"EMAIL_LOWERCASE_LOCAL_PART"`
+	// SCIMEnabled serves the SCIM 2.0 provisioning API under /scim/v2. Tenants
+	// and their tokens are created with `auth-service scim create-tenant`.
+	SCIMEnabled bool `yaml:"scim_enabled" toml:"scim_enabled" env:"SCIM_ENABLED"`
+	// InternalAPIKeys are accepted next to INTERNAL_API_KEY and the keys
+	// minted through /admin/api-keys. They can only be set in the file.
+	InternalAPIKeys []InternalAPIKeyConfig `yaml:"internal_api_keys" toml:"internal_api_keys"`
+
+	JWT       JWTConfig       `yaml:"jwt" toml:"jwt"`
+	HTTP      HTTPConfig      `yaml:"http" toml:"http"`
+	CORS      CORSConfig      `yaml:"cors" toml:"cors"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Mailer    MailerConfig    `yaml:"mailer" toml:"mailer"`
+	MagicLink MagicLinkConfig `yaml:"magic_link" toml:"magic_link"`
+	Social    SocialConfig    `yaml:"social" toml:"social"`
+	LDAP      LDAPConfig      `yaml:"ldap" toml:"ldap"`
+	SAML      SAMLConfig      `yaml:"saml" toml:"saml"`
+
+	PersonalAccessTokens PersonalAccessTokenConfig `yaml:"personal_access_tokens" toml:"personal_access_tokens"`
+	Webhooks             WebhookConfig             `yaml:"webhooks" toml:"webhooks"`
+	TokenExchange        TokenExchangeConfig       `yaml:"token_exchange" toml:"token_exchange"`
+	DeviceAuthorization  DeviceAuthorizationConfig `yaml:"device_authorization" toml:"device_authorization"`
+	Cache                CacheConfig               `yaml:"cache" toml:"cache"`
+}
+
+// InternalAPIKeyConfig is a key another service presents in
+// X-Internal-Api-Key. Only its SHA-256, in hex, is kept in the file.
+type InternalAPIKeyConfig struct {
+	Name      string    `yaml:"name" toml:"name"`
+	KeyHash   string    `yaml:"key_hash" toml:"key_hash"`
+	ExpiresAt time.Time `yaml:"expires_at" toml:"expires_at"`
+}
+
+type HTTPConfig struct {
+	ReadTimeout        time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	ReadHeaderTimeout  time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	WriteTimeout       time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout        time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout    time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+	ShutdownDrainDelay time.Duration `yaml:"shutdown_drain_delay" toml:"shutdown_drain_delay" env:"HTTP_SHUTDOWN_DRAIN_DELAY"`
+	HealthCheckTimeout time.Duration `yaml:"health_check_timeout" toml:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" toml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `yaml:"allowed_methods" toml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" toml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" toml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// JWTConfig sets the registered claims of session tokens and how strictly
+// they are validated.
+type JWTConfig struct {
+	Issuer string `yaml:"issuer" toml:"issuer" env:"JWT_ISSUER"`
+	// Audience lists the audiences tokens are minted for, one per token and
+	// the first by default, and a token is accepted if it names any of
+	// them. Tokens issued to an OAuth client also name the client.
+	Audience []string      `yaml:"audience" toml:"audience" env:"JWT_AUDIENCE"`
+	Leeway   time.Duration `yaml:"leeway" toml:"leeway" env:"JWT_LEEWAY"`
+	// Claims copies these user fields into every token: name, role.
+	Claims []string `yaml:"claims" toml:"claims" env:"JWT_CLAIMS"`
+}
+
+type RateLimitConfig struct {
+	Enabled           bool `yaml:"enabled" toml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	RequestsPerMinute int  `yaml:"requests_per_minute" toml:"requests_per_minute" env:"RATE_LIMIT_REQUESTS_PER_MINUTE"`
+	Burst             int  `yaml:"burst" toml:"burst" env:"RATE_LIMIT_BURST"`
+}
+
+type MailerConfig struct {
+	// Driver is none, log (development only, prints messages) or smtp.
+	Driver       string `yaml:"driver" toml:"driver" env:"MAILER"`
+	From         string `yaml:"from" toml:"from" env:"MAILER_FROM"`
+	SMTPAddr     string `yaml:"smtp_addr" toml:"smtp_addr" env:"SMTP_ADDR"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password" env:"SMTP_PASSWORD"`
+}
+
+// MagicLinkConfig enables passwordless sign-in when URL is set.
+type MagicLinkConfig struct {
+	// URL is the storefront page the emailed link points to; it receives the
+	// token as a query parameter and posts it to /login/magic-link/verify.
+	URL        string        `yaml:"url" toml:"url" env:"MAGIC_LINK_URL"`
+	TTL        time.Duration `yaml:"ttl" toml:"ttl" env:"MAGIC_LINK_TTL"`
+	MaxPerHour int           `yaml:"max_per_hour" toml:"max_per_hour" env:"MAGIC_LINK_MAX_PER_HOUR"`
+	// Workers send links in the background; QueueSize requests may wait for
+	// one before new requests are turned away.
+	Workers   int `yaml:"workers" toml:"workers" env:"MAGIC_LINK_WORKERS"`
+	QueueSize int `yaml:"queue_size" toml:"queue_size" env:"MAGIC_LINK_QUEUE_SIZE"`
+	// BindToBrowser only accepts a link in the browser that asked for it.
+	BindToBrowser bool `yaml:"bind_to_browser" toml:"bind_to_browser" env:"MAGIC_LINK_BIND_TO_BROWSER"`
+}
+
+// PersonalAccessTokenConfig lets users create long-lived, scoped tokens for
+// scripts and integrations under /profile/tokens.
+type PersonalAccessTokenConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"PAT_ENABLED"`
+	// DefaultTTL applies when a token is created without an expiry; MaxTTL
+	// caps the expiry a user can ask for.
+	DefaultTTL time.Duration `yaml:"default_ttl" toml:"default_ttl" env:"PAT_DEFAULT_TTL"`
+	MaxTTL     time.Duration `yaml:"max_ttl" toml:"max_ttl" env:"PAT_MAX_TTL"`
+}
+
+// WebhookConfig records account events in an outbox and delivers them to
+// the URLs subscribed under /admin/webhooks.
+type WebhookConfig struct {
+	Enabled      bool          `yaml:"enabled" toml:"enabled" env:"WEBHOOKS_ENABLED"`
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL"`
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout" env:"WEBHOOKS_TIMEOUT"`
+	// A failed delivery is retried after BackoffBase, doubled after every
+	// further failure up to BackoffMax, and dead after MaxAttempts.
+	MaxAttempts int           `yaml:"max_attempts" toml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS"`
+	BackoffBase time.Duration `yaml:"backoff_base" toml:"backoff_base" env:"WEBHOOKS_BACKOFF_BASE"`
+	BackoffMax  time.Duration `yaml:"backoff_max" toml:"backoff_max" env:"WEBHOOKS_BACKOFF_MAX"`
+}
+
+// TokenExchangeConfig enables the token exchange grant on /oauth/token,
+// which lets support staff impersonate customers and the listed internal
+// services act on behalf of users.
+type TokenExchangeConfig struct {
+	Enabled bool          `yaml:"enabled" toml:"enabled" env:"TOKEN_EXCHANGE_ENABLED"`
+	TTL     time.Duration `yaml:"ttl" toml:"ttl" env:"TOKEN_EXCHANGE_TTL"`
+	// Delegates are the names of the internal API keys allowed to exchange
+	// a user's token for one that records them as the actor.
+	Delegates []string `yaml:"delegates" toml:"delegates" env:"TOKEN_EXCHANGE_DELEGATES"`
+}
+
+// DeviceAuthorizationConfig enables the device authorization grant, which
+// signs in smart TVs and CLIs through a code the user approves elsewhere.
+type DeviceAuthorizationConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"DEVICE_AUTH_ENABLED"`
+	// VerificationURI is the page devices tell the user to open; it signs
+	// the user in, asks for the code and calls /device/approve.
+	VerificationURI string        `yaml:"verification_uri" toml:"verification_uri" env:"DEVICE_AUTH_VERIFICATION_URI"`
+	TTL             time.Duration `yaml:"ttl" toml:"ttl" env:"DEVICE_AUTH_TTL"`
+	// Interval is how long devices wait between polls of the token endpoint.
+	Interval time.Duration `yaml:"interval" toml:"interval" env:"DEVICE_AUTH_INTERVAL"`
+}
+
+// CacheConfig caches the user read on every token validation. The cache is
+// in-process, so with several replicas a revoke reaches the others only when
+// their entry expires.
+type CacheConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"CACHE_ENABLED"`
+	// Size is the most entries each cache holds. It also bounds the opaque
+	// token cache, which is always on.
+	Size int           `yaml:"size" toml:"size" env:"CACHE_SIZE"`
+	TTL  time.Duration `yaml:"ttl" toml:"ttl" env:"CACHE_TTL"`
+}
+
+// SocialConfig enables "Sign in with ..." for every listed provider.
+type SocialConfig struct {
+	// CallbackBaseURL is the public URL of this service; each provider
+	// redirects back to <CallbackBaseURL>/auth/<name>/callback.
+	CallbackBaseURL string `yaml:"callback_base_url" toml:"callback_base_url" env:"SOCIAL_CALLBACK_BASE_URL"`
+	// SuccessURL is the storefront page the browser lands on, with the token
+	// or an error code in the fragment. Without it the callback answers JSON.
+	SuccessURL string                 `yaml:"success_url" toml:"success_url" env:"SOCIAL_SUCCESS_URL"`
+	Providers  []SocialProviderConfig `yaml:"providers" toml:"providers"`
+}
+
+// SocialProviderConfig describes one upstream provider. Its client secret can
+// also be set with SOCIAL_<NAME>_CLIENT_SECRET (or _FILE).
+type SocialProviderConfig struct {
+	Name string `yaml:"name" toml:"name"`
+	// Type is oidc (endpoints discovered from Issuer) or github.
+	Type                  string   `yaml:"type" toml:"type"`
+	Issuer                string   `yaml:"issuer" toml:"issuer"`
+	ClientID              string   `yaml:"client_id" toml:"client_id"`
+	ClientSecret          string   `yaml:"client_secret" toml:"client_secret"`
+	Scopes                []string `yaml:"scopes" toml:"scopes"`
+	AutoLinkVerifiedEmail bool     `yaml:"auto_link_verified_email" toml:"auto_link_verified_email"`
+	// AuthURL, TokenURL and APIURL override the github endpoints, for
+	// GitHub Enterprise.
+	AuthURL  string `yaml:"auth_url" toml:"auth_url"`
+	TokenURL string `yaml:"token_url" toml:"token_url"`
+	APIURL   string `yaml:"api_url" toml:"api_url"`
+}
+
+// ClientSecretEnv is the variable that overrides the provider's secret.
+func (p SocialProviderConfig) ClientSecretEnv() string {
+	return "SOCIAL_" + strings.ToUpper(strings.ReplaceAll(p.Name, "-", "_")) + "_CLIENT_SECRET"
+}
+
+// SAMLConfig enables SAML 2.0 single sign-on with every listed IdP.
+type SAMLConfig struct {
+	// BaseURL is the public URL of this service; each IdP knows us as
+	// <BaseURL>/saml/<name>/metadata and posts to <BaseURL>/saml/<name>/acs.
+	BaseURL string `yaml:"base_url" toml:"base_url" env:"SAML_BASE_URL"`
+	// CertFile and KeyFile hold the PEM certificate and key we sign
+	// requests and decrypt assertions with.
+	CertFile string `yaml:"cert_file" toml:"cert_file" env:"SAML_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" toml:"key_file" env:"SAML_KEY_FILE"`
+	// SuccessURL is the storefront page the browser lands on, with the token
+	// or an error code in the fragment. Without it the ACS answers JSON.
+	SuccessURL string               `yaml:"success_url" toml:"success_url" env:"SAML_SUCCESS_URL"`
+	Providers  []SAMLProviderConfig `yaml:"providers" toml:"providers"`
+}
+
+// SAMLProviderConfig describes one IdP; it can only be set in the config
+// file.
+type SAMLProviderConfig struct {
+	Name string `yaml:"name" toml:"name"`
+	// MetadataURL is fetched on first use; MetadataFile is read on start.
+	MetadataURL      string `yaml:"metadata_url" toml:"metadata_url"`
+	MetadataFile     string `yaml:"metadata_file" toml:"metadata_file"`
+	NameIDFormat     string `yaml:"name_id_format" toml:"name_id_format"`
+	EmailAttribute   string `yaml:"email_attribute" toml:"email_attribute"`
+	NameAttribute    string `yaml:"name_attribute" toml:"name_attribute"`
+	SubjectAttribute string `yaml:"subject_attribute" toml:"subject_attribute"`
+	GroupAttribute   string `yaml:"group_attribute" toml:"group_attribute"`
+	// GroupRoles maps group names to local roles.
+	GroupRoles        map[string]string `yaml:"group_roles" toml:"group_roles"`
+	DefaultRole       string            `yaml:"default_role" toml:"default_role"`
+	ProvisionUsers    bool              `yaml:"provision_users" toml:"provision_users"`
+	LinkExistingEmail bool              `yaml:"link_existing_email" toml:"link_existing_email"`
+	AllowIDPInitiated bool              `yaml:"allow_idp_initiated" toml:"allow_idp_initiated"`
 }
 
-func Load() *Config {
+// LDAPConfig checks logins against an LDAP or Active Directory server when
+// URL is set.
+type LDAPConfig struct {
+	URL string `yaml:"url" toml:"url" env:"LDAP_URL"`
+	// Mode is first (try LDAP, then local passwords) or only (LDAP only).
+	Mode               string `yaml:"mode" toml:"mode" env:"LDAP_MODE"`
+	StartTLS           bool   `yaml:"start_tls" toml:"start_tls" env:"LDAP_START_TLS"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" toml:"insecure_skip_verify" env:"LDAP_INSECURE_SKIP_VERIFY"`
+	BindDN             string `yaml:"bind_dn" toml:"bind_dn" env:"LDAP_BIND_DN"`
+	BindPassword       string `yaml:"bind_password" toml:"bind_password" env:"LDAP_BIND_PASSWORD"`
+	BaseDN             string `yaml:"base_dn" toml:"base_dn" env:"LDAP_BASE_DN"`
+	// UserFilter finds the entry for a login; {login} is replaced by the
+	// escaped value the user typed.
+	UserFilter       string `yaml:"user_filter" toml:"user_filter" env:"LDAP_USER_FILTER"`
+	EmailAttribute   string `yaml:"email_attribute" toml:"email_attribute" env:"LDAP_EMAIL_ATTRIBUTE"`
+	NameAttribute    string `yaml:"name_attribute" toml:"name_attribute" env:"LDAP_NAME_ATTRIBUTE"`
+	SubjectAttribute string `yaml:"subject_attribute" toml:"subject_attribute" env:"LDAP_SUBJECT_ATTRIBUTE"`
+	GroupAttribute   string `yaml:"group_attribute" toml:"group_attribute" env:"LDAP_GROUP_ATTRIBUTE"`
+	// GroupRoles maps group DNs to local roles; it can only be set in the
+	// config file.
+	GroupRoles        map[string]string `yaml:"group_roles" toml:"group_roles"`
+	DefaultRole       string            `yaml:"default_role" toml:"default_role" env:"LDAP_DEFAULT_ROLE"`
+	ProvisionUsers    bool              `yaml:"provision_users" toml:"provision_users" env:"LDAP_PROVISION_USERS"`
+	LinkExistingEmail bool              `yaml:"link_existing_email" toml:"link_existing_email" env:"LDAP_LINK_EXISTING_EMAIL"`
+	Timeout           time.Duration     `yaml:"timeout" toml:"timeout" env:"LDAP_TIMEOUT"`
+}
+
+func Default() *Config {
 	return &Config{
-		ListenAddr:     getEnv("LISTEN_ADDR", ":8081"),
-		JWTSecret:      getEnv("JWT_SECRET", ""),
-		InternalAPIKey: getEnv("INTERNAL_API_KEY", ""),
-		DatabaseURL:    getEnv("DATABASE_URL", ""),
+		ListenAddr:    ":8081",
+		ServiceName:   "auth-service",
+		TraceExporter: "none",
+		LogLevel:      "info",
+		LogFormat:     "json",
+		TokenTTL:      24 * time.Hour,
+		TokenFormat:   "jwt",
+		BcryptCost:    bcrypt.DefaultCost,
+		JWT: JWTConfig{
+			Issuer:   "auth-service",
+			Audience: []string{"auth-service"},
+			Leeway:   30 * time.Second,
+		},
+		OpaqueTokenCacheTTL: 30 * time.Second,
+		HTTP: HTTPConfig{
+			ReadTimeout:        10 * time.Second,
+			ReadHeaderTimeout:  5 * time.Second,
+			WriteTimeout:       15 * time.Second,
+			IdleTimeout:        60 * time.Second,
+			ShutdownTimeout:    15 * time.Second,
+			ShutdownDrainDelay: 5 * time.Second,
+			HealthCheckTimeout: 2 * time.Second,
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-Id"},
+			MaxAge:         10 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:           true,
+			RequestsPerMinute: 60,
+			Burst:             10,
+		},
+		Mailer: MailerConfig{
+			Driver: "none",
+		},
+		MagicLink: MagicLinkConfig{
+			TTL:           15 * time.Minute,
+			MaxPerHour:    5,
+			Workers:       4,
+			QueueSize:     100,
+			BindToBrowser: true,
+		},
+		PersonalAccessTokens: PersonalAccessTokenConfig{
+			DefaultTTL: 90 * 24 * time.Hour,
+			MaxTTL:     365 * 24 * time.Hour,
+		},
+		Webhooks: WebhookConfig{
+			PollInterval: 5 * time.Second,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  10,
+			BackoffBase:  30 * time.Second,
+			BackoffMax:   6 * time.Hour,
+		},
+		TokenExchange: TokenExchangeConfig{
+			TTL: 15 * time.Minute,
+		},
+		DeviceAuthorization: DeviceAuthorizationConfig{
+			TTL:      10 * time.Minute,
+			Interval: 5 * time.Second,
+		},
+		Cache: CacheConfig{
+			Size: 10000,
+			TTL:  5 * time.Second,
+		},
+		LDAP: LDAPConfig{
+			Mode:           "first",
+			UserFilter:     "(&(objectClass=person)(|(mail={login})(sAMAccountName={login})(uid={login})))",
+			EmailAttribute: "mail",
+			NameAttribute:  "displayName",
+			GroupAttribute: "memberOf",
+			DefaultRole:    "user",
+			ProvisionUsers: true,
+			Timeout:        5 * time.Second,
+		},
 	}
 }
 
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
+// Load builds the configuration and validates it, returning every problem
+// found joined into a single error so they can all be fixed in one go.
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path, ok, err := lookupEnv("CONFIG_FILE"); err != nil {
+		return nil, err
+	} else if ok && path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	var problems []error
+	applyEnv(reflect.ValueOf(cfg).Elem(), &problems)
+	for i := range cfg.Social.Providers {
+		provider := &cfg.Social.Providers[i]
+		if secret, ok, err := lookupEnv(provider.ClientSecretEnv()); err != nil {
+			problems = append(problems, err)
+		} else if ok {
+			provider.ClientSecret = secret
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err)
+	}
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("unsupported config file extension %q, expected .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func applyEnv(v reflect.Value, problems *[]error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			applyEnv(value, problems)
+			continue
+		}
+		key := field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		raw, ok, err := lookupEnv(key)
+		if err != nil {
+			*problems = append(*problems, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := setValue(value, raw); err != nil {
+			*problems = append(*problems, fmt.Errorf("%s: %w", key, err))
+		}
 	}
-	return fallback
+}
+
+func setValue(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+// lookupEnv reads KEY, or the contents of the file named by KEY_FILE.
+func lookupEnv(key string) (string, bool, error) {
+	if path, ok := os.LookupEnv(key + "_FILE"); ok && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", key, err)
+		}
+		return strings.TrimSpace(string(data)), true, nil
+	}
+	value, ok := os.LookupEnv(key)
+	return value, ok, nil
+}
+
+func (c *Config) Validate() error {
+	var problems []error
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if c.ListenAddr == "" {
+		add("LISTEN_ADDR is required")
+	}
+	switch keyLength, paseto := pasetoKeyLengths[c.TokenFormat]; {
+	case paseto:
+		if key, err := hex.DecodeString(c.PasetoKey); err != nil || len(key) != keyLength {
+			add("PASETO_KEY must be a %d-byte hex key for %s", keyLength, c.TokenFormat)
+		}
+	case c.TokenFormat == "opaque":
+		if c.OpaqueTokenCacheTTL <= 0 {
+			add("OPAQUE_TOKEN_CACHE_TTL must be positive")
+		}
+	case c.TokenFormat != "jwt":
+		add("TOKEN_FORMAT must be one of jwt, paseto-v4-public, paseto-v4-local, opaque")
+	case c.JWTSecret == "":
+		if c.SigningKeysFile == "" {
+			add("JWT_SECRET or SIGNING_KEYS_FILE is required")
+		}
+	case len(c.JWTSecret) < minSecretLength:
+		add("JWT_SECRET must be at least %d characters long", minSecretLength)
+	}
+	if c.InternalAPIKey == "" {
+		if len(c.InternalAPIKeys) == 0 {
+			add("INTERNAL_API_KEY is required unless internal_api_keys are configured")
+		}
+	} else if len(c.InternalAPIKey) < minSecretLength {
+		add("INTERNAL_API_KEY must be at least %d characters long", minSecretLength)
+	}
+	for i, key := range c.InternalAPIKeys {
+		if key.Name == "" {
+			add("internal_api_keys[%d] needs a name", i)
+		}
+		if _, err := hex.DecodeString(key.KeyHash); err != nil || len(key.KeyHash) != 64 {
+			add("internal_api_keys[%d] key_hash must be a hex SHA-256", i)
+		}
+		if key.ExpiresAt.IsZero() {
+			add("internal_api_keys[%d] needs an expires_at", i)
+		}
+	}
+	if c.DatabaseURL == "" {
+		add("DATABASE_URL is required")
+	} else if u, err := url.Parse(c.DatabaseURL); err != nil || !supportedDatabaseSchemes[u.Scheme] {
+		add("DATABASE_URL must be a postgres://, sqlite:// or memory:// URL")
+	}
+
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		add("LOG_LEVEL must be one of debug, info, warn, error")
+	}
+	switch strings.ToLower(c.LogFormat) {
+	case "json", "text":
+	default:
+		add("LOG_FORMAT must be json or text")
+	}
+	switch strings.ToLower(c.TraceExporter) {
+	case "none", "stdout", "otlp":
+	default:
+		add("OTEL_TRACES_EXPORTER must be one of none, stdout, otlp")
+	}
+	if c.TraceEmailKey != "" && len(c.TraceEmailKey) < minSecretLength {
+		add("TRACE_EMAIL_KEY must be at least %d characters long", minSecretLength)
+	}
+
+	if c.TokenTTL <= 0 {
+		add("TOKEN_TTL must be positive")
+	}
+	if c.JWT.Issuer == "" {
+		add("JWT_ISSUER is required")
+	}
+	if len(c.JWT.Audience) == 0 || slices.Contains(c.JWT.Audience, "") {
+		add("JWT_AUDIENCE must list at least one audience")
+	}
+	if c.JWT.Leeway < 0 || c.JWT.Leeway > maxJWTLeeway {
+		add("JWT_LEEWAY must be between 0 and %s", maxJWTLeeway)
+	}
+	for _, claim := range c.JWT.Claims {
+		if !slices.Contains(jwtUserClaims, claim) {
+			add("JWT_CLAIMS contains unknown claim %q, supported: %s", claim, strings.Join(jwtUserClaims, ", "))
+		}
+	}
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		add("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.HTTP.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", c.HTTP.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTP.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", c.HTTP.ShutdownTimeout},
+		{"HEALTH_CHECK_TIMEOUT", c.HTTP.HealthCheckTimeout},
+	} {
+		if timeout.value <= 0 {
+			add("%s must be positive", timeout.name)
+		}
+	}
+	if c.HTTP.ShutdownDrainDelay < 0 {
+		add("HTTP_SHUTDOWN_DRAIN_DELAY must not be negative")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				add("CORS_ALLOWED_ORIGINS cannot contain * when CORS_ALLOW_CREDENTIALS is true")
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			add("CORS_ALLOWED_ORIGINS contains an invalid origin %q", origin)
+		}
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.RequestsPerMinute <= 0 {
+			add("RATE_LIMIT_REQUESTS_PER_MINUTE must be positive")
+		}
+		if c.RateLimit.Burst <= 0 {
+			add("RATE_LIMIT_BURST must be positive")
+		}
+	}
+
+	switch c.Mailer.Driver {
+	case "none", "log":
+	case "smtp":
+		if c.Mailer.SMTPAddr == "" {
+			add("SMTP_ADDR is required when MAILER is smtp")
+		}
+		if c.Mailer.From == "" {
+			add("MAILER_FROM is required when MAILER is smtp")
+		}
+	default:
+		add("MAILER must be one of none, log, smtp")
+	}
+	if c.MagicLink.URL != "" {
+		if u, err := url.Parse(c.MagicLink.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("MAGIC_LINK_URL must be an absolute http(s) URL")
+		}
+		if c.Mailer.Driver == "none" {
+			add("MAGIC_LINK_URL requires MAILER to be log or smtp")
+		}
+		if c.MagicLink.TTL <= 0 {
+			add("MAGIC_LINK_TTL must be positive")
+		}
+		if c.MagicLink.MaxPerHour <= 0 {
+			add("MAGIC_LINK_MAX_PER_HOUR must be positive")
+		}
+		if c.MagicLink.Workers <= 0 || c.MagicLink.QueueSize <= 0 {
+			add("MAGIC_LINK_WORKERS and MAGIC_LINK_QUEUE_SIZE must be positive")
+		}
+	}
+
+	if c.PersonalAccessTokens.Enabled {
+		if c.PersonalAccessTokens.DefaultTTL <= 0 {
+			add("PAT_DEFAULT_TTL must be positive")
+		}
+		if c.PersonalAccessTokens.MaxTTL < c.PersonalAccessTokens.DefaultTTL {
+			add("PAT_MAX_TTL must not be shorter than PAT_DEFAULT_TTL")
+		}
+	}
+
+	if c.Webhooks.Enabled {
+		if c.Webhooks.PollInterval <= 0 {
+			add("WEBHOOKS_POLL_INTERVAL must be positive")
+		}
+		if c.Webhooks.Timeout <= 0 {
+			add("WEBHOOKS_TIMEOUT must be positive")
+		}
+		if c.Webhooks.MaxAttempts < 1 {
+			add("WEBHOOKS_MAX_ATTEMPTS must be at least 1")
+		}
+		if c.Webhooks.BackoffBase <= 0 {
+			add("WEBHOOKS_BACKOFF_BASE must be positive")
+		}
+		if c.Webhooks.BackoffMax < c.Webhooks.BackoffBase {
+			add("WEBHOOKS_BACKOFF_MAX must not be shorter than WEBHOOKS_BACKOFF_BASE")
+		}
+	}
+
+	if c.TokenExchange.Enabled {
+		if c.TokenExchange.TTL <= 0 || c.TokenExchange.TTL > maxTokenExchangeTTL {
+			add("TOKEN_EXCHANGE_TTL must be positive and at most %s", maxTokenExchangeTTL)
+		}
+	}
+
+	if c.DeviceAuthorization.Enabled {
+		if u, err := url.Parse(c.DeviceAuthorization.VerificationURI); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("DEVICE_AUTH_VERIFICATION_URI must be an absolute http(s) URL")
+		}
+		if c.DeviceAuthorization.TTL <= 0 {
+			add("DEVICE_AUTH_TTL must be positive")
+		}
+		if c.DeviceAuthorization.Interval < time.Second {
+			add("DEVICE_AUTH_INTERVAL must be at least 1s")
+		}
+	}
+
+	if c.Cache.Size < 1 {
+		add("CACHE_SIZE must be at least 1")
+	}
+	if c.Cache.Enabled && c.Cache.TTL <= 0 {
+		add("CACHE_TTL must be positive")
+	}
+
+	if len(c.Social.Providers) > 0 {
+		if u, err := url.Parse(c.Social.CallbackBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("SOCIAL_CALLBACK_BASE_URL must be an absolute http(s) URL when social providers are configured")
+		}
+	}
+	if c.Social.SuccessURL != "" {
+		if u, err := url.Parse(c.Social.SuccessURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("SOCIAL_SUCCESS_URL must be an absolute http(s) URL")
+		}
+	}
+	if c.LDAP.URL != "" {
+		if u, err := url.Parse(c.LDAP.URL); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+			add("LDAP_URL must be an ldap:// or ldaps:// URL")
+		} else if u.Scheme == "ldaps" && c.LDAP.StartTLS {
+			add("LDAP_START_TLS cannot be used with an ldaps:// URL")
+		}
+		if c.LDAP.Mode != "first" && c.LDAP.Mode != "only" {
+			add("LDAP_MODE must be first or only")
+		}
+		if c.LDAP.BaseDN == "" {
+			add("LDAP_BASE_DN is required when LDAP_URL is set")
+		}
+		if !strings.Contains(c.LDAP.UserFilter, "{login}") {
+			add("LDAP_USER_FILTER must contain {login}")
+		}
+		if !validRole(c.LDAP.DefaultRole) {
+			add("LDAP_DEFAULT_ROLE must be user, support or admin")
+		}
+		for group, role := range c.LDAP.GroupRoles {
+			if !validRole(role) {
+				add("ldap group_roles: role for %s must be user, support or admin", group)
+			}
+		}
+		if c.LDAP.Timeout <= 0 {
+			add("LDAP_TIMEOUT must be positive")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, p := range c.Social.Providers {
+		if !socialProviderName.MatchString(p.Name) {
+			add("social provider %d: name must be lowercase letters, digits, - or _", i)
+			continue
+		}
+		if seen[p.Name] {
+			add("social provider %s is configured twice", p.Name)
+		}
+		seen[p.Name] = true
+		switch p.Type {
+		case "oidc":
+			if p.Issuer == "" {
+				add("social provider %s: issuer is required for oidc providers", p.Name)
+			}
+		case "github":
+		default:
+			add("social provider %s: type must be oidc or github", p.Name)
+		}
+		if p.ClientID == "" {
+			add("social provider %s: client_id is required", p.Name)
+		}
+		if p.ClientSecret == "" {
+			add("social provider %s: client_secret or %s is required", p.Name, p.ClientSecretEnv())
+		}
+	}
+
+	if len(c.SAML.Providers) > 0 {
+		if u, err := url.Parse(c.SAML.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("SAML_BASE_URL must be an absolute http(s) URL when SAML providers are configured")
+		}
+		if c.SAML.CertFile == "" || c.SAML.KeyFile == "" {
+			add("SAML_CERT_FILE and SAML_KEY_FILE are required when SAML providers are configured")
+		}
+	}
+	if c.SAML.SuccessURL != "" {
+		if u, err := url.Parse(c.SAML.SuccessURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("SAML_SUCCESS_URL must be an absolute http(s) URL")
+		}
+	}
+	seenSAML := make(map[string]bool)
+	for i, p := range c.SAML.Providers {
+		if !socialProviderName.MatchString(p.Name) {
+			add("SAML provider %d: name must be lowercase letters, digits, - or _", i)
+			continue
+		}
+		if seenSAML[p.Name] {
+			add("SAML provider %s is configured twice", p.Name)
+		}
+		seenSAML[p.Name] = true
+		if (p.MetadataURL == "") == (p.MetadataFile == "") {
+			add("SAML provider %s: set exactly one of metadata_url and metadata_file", p.Name)
+		}
+		if p.DefaultRole != "" && !validRole(p.DefaultRole) {
+			add("SAML provider %s: default_role must be user, support or admin", p.Name)
+		}
+		for group, role := range p.GroupRoles {
+			if !validRole(role) {
+				add("SAML provider %s: role for group %s must be user, support or admin", p.Name, group)
+			}
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+func validRole(role string) bool {
+	return role == "user" || role == "support" || role == "admin"
 }
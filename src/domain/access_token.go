@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AccessTokenPrefix starts every opaque access token, so it can be told
+// apart from a JWT or PASETO token without a lookup.
+const AccessTokenPrefix = "aot_"
+
+// AccessToken is the server-side record behind an opaque access token. The
+// token itself carries no data; its claims are kept here and only a hash
+// of it is stored.
+type AccessToken struct {
+	TokenHash string
+	UserID    string
+	Claims    json.RawMessage
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+func (t *AccessToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.After(now)
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// APIKeyPrefix starts every API key minted by the service, so secret
+// scanners can recognize one that leaked.
+const APIKeyPrefix = "ask_"
+
+// LegacyAPIKeyName identifies callers using the single INTERNAL_API_KEY.
+const LegacyAPIKeyName = "internal"
+
+// APIKey lets another service call the internal endpoints. Name identifies
+// the calling service; during a rotation two keys share it until the old one
+// expires or is revoked. Only a hash of the key is stored.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	KeyHash   string     `json:"-"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Active reports whether the key is accepted at now. A zero ExpiresAt never
+// expires; only the legacy INTERNAL_API_KEY is configured that way.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt.IsZero() || k.ExpiresAt.After(now))
+}
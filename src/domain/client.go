@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// Client is an application registered to obtain tokens from this service.
+type Client struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	SecretHash string    `json:"-"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// GrantTypeDeviceCode is the grant a device polls the token endpoint with,
+// from OAuth 2.0 Device Authorization Grant (RFC 8628).
+const GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+// DeviceCodePrefix starts every device code, so secret scanners can
+// recognize one that leaked.
+const DeviceCodePrefix = "adc_"
+
+// UserCodeAlphabet has no vowels, so user codes never spell words, and no
+// digits, so they are easy to type on a TV remote.
+const UserCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// UserCodeLength is the number of characters in a user code, not counting
+// the dash shown in the middle.
+const UserCodeLength = 8
+
+// Where a device authorization stands.
+const (
+	DeviceAuthorizationPending  = "pending"
+	DeviceAuthorizationApproved = "approved"
+	DeviceAuthorizationDenied   = "denied"
+)
+
+// DeviceAuthorization lets a device without a keyboard or browser, such as
+// a smart TV or a CLI, sign a user in. The device shows UserCode, the user
+// approves it from a signed-in session elsewhere, and the device, polling
+// with the device code, receives a token for that user. Only a hash of the
+// device code is stored.
+type DeviceAuthorization struct {
+	DeviceCodeHash string
+	// UserCode is stored normalized, without the dash.
+	UserCode string
+	ClientID string
+	Status   string
+	// UserID is who approved or denied the request.
+	UserID string
+	// Interval is how long the device must wait between polls. It grows
+	// every time the device polls too fast.
+	Interval     time.Duration
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	LastPolledAt *time.Time
+}
+
+// NormalizeUserCode uppercases a user code as typed and drops everything
+// but letters, so "bcdf-ghjk" and "BCDF GHJK" both match BCDFGHJK.
+func NormalizeUserCode(code string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// FormatUserCode splits a normalized user code in two halves with a dash,
+// the way it is shown to the user.
+func FormatUserCode(code string) string {
+	if len(code) != UserCodeLength {
+		return code
+	}
+	return code[:UserCodeLength/2] + "-" + code[UserCodeLength/2:]
+}
@@ -0,0 +1,18 @@
+package domain
+
+// DirectoryAccount is a user as described by an external password
+// directory such as LDAP or Active Directory.
+type DirectoryAccount struct {
+	// Subject is the directory's stable ID for the account, never the email.
+	Subject string
+	Email   string
+	Name    string
+	// Role is the local role derived from the account's directory groups.
+	Role string
+	// EmailUnverified marks an email the provider has not checked, which is
+	// never used to link or create an account.
+	EmailUnverified bool
+	// EmailVerified marks an email the provider has seen the user prove they
+	// own. Directories and IdPs vouch for neither, so both are false.
+	EmailVerified bool
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// maxEmailLength is the longest address SMTP can carry (RFC 5321 4.5.3.1).
+const maxEmailLength = 254
+
+// NormalizeEmail returns the canonical form an email is stored and looked
+// up by: surrounding whitespace trimmed, a bare RFC 5322 addr-spec, and the
+// domain converted to lowercase ASCII (IDNA). The local part is only
+// lowercased when lowercaseLocal is set, since RFC 5321 leaves its case to
+// the receiving server; lookups stay case-insensitive either way.
+func NormalizeEmail(email string, lowercaseLocal bool) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" || len(email) > maxEmailLength || strings.ContainsAny(email, "<>") {
+		return "", ErrInvalidEmail
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" {
+		return "", ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(addr.Address, "@")
+	local, domain := addr.Address[:at], addr.Address[at+1:]
+	domain, err = idna.Lookup.ToASCII(domain)
+	if err != nil || domain == "" {
+		return "", ErrInvalidEmail
+	}
+	if lowercaseLocal {
+		local = strings.ToLower(local)
+	}
+	return local + "@" + domain, nil
+}
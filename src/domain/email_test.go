@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	cases := []struct {
+		name           string
+		input          string
+		lowercaseLocal bool
+		want           string
+	}{
+		{"trims and lowercases the domain", "  Bob@Example.COM ", false, "Bob@example.com"},
+		{"lowercases the local part when asked", "Bob@Example.COM", true, "bob@example.com"},
+		{"converts unicode domains to punycode", "ana@Exämple.com", false, "ana@xn--exmple-cua.com"},
+		{"keeps plus addressing", "bob+tag@example.com", false, "bob+tag@example.com"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeEmail(tc.input, tc.lowercaseLocal)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestNormalizeEmail_RejectsInvalidSyntax(t *testing.T) {
+	for _, input := range []string{"", "bob", "bob@", "@example.com", "bob@@example.com", "Bob <bob@example.com>", "bob@exa mple.com", "bob@-example-.com"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := NormalizeEmail(input, false)
+
+			assert.ErrorIs(t, err, ErrInvalidEmail)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Event types published to webhook subscribers.
+const (
+	EventUserRegistered = "user.registered"
+	// EventUserEmailVerified is recorded the first time a user proves they
+	// own their address.
+	EventUserEmailVerified = "user.email_verified"
+	// EventUserDeleted is recorded when an account is disabled, which is how
+	// the service removes users while keeping their history.
+	EventUserDeleted     = "user.deleted"
+	EventPasswordChanged = "password.changed"
+)
+
+var EventTypes = []string{EventUserRegistered, EventUserEmailVerified, EventUserDeleted, EventPasswordChanged}
+
+func ValidEventType(eventType string) bool {
+	return slices.Contains(EventTypes, eventType)
+}
+
+// Event is a change recorded in the outbox in the same transaction as the
+// change itself. DispatchedAt is set once a delivery has been queued for
+// every subscription interested in it.
+type Event struct {
+	ID           string          `json:"id"`
+	Type         string          `json:"type"`
+	Data         json.RawMessage `json:"data"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	DispatchedAt *time.Time      `json:"-"`
+}
+
+// UserEventData is the data of every user.* and password.* event.
+type UserEventData struct {
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+}
+
+func NewUserEvent(id, eventType string, user *User, at time.Time) (*Event, error) {
+	data, err := json.Marshal(UserEventData{UserID: user.ID, Email: user.Email, Name: user.Name, Role: user.Role})
+	if err != nil {
+		return nil, err
+	}
+	return &Event{ID: id, Type: eventType, Data: data, CreatedAt: at}, nil
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// Identity links an account at an external provider (Google, GitHub, ...)
+// to a local user. The pair Provider/Subject is unique.
+type Identity struct {
+	UserID   string
+	Provider string
+	// Subject is the provider's stable user ID, never the email.
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
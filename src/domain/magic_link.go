@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// MagicLink is a single-use sign-in link. Only hashes of the emailed token
+// and of the optional browser nonce are stored.
+type MagicLink struct {
+	TokenHash string
+	UserID    string
+	// NonceHash binds the link to the browser that requested it; empty when
+	// the link can be opened anywhere.
+	NonceHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
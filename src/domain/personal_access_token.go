@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, so secret
+// scanners can recognize one that leaked.
+const PersonalAccessTokenPrefix = "asp_"
+
+// Scopes a personal access token can be granted. Session JWTs are not
+// scoped and reach everything their user can.
+const (
+	ScopeProfileRead = "profile:read"
+	ScopeTokensRead  = "tokens:read"
+	ScopeTokensWrite = "tokens:write"
+)
+
+var PersonalAccessTokenScopes = []string{ScopeProfileRead, ScopeTokensRead, ScopeTokensWrite}
+
+// PersonalAccessToken is a long-lived, named credential a user creates for
+// scripts and integrations. Only a hash of the token is stored.
+type PersonalAccessToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"-"`
+}
+
+func (t *PersonalAccessToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.After(now)
+}
+
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(PersonalAccessTokenScopes, scope)
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// SCIMTenant is a customer's identity provider allowed to provision users
+// through SCIM. It only sees the users and groups it created itself.
+type SCIMTenant struct {
+	ID        string
+	Name      string
+	TokenHash string
+	CreatedAt time.Time
+}
+
+// SCIMUser records that a tenant provisioned a user, along with the ID the
+// tenant's identity provider uses for it.
+type SCIMUser struct {
+	TenantID   string
+	UserID     string
+	ExternalID string
+	CreatedAt  time.Time
+	// Deactivated is set when this tenant disabled the account; only then
+	// may the tenant enable it again.
+	Deactivated bool
+}
+
+// SCIMGroup is a group pushed by a tenant. Groups carry no permissions of
+// their own; they are kept so identity providers can sync them.
+type SCIMGroup struct {
+	ID          string
+	TenantID    string
+	DisplayName string
+	ExternalID  string
+	MemberIDs   []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,60 @@
+package domain
+
+import "time"
+
+// Identifiers from OAuth 2.0 Token Exchange (RFC 8693).
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	TokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// Kinds of actor an exchanged token can record.
+const (
+	ActorUser    = "user"
+	ActorService = "service"
+)
+
+// Actor is the act claim of an exchanged token: who is really making the
+// requests the token's subject appears to make. Subject is a user ID for
+// ActorUser and an API key name for ActorService. Exchanging the token again
+// nests the previous actor in Actor, so the chain runs from the current
+// actor back to the first.
+type Actor struct {
+	Subject string `json:"sub"`
+	Type    string `json:"type"`
+	Email   string `json:"email,omitempty"`
+	Actor   *Actor `json:"act,omitempty"`
+}
+
+// Chain lists the actors from the current one back to the first.
+func (a *Actor) Chain() []*Actor {
+	var chain []*Actor
+	for actor := a; actor != nil; actor = actor.Actor {
+		chain = append(chain, actor)
+	}
+	return chain
+}
+
+// TokenExchange is a token exchange request. A user impersonates someone by
+// sending their own session as ActorToken and the account to act as in
+// RequestedSubject; an internal service (Caller) acts on behalf of a user by
+// sending that user's token as SubjectToken. The issued token names only
+// Audience, or the first configured audience when it is empty.
+type TokenExchange struct {
+	SubjectToken     string
+	SubjectTokenType string
+	ActorToken       string
+	ActorTokenType   string
+	RequestedSubject string
+	Audience         string
+	Caller           *APIKey
+}
+
+// ExchangedToken is the short-lived token a token exchange issues.
+type ExchangedToken struct {
+	AccessToken string
+	ExpiresAt   time.Time
+	UserID      string
+	Actor       *Actor
+}
@@ -1,11 +1,52 @@
 package domain
 
-import "time"
+import (
+	"slices"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+	// RoleSupport is for support staff, who may act as customers to debug
+	// their accounts but cannot administer the service.
+	RoleSupport = "support"
+)
+
+// PermissionImpersonate lets a user exchange their session for a token that
+// acts as another, non-admin user.
+const PermissionImpersonate = "users:impersonate"
+
+var rolePermissions = map[string][]string{
+	RoleAdmin:   {PermissionImpersonate},
+	RoleSupport: {PermissionImpersonate},
+}
+
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin || role == RoleSupport
+}
 
 type User struct {
 	ID           string    `json:"id"`
 	Name         string    `json:"name"`
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"`
+	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"createdAt"`
+	// DisabledAt is set when the account has been disabled by an operator.
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+	// TokensValidAfter revokes every token issued before it.
+	TokensValidAfter *time.Time `json:"-"`
+	// EmailVerifiedAt is when the user first proved they own Email, by a
+	// magic link or a social login whose provider verified the address. It
+	// is cleared when the email changes.
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+}
+
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+func (u *User) Can(permission string) bool {
+	return slices.Contains(rolePermissions[u.Role], permission)
 }
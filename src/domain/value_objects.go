@@ -16,4 +16,55 @@ var (
 	ErrUnexpected            = errors.New("an unexpected error occurred")
 	ErrInvalidRequestBody    = errors.New("invalid request body")
 	ErrFailedHashingPassword = errors.New("failed to hash password")
+	ErrRateLimited           = errors.New("too many requests, try again later")
+	ErrAccountDisabled       = errors.New("account is disabled")
+	ErrTokenRevoked          = errors.New("token has been revoked")
+	ErrClientNotFound        = errors.New("client not found")
+	ErrInvalidRole           = errors.New("invalid role")
+	ErrInvalidEmail          = errors.New("invalid email address")
+	ErrMagicLinkInvalid      = errors.New("sign-in link is invalid, expired or already used")
+	ErrUnknownProvider       = errors.New("unknown identity provider")
+	ErrSocialLoginFailed     = errors.New("sign-in with the external provider failed")
+	ErrSAMLLoginFailed       = errors.New("SAML sign-in failed")
+	ErrAssertionConsumed     = errors.New("SAML assertion was already consumed")
+	ErrEmailNotVerified      = errors.New("the provider did not confirm this email address")
+	ErrAccountLinkRequired   = errors.New("an account with this email already exists, sign in with it first")
+	ErrIdentityNotFound      = errors.New("external identity not found")
+	ErrIdentityAlreadyLinked = errors.New("external identity is already linked to an account")
+	ErrSCIMTenantNotFound    = errors.New("SCIM tenant not found")
+	ErrSCIMUserExists        = errors.New("user is already provisioned by this tenant")
+	ErrSCIMGroupNotFound     = errors.New("SCIM group not found")
+	ErrSCIMGroupExists       = errors.New("a group with this display name already exists")
+	ErrTokenNotFound         = errors.New("personal access token not found")
+	ErrTokenNameRequired     = errors.New("token name is required")
+	ErrInvalidScope          = errors.New("unknown or missing token scope")
+	ErrInvalidExpiry         = errors.New("token expiry must be in the future and within the allowed lifetime")
+	ErrInsufficientScope     = errors.New("token does not grant the required scope")
+	ErrAPIKeyNotFound        = errors.New("API key not found")
+	ErrAPIKeyNameRequired    = errors.New("API key name is required")
+	ErrInvalidAPIKey         = errors.New("invalid, expired or revoked API key")
+	ErrAdminRequired         = errors.New("this operation requires an administrator")
+	ErrWebhookNotFound       = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound      = errors.New("dead webhook delivery not found")
+	ErrEventNotFound         = errors.New("event not found")
+	ErrInvalidWebhookURL     = errors.New("webhook URL must be an absolute http or https URL")
+	ErrInvalidEventType      = errors.New("unknown or missing event type")
+	ErrUnsupportedGrantType  = errors.New("unsupported grant type")
+	ErrInvalidExchange       = errors.New("token exchange needs a subject token or requested subject, and an actor")
+	ErrInvalidGrant          = errors.New("subject or actor token is invalid, expired or revoked")
+	ErrInvalidTarget         = errors.New("tokens are only issued for the configured audiences")
+	ErrUnsupportedTokenType  = errors.New("tokens in this format cannot be revoked one at a time")
+	ErrExchangeNotPermitted  = errors.New("not allowed to act on behalf of this user")
+	ErrUserCodeInvalid       = errors.New("code is invalid, expired or already used")
+	ErrAuthorizationPending  = errors.New("the user has not approved the device yet")
+	ErrSlowDown              = errors.New("polling too fast, wait longer between requests")
+	ErrAccessDenied          = errors.New("the user denied the device")
+	ErrDeviceCodeExpired     = errors.New("device code has expired, start over")
+	ErrDeviceCodeInvalid     = errors.New("device code is invalid or was already used")
+	ErrSessionRequired       = errors.New("this operation requires a signed-in session")
+	ErrClientIDRequired      = errors.New("client_id is required")
+	ErrTokenExpired          = errors.New("token has expired")
+	ErrTokenNotYetValid      = errors.New("token is not valid yet")
+	ErrTokenAudience         = errors.New("token was not issued for this audience")
+	ErrTokenIssuer           = errors.New("token was issued by someone else")
 )
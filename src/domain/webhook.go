@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// WebhookSecretPrefix starts every webhook signing secret.
+const WebhookSecretPrefix = "whsec_"
+
+// Delivery states. A delivery that keeps failing ends up dead and is only
+// attempted again when an administrator retries it.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookSubscription receives the events of the listed types. The secret
+// signs every request and, unlike credentials the service checks, has to be
+// stored in the clear.
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	return slices.Contains(s.Events, eventType)
+}
+
+// WebhookDelivery tracks one event on its way to one subscription.
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	EventID        string     `json:"eventId"`
+	SubscriptionID string     `json:"subscriptionId"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`
+	LastError      string     `json:"lastError,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+}
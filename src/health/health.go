@@ -0,0 +1,147 @@
+package health
+
+import (
+	"auth-service/src/jwt"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusError       = "error"
+)
+
+var ErrShuttingDown = errors.New("service is shutting down")
+
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type Checker struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// MarkShuttingDown makes every subsequent readiness probe fail so that load
+// balancers stop routing traffic while in-flight requests are drained.
+func (c *Checker) MarkShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(c.checks)+1)}
+	if c.shuttingDown.Load() {
+		report.Status = StatusUnavailable
+		report.Checks["shutdown"] = CheckResult{Status: StatusError, Duration: "0s", Error: ErrShuttingDown.Error()}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Run(ctx)
+			result := CheckResult{Status: StatusOK, Duration: time.Since(start).String()}
+			if err != nil {
+				result.Status = StatusError
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if err != nil {
+				report.Status = StatusUnavailable
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+func (c *Checker) HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+func (c *Checker) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	report := c.Ready(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+func DatabaseCheck(db Pinger) Check {
+	return Check{Name: "database", Run: db.Ping}
+}
+
+func SigningKeysCheck(keys jwt.KeyProvider) Check {
+	return Check{Name: "signing_keys", Run: func(ctx context.Context) error {
+		if _, err := keys.SigningKey(); err != nil {
+			return fmt.Errorf("no signing key loaded: %w", err)
+		}
+		return nil
+	}}
+}
+
+// MigrationCheck compares the version recorded in schema_migrations with
+// the latest migration embedded in this binary.
+func MigrationCheck(versionOf func(ctx context.Context) (uint, bool, error), expected uint) Check {
+	return Check{Name: "migrations", Run: func(ctx context.Context) error {
+		version, dirty, err := versionOf(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read migration version: %w", err)
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if version != expected {
+			return fmt.Errorf("migration version is %d, expected %d", version, expected)
+		}
+		return nil
+	}}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to write JSON response", "error", err)
+	}
+}
@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func passing(name string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error { return nil }}
+}
+
+func failing(name string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error { return errors.New("boom") }}
+}
+
+func TestHandleReadiness_AllChecksPass(t *testing.T) {
+	// Arrange
+	checker := NewChecker(time.Second, passing("database"), passing("signing_keys"))
+	rr := httptest.NewRecorder()
+
+	// Act
+	checker.HandleReadiness(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report Report
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, StatusOK, report.Checks["signing_keys"].Status)
+}
+
+func TestHandleReadiness_FailingCheck(t *testing.T) {
+	// Arrange
+	checker := NewChecker(time.Second, passing("database"), failing("migrations"))
+	rr := httptest.NewRecorder()
+
+	// Act
+	checker.HandleReadiness(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var report Report
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, "boom", report.Checks["migrations"].Error)
+}
+
+func TestHandleReadiness_ShuttingDown(t *testing.T) {
+	// Arrange
+	checker := NewChecker(time.Second, passing("database"))
+	checker.MarkShuttingDown()
+	rr := httptest.NewRecorder()
+
+	// Act
+	checker.HandleReadiness(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	// Assert: liveness continua OK, apenas a prontidão falha
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	live := httptest.NewRecorder()
+	checker.HandleLiveness(live, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, live.Code)
+}
+
+func TestMigrationCheck(t *testing.T) {
+	versionOf := func(version uint, dirty bool) func(ctx context.Context) (uint, bool, error) {
+		return func(ctx context.Context) (uint, bool, error) { return version, dirty, nil }
+	}
+
+	assert.NoError(t, MigrationCheck(versionOf(3, false), 3).Run(context.Background()))
+	assert.ErrorContains(t, MigrationCheck(versionOf(2, false), 3).Run(context.Background()), "expected 3")
+	assert.ErrorContains(t, MigrationCheck(versionOf(3, true), 3).Run(context.Background()), "dirty")
+}
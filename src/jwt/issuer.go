@@ -0,0 +1,50 @@
+package jwt
+
+import (
+	"auth-service/src/domain"
+	"context"
+	"slices"
+	"time"
+)
+
+// TokenIssuer mints and validates session tokens in one format, so the
+// services do not depend on whether they are JWTs or PASETOs.
+type TokenIssuer interface {
+	// Issue mints a token for user that expires at expiresAt. actor, when
+	// set, is recorded in the act claim; opts are applied after the ones
+	// the issuer was created with.
+	Issue(ctx context.Context, user *domain.User, actor *domain.Actor, expiresAt time.Time, opts ...Option) (string, error)
+	// Validate checks the token and returns its claims, with iat, nbf and
+	// exp as Unix seconds whatever the format.
+	Validate(ctx context.Context, token string) (map[string]interface{}, error)
+}
+
+// Revoker is implemented by the issuers whose tokens can be revoked one at
+// a time. Self-contained tokens cannot, so only the opaque issuer has it.
+type Revoker interface {
+	// Revoke makes token invalid. A token the issuer does not know is not
+	// an error.
+	Revoke(ctx context.Context, token string) error
+}
+
+type jwtIssuer struct {
+	keys KeyProvider
+	opts []Option
+}
+
+// NewJWTIssuer mints HS256 JWTs signed with the current key of keys.
+func NewJWTIssuer(keys KeyProvider, opts ...Option) TokenIssuer {
+	return &jwtIssuer{keys: keys, opts: opts}
+}
+
+func (i *jwtIssuer) Issue(ctx context.Context, user *domain.User, actor *domain.Actor, expiresAt time.Time, opts ...Option) (string, error) {
+	return create(user, actor, i.keys, time.Now(), expiresAt, newOptions(append(slices.Clone(i.opts), opts...)))
+}
+
+func (i *jwtIssuer) Validate(ctx context.Context, token string) (map[string]interface{}, error) {
+	claims, err := ValidateToken(token, i.keys, i.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
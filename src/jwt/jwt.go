@@ -2,40 +2,287 @@ package jwt
 
 import (
 	"auth-service/src/domain"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-func CreateToken(user *domain.User, secret string) (string, error) {
-	if secret == "" {
-		return "", domain.ErrJwtSecretMissing
+// reservedClaims are set by the service itself and cannot be replaced by a
+// ClaimsFunc.
+var reservedClaims = []string{"sub", "email", "act", "iss", "aud", "iat", "nbf", "exp", "jti"}
+
+// ClaimsFunc adds claims taken from the user record, such as name, role or
+// tenant, to every token minted for them.
+type ClaimsFunc func(user *domain.User) map[string]interface{}
+
+type options struct {
+	issuer   string
+	audience []string
+	leeway   time.Duration
+	claims   ClaimsFunc
+	// err is set by an option that cannot apply, and fails Issue.
+	err error
+}
+
+// Option configures the registered claims set on tokens and how strictly
+// they are validated. The same options must be passed when creating and
+// validating, or the service rejects its own tokens.
+type Option func(*options)
+
+// WithIssuer sets iss on new tokens and rejects tokens from other issuers.
+func WithIssuer(issuer string) Option {
+	return func(o *options) {
+		o.issuer = issuer
+	}
+}
+
+// WithAudience sets aud on new tokens. When validating, a token is accepted
+// if its aud names any of them.
+func WithAudience(audience ...string) Option {
+	return func(o *options) {
+		o.audience = audience
+	}
+}
+
+// AddAudience appends to the audiences set so far, for tokens minted for a
+// particular client.
+func AddAudience(audience ...string) Option {
+	return func(o *options) {
+		o.audience = append(slices.Clone(o.audience), audience...)
+	}
+}
+
+// ForAudience mints a token for one of the audiences set so far instead of
+// all of them: target, or the first one when target is empty. A target
+// that was not set is domain.ErrInvalidTarget. It is only meant for
+// issuing; validation must still accept every audience.
+func ForAudience(target string) Option {
+	return func(o *options) {
+		switch {
+		case len(o.audience) == 0:
+		case target == "":
+			o.audience = o.audience[:1]
+		case slices.Contains(o.audience, target):
+			o.audience = []string{target}
+		default:
+			o.err = fmt.Errorf("Error issuing token for %q: %w", target, domain.ErrInvalidTarget)
+		}
+	}
+}
+
+// WithLeeway tolerates that much clock skew on exp, nbf and iat.
+func WithLeeway(leeway time.Duration) Option {
+	return func(o *options) {
+		o.leeway = leeway
+	}
+}
+
+// WithClaims adds custom claims to new tokens. It has no effect on
+// validation.
+func WithClaims(fn ClaimsFunc) Option {
+	return func(o *options) {
+		o.claims = fn
+	}
+}
+
+// UserClaims copies the named fields of the user record into the token.
+// Supported names are name and role.
+func UserClaims(names ...string) ClaimsFunc {
+	return func(user *domain.User) map[string]interface{} {
+		claims := map[string]interface{}{}
+		for _, name := range names {
+			switch name {
+			case "name":
+				claims["name"] = user.Name
+			case "role":
+				claims["role"] = user.Role
+			}
+		}
+		return claims
 	}
-	claims := &jwt.MapClaims{
-		"sub":   user.ID,
-		"email": user.Email,
-		"exp":   time.Now().Add(time.Hour * 24).Unix(),
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
 	}
+	return o
+}
+
+func CreateToken(user *domain.User, keys KeyProvider, ttl time.Duration, opts ...Option) (string, error) {
+	now := time.Now()
+	return create(user, nil, keys, now, now.Add(ttl), newOptions(opts))
+}
+
+// CreateActorToken mints a token for user that records actor in the act
+// claim, as RFC 8693 token exchange defines it.
+func CreateActorToken(user *domain.User, actor *domain.Actor, keys KeyProvider, expiresAt time.Time, opts ...Option) (string, error) {
+	return create(user, actor, keys, time.Now(), expiresAt, newOptions(opts))
+}
+
+func create(user *domain.User, actor *domain.Actor, keys KeyProvider, now, expiresAt time.Time, o *options) (string, error) {
+	key, err := keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	claims, err := newClaims(user, actor, now, expiresAt, o)
+	if err != nil {
+		return "", err
+	}
+	return sign(claims, key)
+}
+
+// newClaims builds the claims of a token whatever its format, with the
+// times as Unix seconds.
+func newClaims(user *domain.User, actor *domain.Actor, now, expiresAt time.Time, o *options) (jwt.MapClaims, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	claims := jwt.MapClaims{}
+	if o.claims != nil {
+		for name, value := range o.claims(user) {
+			if !slices.Contains(reservedClaims, name) {
+				claims[name] = value
+			}
+		}
+	}
+	claims["sub"] = user.ID
+	claims["email"] = user.Email
+	if actor != nil {
+		claims["act"] = actor
+	}
+	if o.issuer != "" {
+		claims["iss"] = o.issuer
+	}
+	if len(o.audience) > 0 {
+		claims["aud"] = o.audience
+	}
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = expiresAt.Unix()
+	claims["jti"] = uuid.NewString()
+	return claims, nil
+}
+
+func sign(claims jwt.MapClaims, key Key) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString([]byte(key.Secret))
 }
 
-func ValidateToken(tokenString, secret string) (jwt.MapClaims, error) {
-	if secret == "" {
-		return nil, domain.ErrJwtSecretMissing
+// ValidateToken checks the signature and the registered claims. exp is
+// required; iss and aud are checked when configured. Failures are reported
+// as domain.ErrTokenExpired, ErrTokenNotYetValid, ErrTokenAudience,
+// ErrTokenIssuer or, for anything else, ErrInvalidToken.
+func ValidateToken(tokenString string, keys KeyProvider, opts ...Option) (jwt.MapClaims, error) {
+	if _, err := keys.SigningKey(); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+	parserOpts := []jwt.ParserOption{
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(o.leeway),
+	}
+	if o.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(o.issuer))
+	}
+	if len(o.audience) > 0 {
+		parserOpts = append(parserOpts, jwt.WithAudience(o.audience...))
 	}
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signature method: %v", token.Header["alg"])
 		}
-		return []byte(secret), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, err := keys.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(key.Secret), nil
+	}, parserOpts...)
 	if err != nil {
-		return nil, err
+		return nil, validationError(err)
 	}
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		return claims, nil
 	}
 	return nil, domain.ErrInvalidToken
 }
+
+// validationError turns the parser's error into the domain error callers
+// can tell apart, keeping the detail for logs.
+func validationError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenMalformed), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return fmt.Errorf("%w: %w", domain.ErrInvalidToken, err)
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return fmt.Errorf("%w: %w", domain.ErrTokenExpired, err)
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return fmt.Errorf("%w: %w", domain.ErrTokenNotYetValid, err)
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return fmt.Errorf("%w: %w", domain.ErrTokenAudience, err)
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return fmt.Errorf("%w: %w", domain.ErrTokenIssuer, err)
+	}
+	return fmt.Errorf("%w: %w", domain.ErrInvalidToken, err)
+}
+
+// HasAudience reports whether the aud claim names audience.
+func HasAudience(claims jwt.MapClaims, audience string) bool {
+	aud, err := claims.GetAudience()
+	return err == nil && slices.Contains(aud, audience)
+}
+
+// IssuedAt returns the iat claim, or the zero time for tokens minted before it was added.
+func IssuedAt(claims map[string]interface{}) time.Time {
+	if iat, ok := claims["iat"].(float64); ok {
+		return time.Unix(int64(iat), 0)
+	}
+	return time.Time{}
+}
+
+// ExpiresAt returns the exp claim, or the zero time when there is none.
+func ExpiresAt(claims map[string]interface{}) time.Time {
+	if exp, ok := claims["exp"].(float64); ok {
+		return time.Unix(int64(exp), 0)
+	}
+	return time.Time{}
+}
+
+// Actor returns the act claim of an exchanged token, or nil for a token
+// issued to its subject directly.
+func Actor(claims map[string]interface{}) (*domain.Actor, error) {
+	act, ok := claims["act"]
+	if !ok {
+		return nil, nil
+	}
+	raw, err := json.Marshal(act)
+	if err != nil {
+		return nil, domain.ErrInvalidToken
+	}
+	actor := &domain.Actor{}
+	if err := json.Unmarshal(raw, actor); err != nil || actor.Subject == "" {
+		return nil, domain.ErrInvalidToken
+	}
+	return actor, nil
+}
+
+// Decode returns the header and claims of a token without verifying it, for
+// diagnostics only.
+func Decode(tokenString string) (map[string]interface{}, jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+	return token.Header, claims, nil
+}
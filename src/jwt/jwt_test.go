@@ -2,9 +2,11 @@ package jwt
 
 import (
 	"auth-service/src/domain"
+	"path/filepath"
 	"testing"
 	"time"
 
+	jwtlib "github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -18,14 +20,14 @@ func TestCreateAndValidateToken(t *testing.T) {
 	secret := "my-super-secret-key-for-testing"
 
 	// Act: Cria o token
-	tokenString, err := CreateToken(user, secret)
+	tokenString, err := CreateToken(user, NewStaticKeys(secret), time.Hour)
 
 	// Assert: Verifica se a criação foi bem-sucedida
 	require.NoError(t, err)
 	require.NotEmpty(t, tokenString)
 
 	// Act: Valida o token recém-criado
-	claims, err := ValidateToken(tokenString, secret)
+	claims, err := ValidateToken(tokenString, NewStaticKeys(secret))
 
 	// Assert: Verifica se a validação foi bem-sucedida e se os dados estão corretos
 	assert.NoError(t, err)
@@ -39,17 +41,154 @@ func TestCreateAndValidateToken(t *testing.T) {
 	assert.Greater(t, exp, float64(time.Now().Unix()))
 }
 
+func TestCreateActorToken_RecordsActorChain(t *testing.T) {
+	// Arrange
+	user := &domain.User{ID: "customer-id", Email: "customer@example.com"}
+	actor := &domain.Actor{Subject: "orders", Type: domain.ActorService, Actor: &domain.Actor{Subject: "agent-id", Type: domain.ActorUser, Email: "agent@example.com"}}
+	keys := NewStaticKeys("my-super-secret-key-for-testing")
+	expiresAt := time.Now().Add(5 * time.Minute).Truncate(time.Second)
+
+	// Act
+	tokenString, err := CreateActorToken(user, actor, keys, expiresAt)
+	require.NoError(t, err)
+	claims, err := ValidateToken(tokenString, keys)
+	require.NoError(t, err)
+	parsed, err := Actor(claims)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims["sub"])
+	assert.Equal(t, actor, parsed)
+	assert.Equal(t, expiresAt, ExpiresAt(claims))
+}
+
+func TestActor_AbsentOrMalformed(t *testing.T) {
+	// Act
+	none, noneErr := Actor(map[string]interface{}{"sub": "user-id"})
+	_, badErr := Actor(map[string]interface{}{"act": "orders"})
+
+	// Assert
+	assert.NoError(t, noneErr)
+	assert.Nil(t, none)
+	assert.ErrorIs(t, badErr, domain.ErrInvalidToken)
+}
+
+func TestCreateToken_SetsRegisteredAndCustomClaims(t *testing.T) {
+	// Arrange
+	user := &domain.User{ID: "user-id", Email: "test@example.com", Name: "Ana", Role: "admin"}
+	keys := NewStaticKeys("my-super-secret-key-for-testing")
+	opts := []Option{
+		WithIssuer("https://auth.example.com"),
+		WithAudience("storefront"),
+		WithClaims(func(u *domain.User) map[string]interface{} {
+			claims := UserClaims("name", "role")(u)
+			claims["tenant"] = "acme"
+			claims["sub"] = "someone-else"
+			return claims
+		}),
+	}
+
+	// Act
+	tokenString, err := CreateToken(user, keys, time.Hour, append(opts, AddAudience("tv-app"))...)
+	require.NoError(t, err)
+	claims, err := ValidateToken(tokenString, keys, opts...)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "https://auth.example.com", claims["iss"])
+	assert.Equal(t, []interface{}{"storefront", "tv-app"}, claims["aud"])
+	assert.True(t, HasAudience(claims, "tv-app"))
+	assert.False(t, HasAudience(claims, "orders"))
+	assert.Equal(t, claims["iat"], claims["nbf"])
+	assert.NotEmpty(t, claims["jti"])
+	assert.Equal(t, "user-id", claims["sub"])
+	assert.Equal(t, "Ana", claims["name"])
+	assert.Equal(t, "admin", claims["role"])
+	assert.Equal(t, "acme", claims["tenant"])
+}
+
+func TestCreateToken_ForAudience(t *testing.T) {
+	// Arrange
+	user := &domain.User{ID: "user-id", Email: "test@example.com"}
+	keys := NewStaticKeys("my-super-secret-key-for-testing")
+	opts := []Option{WithAudience("storefront", "tv-app")}
+
+	// Act
+	chosen, chosenErr := CreateToken(user, keys, time.Hour, append(opts, ForAudience("tv-app"))...)
+	fallback, fallbackErr := CreateToken(user, keys, time.Hour, append(opts, ForAudience(""))...)
+	_, unknownErr := CreateToken(user, keys, time.Hour, append(opts, ForAudience("orders"))...)
+
+	// Assert
+	require.NoError(t, chosenErr)
+	require.NoError(t, fallbackErr)
+	chosenClaims, err := ValidateToken(chosen, keys, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"tv-app"}, chosenClaims["aud"])
+	fallbackClaims, err := ValidateToken(fallback, keys, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"storefront"}, fallbackClaims["aud"])
+	assert.ErrorIs(t, unknownErr, domain.ErrInvalidTarget)
+}
+
+func TestValidateToken_TypedErrors(t *testing.T) {
+	// Arrange
+	keys := NewStaticKeys("my-super-secret-key-for-testing")
+	key, err := keys.SigningKey()
+	require.NoError(t, err)
+	now := time.Now()
+	opts := []Option{WithIssuer("auth-service"), WithAudience("storefront", "tv-app"), WithLeeway(30 * time.Second)}
+	token := func(overrides jwtlib.MapClaims) string {
+		claims := jwtlib.MapClaims{"sub": "user-id", "iss": "auth-service", "aud": []string{"tv-app"}, "iat": now.Unix(), "nbf": now.Unix(), "exp": now.Add(time.Hour).Unix()}
+		for name, value := range overrides {
+			if value == nil {
+				delete(claims, name)
+				continue
+			}
+			claims[name] = value
+		}
+		tokenString, err := sign(claims, key)
+		require.NoError(t, err)
+		return tokenString
+	}
+	cases := []struct {
+		name   string
+		claims jwtlib.MapClaims
+		err    error
+	}{
+		{"expired", jwtlib.MapClaims{"exp": now.Add(-time.Minute).Unix()}, domain.ErrTokenExpired},
+		{"not yet valid", jwtlib.MapClaims{"nbf": now.Add(time.Minute).Unix()}, domain.ErrTokenNotYetValid},
+		{"issued in the future", jwtlib.MapClaims{"iat": now.Add(time.Minute).Unix()}, domain.ErrTokenNotYetValid},
+		{"wrong audience", jwtlib.MapClaims{"aud": "orders"}, domain.ErrTokenAudience},
+		{"no audience", jwtlib.MapClaims{"aud": nil}, domain.ErrInvalidToken},
+		{"wrong issuer", jwtlib.MapClaims{"iss": "someone-else"}, domain.ErrTokenIssuer},
+		{"no expiry", jwtlib.MapClaims{"exp": nil}, domain.ErrInvalidToken},
+	}
+
+	// Act
+	_, skewErr := ValidateToken(token(jwtlib.MapClaims{"exp": now.Add(-10 * time.Second).Unix(), "nbf": now.Add(10 * time.Second).Unix()}), keys, opts...)
+
+	// Assert
+	assert.NoError(t, skewErr, "clock skew within the leeway is tolerated")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := ValidateToken(token(tc.claims), keys, opts...)
+			assert.Nil(t, claims)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
 func TestValidateToken_InvalidSignature(t *testing.T) {
 	// Arrange
 	user := &domain.User{ID: "user-id", Email: "test@example.com"}
 	secret1 := "secret-one"
 	secret2 := "secret-two" // secret diferente
 
-	tokenString, err := CreateToken(user, secret1)
+	tokenString, err := CreateToken(user, NewStaticKeys(secret1), time.Hour)
 	require.NoError(t, err)
 
 	// Act: Tenta validar o token com o secret errado
-	claims, err := ValidateToken(tokenString, secret2)
+	claims, err := ValidateToken(tokenString, NewStaticKeys(secret2))
 
 	// Assert
 	assert.Error(t, err)
@@ -59,9 +198,61 @@ func TestValidateToken_InvalidSignature(t *testing.T) {
 
 func TestValidateToken_MissingSecret(t *testing.T) {
 	// Act: Tenta validar com um secret vazio
-	_, err := ValidateToken("any-token", "")
+	_, err := ValidateToken("any-token", NewStaticKeys(""))
 
 	// Assert
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, domain.ErrJwtSecretMissing)
 }
+
+func TestKeySet_RotationKeepsOldTokensValid(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "keys.json")
+	ks := &KeySet{}
+	_, err := ks.Rotate(time.Now(), time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, ks.Save(path))
+
+	keys, err := NewFileKeys(path, "legacy-secret")
+	require.NoError(t, err)
+	user := &domain.User{ID: "user-id", Email: "test@example.com"}
+	oldToken, err := CreateToken(user, keys, time.Hour)
+	require.NoError(t, err)
+	legacyToken, err := CreateToken(user, NewStaticKeys("legacy-secret"), time.Hour)
+	require.NoError(t, err)
+
+	// Act: rotaciona e recarrega o arquivo
+	newKey, err := ks.Rotate(time.Now(), time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, ks.Save(path))
+	keys, err = NewFileKeys(path, "legacy-secret")
+	require.NoError(t, err)
+
+	// Assert
+	signing, err := keys.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, newKey.ID, signing.ID)
+	_, err = ValidateToken(oldToken, keys)
+	assert.NoError(t, err)
+	_, err = ValidateToken(legacyToken, keys)
+	assert.NoError(t, err)
+}
+
+func TestKeySet_RotatePrunesExpiredKeys(t *testing.T) {
+	// Arrange
+	ks := &KeySet{}
+	start := time.Now()
+	first, err := ks.Rotate(start, time.Hour)
+	require.NoError(t, err)
+
+	// Act
+	_, err = ks.Rotate(start, time.Hour)
+	require.NoError(t, err)
+	_, err = ks.Rotate(start.Add(2*time.Hour), time.Hour)
+	require.NoError(t, err)
+
+	// Assert
+	_, err = ks.VerificationKey(first.ID)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Len(t, ks.Keys, 2)
+}
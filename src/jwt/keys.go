@@ -0,0 +1,197 @@
+package jwt
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/secret"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type Key struct {
+	ID        string     `json:"kid"`
+	Secret    string     `json:"secret"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RetiredAt *time.Time `json:"retiredAt,omitempty"`
+}
+
+// KeyProvider supplies the key used to sign new tokens and the keys still
+// accepted when verifying tokens signed before a rotation.
+type KeyProvider interface {
+	SigningKey() (Key, error)
+	VerificationKey(kid string) (Key, error)
+}
+
+type staticKeys struct {
+	secret string
+}
+
+// NewStaticKeys wraps the single JWT_SECRET. Tokens signed with it carry no kid.
+func NewStaticKeys(secret string) KeyProvider {
+	return staticKeys{secret: secret}
+}
+
+func (k staticKeys) SigningKey() (Key, error) {
+	if k.secret == "" {
+		return Key{}, domain.ErrJwtSecretMissing
+	}
+	return Key{Secret: k.secret}, nil
+}
+
+func (k staticKeys) VerificationKey(kid string) (Key, error) {
+	if k.secret == "" {
+		return Key{}, domain.ErrJwtSecretMissing
+	}
+	if kid != "" {
+		return Key{}, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	return Key{Secret: k.secret}, nil
+}
+
+// KeySet is the on-disk format written by `auth-service keys rotate`. The
+// newest non-retired key signs; retired keys only verify until they are pruned.
+type KeySet struct {
+	Keys []Key `json:"keys"`
+}
+
+func LoadKeySet(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &KeySet{}, nil
+		}
+		return nil, fmt.Errorf("failed to read signing keys: %w", err)
+	}
+	var ks KeySet
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("failed to parse signing keys: %w", err)
+	}
+	return &ks, nil
+}
+
+func (ks *KeySet) Save(path string) error {
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write signing keys: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Rotate adds a fresh signing key, retires the current one and prunes keys
+// retired for longer than retain (which should exceed the token TTL).
+func (ks *KeySet) Rotate(now time.Time, retain time.Duration) (Key, error) {
+	value, err := secret.Generate("", 48)
+	if err != nil {
+		return Key{}, err
+	}
+	kid, err := secret.Generate("", 9)
+	if err != nil {
+		return Key{}, err
+	}
+
+	kept := make([]Key, 0, len(ks.Keys)+1)
+	for _, k := range ks.Keys {
+		if k.RetiredAt == nil {
+			retired := now
+			k.RetiredAt = &retired
+		}
+		if now.Sub(*k.RetiredAt) <= retain {
+			kept = append(kept, k)
+		}
+	}
+	key := Key{ID: kid, Secret: value, CreatedAt: now}
+	ks.Keys = append([]Key{key}, kept...)
+	return key, nil
+}
+
+func (ks *KeySet) SigningKey() (Key, error) {
+	for _, k := range ks.Keys {
+		if k.RetiredAt == nil {
+			return k, nil
+		}
+	}
+	return Key{}, domain.ErrJwtSecretMissing
+}
+
+func (ks *KeySet) VerificationKey(kid string) (Key, error) {
+	for _, k := range ks.Keys {
+		if k.ID == kid {
+			return k, nil
+		}
+	}
+	return Key{}, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+}
+
+// FileKeys serves a KeySet from disk and picks up rotations without a
+// restart. Tokens without a kid (issued before keys were rotated) are
+// verified with the legacy JWT_SECRET when one is configured.
+type FileKeys struct {
+	path     string
+	legacy   KeyProvider
+	interval time.Duration
+
+	mu        sync.Mutex
+	set       *KeySet
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func NewFileKeys(path, legacySecret string) (*FileKeys, error) {
+	f := &FileKeys{path: path, legacy: NewStaticKeys(legacySecret), interval: 10 * time.Second}
+	if err := f.reload(time.Now()); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileKeys) current() *KeySet {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	if now.Sub(f.checkedAt) >= f.interval {
+		// Se o arquivo estiver temporariamente ilegível, seguimos com as chaves já carregadas.
+		_ = f.reload(now)
+	}
+	return f.set
+}
+
+func (f *FileKeys) reload(now time.Time) error {
+	f.checkedAt = now
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat signing keys: %w", err)
+	}
+	if f.set != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+	set, err := LoadKeySet(f.path)
+	if err != nil {
+		return err
+	}
+	f.set, f.modTime = set, info.ModTime()
+	return nil
+}
+
+func (f *FileKeys) SigningKey() (Key, error) {
+	key, err := f.current().SigningKey()
+	if err != nil {
+		return f.legacy.SigningKey()
+	}
+	return key, nil
+}
+
+func (f *FileKeys) VerificationKey(kid string) (Key, error) {
+	if kid == "" {
+		return f.legacy.VerificationKey(kid)
+	}
+	return f.current().VerificationKey(kid)
+}
@@ -0,0 +1,153 @@
+package jwt
+
+import (
+	"auth-service/src/cache"
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/secret"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// opaqueSweepInterval is how often issuing a token also deletes the expired
+// ones.
+const opaqueSweepInterval = time.Minute
+
+type opaqueIssuer struct {
+	store    repository.AccessTokenRepository
+	cache    cache.Cache
+	cacheTTL time.Duration
+	logger   *slog.Logger
+	opts     []Option
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewOpaqueIssuer mints random reference tokens that carry no data. Their
+// claims are stored under a hash of the token and looked up on every
+// validation, through c, which keeps an entry for at most cacheTTL.
+// Revoking a user is still immediate, since the service checks the user
+// after the lookup, and the issuer is a Revoker for single tokens.
+func NewOpaqueIssuer(store repository.AccessTokenRepository, c cache.Cache, cacheTTL time.Duration, logger *slog.Logger, opts ...Option) TokenIssuer {
+	return &opaqueIssuer{store: store, cache: c, cacheTTL: cacheTTL, logger: logger, opts: opts}
+}
+
+func (i *opaqueIssuer) Issue(ctx context.Context, user *domain.User, actor *domain.Actor, expiresAt time.Time, opts ...Option) (string, error) {
+	now := time.Now()
+	claims, err := newClaims(user, actor, now, expiresAt, newOptions(append(slices.Clone(i.opts), opts...)))
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	token, err := secret.Generate(domain.AccessTokenPrefix, 0)
+	if err != nil {
+		return "", err
+	}
+	record := &domain.AccessToken{
+		TokenHash: secret.Hash(token),
+		UserID:    user.ID,
+		Claims:    data,
+		IssuedAt:  now.UTC().Truncate(time.Second),
+		ExpiresAt: expiresAt.UTC().Truncate(time.Second),
+	}
+
+	i.sweep(ctx, now)
+	if err := i.store.Create(ctx, record); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Validate looks the token up and returns the claims it was issued with.
+// An unknown token is domain.ErrInvalidToken and an expired one
+// domain.ErrTokenExpired.
+func (i *opaqueIssuer) Validate(ctx context.Context, token string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(token, domain.AccessTokenPrefix) {
+		return nil, fmt.Errorf("Error validating token: not an opaque token: %w", domain.ErrInvalidToken)
+	}
+	now := time.Now()
+	record, err := i.lookup(ctx, secret.Hash(token), now)
+	if err != nil {
+		return nil, err
+	}
+	if record.Expired(now) {
+		return nil, fmt.Errorf("Error validating token: %w", domain.ErrTokenExpired)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(record.Claims, &claims); err != nil {
+		return nil, fmt.Errorf("Error validating token: unreadable claims: %w", domain.ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// Revoke deletes the token and leaves a tombstone in the cache, so a
+// lookup that read the token just before cannot cache it again. The
+// tombstone is kept for cacheTTL, which is as long as any entry could live.
+func (i *opaqueIssuer) Revoke(ctx context.Context, token string) error {
+	if !strings.HasPrefix(token, domain.AccessTokenPrefix) {
+		return nil
+	}
+	tokenHash := secret.Hash(token)
+	if err := i.store.Revoke(ctx, tokenHash); err != nil {
+		return err
+	}
+	if err := cache.Invalidate(ctx, i.cache, "access_token:"+tokenHash, i.cacheTTL); err != nil {
+		return fmt.Errorf("Error revoking access token: cache still holds it: %w", err)
+	}
+	return nil
+}
+
+func (i *opaqueIssuer) lookup(ctx context.Context, tokenHash string, now time.Time) (*domain.AccessToken, error) {
+	key := "access_token:" + tokenHash
+	data, ok, err := i.cache.Get(ctx, key)
+	if err != nil {
+		i.logger.WarnContext(ctx, "failed to read access token cache", "error", err)
+	}
+	if ok && len(data) > 0 {
+		var record domain.AccessToken
+		if err := json.Unmarshal(data, &record); err == nil {
+			return &record, nil
+		}
+	}
+
+	record, err := i.store.FindByHash(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	// Add rather than Set, so a token revoked since the read above stays
+	// out of the cache.
+	if ttl := min(i.cacheTTL, record.ExpiresAt.Sub(now)); ttl > 0 {
+		if data, err := json.Marshal(record); err == nil {
+			if _, err := i.cache.Add(ctx, key, data, ttl); err != nil {
+				i.logger.WarnContext(ctx, "failed to write access token cache", "error", err)
+			}
+		}
+	}
+	return record, nil
+}
+
+func (i *opaqueIssuer) sweep(ctx context.Context, now time.Time) {
+	i.mu.Lock()
+	due := now.Sub(i.lastSweep) >= opaqueSweepInterval
+	if due {
+		i.lastSweep = now
+	}
+	i.mu.Unlock()
+	if !due {
+		return
+	}
+	if err := i.store.DeleteExpired(ctx, now); err != nil {
+		i.logger.WarnContext(ctx, "failed to delete expired access tokens", "error", err)
+	}
+}
@@ -0,0 +1,150 @@
+package jwt
+
+import (
+	"auth-service/src/cache"
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"auth-service/src/secret"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpaqueIssuer_StoresClaimsServerSide(t *testing.T) {
+	// Arrange
+	store := memory.NewAccessToken()
+	issuer := NewOpaqueIssuer(store, cache.NewLRU(100), time.Minute, slog.Default(), WithIssuer("auth-service"), WithAudience("storefront"), WithClaims(UserClaims("role")))
+	user := &domain.User{ID: "user-id", Email: "test@example.com", Role: "admin"}
+	actor := &domain.Actor{Subject: "orders", Type: domain.ActorService}
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	// Act
+	token, err := issuer.Issue(context.Background(), user, actor, expiresAt, AddAudience("tv-app"))
+	require.NoError(t, err)
+	claims, err := issuer.Validate(context.Background(), token)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, domain.AccessTokenPrefix))
+	assert.NotContains(t, token, ".", "an opaque token carries no readable data")
+	record, err := store.FindByHash(context.Background(), secret.Hash(token))
+	require.NoError(t, err)
+	assert.Equal(t, "user-id", record.UserID)
+	assert.Equal(t, expiresAt.UTC(), record.ExpiresAt)
+	assert.Equal(t, "user-id", claims["sub"])
+	assert.Equal(t, "admin", claims["role"])
+	assert.Equal(t, "auth-service", claims["iss"])
+	assert.True(t, HasAudience(claims, "tv-app"))
+	assert.Equal(t, expiresAt, ExpiresAt(claims))
+	assert.False(t, IssuedAt(claims).IsZero())
+	parsed, err := Actor(claims)
+	require.NoError(t, err)
+	assert.Equal(t, actor, parsed)
+}
+
+func TestOpaqueIssuer_TypedErrors(t *testing.T) {
+	// Arrange
+	issuer := NewOpaqueIssuer(memory.NewAccessToken(), cache.NewLRU(100), time.Minute, slog.Default())
+	user := &domain.User{ID: "user-id", Email: "test@example.com"}
+	expired, err := issuer.Issue(context.Background(), user, nil, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	jwtToken, err := NewJWTIssuer(NewStaticKeys("my-super-secret-key-for-testing")).Issue(context.Background(), user, nil, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	cases := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"expired", expired, domain.ErrTokenExpired},
+		{"unknown", domain.AccessTokenPrefix + "unknown", domain.ErrInvalidToken},
+		{"jwt", jwtToken, domain.ErrInvalidToken},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			claims, err := issuer.Validate(context.Background(), tc.token)
+
+			// Assert
+			assert.Nil(t, claims)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestOpaqueIssuer_CachesLookups(t *testing.T) {
+	// Arrange
+	store := &countingAccessTokens{AccessTokenRepository: memory.NewAccessToken()}
+	issuer := NewOpaqueIssuer(store, cache.NewLRU(100), time.Minute, slog.Default())
+	token, err := issuer.Issue(context.Background(), &domain.User{ID: "user-id"}, nil, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Act
+	first, firstErr := issuer.Validate(context.Background(), token)
+	first["sub"] = "tampered"
+	second, secondErr := issuer.Validate(context.Background(), token)
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.Equal(t, 1, store.finds)
+	assert.Equal(t, "user-id", second["sub"], "callers get their own copy of the claims")
+}
+
+func TestOpaqueIssuer_RevokeDropsCachedToken(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := &countingAccessTokens{AccessTokenRepository: memory.NewAccessToken()}
+	issuer := NewOpaqueIssuer(store, cache.NewLRU(100), time.Minute, slog.Default())
+	cached, err := issuer.Issue(ctx, &domain.User{ID: "user-id"}, nil, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	racing, err := issuer.Issue(ctx, &domain.User{ID: "user-id"}, nil, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	kept, err := issuer.Issue(ctx, &domain.User{ID: "user-id"}, nil, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = issuer.Validate(ctx, cached)
+	require.NoError(t, err)
+	// The lookup of racing reads the token, then the token is revoked
+	// before the lookup writes it to the cache.
+	store.afterFind = func() {
+		store.afterFind = nil
+		require.NoError(t, issuer.(Revoker).Revoke(ctx, racing))
+	}
+	_, err = issuer.Validate(ctx, racing)
+	require.NoError(t, err)
+
+	// Act
+	revokeErr := issuer.(Revoker).Revoke(ctx, cached)
+	_, cachedErr := issuer.Validate(ctx, cached)
+	_, racingErr := issuer.Validate(ctx, racing)
+	_, keptErr := issuer.Validate(ctx, kept)
+	unknownErr := issuer.(Revoker).Revoke(ctx, "not-a-token")
+
+	// Assert
+	require.NoError(t, revokeErr)
+	assert.ErrorIs(t, cachedErr, domain.ErrInvalidToken)
+	assert.ErrorIs(t, racingErr, domain.ErrInvalidToken)
+	assert.NoError(t, keptErr)
+	assert.NoError(t, unknownErr)
+}
+
+type countingAccessTokens struct {
+	repository.AccessTokenRepository
+	finds     int
+	afterFind func()
+}
+
+func (c *countingAccessTokens) FindByHash(ctx context.Context, tokenHash string) (*domain.AccessToken, error) {
+	c.finds++
+	token, err := c.AccessTokenRepository.FindByHash(ctx, tokenHash)
+	if c.afterFind != nil {
+		c.afterFind()
+	}
+	return token, err
+}
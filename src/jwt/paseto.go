@@ -0,0 +1,150 @@
+package jwt
+
+import (
+	"auth-service/src/domain"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PASETO v4 purposes: public tokens are signed with Ed25519 and readable by
+// anyone, local tokens are encrypted and only this service can read them.
+const (
+	PasetoPublic = "v4.public"
+	PasetoLocal  = "v4.local"
+)
+
+var ErrUnknownPurpose = errors.New("unknown PASETO purpose")
+
+// pasetoTimeClaims are RFC 3339 strings on the wire, as PASETO requires.
+var pasetoTimeClaims = []string{"iat", "nbf", "exp"}
+
+type pasetoIssuer struct {
+	purpose   string
+	secretKey paseto.V4AsymmetricSecretKey
+	publicKey paseto.V4AsymmetricPublicKey
+	localKey  paseto.V4SymmetricKey
+	opts      []Option
+}
+
+// NewPasetoIssuer mints PASETO v4 tokens, which have no algorithm header
+// to confuse. keyHex is the Ed25519 secret key for v4.public or the 32-byte
+// symmetric key for v4.local.
+func NewPasetoIssuer(purpose, keyHex string, opts ...Option) (TokenIssuer, error) {
+	i := &pasetoIssuer{purpose: purpose, opts: opts}
+	var err error
+	switch purpose {
+	case PasetoPublic:
+		i.secretKey, err = paseto.NewV4AsymmetricSecretKeyFromHex(keyHex)
+		i.publicKey = i.secretKey.Public()
+	case PasetoLocal:
+		i.localKey, err = paseto.V4SymmetricKeyFromHex(keyHex)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPurpose, purpose)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s key: %w", purpose, err)
+	}
+	return i, nil
+}
+
+// GeneratePasetoKey returns a new hex key for purpose and, for v4.public,
+// the public key other services verify tokens with.
+func GeneratePasetoKey(purpose string) (key, publicKey string, err error) {
+	switch purpose {
+	case PasetoPublic:
+		secretKey := paseto.NewV4AsymmetricSecretKey()
+		return secretKey.ExportHex(), secretKey.Public().ExportHex(), nil
+	case PasetoLocal:
+		return paseto.NewV4SymmetricKey().ExportHex(), "", nil
+	}
+	return "", "", fmt.Errorf("%w: %q", ErrUnknownPurpose, purpose)
+}
+
+func (i *pasetoIssuer) Issue(ctx context.Context, user *domain.User, actor *domain.Actor, expiresAt time.Time, opts ...Option) (string, error) {
+	claims, err := newClaims(user, actor, time.Now(), expiresAt, newOptions(append(slices.Clone(i.opts), opts...)))
+	if err != nil {
+		return "", err
+	}
+	for _, name := range pasetoTimeClaims {
+		claims[name] = time.Unix(claims[name].(int64), 0).UTC().Format(time.RFC3339)
+	}
+	token, err := paseto.MakeToken(claims, nil)
+	if err != nil {
+		return "", err
+	}
+	if i.purpose == PasetoPublic {
+		return token.V4Sign(i.secretKey, nil), nil
+	}
+	return token.V4Encrypt(i.localKey, nil), nil
+}
+
+// Validate applies the same rules as ValidateToken: exp is required, iss
+// and aud are checked when configured, and the leeway covers clock skew.
+func (i *pasetoIssuer) Validate(ctx context.Context, tainted string) (map[string]interface{}, error) {
+	parser := paseto.MakeParser(nil)
+	var token *paseto.Token
+	var err error
+	if i.purpose == PasetoPublic {
+		token, err = parser.ParseV4Public(i.publicKey, tainted, nil)
+	} else {
+		token, err = parser.ParseV4Local(i.localKey, tainted, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidToken, err)
+	}
+
+	claims := jwt.MapClaims(token.Claims())
+	for _, name := range pasetoTimeClaims {
+		raw, ok := claims[name]
+		if !ok {
+			continue
+		}
+		value, _ := raw.(string)
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("Error validating token: %s is not an RFC 3339 time: %w", name, domain.ErrInvalidToken)
+		}
+		claims[name] = float64(at.Unix())
+	}
+	if err := i.check(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (i *pasetoIssuer) check(claims jwt.MapClaims, now time.Time) error {
+	o := newOptions(i.opts)
+	exp := ExpiresAt(claims)
+	switch {
+	case exp.IsZero():
+		return fmt.Errorf("Error validating token: exp claim is required: %w", domain.ErrInvalidToken)
+	case !now.Before(exp.Add(o.leeway)):
+		return fmt.Errorf("Error validating token: %w", domain.ErrTokenExpired)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(o.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("Error validating token: %w", domain.ErrTokenNotYetValid)
+	}
+	if iat := IssuedAt(claims); !iat.IsZero() && now.Add(o.leeway).Before(iat) {
+		return fmt.Errorf("Error validating token: %w", domain.ErrTokenNotYetValid)
+	}
+	if o.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != o.issuer {
+			return fmt.Errorf("Error validating token: %w", domain.ErrTokenIssuer)
+		}
+	}
+	if len(o.audience) > 0 {
+		if _, ok := claims["aud"]; !ok {
+			return fmt.Errorf("Error validating token: aud claim is required: %w", domain.ErrInvalidToken)
+		}
+		if !slices.ContainsFunc(o.audience, func(aud string) bool { return HasAudience(claims, aud) }) {
+			return fmt.Errorf("Error validating token: %w", domain.ErrTokenAudience)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package jwt
+
+import (
+	"auth-service/src/domain"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasetoIssuer_RoundTripsClaims(t *testing.T) {
+	for _, purpose := range []string{PasetoPublic, PasetoLocal} {
+		t.Run(purpose, func(t *testing.T) {
+			// Arrange
+			key, _, err := GeneratePasetoKey(purpose)
+			require.NoError(t, err)
+			issuer, err := NewPasetoIssuer(purpose, key, WithIssuer("auth-service"), WithAudience("storefront"), WithClaims(UserClaims("role")))
+			require.NoError(t, err)
+			user := &domain.User{ID: "user-id", Email: "test@example.com", Role: "admin"}
+			actor := &domain.Actor{Subject: "orders", Type: domain.ActorService}
+			expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+			// Act
+			token, err := issuer.Issue(context.Background(), user, actor, expiresAt, AddAudience("tv-app"))
+			require.NoError(t, err)
+			claims, err := issuer.Validate(context.Background(), token)
+
+			// Assert
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(token, purpose+"."))
+			assert.Equal(t, "user-id", claims["sub"])
+			assert.Equal(t, "admin", claims["role"])
+			assert.Equal(t, "auth-service", claims["iss"])
+			assert.True(t, HasAudience(claims, "tv-app"))
+			assert.Equal(t, expiresAt, ExpiresAt(claims))
+			assert.False(t, IssuedAt(claims).IsZero())
+			parsed, err := Actor(claims)
+			require.NoError(t, err)
+			assert.Equal(t, actor, parsed)
+		})
+	}
+}
+
+func TestPasetoIssuer_TypedErrors(t *testing.T) {
+	// Arrange
+	key, _, err := GeneratePasetoKey(PasetoLocal)
+	require.NoError(t, err)
+	otherKey, _, err := GeneratePasetoKey(PasetoLocal)
+	require.NoError(t, err)
+	newIssuer := func(key string, opts ...Option) TokenIssuer {
+		issuer, err := NewPasetoIssuer(PasetoLocal, key, opts...)
+		require.NoError(t, err)
+		return issuer
+	}
+	issuer := newIssuer(key, WithIssuer("auth-service"), WithAudience("storefront"), WithLeeway(30*time.Second))
+	user := &domain.User{ID: "user-id", Email: "test@example.com"}
+	issue := func(issuer TokenIssuer, expiresAt time.Time) string {
+		token, err := issuer.Issue(context.Background(), user, nil, expiresAt)
+		require.NoError(t, err)
+		return token
+	}
+	cases := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"expired", issue(issuer, time.Now().Add(-time.Minute)), domain.ErrTokenExpired},
+		{"wrong audience", issue(newIssuer(key, WithIssuer("auth-service"), WithAudience("orders")), time.Now().Add(time.Hour)), domain.ErrTokenAudience},
+		{"wrong issuer", issue(newIssuer(key, WithIssuer("someone-else"), WithAudience("storefront")), time.Now().Add(time.Hour)), domain.ErrTokenIssuer},
+		{"other key", issue(newIssuer(otherKey, WithIssuer("auth-service"), WithAudience("storefront")), time.Now().Add(time.Hour)), domain.ErrInvalidToken},
+		{"jwt", issue(NewJWTIssuer(NewStaticKeys("my-super-secret-key-for-testing")), time.Now().Add(time.Hour)), domain.ErrInvalidToken},
+	}
+
+	// Act
+	_, skewErr := issuer.Validate(context.Background(), issue(issuer, time.Now().Add(-10*time.Second)))
+
+	// Assert
+	assert.NoError(t, skewErr, "clock skew within the leeway is tolerated")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := issuer.Validate(context.Background(), tc.token)
+			assert.Nil(t, claims)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestNewPasetoIssuer_RejectsBadKeys(t *testing.T) {
+	// Act
+	_, shortErr := NewPasetoIssuer(PasetoLocal, "abcd")
+	_, purposeErr := NewPasetoIssuer("v2.local", strings.Repeat("00", 32))
+
+	// Assert
+	assert.Error(t, shortErr)
+	assert.ErrorIs(t, purposeErr, ErrUnknownPurpose)
+}
@@ -0,0 +1,205 @@
+// Package ldapauth checks passwords against an LDAP or Active Directory
+// server: it finds the user's entry with a service account, then binds as
+// that entry with the password the user typed.
+package ldapauth
+
+import (
+	"auth-service/src/domain"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	DefaultUserFilter = "(&(objectClass=person)(|(mail={login})(sAMAccountName={login})(uid={login})))"
+	defaultTimeout    = 5 * time.Second
+)
+
+// Config describes the directory. UserFilter must contain {login}, which is
+// replaced by the escaped login the user typed.
+type Config struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string
+	EmailAttribute     string
+	NameAttribute      string
+	// SubjectAttribute holds a stable ID such as objectGUID or entryUUID.
+	// When empty, the entry's DN is used.
+	SubjectAttribute string
+	GroupAttribute   string
+	// GroupRoles maps group DNs, case-insensitively, to local roles. Admin
+	// wins when a user belongs to several mapped groups.
+	GroupRoles  map[string]string
+	DefaultRole string
+	Timeout     time.Duration
+}
+
+type Provider struct {
+	cfg        Config
+	groupRoles map[string]string
+}
+
+func New(cfg Config) *Provider {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = DefaultUserFilter
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "displayName"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = domain.RoleUser
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	groupRoles := make(map[string]string, len(cfg.GroupRoles))
+	for group, role := range cfg.GroupRoles {
+		groupRoles[normalizeDN(group)] = role
+	}
+	return &Provider{cfg: cfg, groupRoles: groupRoles}
+}
+
+func (p *Provider) Name() string {
+	return "ldap"
+}
+
+// Authenticate returns domain.ErrInvalidCredentials when the login matches
+// no entry, several entries, or the password is wrong. Any other error means
+// the directory could not be asked.
+func (p *Provider) Authenticate(ctx context.Context, login, password string) (*domain.DirectoryAccount, error) {
+	// An empty password would be an unauthenticated bind, which many
+	// servers accept without checking anything.
+	if login == "" || password == "" {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if p.cfg.BindDN != "" {
+		if err := conn.Bind(p.cfg.BindDN, p.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind the LDAP service account: %w", err)
+		}
+	}
+
+	attributes := []string{p.cfg.EmailAttribute, p.cfg.NameAttribute, p.cfg.GroupAttribute}
+	if p.cfg.SubjectAttribute != "" {
+		attributes = append(attributes, p.cfg.SubjectAttribute)
+	}
+	filter := strings.ReplaceAll(p.cfg.UserFilter, "{login}", ldap.EscapeFilter(login))
+	result, err := conn.Search(ldap.NewSearchRequest(
+		p.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(p.cfg.Timeout.Seconds()), false, filter, attributes, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("failed to search the LDAP directory: %w", err)
+	}
+	if result == nil || len(result.Entries) != 1 {
+		return nil, fmt.Errorf("Error looking up %q in the LDAP directory: %w", login, domain.ErrInvalidCredentials)
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, fmt.Errorf("Error binding as %s: %w", entry.DN, domain.ErrInvalidCredentials)
+		}
+		return nil, fmt.Errorf("failed to bind as %s: %w", entry.DN, err)
+	}
+
+	return &domain.DirectoryAccount{
+		Subject: p.subject(entry),
+		Email:   entry.GetAttributeValue(p.cfg.EmailAttribute),
+		Name:    entry.GetAttributeValue(p.cfg.NameAttribute),
+		Role:    p.role(entry.GetAttributeValues(p.cfg.GroupAttribute)),
+	}, nil
+}
+
+func (p *Provider) dial(ctx context.Context) (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: p.cfg.Timeout}
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.cfg.InsecureSkipVerify}
+	conn, err := ldap.DialURL(p.cfg.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP: %w", err)
+	}
+	conn.SetTimeout(p.cfg.Timeout)
+	// go-ldap has no per-call context, so a cancelled request closes the
+	// connection to abort whatever is in flight.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	if p.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			stop()
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS with LDAP: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (p *Provider) subject(entry *ldap.Entry) string {
+	if p.cfg.SubjectAttribute == "" {
+		return normalizeDN(entry.DN)
+	}
+	raw := entry.GetRawAttributeValue(p.cfg.SubjectAttribute)
+	// objectGUID and similar IDs are binary.
+	if !utf8.Valid(raw) {
+		return hex.EncodeToString(raw)
+	}
+	return string(raw)
+}
+
+// role maps the groups to a role: admin wins over support, which wins over
+// user.
+func (p *Provider) role(groups []string) string {
+	role := p.cfg.DefaultRole
+	for _, group := range groups {
+		mapped, ok := p.groupRoles[normalizeDN(group)]
+		if !ok {
+			continue
+		}
+		if mapped == domain.RoleAdmin {
+			return domain.RoleAdmin
+		}
+		if mapped == domain.RoleSupport || role != domain.RoleSupport {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// normalizeDN compares DNs case-insensitively and ignores spaces after
+// commas, which directories format inconsistently.
+func normalizeDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(dn))
+	}
+	rdns := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		parts := make([]string, 0, len(rdn.Attributes))
+		for _, attr := range rdn.Attributes {
+			parts = append(parts, strings.ToLower(attr.Type)+"="+strings.ToLower(attr.Value))
+		}
+		rdns = append(rdns, strings.Join(parts, "+"))
+	}
+	return strings.Join(rdns, ",")
+}
@@ -0,0 +1,159 @@
+package ldapauth
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/test_artefacts/ldapserver"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	baseDN    = "dc=example,dc=com"
+	serviceDN = "cn=auth-service,ou=services,dc=example,dc=com"
+	adminsDN  = "cn=Shop Admins,ou=groups,dc=example,dc=com"
+)
+
+func startDirectory(t *testing.T) *ldapserver.Server {
+	t.Helper()
+	dir, err := ldapserver.Start()
+	require.NoError(t, err)
+	t.Cleanup(dir.Close)
+
+	dir.Add(serviceDN, map[string][]string{"objectClass": {"account"}, ldapserver.Password: {"service-secret"}})
+	dir.Add("uid=alice,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass":       {"person"},
+		"uid":               {"alice"},
+		"mail":              {"Alice@Example.com"},
+		"displayName":       {"Alice Admin"},
+		"memberOf":          {"CN=Shop Admins, OU=Groups, DC=example, DC=com"},
+		"entryUUID":         {"4f3c-alice"},
+		ldapserver.Password: {"alice-password"},
+	})
+	dir.Add("uid=bob,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass":       {"person"},
+		"uid":               {"bob"},
+		"mail":              {"bob@example.com"},
+		"displayName":       {"Bob"},
+		ldapserver.Password: {"bob-password"},
+	})
+	return dir
+}
+
+func newProvider(dir *ldapserver.Server) *Provider {
+	return New(Config{
+		URL:          dir.URL,
+		BindDN:       serviceDN,
+		BindPassword: "service-secret",
+		BaseDN:       baseDN,
+		GroupRoles:   map[string]string{adminsDN: domain.RoleAdmin},
+	})
+}
+
+func TestAuthenticate_ByEmailMapsGroupsToRole(t *testing.T) {
+	// Arrange
+	dir := startDirectory(t)
+
+	// Act
+	account, err := newProvider(dir).Authenticate(context.Background(), "alice@example.com", "alice-password")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, &domain.DirectoryAccount{
+		Subject: "uid=alice,ou=people,dc=example,dc=com",
+		Email:   "Alice@Example.com",
+		Name:    "Alice Admin",
+		Role:    domain.RoleAdmin,
+	}, account)
+}
+
+func TestAuthenticate_ByUsernameWithDefaultRole(t *testing.T) {
+	// Arrange
+	dir := startDirectory(t)
+
+	// Act
+	account, err := newProvider(dir).Authenticate(context.Background(), "bob", "bob-password")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", account.Email)
+	assert.Equal(t, domain.RoleUser, account.Role)
+}
+
+func TestAuthenticate_SubjectAttribute(t *testing.T) {
+	// Arrange
+	dir := startDirectory(t)
+	cfg := newProvider(dir).cfg
+	cfg.SubjectAttribute = "entryUUID"
+
+	// Act
+	account, err := New(cfg).Authenticate(context.Background(), "alice", "alice-password")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "4f3c-alice", account.Subject)
+}
+
+func TestAuthenticate_InvalidCredentials(t *testing.T) {
+	dir := startDirectory(t)
+	provider := newProvider(dir)
+
+	tests := []struct {
+		name     string
+		login    string
+		password string
+	}{
+		{"wrong password", "alice", "wrong"},
+		{"unknown login", "mallory", "alice-password"},
+		{"empty password", "alice", ""},
+		{"filter injection", "*", "alice-password"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			_, err := provider.Authenticate(context.Background(), tc.login, tc.password)
+
+			// Assert
+			assert.True(t, errors.Is(err, domain.ErrInvalidCredentials), "got %v", err)
+		})
+	}
+}
+
+func TestAuthenticate_ServiceAccountRejected(t *testing.T) {
+	// Arrange
+	dir := startDirectory(t)
+	cfg := newProvider(dir).cfg
+	cfg.BindPassword = "rotated"
+
+	// Act
+	_, err := New(cfg).Authenticate(context.Background(), "alice", "alice-password")
+
+	// Assert: uma falha de configuração não pode parecer senha errada
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, domain.ErrInvalidCredentials))
+}
+
+func TestAuthenticate_Unreachable(t *testing.T) {
+	_, err := New(Config{URL: "ldap://127.0.0.1:1", BaseDN: baseDN}).Authenticate(context.Background(), "alice", "alice-password")
+
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, domain.ErrInvalidCredentials))
+}
+
+func TestRole_AdminThenSupportThenUser(t *testing.T) {
+	// Arrange
+	p := New(Config{GroupRoles: map[string]string{
+		"cn=admins,dc=example,dc=com":  domain.RoleAdmin,
+		"cn=support,dc=example,dc=com": domain.RoleSupport,
+		"cn=staff,dc=example,dc=com":   domain.RoleUser,
+	}})
+
+	// Act & Assert
+	assert.Equal(t, domain.RoleSupport, p.role([]string{"cn=support,dc=example,dc=com", "cn=staff,dc=example,dc=com"}))
+	assert.Equal(t, domain.RoleSupport, p.role([]string{"cn=staff,dc=example,dc=com", "cn=support,dc=example,dc=com"}))
+	assert.Equal(t, domain.RoleAdmin, p.role([]string{"cn=support,dc=example,dc=com", "cn=admins,dc=example,dc=com"}))
+	assert.Equal(t, domain.RoleUser, p.role([]string{"cn=unmapped,dc=example,dc=com"}))
+}
@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	return line
+}
+
+func TestNew_RedactsSensitiveValues(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger, err := New(&buf, "debug", FormatJSON)
+	require.NoError(t, err)
+
+	// Act
+	logger.Info("login",
+		"email", "someone@example.com",
+		"password", "hunter22",
+		"error", errors.New(`duplicate key: Key (email)=(bob@example.com) already exists`),
+		"detail", "token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.abc",
+	)
+
+	// Assert
+	line := decodeLine(t, &buf)
+	assert.Equal(t, redacted, line["email"])
+	assert.Equal(t, redacted, line["password"])
+	assert.NotContains(t, line["error"], "bob@example.com")
+	assert.NotContains(t, line["detail"], "eyJ")
+}
+
+func TestNew_InvalidConfiguration(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "verbose", FormatJSON)
+	assert.Error(t, err)
+
+	_, err = New(&bytes.Buffer{}, "info", "xml")
+	assert.Error(t, err)
+}
+
+func TestMiddleware_AttachesRequestID(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(Middleware(logger))
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	line := decodeLine(t, &buf)
+	assert.Equal(t, "req-123", line["request_id"])
+	assert.Equal(t, "/users/{id}", line["route"])
+	assert.Equal(t, float64(http.StatusTeapot), line["status"])
+}
+
+func TestContextHandler_WithoutRequestID(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	require.NoError(t, err)
+
+	// Act
+	logger.With("component", "test").InfoContext(context.Background(), "hello")
+
+	// Assert
+	line := decodeLine(t, &buf)
+	assert.Equal(t, "test", line["component"])
+	assert.NotContains(t, line, "request_id")
+}
+
+func TestMiddleware_LogsCallerSetDownstream(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(Middleware(logger))
+	router.Post("/auth/validate", func(w http.ResponseWriter, r *http.Request) {
+		WithCaller(r.Context(), "orders")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/auth/validate", nil))
+
+	// Assert
+	line := decodeLine(t, &buf)
+	assert.Equal(t, "orders", line["caller"])
+}
@@ -0,0 +1,81 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP accepts a single message without authentication and returns the
+// raw DATA section through the channel.
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		var body strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					data <- body.String()
+					reply("250 OK")
+					continue
+				}
+				body.WriteString(line)
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), data
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	// Arrange
+	addr, data := fakeSMTP(t)
+	m := NewSMTP(addr, "no-reply@example.com", "", "")
+
+	// Act
+	err := m.Send(context.Background(), Message{To: "bob@example.com", Subject: "Hello", Body: "line one\nline two"})
+
+	// Assert
+	require.NoError(t, err)
+	raw := <-data
+	assert.Contains(t, raw, "To: bob@example.com\r\n")
+	assert.Contains(t, raw, "Subject: Hello\r\n")
+	assert.Contains(t, raw, "line one\r\nline two")
+}
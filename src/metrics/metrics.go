@@ -0,0 +1,56 @@
+package metrics
+
+import "time"
+
+type LoginOutcome string
+
+const (
+	LoginSuccess            LoginOutcome = "success"
+	LoginInvalidCredentials LoginOutcome = "invalid_credentials"
+	LoginLocked             LoginOutcome = "locked"
+)
+
+type TokenOutcome string
+
+const (
+	TokenValid   TokenOutcome = "valid"
+	TokenExpired TokenOutcome = "expired"
+	TokenRevoked TokenOutcome = "revoked"
+	TokenInvalid TokenOutcome = "invalid"
+)
+
+type HashOperation string
+
+const (
+	HashGenerate HashOperation = "generate"
+	HashCompare  HashOperation = "compare"
+)
+
+type CacheOutcome string
+
+const (
+	CacheHit  CacheOutcome = "hit"
+	CacheMiss CacheOutcome = "miss"
+)
+
+// Recorder is the set of domain metrics emitted by the service layer. It is
+// kept small on purpose so tests can swap in RecorderMock.
+type Recorder interface {
+	UserRegistered()
+	LoginAttempted(outcome LoginOutcome)
+	TokenValidated(outcome TokenOutcome)
+	PasswordHashed(op HashOperation, duration time.Duration)
+	CacheLookup(cache string, outcome CacheOutcome)
+}
+
+type noop struct{}
+
+func NewNoop() Recorder {
+	return noop{}
+}
+
+func (noop) UserRegistered()                             {}
+func (noop) LoginAttempted(LoginOutcome)                 {}
+func (noop) TokenValidated(TokenOutcome)                 {}
+func (noop) PasswordHashed(HashOperation, time.Duration) {}
+func (noop) CacheLookup(string, CacheOutcome)            {}
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "auth"
+
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	registrations    prometheus.Counter
+	logins           *prometheus.CounterVec
+	tokenValidations *prometheus.CounterVec
+	passwordHashing  *prometheus.HistogramVec
+	cacheLookups     *prometheus.CounterVec
+}
+
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by chi route pattern, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by chi route pattern and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registrations_total",
+			Help:      "Users successfully registered.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts, by outcome.",
+		}, []string{"outcome"}),
+		tokenValidations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_validations_total",
+			Help:      "Token validations, by outcome.",
+		}, []string{"outcome"}),
+		passwordHashing: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "password_hash_duration_seconds",
+			Help:      "Time spent in bcrypt, by operation.",
+			Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Cache lookups, by cache and outcome.",
+		}, []string{"cache", "outcome"}),
+	}
+
+	p.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		p.httpRequests, p.httpDuration,
+		p.registrations, p.logins, p.tokenValidations, p.passwordHashing, p.cacheLookups,
+	)
+	return p
+}
+
+func (p *Prometheus) UserRegistered() {
+	p.registrations.Inc()
+}
+
+func (p *Prometheus) LoginAttempted(outcome LoginOutcome) {
+	p.logins.WithLabelValues(string(outcome)).Inc()
+}
+
+func (p *Prometheus) TokenValidated(outcome TokenOutcome) {
+	p.tokenValidations.WithLabelValues(string(outcome)).Inc()
+}
+
+func (p *Prometheus) PasswordHashed(op HashOperation, duration time.Duration) {
+	p.passwordHashing.WithLabelValues(string(op)).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) CacheLookup(cache string, outcome CacheOutcome) {
+	p.cacheLookups.WithLabelValues(cache, string(outcome)).Inc()
+}
+
+func (p *Prometheus) RegisterPool(pool *pgxpool.Pool) {
+	p.registry.MustRegister(newPoolCollector(pool))
+}
+
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{Registry: p.registry})
+}
+
+// Middleware records request counts and latencies labelled with the chi route
+// pattern (e.g. "/users/{id}") rather than the raw path, to keep cardinality bounded.
+func (p *Prometheus) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		p.httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		p.httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns *prometheus.Desc
+	idleConns     *prometheus.Desc
+	totalConns    *prometheus.Desc
+	maxConns      *prometheus.Desc
+	acquireCount  *prometheus.Desc
+	acquireWait   *prometheus.Desc
+	emptyAcquire  *prometheus.Desc
+}
+
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		pool:          pool,
+		acquiredConns: desc("acquired_connections", "Connections currently checked out of the pool."),
+		idleConns:     desc("idle_connections", "Idle connections in the pool."),
+		totalConns:    desc("total_connections", "Total connections in the pool."),
+		maxConns:      desc("max_connections", "Maximum size of the pool."),
+		acquireCount:  desc("acquires_total", "Successful connection acquisitions."),
+		acquireWait:   desc("acquire_wait_seconds_total", "Time spent waiting to acquire a connection."),
+		emptyAcquire:  desc("empty_acquires_total", "Acquisitions that had to wait because the pool was empty."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireWait
+	ch <- c.emptyAcquire
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireWait, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+}
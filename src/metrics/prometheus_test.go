@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	// Arrange
+	prom := NewPrometheus()
+	router := chi.NewRouter()
+	router.Use(prom.Middleware)
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Act
+	for _, id := range []string{"1", "2", "3"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/"+id, nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	// Assert: os três IDs caem na mesma série
+	assert.Equal(t, 3.0, testutil.ToFloat64(prom.httpRequests.WithLabelValues("/users/{id}", http.MethodGet, "204")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(prom.httpRequests.WithLabelValues("unmatched", http.MethodGet, "404")))
+}
+
+func TestRecorder_DomainCounters(t *testing.T) {
+	// Arrange
+	prom := NewPrometheus()
+
+	// Act
+	prom.UserRegistered()
+	prom.LoginAttempted(LoginSuccess)
+	prom.LoginAttempted(LoginInvalidCredentials)
+	prom.LoginAttempted(LoginInvalidCredentials)
+	prom.TokenValidated(TokenExpired)
+	prom.PasswordHashed(HashGenerate, 50*time.Millisecond)
+	prom.CacheLookup("users", CacheHit)
+	prom.CacheLookup("users", CacheMiss)
+	prom.CacheLookup("users", CacheHit)
+
+	// Assert
+	assert.Equal(t, 1.0, testutil.ToFloat64(prom.registrations))
+	assert.Equal(t, 1.0, testutil.ToFloat64(prom.logins.WithLabelValues("success")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(prom.logins.WithLabelValues("invalid_credentials")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(prom.tokenValidations.WithLabelValues("expired")))
+	assert.Equal(t, 1, testutil.CollectAndCount(prom.passwordHashing))
+	assert.Equal(t, 2.0, testutil.ToFloat64(prom.cacheLookups.WithLabelValues("users", "hit")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(prom.cacheLookups.WithLabelValues("users", "miss")))
+
+	rr := httptest.NewRecorder()
+	prom.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.True(t, strings.Contains(rr.Body.String(), "auth_logins_total"))
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type RecorderMock struct {
+	mock.Mock
+}
+
+func (m *RecorderMock) UserRegistered() {
+	m.Called()
+}
+
+func (m *RecorderMock) LoginAttempted(outcome LoginOutcome) {
+	m.Called(outcome)
+}
+
+func (m *RecorderMock) TokenValidated(outcome TokenOutcome) {
+	m.Called(outcome)
+}
+
+func (m *RecorderMock) PasswordHashed(op HashOperation, duration time.Duration) {
+	m.Called(op, duration)
+}
+
+func (m *RecorderMock) CacheLookup(cache string, outcome CacheOutcome) {
+	m.Called(cache, outcome)
+}
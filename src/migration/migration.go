@@ -0,0 +1,207 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	ErrNoChange       = errors.New("no migrations to apply")
+	ErrDirty          = errors.New("database is in a dirty migration state")
+	ErrUnknownVersion = errors.New("database version is not known to this binary")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Load reads golang-migrate style files (000001_name.up.sql / .down.sql)
+// from the root of fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies migrations using the same schema_migrations table as
+// golang-migrate, so databases migrated by the old container keep working.
+type Migrator struct {
+	driver     driver
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+// driver hides how a backend reads the schema version and serializes
+// concurrent migrators.
+type driver interface {
+	version(ctx context.Context) (uint, bool, error)
+	// lock runs fn while holding the backend's migration lock, after making
+	// sure schema_migrations exists.
+	lock(ctx context.Context, fn func(s session) error) error
+}
+
+// session is a single connection holding the migration lock.
+type session interface {
+	version(ctx context.Context) (uint, bool, error)
+	// apply runs one migration and records the resulting version in a single
+	// transaction, so a failure leaves the schema untouched instead of dirty.
+	apply(ctx context.Context, sql string, version uint) error
+}
+
+func newMigrator(d driver, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{driver: d, migrations: migrations, logger: logger}, nil
+}
+
+// Latest is the highest version embedded in the binary.
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	return m.driver.version(ctx)
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	current, _, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		statuses = append(statuses, Status{Version: mig.Version, Name: mig.Name, Applied: mig.Version <= current})
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration while holding the migration lock.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.driver.lock(ctx, func(s session) error {
+		current, dirty, err := s.version(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w at version %d", ErrDirty, current)
+		}
+
+		applied := 0
+		for _, mig := range m.migrations {
+			if mig.Version <= current {
+				continue
+			}
+			m.logger.InfoContext(ctx, "applying migration", "version", mig.Version, "name", mig.Name)
+			if err := s.apply(ctx, mig.Up, mig.Version); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			applied++
+		}
+		if applied == 0 {
+			return ErrNoChange
+		}
+		return nil
+	})
+}
+
+// Down reverts the last `steps` applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.driver.lock(ctx, func(s session) error {
+		current, dirty, err := s.version(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w at version %d", ErrDirty, current)
+		}
+		if current == 0 {
+			return ErrNoChange
+		}
+
+		idx := -1
+		for i, mig := range m.migrations {
+			if mig.Version == current {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, current)
+		}
+
+		for ; steps > 0 && idx >= 0; steps, idx = steps-1, idx-1 {
+			mig := m.migrations[idx]
+			previous := uint(0)
+			if idx > 0 {
+				previous = m.migrations[idx-1].Version
+			}
+			m.logger.InfoContext(ctx, "reverting migration", "version", mig.Version, "name", mig.Name)
+			if err := s.apply(ctx, mig.Down, previous); err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+package migration
+
+import (
+	"auth-service/database"
+	sqlitemigrations "auth-service/database/sqlite"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_SortsAndPairsFiles(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{
+		"000002_add_index.up.sql":      {Data: []byte("CREATE INDEX ...")},
+		"000002_add_index.down.sql":    {Data: []byte("DROP INDEX ...")},
+		"000001_create_users.up.sql":   {Data: []byte("CREATE TABLE users ...")},
+		"000001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"README.md":                    {Data: []byte("ignored")},
+	}
+
+	// Act
+	migrations, err := Load(fsys)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, uint(1), migrations[0].Version)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE users", migrations[0].Down)
+	assert.Equal(t, uint(2), migrations[1].Version)
+}
+
+func TestLoad_MissingUpFile(t *testing.T) {
+	fsys := fstest.MapFS{"000001_create_users.down.sql": {Data: []byte("DROP TABLE users")}}
+
+	_, err := Load(fsys)
+
+	assert.ErrorContains(t, err, "has no up file")
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	// As migrations embutidas no binário devem sempre ser carregáveis
+	migrations, err := Load(database.Migrations)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for _, m := range migrations {
+		assert.NotEmpty(t, m.Down, "migration %d_%s has no down file", m.Version, m.Name)
+	}
+}
+
+func TestLoad_SQLiteMirrorsPostgres(t *testing.T) {
+	// Os dois backends devem reportar as mesmas versões
+	postgres, err := Load(database.Migrations)
+	require.NoError(t, err)
+	sqlite, err := Load(sqlitemigrations.Migrations)
+	require.NoError(t, err)
+
+	require.Len(t, sqlite, len(postgres))
+	for i := range postgres {
+		assert.Equal(t, postgres[i].Version, sqlite[i].Version)
+		assert.Equal(t, postgres[i].Name, sqlite[i].Name)
+		assert.NotEmpty(t, sqlite[i].Down, "migration %d_%s has no down file", sqlite[i].Version, sqlite[i].Name)
+	}
+}
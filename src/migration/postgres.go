@@ -0,0 +1,99 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// advisoryLockKey serializes migrations across replicas started at the same
+// time. It is an arbitrary constant shared by every instance of the service.
+const advisoryLockKey int64 = 0x61757468_6d696772
+
+func New(db *pgxpool.Pool, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	return newMigrator(&postgresDriver{db: db, logger: logger}, fsys, logger)
+}
+
+type postgresDriver struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func (d *postgresDriver) version(ctx context.Context) (uint, bool, error) {
+	return readPostgresVersion(ctx, d.db)
+}
+
+func (d *postgresDriver) lock(ctx context.Context, fn func(s session) error) error {
+	conn, err := d.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, advisoryLockKey); err != nil {
+			d.logger.ErrorContext(ctx, "failed to release migration lock", "error", err)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(postgresSession{conn: conn})
+}
+
+type postgresSession struct {
+	conn *pgxpool.Conn
+}
+
+func (s postgresSession) version(ctx context.Context) (uint, bool, error) {
+	return readPostgresVersion(ctx, s.conn)
+}
+
+func (s postgresSession) apply(ctx context.Context, sql string, version uint) error {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, int64(version)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func readPostgresVersion(ctx context.Context, db querier) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == "42P01") {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return uint(version), dirty, nil
+}
@@ -0,0 +1,94 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+)
+
+// NewSQLite builds a Migrator for a SQLite database. SQLite has no advisory
+// locks, so unlike Postgres it does not guard against two processes
+// migrating the same file at once; a SQLite database is expected to be
+// owned by a single instance of the service.
+func NewSQLite(db *sql.DB, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	return newMigrator(&sqliteDriver{db: db}, fsys, logger)
+}
+
+type sqliteDriver struct {
+	db *sql.DB
+}
+
+func (d *sqliteDriver) version(ctx context.Context) (uint, bool, error) {
+	return readSQLiteVersion(ctx, d.db)
+}
+
+func (d *sqliteDriver) lock(ctx context.Context, fn func(s session) error) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(sqliteSession{conn: conn})
+}
+
+type sqliteSession struct {
+	conn *sql.Conn
+}
+
+func (s sqliteSession) version(ctx context.Context) (uint, bool, error) {
+	return readSQLiteVersion(ctx, s.conn)
+}
+
+func (s sqliteSession) apply(ctx context.Context, query string, version uint) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES (?, false)`, int64(version)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type sqlQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func readSQLiteVersion(ctx context.Context, db sqlQuerier) (uint, bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`).Scan(&exists)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if !exists {
+		return 0, false, nil
+	}
+
+	var version int64
+	var dirty bool
+	err = db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return uint(version), dirty, nil
+}
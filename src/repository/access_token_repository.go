@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AccessTokenRepository interface {
+	Create(ctx context.Context, token *domain.AccessToken) error
+	// FindByHash returns the token whatever its expiry, so the caller can
+	// tell an expired token from an unknown one.
+	FindByHash(ctx context.Context, tokenHash string) (*domain.AccessToken, error)
+	// Revoke deletes the token. An unknown hash is not an error, so revoking
+	// twice succeeds.
+	Revoke(ctx context.Context, tokenHash string) error
+	// DeleteExpired removes the tokens that expired before the given time.
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+type postgresAccessTokenRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewAccessToken(db *pgxpool.Pool, opts ...Option) AccessTokenRepository {
+	return &postgresAccessTokenRepository{db: db, options: newOptions(opts)}
+}
+
+func (r *postgresAccessTokenRepository) Create(ctx context.Context, token *domain.AccessToken) (err error) {
+	ctx, span := tracer.Start(ctx, "AccessTokenRepository.Create", tracing.WithDBOperation("INSERT", "access_tokens"), tracing.WithUserID(token.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO access_tokens (token_hash, user_id, claims, issued_at, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err = conn(ctx, r.db).Exec(ctx, query, token.TokenHash, token.UserID, token.Claims, token.IssuedAt, token.ExpiresAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to insert access token", "user_id", token.UserID, "error", err)
+		return fmt.Errorf("Error creating access token: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (_ *domain.AccessToken, err error) {
+	ctx, span := tracer.Start(ctx, "AccessTokenRepository.FindByHash", tracing.WithDBOperation("SELECT", "access_tokens"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT token_hash, user_id, claims, issued_at, expires_at FROM access_tokens WHERE token_hash = $1`
+	token := &domain.AccessToken{}
+	err = conn(ctx, r.db).QueryRow(ctx, query, tokenHash).Scan(&token.TokenHash, &token.UserID, &token.Claims, &token.IssuedAt, &token.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error finding access token: %w", domain.ErrInvalidToken)
+		}
+		return nil, fmt.Errorf("Error finding access token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *postgresAccessTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "AccessTokenRepository.DeleteExpired", tracing.WithDBOperation("DELETE", "access_tokens"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).Exec(ctx, `DELETE FROM access_tokens WHERE expires_at < $1`, before); err != nil {
+		return fmt.Errorf("Error deleting expired access tokens: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresAccessTokenRepository) Revoke(ctx context.Context, tokenHash string) (err error) {
+	ctx, span := tracer.Start(ctx, "AccessTokenRepository.Revoke", tracing.WithDBOperation("DELETE", "access_tokens"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).Exec(ctx, `DELETE FROM access_tokens WHERE token_hash = $1`, tokenHash); err != nil {
+		return fmt.Errorf("Error revoking access token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	// FindByHash returns the key with that hash, revoked or not.
+	FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	// List returns every key, revoked and expired ones included, ordered by
+	// name and then creation.
+	List(ctx context.Context) ([]*domain.APIKey, error)
+	// Revoke returns domain.ErrAPIKeyNotFound unless the key exists and is
+	// not revoked yet.
+	Revoke(ctx context.Context, id string, at time.Time) error
+}
+
+const apiKeyColumns = `id, name, key_hash, created_at, expires_at, revoked_at`
+
+type postgresAPIKeyRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewAPIKey(db *pgxpool.Pool, opts ...Option) APIKeyRepository {
+	return &postgresAPIKeyRepository{db: db, options: newOptions(opts)}
+}
+
+func (r *postgresAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) (err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyRepository.Create", tracing.WithDBOperation("INSERT", "api_keys"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO api_keys (id, name, key_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err = conn(ctx, r.db).Exec(ctx, query, key.ID, key.Name, key.KeyHash, key.CreatedAt, key.ExpiresAt); err != nil {
+		r.logger.ErrorContext(ctx, "failed to insert API key", "name", key.Name, "error", err)
+		return fmt.Errorf("Error creating API key: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (_ *domain.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyRepository.FindByHash", tracing.WithDBOperation("SELECT", "api_keys"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+	key := &domain.APIKey{}
+	err = conn(ctx, r.db).QueryRow(ctx, query, keyHash).Scan(&key.ID, &key.Name, &key.KeyHash, &key.CreatedAt, &key.ExpiresAt, &key.RevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for API key: %w", domain.ErrAPIKeyNotFound)
+		}
+		r.logger.ErrorContext(ctx, "failed to query API key", "error", err)
+		return nil, fmt.Errorf("Error when searching for API key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *postgresAPIKeyRepository) List(ctx context.Context) (_ []*domain.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyRepository.List", tracing.WithDBOperation("SELECT", "api_keys"))
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := conn(ctx, r.db).Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY name, created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing API keys: %w", err)
+	}
+	keys, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.APIKey, error) {
+		key := &domain.APIKey{}
+		err := row.Scan(&key.ID, &key.Name, &key.KeyHash, &key.CreatedAt, &key.ExpiresAt, &key.RevokedAt)
+		return key, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *postgresAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyRepository.Revoke", tracing.WithDBOperation("UPDATE", "api_keys"))
+	defer func() { tracing.End(span, err) }()
+
+	tag, err := conn(ctx, r.db).Exec(ctx, `UPDATE api_keys SET revoked_at = $2 WHERE id::text = $1 AND revoked_at IS NULL`, id, at)
+	if err != nil {
+		return fmt.Errorf("Error revoking API key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Error revoking API key: %w", domain.ErrAPIKeyNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"auth-service/src/cache"
+	"auth-service/src/domain"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// cachedUserRepository serves FindByID, which every token validation calls
+// to check for revocation, from a cache. Update replaces the entry with a
+// tombstone, so a revoke, a disable or a profile change is seen at once by
+// this process and, with a shared cache, by every replica; otherwise other
+// replicas see it within the TTL. Lookups fill the cache with cache.Add, so
+// one that read the user before the update cannot store the old copy over
+// the tombstone.
+type cachedUserRepository struct {
+	UserRepository
+	cache cache.Cache
+	ttl   time.Duration
+	options
+}
+
+// cachedUser is what the cache keeps of a domain.User. The password hash is
+// left out so it never reaches a shared cache; the struct has no JSON tags
+// because those of domain.User also drop the revocation time.
+type cachedUser struct {
+	ID               string
+	Name             string
+	Email            string
+	Role             string
+	CreatedAt        time.Time
+	DisabledAt       *time.Time
+	TokensValidAfter *time.Time
+	EmailVerifiedAt  *time.Time
+}
+
+func newCachedUser(user *domain.User) cachedUser {
+	return cachedUser{
+		ID:               user.ID,
+		Name:             user.Name,
+		Email:            user.Email,
+		Role:             user.Role,
+		CreatedAt:        user.CreatedAt,
+		DisabledAt:       user.DisabledAt,
+		TokensValidAfter: user.TokensValidAfter,
+		EmailVerifiedAt:  user.EmailVerifiedAt,
+	}
+}
+
+func (c cachedUser) user() *domain.User {
+	return &domain.User{
+		ID:               c.ID,
+		Name:             c.Name,
+		Email:            c.Email,
+		Role:             c.Role,
+		CreatedAt:        c.CreatedAt,
+		DisabledAt:       c.DisabledAt,
+		TokensValidAfter: c.TokensValidAfter,
+		EmailVerifiedAt:  c.EmailVerifiedAt,
+	}
+}
+
+// NewCachedUser wraps users so that FindByID is cached for ttl. FindByID
+// then never returns the password hash, cached or not: callers that need it,
+// or that write the user back with Update, read with FindByIDForUpdate or
+// FindByEmail, which bypass the cache. It must wrap NewOutboxUser rather
+// than the reverse, so the reads made inside the outbox transaction bypass
+// the cache.
+func NewCachedUser(users UserRepository, c cache.Cache, ttl time.Duration, opts ...Option) UserRepository {
+	return &cachedUserRepository{UserRepository: users, cache: c, ttl: ttl, options: newOptions(opts)}
+}
+
+func userCacheKey(id string) string {
+	return "user:" + id
+}
+
+func (r *cachedUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	key := userCacheKey(id)
+	data, ok, err := r.cache.Get(ctx, key)
+	if err != nil {
+		r.logger.WarnContext(ctx, "failed to read user cache", "user_id", id, "error", err)
+	}
+	if ok && len(data) > 0 {
+		var cached cachedUser
+		if err := json.Unmarshal(data, &cached); err == nil {
+			return cached.user(), nil
+		}
+	}
+
+	user, err := r.UserRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cached := newCachedUser(user)
+	if data, err := json.Marshal(cached); err == nil {
+		if _, err := r.cache.Add(ctx, key, data, r.ttl); err != nil {
+			r.logger.WarnContext(ctx, "failed to write user cache", "user_id", id, "error", err)
+		}
+	}
+	return cached.user(), nil
+}
+
+// Update leaves a tombstone for ttl, which outlasts any lookup that read the
+// user before the write. Inside a longer transaction the write only commits
+// later, so a lookup may still read the old row; the tombstone keeps it out
+// of the cache as long as the transaction commits within ttl.
+func (r *cachedUserRepository) Update(ctx context.Context, user *domain.User) error {
+	err := r.UserRepository.Update(ctx, user)
+	// Invalidate even when the update failed: it may have committed anyway.
+	if cacheErr := cache.Invalidate(ctx, r.cache, userCacheKey(user.ID), r.ttl); cacheErr != nil {
+		r.logger.ErrorContext(ctx, "failed to invalidate user cache", "user_id", user.ID, "error", cacheErr)
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ClientRepository interface {
+	Create(ctx context.Context, client *domain.Client) error
+	FindByID(ctx context.Context, id string) (*domain.Client, error)
+}
+
+type postgresClientRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewClient(db *pgxpool.Pool, opts ...Option) ClientRepository {
+	return &postgresClientRepository{db: db, options: newOptions(opts)}
+}
+
+func (r *postgresClientRepository) Create(ctx context.Context, client *domain.Client) (err error) {
+	ctx, span := tracer.Start(ctx, "ClientRepository.Create", tracing.WithDBOperation("INSERT", "clients"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO clients (id, name, secret_hash, created_at) VALUES ($1, $2, $3, $4)`
+	if _, err = conn(ctx, r.db).Exec(ctx, query, client.ID, client.Name, client.SecretHash, client.CreatedAt); err != nil {
+		return fmt.Errorf("Error creating client: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresClientRepository) FindByID(ctx context.Context, id string) (_ *domain.Client, err error) {
+	ctx, span := tracer.Start(ctx, "ClientRepository.FindByID", tracing.WithDBOperation("SELECT", "clients"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT id, name, secret_hash, created_at FROM clients WHERE id = $1`
+	client := &domain.Client{}
+	err = conn(ctx, r.db).QueryRow(ctx, query, id).Scan(&client.ID, &client.Name, &client.SecretHash, &client.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for client by ID: %w", domain.ErrClientNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for client by ID: %w", err)
+	}
+	return client, nil
+}
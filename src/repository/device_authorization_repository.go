@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DeviceAuthorizationRepository interface {
+	Create(ctx context.Context, auth *domain.DeviceAuthorization) error
+	// FindByDeviceCode returns the authorization whatever its status or
+	// expiry, since the device must learn which of them applies.
+	FindByDeviceCode(ctx context.Context, deviceCodeHash string) (*domain.DeviceAuthorization, error)
+	// FindPendingByUserCode returns an authorization still waiting for the
+	// user, provided it has not expired at now.
+	FindPendingByUserCode(ctx context.Context, userCode string, now time.Time) (*domain.DeviceAuthorization, error)
+	// Decide records that userID approved or denied a pending authorization
+	// that has not expired at now. It is atomic, so a code can only be
+	// decided once.
+	Decide(ctx context.Context, userCode, userID, status string, now time.Time) error
+	// RecordPoll stores when the device last polled and the interval it
+	// must keep from then on.
+	RecordPoll(ctx context.Context, deviceCodeHash string, at time.Time, interval time.Duration) error
+	// Delete removes an authorization, failing if it is already gone, so
+	// only one poll can redeem an approval.
+	Delete(ctx context.Context, deviceCodeHash string) error
+	// DeleteExpired removes the authorizations that expired before the
+	// given time.
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+type postgresDeviceAuthorizationRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewDeviceAuthorization(db *pgxpool.Pool, opts ...Option) DeviceAuthorizationRepository {
+	return &postgresDeviceAuthorizationRepository{db: db, options: newOptions(opts)}
+}
+
+const deviceAuthorizationColumns = `device_code_hash, user_code, client_id, status, COALESCE(user_id::text, ''), interval_seconds, created_at, expires_at, last_polled_at`
+
+func scanDeviceAuthorization(row pgx.Row) (*domain.DeviceAuthorization, error) {
+	auth := &domain.DeviceAuthorization{}
+	var interval int
+	if err := row.Scan(&auth.DeviceCodeHash, &auth.UserCode, &auth.ClientID, &auth.Status, &auth.UserID, &interval, &auth.CreatedAt, &auth.ExpiresAt, &auth.LastPolledAt); err != nil {
+		return nil, err
+	}
+	auth.Interval = time.Duration(interval) * time.Second
+	return auth, nil
+}
+
+func (r *postgresDeviceAuthorizationRepository) Create(ctx context.Context, auth *domain.DeviceAuthorization) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.Create", tracing.WithDBOperation("INSERT", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO device_authorizations (device_code_hash, user_code, client_id, status, interval_seconds, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = conn(ctx, r.db).Exec(ctx, query, auth.DeviceCodeHash, auth.UserCode, auth.ClientID, auth.Status, int(auth.Interval.Seconds()), auth.CreatedAt, auth.ExpiresAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to insert device authorization", "client_id", auth.ClientID, "error", err)
+		return fmt.Errorf("Error creating device authorization: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresDeviceAuthorizationRepository) FindByDeviceCode(ctx context.Context, deviceCodeHash string) (_ *domain.DeviceAuthorization, err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.FindByDeviceCode", tracing.WithDBOperation("SELECT", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + deviceAuthorizationColumns + ` FROM device_authorizations WHERE device_code_hash = $1`
+	auth, err := scanDeviceAuthorization(conn(ctx, r.db).QueryRow(ctx, query, deviceCodeHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error finding device authorization: %w", domain.ErrDeviceCodeInvalid)
+		}
+		return nil, fmt.Errorf("Error finding device authorization: %w", err)
+	}
+	return auth, nil
+}
+
+func (r *postgresDeviceAuthorizationRepository) FindPendingByUserCode(ctx context.Context, userCode string, now time.Time) (_ *domain.DeviceAuthorization, err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.FindPendingByUserCode", tracing.WithDBOperation("SELECT", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + deviceAuthorizationColumns + ` FROM device_authorizations
+		WHERE user_code = $1 AND status = $2 AND expires_at > $3`
+	auth, err := scanDeviceAuthorization(conn(ctx, r.db).QueryRow(ctx, query, userCode, domain.DeviceAuthorizationPending, now))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error finding device authorization: %w", domain.ErrUserCodeInvalid)
+		}
+		return nil, fmt.Errorf("Error finding device authorization: %w", err)
+	}
+	return auth, nil
+}
+
+func (r *postgresDeviceAuthorizationRepository) Decide(ctx context.Context, userCode, userID, status string, now time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.Decide", tracing.WithDBOperation("UPDATE", "device_authorizations"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE device_authorizations SET status = $3, user_id = $2
+		WHERE user_code = $1 AND status = $4 AND expires_at > $5`
+	tag, err := conn(ctx, r.db).Exec(ctx, query, userCode, userID, status, domain.DeviceAuthorizationPending, now)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to decide device authorization", "user_id", userID, "error", err)
+		return fmt.Errorf("Error deciding device authorization: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Error deciding device authorization: %w", domain.ErrUserCodeInvalid)
+	}
+	return nil
+}
+
+func (r *postgresDeviceAuthorizationRepository) RecordPoll(ctx context.Context, deviceCodeHash string, at time.Time, interval time.Duration) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.RecordPoll", tracing.WithDBOperation("UPDATE", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE device_authorizations SET last_polled_at = $2, interval_seconds = $3 WHERE device_code_hash = $1`
+	if _, err = conn(ctx, r.db).Exec(ctx, query, deviceCodeHash, at, int(interval.Seconds())); err != nil {
+		return fmt.Errorf("Error recording device poll: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresDeviceAuthorizationRepository) Delete(ctx context.Context, deviceCodeHash string) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.Delete", tracing.WithDBOperation("DELETE", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	tag, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM device_authorizations WHERE device_code_hash = $1`, deviceCodeHash)
+	if err != nil {
+		return fmt.Errorf("Error deleting device authorization: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Error deleting device authorization: %w", domain.ErrDeviceCodeInvalid)
+	}
+	return nil
+}
+
+func (r *postgresDeviceAuthorizationRepository) DeleteExpired(ctx context.Context, before time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.DeleteExpired", tracing.WithDBOperation("DELETE", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).Exec(ctx, `DELETE FROM device_authorizations WHERE expires_at < $1`, before); err != nil {
+		return fmt.Errorf("Error deleting expired device authorizations: %w", err)
+	}
+	return nil
+}
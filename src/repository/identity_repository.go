@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdentityRepository interface {
+	Create(ctx context.Context, identity *domain.Identity) error
+	FindByProviderSubject(ctx context.Context, provider, subject string) (*domain.Identity, error)
+	ListByUser(ctx context.Context, userID string) ([]*domain.Identity, error)
+}
+
+const identityColumns = `provider, subject, user_id, email, created_at`
+
+type postgresIdentityRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewIdentity(db *pgxpool.Pool, opts ...Option) IdentityRepository {
+	return &postgresIdentityRepository{db: db, options: newOptions(opts)}
+}
+
+func (r *postgresIdentityRepository) Create(ctx context.Context, identity *domain.Identity) (err error) {
+	ctx, span := tracer.Start(ctx, "IdentityRepository.Create", tracing.WithDBOperation("INSERT", "user_identities"), tracing.WithUserID(identity.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO user_identities (` + identityColumns + `) VALUES ($1, $2, $3, $4, $5)`
+	_, err = conn(ctx, r.db).Exec(ctx, query, identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("Error creating identity: %w", domain.ErrIdentityAlreadyLinked)
+		}
+		r.logger.ErrorContext(ctx, "failed to insert identity", "user_id", identity.UserID, "provider", identity.Provider, "error", err)
+		return fmt.Errorf("Error creating identity: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresIdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (_ *domain.Identity, err error) {
+	ctx, span := tracer.Start(ctx, "IdentityRepository.FindByProviderSubject", tracing.WithDBOperation("SELECT", "user_identities"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE provider = $1 AND subject = $2`
+	identity, err := scanIdentity(conn(ctx, r.db).QueryRow(ctx, query, provider, subject))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for identity: %w", domain.ErrIdentityNotFound)
+		}
+		r.logger.ErrorContext(ctx, "failed to query identity", "provider", provider, "error", err)
+		return nil, fmt.Errorf("Error when searching for identity: %w", err)
+	}
+	return identity, nil
+}
+
+func (r *postgresIdentityRepository) ListByUser(ctx context.Context, userID string) (_ []*domain.Identity, err error) {
+	ctx, span := tracer.Start(ctx, "IdentityRepository.ListByUser", tracing.WithDBOperation("SELECT", "user_identities"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE user_id = $1 ORDER BY created_at, provider`
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []*domain.Identity{}
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing identities: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing identities: %w", err)
+	}
+	return identities, nil
+}
+
+func scanIdentity(row pgx.Row) (*domain.Identity, error) {
+	identity := &domain.Identity{}
+	if err := row.Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.CreatedAt); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MagicLinkRepository interface {
+	Create(ctx context.Context, link *domain.MagicLink) error
+	// Consume marks the link as used and returns it, provided it exists, is
+	// unused, has not expired at now and either is unbound or was issued to
+	// nonceHash. It is atomic, so a link can never be redeemed twice.
+	Consume(ctx context.Context, tokenHash, nonceHash string, now time.Time) (*domain.MagicLink, error)
+	// CountSince counts the links issued to a user since the given time.
+	CountSince(ctx context.Context, userID string, since time.Time) (int, error)
+}
+
+type postgresMagicLinkRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewMagicLink(db *pgxpool.Pool, opts ...Option) MagicLinkRepository {
+	return &postgresMagicLinkRepository{db: db, options: newOptions(opts)}
+}
+
+func (r *postgresMagicLinkRepository) Create(ctx context.Context, link *domain.MagicLink) (err error) {
+	ctx, span := tracer.Start(ctx, "MagicLinkRepository.Create", tracing.WithDBOperation("INSERT", "magic_links"), tracing.WithUserID(link.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO magic_links (token_hash, user_id, nonce_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err = conn(ctx, r.db).Exec(ctx, query, link.TokenHash, link.UserID, link.NonceHash, link.CreatedAt, link.ExpiresAt); err != nil {
+		r.logger.ErrorContext(ctx, "failed to insert magic link", "user_id", link.UserID, "error", err)
+		return fmt.Errorf("Error creating magic link: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresMagicLinkRepository) Consume(ctx context.Context, tokenHash, nonceHash string, now time.Time) (_ *domain.MagicLink, err error) {
+	ctx, span := tracer.Start(ctx, "MagicLinkRepository.Consume", tracing.WithDBOperation("UPDATE", "magic_links"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE magic_links SET used_at = $3
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $3 AND (nonce_hash = '' OR nonce_hash = $2)
+		RETURNING token_hash, user_id, nonce_hash, created_at, expires_at, used_at`
+	link := &domain.MagicLink{}
+	err = conn(ctx, r.db).QueryRow(ctx, query, tokenHash, nonceHash, now).
+		Scan(&link.TokenHash, &link.UserID, &link.NonceHash, &link.CreatedAt, &link.ExpiresAt, &link.UsedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error consuming magic link: %w", domain.ErrMagicLinkInvalid)
+		}
+		r.logger.ErrorContext(ctx, "failed to consume magic link", "error", err)
+		return nil, fmt.Errorf("Error consuming magic link: %w", err)
+	}
+	return link, nil
+}
+
+func (r *postgresMagicLinkRepository) CountSince(ctx context.Context, userID string, since time.Time) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "MagicLinkRepository.CountSince", tracing.WithDBOperation("SELECT", "magic_links"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	var count int
+	query := `SELECT count(*) FROM magic_links WHERE user_id = $1 AND created_at >= $2`
+	if err = conn(ctx, r.db).QueryRow(ctx, query, userID, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("Error counting magic links: %w", err)
+	}
+	return count, nil
+}
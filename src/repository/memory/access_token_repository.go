@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type accessTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]domain.AccessToken
+}
+
+func NewAccessToken() repository.AccessTokenRepository {
+	return &accessTokenRepository{tokens: make(map[string]domain.AccessToken)}
+}
+
+func (r *accessTokenRepository) Create(ctx context.Context, token *domain.AccessToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tokens[token.TokenHash]; ok {
+		return fmt.Errorf("Error creating access token: duplicate token")
+	}
+	stored := *token
+	stored.Claims = slices.Clone(token.Claims)
+	r.tokens[token.TokenHash] = stored
+	return nil
+}
+
+func (r *accessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.AccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenHash]
+	if !ok {
+		return nil, fmt.Errorf("Error finding access token: %w", domain.ErrInvalidToken)
+	}
+	token.Claims = slices.Clone(token.Claims)
+	return &token, nil
+}
+
+func (r *accessTokenRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, token := range r.tokens {
+		if token.ExpiresAt.Before(before) {
+			delete(r.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func (r *accessTokenRepository) Revoke(ctx context.Context, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.tokens, tokenHash)
+	return nil
+}
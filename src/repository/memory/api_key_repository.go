@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type apiKeyRepository struct {
+	mu   sync.Mutex
+	keys map[string]domain.APIKey
+}
+
+func NewAPIKey() repository.APIKeyRepository {
+	return &apiKeyRepository{keys: make(map[string]domain.APIKey)}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.ID == key.ID || existing.KeyHash == key.KeyHash {
+			return fmt.Errorf("Error creating API key: duplicate key")
+		}
+	}
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			return &key, nil
+		}
+	}
+	return nil, fmt.Errorf("Error when searching for API key: %w", domain.ErrAPIKeyNotFound)
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]*domain.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, &key)
+	}
+	slices.SortFunc(keys, func(a, b *domain.APIKey) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return fmt.Errorf("Error revoking API key: %w", domain.ErrAPIKeyNotFound)
+	}
+	key.RevokedAt = &at
+	r.keys[id] = key
+	return nil
+}
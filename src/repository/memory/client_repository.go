@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"sync"
+)
+
+type clientRepository struct {
+	mu      sync.RWMutex
+	clients map[string]domain.Client
+}
+
+func NewClient() repository.ClientRepository {
+	return &clientRepository{clients: make(map[string]domain.Client)}
+}
+
+func (r *clientRepository) Create(ctx context.Context, client *domain.Client) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.clients[client.ID]; exists {
+		return fmt.Errorf("Error creating client: duplicate id %s", client.ID)
+	}
+	r.clients[client.ID] = *client
+	return nil
+}
+
+func (r *clientRepository) FindByID(ctx context.Context, id string) (*domain.Client, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	client, ok := r.clients[id]
+	if !ok {
+		return nil, fmt.Errorf("Error when searching for client by ID: %w", domain.ErrClientNotFound)
+	}
+	return &client, nil
+}
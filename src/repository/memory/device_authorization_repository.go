@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type deviceAuthorizationRepository struct {
+	mu    sync.Mutex
+	auths map[string]domain.DeviceAuthorization
+}
+
+func NewDeviceAuthorization() repository.DeviceAuthorizationRepository {
+	return &deviceAuthorizationRepository{auths: make(map[string]domain.DeviceAuthorization)}
+}
+
+func (r *deviceAuthorizationRepository) Create(ctx context.Context, auth *domain.DeviceAuthorization) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.auths {
+		if existing.DeviceCodeHash == auth.DeviceCodeHash || existing.UserCode == auth.UserCode {
+			return fmt.Errorf("Error creating device authorization: duplicate code")
+		}
+	}
+	r.auths[auth.DeviceCodeHash] = *auth
+	return nil
+}
+
+func (r *deviceAuthorizationRepository) FindByDeviceCode(ctx context.Context, deviceCodeHash string) (*domain.DeviceAuthorization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	auth, ok := r.auths[deviceCodeHash]
+	if !ok {
+		return nil, fmt.Errorf("Error finding device authorization: %w", domain.ErrDeviceCodeInvalid)
+	}
+	return &auth, nil
+}
+
+func (r *deviceAuthorizationRepository) FindPendingByUserCode(ctx context.Context, userCode string, now time.Time) (*domain.DeviceAuthorization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	auth, ok := r.pending(userCode, now)
+	if !ok {
+		return nil, fmt.Errorf("Error finding device authorization: %w", domain.ErrUserCodeInvalid)
+	}
+	return &auth, nil
+}
+
+func (r *deviceAuthorizationRepository) Decide(ctx context.Context, userCode, userID, status string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	auth, ok := r.pending(userCode, now)
+	if !ok {
+		return fmt.Errorf("Error deciding device authorization: %w", domain.ErrUserCodeInvalid)
+	}
+	auth.Status = status
+	auth.UserID = userID
+	r.auths[auth.DeviceCodeHash] = auth
+	return nil
+}
+
+func (r *deviceAuthorizationRepository) RecordPoll(ctx context.Context, deviceCodeHash string, at time.Time, interval time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if auth, ok := r.auths[deviceCodeHash]; ok {
+		auth.LastPolledAt = &at
+		auth.Interval = interval
+		r.auths[deviceCodeHash] = auth
+	}
+	return nil
+}
+
+func (r *deviceAuthorizationRepository) Delete(ctx context.Context, deviceCodeHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.auths[deviceCodeHash]; !ok {
+		return fmt.Errorf("Error deleting device authorization: %w", domain.ErrDeviceCodeInvalid)
+	}
+	delete(r.auths, deviceCodeHash)
+	return nil
+}
+
+func (r *deviceAuthorizationRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, auth := range r.auths {
+		if auth.ExpiresAt.Before(before) {
+			delete(r.auths, hash)
+		}
+	}
+	return nil
+}
+
+// pending must be called with mu held.
+func (r *deviceAuthorizationRepository) pending(userCode string, now time.Time) (domain.DeviceAuthorization, bool) {
+	for _, auth := range r.auths {
+		if auth.UserCode == userCode && auth.Status == domain.DeviceAuthorizationPending && auth.ExpiresAt.After(now) {
+			return auth, true
+		}
+	}
+	return domain.DeviceAuthorization{}, false
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type identityKey struct {
+	provider, subject string
+}
+
+type identityRepository struct {
+	mu         sync.RWMutex
+	identities map[identityKey]domain.Identity
+}
+
+func NewIdentity() repository.IdentityRepository {
+	return &identityRepository{identities: make(map[identityKey]domain.Identity)}
+}
+
+func (r *identityRepository) Create(ctx context.Context, identity *domain.Identity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := identityKey{identity.Provider, identity.Subject}
+	if _, exists := r.identities[key]; exists {
+		return fmt.Errorf("Error creating identity: %w", domain.ErrIdentityAlreadyLinked)
+	}
+	r.identities[key] = *identity
+	return nil
+}
+
+func (r *identityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*domain.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	identity, ok := r.identities[identityKey{provider, subject}]
+	if !ok {
+		return nil, fmt.Errorf("Error when searching for identity: %w", domain.ErrIdentityNotFound)
+	}
+	return &identity, nil
+}
+
+func (r *identityRepository) ListByUser(ctx context.Context, userID string) ([]*domain.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	identities := []*domain.Identity{}
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identity := identity
+			identities = append(identities, &identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		if identities[i].CreatedAt.Equal(identities[j].CreatedAt) {
+			return identities[i].Provider < identities[j].Provider
+		}
+		return identities[i].CreatedAt.Before(identities[j].CreatedAt)
+	})
+	return identities, nil
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type magicLinkRepository struct {
+	mu    sync.Mutex
+	links map[string]domain.MagicLink
+}
+
+func NewMagicLink() repository.MagicLinkRepository {
+	return &magicLinkRepository{links: make(map[string]domain.MagicLink)}
+}
+
+func (r *magicLinkRepository) Create(ctx context.Context, link *domain.MagicLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.links[link.TokenHash]; exists {
+		return fmt.Errorf("Error creating magic link: duplicate token")
+	}
+	r.links[link.TokenHash] = *link
+	return nil
+}
+
+func (r *magicLinkRepository) Consume(ctx context.Context, tokenHash, nonceHash string, now time.Time) (*domain.MagicLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.links[tokenHash]
+	if !ok || link.UsedAt != nil || !link.ExpiresAt.After(now) || (link.NonceHash != "" && link.NonceHash != nonceHash) {
+		return nil, fmt.Errorf("Error consuming magic link: %w", domain.ErrMagicLinkInvalid)
+	}
+	usedAt := now
+	link.UsedAt = &usedAt
+	r.links[tokenHash] = link
+	return &link, nil
+}
+
+func (r *magicLinkRepository) CountSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, link := range r.links {
+		if link.UserID == userID && !link.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type outboxRepository struct {
+	mu     sync.Mutex
+	events map[string]domain.Event
+}
+
+func NewOutbox() repository.OutboxRepository {
+	return &outboxRepository{events: make(map[string]domain.Event)}
+}
+
+func (r *outboxRepository) Append(ctx context.Context, events ...*domain.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range events {
+		if _, ok := r.events[event.ID]; ok {
+			return fmt.Errorf("Error appending event: duplicate event")
+		}
+	}
+	for _, event := range events {
+		stored := *event
+		stored.Data = slices.Clone(event.Data)
+		r.events[event.ID] = stored
+	}
+	return nil
+}
+
+func (r *outboxRepository) FindByID(ctx context.Context, id string) (*domain.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[id]
+	if !ok {
+		return nil, fmt.Errorf("Error when searching for event: %w", domain.ErrEventNotFound)
+	}
+	return &event, nil
+}
+
+func (r *outboxRepository) ListUndispatched(ctx context.Context, limit int) ([]*domain.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []*domain.Event{}
+	for _, event := range r.events {
+		if event.DispatchedAt == nil {
+			events = append(events, &event)
+		}
+	}
+	slices.SortFunc(events, func(a, b *domain.Event) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) MarkDispatched(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event, ok := r.events[id]; ok {
+		event.DispatchedAt = &at
+		r.events[id] = event
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type personalAccessTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]domain.PersonalAccessToken
+}
+
+func NewPersonalAccessToken() repository.PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{tokens: make(map[string]domain.PersonalAccessToken)}
+}
+
+func (r *personalAccessTokenRepository) Create(ctx context.Context, token *domain.PersonalAccessToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tokens {
+		if existing.ID == token.ID || existing.TokenHash == token.TokenHash {
+			return fmt.Errorf("Error creating personal access token: duplicate token")
+		}
+	}
+	stored := *token
+	stored.Scopes = slices.Clone(token.Scopes)
+	r.tokens[token.ID] = stored
+	return nil
+}
+
+func (r *personalAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.PersonalAccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return copyToken(token), nil
+		}
+	}
+	return nil, fmt.Errorf("Error when searching for personal access token: %w", domain.ErrTokenNotFound)
+}
+
+func (r *personalAccessTokenRepository) ListByUser(ctx context.Context, userID string) ([]*domain.PersonalAccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tokens := []*domain.PersonalAccessToken{}
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			tokens = append(tokens, copyToken(token))
+		}
+	}
+	slices.SortFunc(tokens, func(a, b *domain.PersonalAccessToken) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		if a.ID < b.ID {
+			return -1
+		}
+		return 1
+	})
+	return tokens, nil
+}
+
+func (r *personalAccessTokenRepository) Revoke(ctx context.Context, userID, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UserID != userID || token.RevokedAt != nil {
+		return fmt.Errorf("Error revoking personal access token: %w", domain.ErrTokenNotFound)
+	}
+	token.RevokedAt = &at
+	r.tokens[id] = token
+	return nil
+}
+
+func (r *personalAccessTokenRepository) MarkUsed(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token, ok := r.tokens[id]; ok {
+		token.LastUsedAt = &at
+		r.tokens[id] = token
+	}
+	return nil
+}
+
+func copyToken(token domain.PersonalAccessToken) *domain.PersonalAccessToken {
+	token.Scopes = slices.Clone(token.Scopes)
+	return &token
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type samlAssertionKey struct {
+	provider    string
+	assertionID string
+}
+
+type samlAssertionRepository struct {
+	mu         sync.Mutex
+	assertions map[samlAssertionKey]time.Time
+}
+
+func NewSAMLAssertion() repository.SAMLAssertionRepository {
+	return &samlAssertionRepository{assertions: make(map[samlAssertionKey]time.Time)}
+}
+
+func (r *samlAssertionRepository) Consume(ctx context.Context, provider, assertionID string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := samlAssertionKey{provider, assertionID}
+	if _, ok := r.assertions[key]; ok {
+		return fmt.Errorf("Error consuming SAML assertion %s: %w", assertionID, domain.ErrAssertionConsumed)
+	}
+	r.assertions[key] = expiresAt
+	return nil
+}
+
+func (r *samlAssertionRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, expiresAt := range r.assertions {
+		if expiresAt.Before(before) {
+			delete(r.assertions, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type scimUserKey struct {
+	tenantID, userID string
+}
+
+type scimRepository struct {
+	mu      sync.RWMutex
+	tenants map[string]domain.SCIMTenant
+	users   map[scimUserKey]domain.SCIMUser
+	groups  map[string]domain.SCIMGroup
+}
+
+func NewSCIM() repository.SCIMRepository {
+	return &scimRepository{
+		tenants: make(map[string]domain.SCIMTenant),
+		users:   make(map[scimUserKey]domain.SCIMUser),
+		groups:  make(map[string]domain.SCIMGroup),
+	}
+}
+
+func (r *scimRepository) CreateTenant(ctx context.Context, tenant *domain.SCIMTenant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tenants {
+		if existing.TokenHash == tenant.TokenHash {
+			return fmt.Errorf("Error creating SCIM tenant: token already in use")
+		}
+	}
+	r.tenants[tenant.ID] = *tenant
+	return nil
+}
+
+func (r *scimRepository) FindTenantByTokenHash(ctx context.Context, tokenHash string) (*domain.SCIMTenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, tenant := range r.tenants {
+		if tenant.TokenHash == tokenHash {
+			return &tenant, nil
+		}
+	}
+	return nil, fmt.Errorf("Error when searching for SCIM tenant: %w", domain.ErrSCIMTenantNotFound)
+}
+
+func (r *scimRepository) ListTenants(ctx context.Context) ([]*domain.SCIMTenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenants := []*domain.SCIMTenant{}
+	for _, tenant := range r.tenants {
+		tenant := tenant
+		tenants = append(tenants, &tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool {
+		if tenants[i].CreatedAt.Equal(tenants[j].CreatedAt) {
+			return tenants[i].ID < tenants[j].ID
+		}
+		return tenants[i].CreatedAt.Before(tenants[j].CreatedAt)
+	})
+	return tenants, nil
+}
+
+func (r *scimRepository) AddUser(ctx context.Context, user *domain.SCIMUser) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := scimUserKey{user.TenantID, user.UserID}
+	if _, exists := r.users[key]; exists {
+		return fmt.Errorf("Error adding SCIM user: %w", domain.ErrSCIMUserExists)
+	}
+	r.users[key] = *user
+	return nil
+}
+
+func (r *scimRepository) FindUser(ctx context.Context, tenantID, userID string) (*domain.SCIMUser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[scimUserKey{tenantID, userID}]
+	if !ok {
+		return nil, fmt.Errorf("Error when searching for SCIM user: %w", domain.ErrUserNotFound)
+	}
+	return &user, nil
+}
+
+func (r *scimRepository) ListUsers(ctx context.Context, tenantID string, limit, offset int) ([]*domain.SCIMUser, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := []*domain.SCIMUser{}
+	for _, user := range r.users {
+		if user.TenantID == tenantID {
+			user := user
+			users = append(users, &user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].UserID < users[j].UserID
+		}
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+	return page(users, limit, offset), len(users), nil
+}
+
+func (r *scimRepository) UpdateUser(ctx context.Context, user *domain.SCIMUser) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := scimUserKey{user.TenantID, user.UserID}
+	existing, ok := r.users[key]
+	if !ok {
+		return fmt.Errorf("Error updating SCIM user: %w", domain.ErrUserNotFound)
+	}
+	existing.ExternalID = user.ExternalID
+	existing.Deactivated = user.Deactivated
+	r.users[key] = existing
+	return nil
+}
+
+func (r *scimRepository) RemoveUser(ctx context.Context, tenantID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := scimUserKey{tenantID, userID}
+	if _, ok := r.users[key]; !ok {
+		return fmt.Errorf("Error removing SCIM user: %w", domain.ErrUserNotFound)
+	}
+	delete(r.users, key)
+	for id, group := range r.groups {
+		if group.TenantID != tenantID {
+			continue
+		}
+		members := []string{}
+		for _, member := range group.MemberIDs {
+			if member != userID {
+				members = append(members, member)
+			}
+		}
+		group.MemberIDs = members
+		r.groups[id] = group
+	}
+	return nil
+}
+
+func (r *scimRepository) CreateGroup(ctx context.Context, group *domain.SCIMGroup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nameTaken(group) {
+		return fmt.Errorf("Error creating SCIM group: %w", domain.ErrSCIMGroupExists)
+	}
+	r.groups[group.ID] = copyGroup(group)
+	return nil
+}
+
+func (r *scimRepository) FindGroup(ctx context.Context, tenantID, groupID string) (*domain.SCIMGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	group, ok := r.groups[groupID]
+	if !ok || group.TenantID != tenantID {
+		return nil, fmt.Errorf("Error when searching for SCIM group: %w", domain.ErrSCIMGroupNotFound)
+	}
+	found := copyGroup(&group)
+	return &found, nil
+}
+
+func (r *scimRepository) ListGroups(ctx context.Context, tenantID, displayName string, limit, offset int) ([]*domain.SCIMGroup, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groups := []*domain.SCIMGroup{}
+	for _, group := range r.groups {
+		if group.TenantID == tenantID && (displayName == "" || group.DisplayName == displayName) {
+			found := copyGroup(&group)
+			groups = append(groups, &found)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].CreatedAt.Equal(groups[j].CreatedAt) {
+			return groups[i].ID < groups[j].ID
+		}
+		return groups[i].CreatedAt.Before(groups[j].CreatedAt)
+	})
+	return page(groups, limit, offset), len(groups), nil
+}
+
+func (r *scimRepository) UpdateGroup(ctx context.Context, group *domain.SCIMGroup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.groups[group.ID]
+	if !ok || existing.TenantID != group.TenantID {
+		return fmt.Errorf("Error updating SCIM group: %w", domain.ErrSCIMGroupNotFound)
+	}
+	if r.nameTaken(group) {
+		return fmt.Errorf("Error updating SCIM group: %w", domain.ErrSCIMGroupExists)
+	}
+	updated := copyGroup(group)
+	updated.CreatedAt = existing.CreatedAt
+	r.groups[group.ID] = updated
+	return nil
+}
+
+func (r *scimRepository) DeleteGroup(ctx context.Context, tenantID, groupID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	group, ok := r.groups[groupID]
+	if !ok || group.TenantID != tenantID {
+		return fmt.Errorf("Error deleting SCIM group: %w", domain.ErrSCIMGroupNotFound)
+	}
+	delete(r.groups, groupID)
+	return nil
+}
+
+// nameTaken reports whether another group of the same tenant already uses
+// the group's display name.
+func (r *scimRepository) nameTaken(group *domain.SCIMGroup) bool {
+	for _, existing := range r.groups {
+		if existing.ID != group.ID && existing.TenantID == group.TenantID && existing.DisplayName == group.DisplayName {
+			return true
+		}
+	}
+	return false
+}
+
+// copyGroup keeps callers from mutating the stored member list, and sorts it
+// the way the SQL backends return it.
+func copyGroup(group *domain.SCIMGroup) domain.SCIMGroup {
+	copied := *group
+	seen := make(map[string]bool, len(group.MemberIDs))
+	copied.MemberIDs = []string{}
+	for _, member := range group.MemberIDs {
+		if !seen[member] {
+			seen[member] = true
+			copied.MemberIDs = append(copied.MemberIDs, member)
+		}
+	}
+	sort.Strings(copied.MemberIDs)
+	return copied
+}
+
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
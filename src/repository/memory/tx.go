@@ -0,0 +1,18 @@
+package memory
+
+import (
+	"auth-service/src/repository"
+	"context"
+)
+
+type txManager struct{}
+
+// NewTxManager runs units of work directly. The in-memory repositories
+// cannot roll a write back, so callers keep the write that may fail first.
+func NewTxManager() repository.TxManager {
+	return txManager{}
+}
+
+func (txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,143 @@
+// Package memory provides thread-safe in-memory repositories with the same
+// semantics as the Postgres ones, for tests and for running the service
+// locally with DATABASE_URL=memory://.
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type userRepository struct {
+	mu      sync.RWMutex
+	byID    map[string]*domain.User
+	byEmail map[string]string
+}
+
+func NewUser() repository.UserRepository {
+	return &userRepository{
+		byID:    make(map[string]*domain.User),
+		byEmail: make(map[string]string),
+	}
+}
+
+func emailKey(email string) string {
+	return strings.ToLower(email)
+}
+
+func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byID[user.ID]; exists {
+		return fmt.Errorf("Error creating user: duplicate id %s", user.ID)
+	}
+	if _, exists := r.byEmail[emailKey(user.Email)]; exists {
+		return fmt.Errorf("Error creating user: %w", domain.ErrEmailAlreadyExists)
+	}
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+	r.byID[user.ID] = copyUser(user)
+	r.byEmail[emailKey(user.Email)] = user.ID
+	return nil
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byEmail[emailKey(email)]
+	if !ok {
+		return nil, fmt.Errorf("Error when searching for user by email: %w", domain.ErrUserNotFound)
+	}
+	return copyUser(r.byID[id]), nil
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("Error when searching for user by ID: %w", domain.ErrUserNotFound)
+	}
+	return copyUser(user), nil
+}
+
+// FindByIDForUpdate is FindByID: the in-memory store has no transactions to
+// hold a lock for.
+func (r *userRepository) FindByIDForUpdate(ctx context.Context, id string) (*domain.User, error) {
+	return r.FindByID(ctx, id)
+}
+
+func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.byID[user.ID]
+	if !ok {
+		return fmt.Errorf("Error updating user: %w", domain.ErrUserNotFound)
+	}
+	oldKey, newKey := emailKey(existing.Email), emailKey(user.Email)
+	if oldKey != newKey {
+		if _, taken := r.byEmail[newKey]; taken {
+			return fmt.Errorf("Error updating user: %w", domain.ErrEmailAlreadyExists)
+		}
+		delete(r.byEmail, oldKey)
+		r.byEmail[newKey] = user.ID
+	}
+
+	updated := copyUser(user)
+	updated.CreatedAt = existing.CreatedAt
+	r.byID[user.ID] = updated
+	return nil
+}
+
+func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*domain.User, 0, len(r.byID))
+	for _, user := range r.byID {
+		users = append(users, copyUser(user))
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].ID < users[j].ID
+		}
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+
+	if offset >= len(users) {
+		return []*domain.User{}, nil
+	}
+	users = users[offset:]
+	if limit < len(users) {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// copyUser keeps callers from mutating stored records through shared pointers.
+func copyUser(user *domain.User) *domain.User {
+	c := *user
+	if user.DisabledAt != nil {
+		t := *user.DisabledAt
+		c.DisabledAt = &t
+	}
+	if user.TokensValidAfter != nil {
+		t := *user.TokensValidAfter
+		c.TokensValidAfter = &t
+	}
+	if user.EmailVerifiedAt != nil {
+		t := *user.EmailVerifiedAt
+		c.EmailVerifiedAt = &t
+	}
+	return &c
+}
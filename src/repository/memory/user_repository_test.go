@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"auth-service/src/cache"
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/conformance"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Memory Repository Suite")
+}
+
+var _ = conformance.UserRepository(func() repository.UserRepository { return NewUser() })
+
+var _ = conformance.UserRepository(func() repository.UserRepository {
+	return repository.NewCachedUser(NewUser(), cache.NewLRU(100), time.Minute)
+})
+
+var _ = conformance.MagicLinkRepository(func() (repository.UserRepository, repository.MagicLinkRepository) {
+	return NewUser(), NewMagicLink()
+})
+
+var _ = conformance.IdentityRepository(func() (repository.UserRepository, repository.IdentityRepository) {
+	return NewUser(), NewIdentity()
+})
+
+var _ = conformance.SCIMRepository(func() (repository.UserRepository, repository.SCIMRepository) {
+	return NewUser(), NewSCIM()
+})
+
+var _ = conformance.PersonalAccessTokenRepository(func() (repository.UserRepository, repository.PersonalAccessTokenRepository) {
+	return NewUser(), NewPersonalAccessToken()
+})
+
+var _ = conformance.APIKeyRepository(func() repository.APIKeyRepository { return NewAPIKey() })
+
+var _ = conformance.OutboxRepository(func() (repository.UserRepository, repository.TxManager, repository.OutboxRepository) {
+	return NewUser(), NewTxManager(), NewOutbox()
+})
+
+var _ = conformance.WebhookRepository(func() (repository.OutboxRepository, repository.WebhookRepository) {
+	return NewOutbox(), NewWebhook()
+})
+
+var _ = conformance.DeviceAuthorizationRepository(func() (repository.UserRepository, repository.ClientRepository, repository.DeviceAuthorizationRepository) {
+	return NewUser(), NewClient(), NewDeviceAuthorization()
+})
+
+var _ = conformance.AccessTokenRepository(func() (repository.UserRepository, repository.AccessTokenRepository) {
+	return NewUser(), NewAccessToken()
+})
+
+var _ = conformance.SAMLAssertionRepository(func() repository.SAMLAssertionRepository {
+	return NewSAMLAssertion()
+})
+
+var _ = Describe("Memory UserRepository", func() {
+	var repo repository.UserRepository
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		repo = NewUser()
+	})
+
+	Context("when many goroutines register the same email", func() {
+		It("should let exactly one of them succeed", func() {
+			// Arrange
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			created := 0
+
+			// Act
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					defer GinkgoRecover()
+					email := "race@example.com"
+					if i%2 == 1 {
+						email = "RACE@EXAMPLE.COM"
+					}
+					user := stubs.NewUserStub().WithEmail(email).Get()
+					if repo.Create(ctx, user) == nil {
+						mu.Lock()
+						created++
+						mu.Unlock()
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			// Assert
+			Expect(created).To(Equal(1))
+		})
+	})
+})
+
+var _ = Describe("Cached UserRepository", func() {
+	var users, cached repository.UserRepository
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		users = NewUser()
+		cached = repository.NewCachedUser(users, cache.NewLRU(100), time.Minute)
+	})
+
+	It("should serve FindByID from the cache until the user is updated through it", func() {
+		// Arrange
+		user := stubs.NewUserStub().Get()
+		Expect(users.Create(ctx, user)).To(Succeed())
+		_, err := cached.FindByID(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		revokedAt := time.Now().UTC().Truncate(time.Second)
+		behindCache := *user
+		behindCache.TokensValidAfter = &revokedAt
+		Expect(users.Update(ctx, &behindCache)).To(Succeed())
+
+		// Act
+		stale, staleErr := cached.FindByID(ctx, user.ID)
+		Expect(cached.Update(ctx, &behindCache)).To(Succeed())
+		fresh, freshErr := cached.FindByID(ctx, user.ID)
+
+		// Assert
+		Expect(staleErr).NotTo(HaveOccurred())
+		Expect(stale.TokensValidAfter).To(BeNil())
+		Expect(freshErr).NotTo(HaveOccurred())
+		Expect(fresh.TokensValidAfter).NotTo(BeNil())
+		Expect(fresh.TokensValidAfter.Equal(revokedAt)).To(BeTrue())
+	})
+
+	It("should not cache a user read before a concurrent update", func() {
+		// Arrange
+		user := stubs.NewUserStub().Get()
+		Expect(users.Create(ctx, user)).To(Succeed())
+		revokedAt := time.Now().UTC().Truncate(time.Second)
+		revoked := *user
+		revoked.TokensValidAfter = &revokedAt
+		slow := &hookedUsers{UserRepository: users}
+		cached = repository.NewCachedUser(slow, cache.NewLRU(100), time.Minute)
+		// The lookup reads the user, then the update commits and invalidates
+		// the entry before the lookup writes to the cache.
+		slow.afterFind = func() {
+			slow.afterFind = nil
+			Expect(cached.Update(ctx, &revoked)).To(Succeed())
+		}
+
+		// Act
+		stale, staleErr := cached.FindByID(ctx, user.ID)
+		fresh, freshErr := cached.FindByID(ctx, user.ID)
+
+		// Assert
+		Expect(staleErr).NotTo(HaveOccurred())
+		Expect(stale.TokensValidAfter).To(BeNil())
+		Expect(freshErr).NotTo(HaveOccurred())
+		Expect(fresh.TokensValidAfter).NotTo(BeNil())
+	})
+
+	It("should leave the password hash out of FindByID", func() {
+		// Arrange
+		user := stubs.NewUserStub().Get()
+		Expect(users.Create(ctx, user)).To(Succeed())
+
+		// Act
+		uncached, uncachedErr := cached.FindByID(ctx, user.ID)
+		fromCache, fromCacheErr := cached.FindByID(ctx, user.ID)
+		forUpdate, forUpdateErr := cached.FindByIDForUpdate(ctx, user.ID)
+
+		// Assert
+		Expect(uncachedErr).NotTo(HaveOccurred())
+		Expect(fromCacheErr).NotTo(HaveOccurred())
+		Expect(forUpdateErr).NotTo(HaveOccurred())
+		Expect(uncached.PasswordHash).To(BeEmpty())
+		Expect(fromCache.PasswordHash).To(BeEmpty())
+		Expect(fromCache.Email).To(Equal(user.Email))
+		Expect(forUpdate.PasswordHash).To(Equal(user.PasswordHash))
+	})
+})
+
+type hookedUsers struct {
+	repository.UserRepository
+	afterFind func()
+}
+
+func (u *hookedUsers) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	user, err := u.UserRepository.FindByID(ctx, id)
+	if u.afterFind != nil {
+		u.afterFind()
+	}
+	return user, err
+}
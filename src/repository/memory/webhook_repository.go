@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type webhookRepository struct {
+	mu            sync.Mutex
+	subscriptions map[string]domain.WebhookSubscription
+	deliveries    map[string]domain.WebhookDelivery
+}
+
+func NewWebhook() repository.WebhookRepository {
+	return &webhookRepository{
+		subscriptions: make(map[string]domain.WebhookSubscription),
+		deliveries:    make(map[string]domain.WebhookDelivery),
+	}
+}
+
+func (r *webhookRepository) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subscriptions[sub.ID]; ok {
+		return fmt.Errorf("Error creating webhook subscription: duplicate subscription")
+	}
+	stored := *sub
+	stored.Events = slices.Clone(sub.Events)
+	r.subscriptions[sub.ID] = stored
+	return nil
+}
+
+func (r *webhookRepository) FindSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subscriptions[id]
+	if !ok {
+		return nil, fmt.Errorf("Error when searching for webhook subscription: %w", domain.ErrWebhookNotFound)
+	}
+	sub.Events = slices.Clone(sub.Events)
+	return &sub, nil
+}
+
+func (r *webhookRepository) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subs := make([]*domain.WebhookSubscription, 0, len(r.subscriptions))
+	for _, sub := range r.subscriptions {
+		sub.Events = slices.Clone(sub.Events)
+		subs = append(subs, &sub)
+	}
+	slices.SortFunc(subs, func(a, b *domain.WebhookSubscription) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return subs, nil
+}
+
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subscriptions[id]; !ok {
+		return fmt.Errorf("Error deleting webhook subscription: %w", domain.ErrWebhookNotFound)
+	}
+	delete(r.subscriptions, id)
+	for deliveryID, delivery := range r.deliveries {
+		if delivery.SubscriptionID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (r *webhookRepository) EnqueueDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.deliveries {
+		if existing.EventID == delivery.EventID && existing.SubscriptionID == delivery.SubscriptionID {
+			return nil
+		}
+	}
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *webhookRepository) ClaimDue(ctx context.Context, now, until time.Time, limit int) ([]*domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := []*domain.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.Status == domain.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, &delivery)
+		}
+	}
+	slices.SortFunc(due, func(a, b *domain.WebhookDelivery) int {
+		if c := a.NextAttemptAt.Compare(b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for _, delivery := range due {
+		delivery.NextAttemptAt = until
+		r.deliveries[delivery.ID] = *delivery
+	}
+	return due, nil
+}
+
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.deliveries[delivery.ID]; ok {
+		r.deliveries[delivery.ID] = *delivery
+	}
+	return nil
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, subscriptionID, status string, limit int) ([]*domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.SubscriptionID == subscriptionID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	slices.SortFunc(deliveries, func(a, b *domain.WebhookDelivery) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) Redeliver(ctx context.Context, subscriptionID, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, ok := r.deliveries[id]
+	if !ok || delivery.SubscriptionID != subscriptionID || delivery.Status != domain.DeliveryDead {
+		return fmt.Errorf("Error redelivering webhook: %w", domain.ErrDeliveryNotFound)
+	}
+	delivery.Status = domain.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = at
+	r.deliveries[id] = delivery
+	return nil
+}
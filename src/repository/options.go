@@ -0,0 +1,23 @@
+package repository
+
+import "log/slog"
+
+type options struct {
+	logger *slog.Logger
+}
+
+type Option func(*options)
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{logger: slog.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OutboxRepository stores the events to publish. Append joins the
+// transaction of a TxManager, which is what makes it an outbox: an event is
+// only ever stored together with the change it describes.
+type OutboxRepository interface {
+	Append(ctx context.Context, events ...*domain.Event) error
+	FindByID(ctx context.Context, id string) (*domain.Event, error)
+	// ListUndispatched returns up to limit events not dispatched yet, oldest
+	// first.
+	ListUndispatched(ctx context.Context, limit int) ([]*domain.Event, error)
+	MarkDispatched(ctx context.Context, id string, at time.Time) error
+}
+
+const eventColumns = `id, type, data, created_at, dispatched_at`
+
+type postgresOutboxRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewOutbox(db *pgxpool.Pool, opts ...Option) OutboxRepository {
+	return &postgresOutboxRepository{db: db, options: newOptions(opts)}
+}
+
+func (r *postgresOutboxRepository) Append(ctx context.Context, events ...*domain.Event) (err error) {
+	ctx, span := tracer.Start(ctx, "OutboxRepository.Append", tracing.WithDBOperation("INSERT", "outbox_events"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO outbox_events (id, type, data, created_at) VALUES ($1, $2, $3, $4)`
+	for _, event := range events {
+		if _, err = conn(ctx, r.db).Exec(ctx, query, event.ID, event.Type, event.Data, event.CreatedAt); err != nil {
+			r.logger.ErrorContext(ctx, "failed to append event", "event_type", event.Type, "error", err)
+			return fmt.Errorf("Error appending event: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresOutboxRepository) FindByID(ctx context.Context, id string) (_ *domain.Event, err error) {
+	ctx, span := tracer.Start(ctx, "OutboxRepository.FindByID", tracing.WithDBOperation("SELECT", "outbox_events"))
+	defer func() { tracing.End(span, err) }()
+
+	event, err := scanEvent(conn(ctx, r.db).QueryRow(ctx, `SELECT `+eventColumns+` FROM outbox_events WHERE id::text = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for event: %w", domain.ErrEventNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for event: %w", err)
+	}
+	return event, nil
+}
+
+func (r *postgresOutboxRepository) ListUndispatched(ctx context.Context, limit int) (_ []*domain.Event, err error) {
+	ctx, span := tracer.Start(ctx, "OutboxRepository.ListUndispatched", tracing.WithDBOperation("SELECT", "outbox_events"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + eventColumns + ` FROM outbox_events WHERE dispatched_at IS NULL ORDER BY created_at, id LIMIT $1`
+	rows, err := conn(ctx, r.db).Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing undispatched events: %w", err)
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.Event, error) {
+		return scanEvent(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing undispatched events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *postgresOutboxRepository) MarkDispatched(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "OutboxRepository.MarkDispatched", tracing.WithDBOperation("UPDATE", "outbox_events"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).Exec(ctx, `UPDATE outbox_events SET dispatched_at = $2 WHERE id = $1`, id, at); err != nil {
+		return fmt.Errorf("Error marking event as dispatched: %w", err)
+	}
+	return nil
+}
+
+func scanEvent(row pgx.Row) (*domain.Event, error) {
+	event := &domain.Event{}
+	if err := row.Scan(&event.ID, &event.Type, &event.Data, &event.CreatedAt, &event.DispatchedAt); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// outboxUserRepository records in the outbox the events a user write
+// implies, in the same transaction as the write. Every path that creates or
+// changes accounts goes through UserRepository, so deciding here keeps SSO
+// provisioning, SCIM and the admin commands from missing an event.
+type outboxUserRepository struct {
+	UserRepository
+	tx     TxManager
+	outbox OutboxRepository
+}
+
+// NewOutboxUser wraps users so that their changes publish events.
+func NewOutboxUser(users UserRepository, tx TxManager, outbox OutboxRepository) UserRepository {
+	return &outboxUserRepository{UserRepository: users, tx: tx, outbox: outbox}
+}
+
+func (r *outboxUserRepository) Create(ctx context.Context, user *domain.User) error {
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := r.UserRepository.Create(ctx, user); err != nil {
+			return err
+		}
+		types := []string{domain.EventUserRegistered}
+		if user.EmailVerifiedAt != nil {
+			types = append(types, domain.EventUserEmailVerified)
+		}
+		return r.record(ctx, user, types...)
+	})
+}
+
+func (r *outboxUserRepository) Update(ctx context.Context, user *domain.User) error {
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// Locking the row makes concurrent updates compare against each
+		// other's result, so a change is only recorded once.
+		before, err := r.UserRepository.FindByIDForUpdate(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if err := r.UserRepository.Update(ctx, user); err != nil {
+			return err
+		}
+		var types []string
+		if user.PasswordHash != before.PasswordHash && user.PasswordHash != "" {
+			types = append(types, domain.EventPasswordChanged)
+		}
+		if user.EmailVerifiedAt != nil && before.EmailVerifiedAt == nil {
+			types = append(types, domain.EventUserEmailVerified)
+		}
+		if user.Disabled() && !before.Disabled() {
+			types = append(types, domain.EventUserDeleted)
+		}
+		return r.record(ctx, user, types...)
+	})
+}
+
+func (r *outboxUserRepository) record(ctx context.Context, user *domain.User, types ...string) error {
+	now := time.Now().UTC()
+	events := make([]*domain.Event, 0, len(types))
+	for _, eventType := range types {
+		event, err := domain.NewUserEvent(uuid.NewString(), eventType, user, now)
+		if err != nil {
+			return fmt.Errorf("Error recording %s event: %w", eventType, err)
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return r.outbox.Append(ctx, events...)
+}
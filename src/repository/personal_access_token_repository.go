@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, token *domain.PersonalAccessToken) error
+	// FindByHash returns the token with that hash, revoked or not.
+	FindByHash(ctx context.Context, tokenHash string) (*domain.PersonalAccessToken, error)
+	// ListByUser returns the user's tokens that were not revoked, newest
+	// first.
+	ListByUser(ctx context.Context, userID string) ([]*domain.PersonalAccessToken, error)
+	// Revoke returns domain.ErrTokenNotFound unless the token belongs to
+	// userID and is not revoked yet.
+	Revoke(ctx context.Context, userID, id string, at time.Time) error
+	MarkUsed(ctx context.Context, id string, at time.Time) error
+}
+
+const personalAccessTokenColumns = `id, user_id, name, token_hash, scopes, created_at, expires_at, last_used_at, revoked_at`
+
+type postgresPersonalAccessTokenRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewPersonalAccessToken(db *pgxpool.Pool, opts ...Option) PersonalAccessTokenRepository {
+	return &postgresPersonalAccessTokenRepository{db: db, options: newOptions(opts)}
+}
+
+func (r *postgresPersonalAccessTokenRepository) Create(ctx context.Context, token *domain.PersonalAccessToken) (err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenRepository.Create", tracing.WithDBOperation("INSERT", "personal_access_tokens"), tracing.WithUserID(token.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = conn(ctx, r.db).Exec(ctx, query, token.ID, token.UserID, token.Name, token.TokenHash, strings.Join(token.Scopes, " "), token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to insert personal access token", "user_id", token.UserID, "error", err)
+		return fmt.Errorf("Error creating personal access token: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresPersonalAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (_ *domain.PersonalAccessToken, err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenRepository.FindByHash", tracing.WithDBOperation("SELECT", "personal_access_tokens"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + personalAccessTokenColumns + ` FROM personal_access_tokens WHERE token_hash = $1`
+	token, err := scanPersonalAccessToken(conn(ctx, r.db).QueryRow(ctx, query, tokenHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for personal access token: %w", domain.ErrTokenNotFound)
+		}
+		r.logger.ErrorContext(ctx, "failed to query personal access token", "error", err)
+		return nil, fmt.Errorf("Error when searching for personal access token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *postgresPersonalAccessTokenRepository) ListByUser(ctx context.Context, userID string) (_ []*domain.PersonalAccessToken, err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenRepository.ListByUser", tracing.WithDBOperation("SELECT", "personal_access_tokens"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + personalAccessTokenColumns + ` FROM personal_access_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC, id`
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing personal access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*domain.PersonalAccessToken{}
+	for rows.Next() {
+		token, err := scanPersonalAccessToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing personal access tokens: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing personal access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *postgresPersonalAccessTokenRepository) Revoke(ctx context.Context, userID, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenRepository.Revoke", tracing.WithDBOperation("UPDATE", "personal_access_tokens"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE personal_access_tokens SET revoked_at = $3 WHERE id::text = $1 AND user_id = $2 AND revoked_at IS NULL`
+	tag, err := conn(ctx, r.db).Exec(ctx, query, id, userID, at)
+	if err != nil {
+		return fmt.Errorf("Error revoking personal access token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Error revoking personal access token: %w", domain.ErrTokenNotFound)
+	}
+	return nil
+}
+
+func (r *postgresPersonalAccessTokenRepository) MarkUsed(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenRepository.MarkUsed", tracing.WithDBOperation("UPDATE", "personal_access_tokens"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE personal_access_tokens SET last_used_at = $2 WHERE id = $1`
+	if _, err = conn(ctx, r.db).Exec(ctx, query, id, at); err != nil {
+		return fmt.Errorf("Error recording personal access token use: %w", err)
+	}
+	return nil
+}
+
+func scanPersonalAccessToken(row pgx.Row) (*domain.PersonalAccessToken, error) {
+	token := &domain.PersonalAccessToken{}
+	var scopes string
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, &scopes,
+		&token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = strings.Fields(scopes)
+	return token, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SAMLAssertionRepository remembers the IDs of consumed SAML assertions, so a
+// response replayed against any replica is rejected.
+type SAMLAssertionRepository interface {
+	// Consume records the assertion ID of a provider until expiresAt. It
+	// fails with domain.ErrAssertionConsumed when the ID is already recorded.
+	Consume(ctx context.Context, provider, assertionID string, expiresAt time.Time) error
+	// DeleteExpired removes the IDs that expired before the given time.
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+type postgresSAMLAssertionRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewSAMLAssertion(db *pgxpool.Pool, opts ...Option) SAMLAssertionRepository {
+	return &postgresSAMLAssertionRepository{db: db, options: newOptions(opts)}
+}
+
+func (r *postgresSAMLAssertionRepository) Consume(ctx context.Context, provider, assertionID string, expiresAt time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "SAMLAssertionRepository.Consume", tracing.WithDBOperation("INSERT", "saml_assertions"), tracing.WithProvider(provider))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO saml_assertions (provider, assertion_id, expires_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	tag, err := conn(ctx, r.db).Exec(ctx, query, provider, assertionID, expiresAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to insert SAML assertion", "provider", provider, "error", err)
+		return fmt.Errorf("Error consuming SAML assertion: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Error consuming SAML assertion %s: %w", assertionID, domain.ErrAssertionConsumed)
+	}
+	return nil
+}
+
+func (r *postgresSAMLAssertionRepository) DeleteExpired(ctx context.Context, before time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "SAMLAssertionRepository.DeleteExpired", tracing.WithDBOperation("DELETE", "saml_assertions"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).Exec(ctx, `DELETE FROM saml_assertions WHERE expires_at < $1`, before); err != nil {
+		return fmt.Errorf("Error deleting expired SAML assertions: %w", err)
+	}
+	return nil
+}
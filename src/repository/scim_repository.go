@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SCIMRepository stores SCIM tenants and what each of them provisioned.
+// Every user and group lookup is scoped to a tenant.
+type SCIMRepository interface {
+	CreateTenant(ctx context.Context, tenant *domain.SCIMTenant) error
+	FindTenantByTokenHash(ctx context.Context, tokenHash string) (*domain.SCIMTenant, error)
+	ListTenants(ctx context.Context) ([]*domain.SCIMTenant, error)
+
+	AddUser(ctx context.Context, user *domain.SCIMUser) error
+	FindUser(ctx context.Context, tenantID, userID string) (*domain.SCIMUser, error)
+	// ListUsers pages through a tenant's users in provisioning order and
+	// also returns how many there are in total.
+	ListUsers(ctx context.Context, tenantID string, limit, offset int) ([]*domain.SCIMUser, int, error)
+	// UpdateUser saves the external ID and the deactivated flag.
+	UpdateUser(ctx context.Context, user *domain.SCIMUser) error
+	// RemoveUser forgets the user and drops it from the tenant's groups. The
+	// account itself is left alone.
+	RemoveUser(ctx context.Context, tenantID, userID string) error
+
+	CreateGroup(ctx context.Context, group *domain.SCIMGroup) error
+	FindGroup(ctx context.Context, tenantID, groupID string) (*domain.SCIMGroup, error)
+	// ListGroups pages through a tenant's groups, only those named
+	// displayName when it is not empty.
+	ListGroups(ctx context.Context, tenantID, displayName string, limit, offset int) ([]*domain.SCIMGroup, int, error)
+	// UpdateGroup saves the name, external ID and the full member list.
+	UpdateGroup(ctx context.Context, group *domain.SCIMGroup) error
+	DeleteGroup(ctx context.Context, tenantID, groupID string) error
+}
+
+const (
+	scimTenantColumns = `id, name, token_hash, created_at`
+	scimUserColumns   = `tenant_id, user_id, external_id, created_at, deactivated`
+	scimGroupColumns  = `id, tenant_id, display_name, external_id, created_at, updated_at`
+)
+
+type postgresSCIMRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewSCIM(db *pgxpool.Pool, opts ...Option) SCIMRepository {
+	return &postgresSCIMRepository{db: db, options: newOptions(opts)}
+}
+
+func (r *postgresSCIMRepository) CreateTenant(ctx context.Context, tenant *domain.SCIMTenant) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.CreateTenant", tracing.WithDBOperation("INSERT", "scim_tenants"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO scim_tenants (` + scimTenantColumns + `) VALUES ($1, $2, $3, $4)`
+	if _, err = conn(ctx, r.db).Exec(ctx, query, tenant.ID, tenant.Name, tenant.TokenHash, tenant.CreatedAt); err != nil {
+		return fmt.Errorf("Error creating SCIM tenant: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresSCIMRepository) FindTenantByTokenHash(ctx context.Context, tokenHash string) (_ *domain.SCIMTenant, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.FindTenantByTokenHash", tracing.WithDBOperation("SELECT", "scim_tenants"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + scimTenantColumns + ` FROM scim_tenants WHERE token_hash = $1`
+	tenant := &domain.SCIMTenant{}
+	err = conn(ctx, r.db).QueryRow(ctx, query, tokenHash).Scan(&tenant.ID, &tenant.Name, &tenant.TokenHash, &tenant.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for SCIM tenant: %w", domain.ErrSCIMTenantNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for SCIM tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+func (r *postgresSCIMRepository) ListTenants(ctx context.Context) (_ []*domain.SCIMTenant, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.ListTenants", tracing.WithDBOperation("SELECT", "scim_tenants"))
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := conn(ctx, r.db).Query(ctx, `SELECT `+scimTenantColumns+` FROM scim_tenants ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing SCIM tenants: %w", err)
+	}
+	tenants, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.SCIMTenant, error) {
+		tenant := &domain.SCIMTenant{}
+		err := row.Scan(&tenant.ID, &tenant.Name, &tenant.TokenHash, &tenant.CreatedAt)
+		return tenant, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing SCIM tenants: %w", err)
+	}
+	return tenants, nil
+}
+
+func (r *postgresSCIMRepository) AddUser(ctx context.Context, user *domain.SCIMUser) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.AddUser", tracing.WithDBOperation("INSERT", "scim_users"), tracing.WithUserID(user.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO scim_users (` + scimUserColumns + `) VALUES ($1, $2, $3, $4, $5)`
+	if _, err = conn(ctx, r.db).Exec(ctx, query, user.TenantID, user.UserID, user.ExternalID, user.CreatedAt, user.Deactivated); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("Error adding SCIM user: %w", domain.ErrSCIMUserExists)
+		}
+		return fmt.Errorf("Error adding SCIM user: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresSCIMRepository) FindUser(ctx context.Context, tenantID, userID string) (_ *domain.SCIMUser, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.FindUser", tracing.WithDBOperation("SELECT", "scim_users"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + scimUserColumns + ` FROM scim_users WHERE tenant_id = $1 AND user_id = $2`
+	user := &domain.SCIMUser{}
+	err = conn(ctx, r.db).QueryRow(ctx, query, tenantID, userID).Scan(&user.TenantID, &user.UserID, &user.ExternalID, &user.CreatedAt, &user.Deactivated)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for SCIM user: %w", domain.ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for SCIM user: %w", err)
+	}
+	return user, nil
+}
+
+func (r *postgresSCIMRepository) ListUsers(ctx context.Context, tenantID string, limit, offset int) (_ []*domain.SCIMUser, _ int, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.ListUsers", tracing.WithDBOperation("SELECT", "scim_users"))
+	defer func() { tracing.End(span, err) }()
+
+	var total int
+	if err = conn(ctx, r.db).QueryRow(ctx, `SELECT count(*) FROM scim_users WHERE tenant_id = $1`, tenantID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("Error counting SCIM users: %w", err)
+	}
+	query := `SELECT ` + scimUserColumns + ` FROM scim_users WHERE tenant_id = $1 ORDER BY created_at, user_id LIMIT $2 OFFSET $3`
+	rows, err := conn(ctx, r.db).Query(ctx, query, tenantID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error listing SCIM users: %w", err)
+	}
+	users, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.SCIMUser, error) {
+		user := &domain.SCIMUser{}
+		err := row.Scan(&user.TenantID, &user.UserID, &user.ExternalID, &user.CreatedAt, &user.Deactivated)
+		return user, err
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error listing SCIM users: %w", err)
+	}
+	return users, total, nil
+}
+
+func (r *postgresSCIMRepository) UpdateUser(ctx context.Context, user *domain.SCIMUser) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.UpdateUser", tracing.WithDBOperation("UPDATE", "scim_users"), tracing.WithUserID(user.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE scim_users SET external_id = $3, deactivated = $4 WHERE tenant_id = $1 AND user_id = $2`
+	tag, err := conn(ctx, r.db).Exec(ctx, query, user.TenantID, user.UserID, user.ExternalID, user.Deactivated)
+	if err != nil {
+		return fmt.Errorf("Error updating SCIM user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Error updating SCIM user: %w", domain.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (r *postgresSCIMRepository) RemoveUser(ctx context.Context, tenantID, userID string) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.RemoveUser", tracing.WithDBOperation("DELETE", "scim_users"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	return pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM scim_users WHERE tenant_id = $1 AND user_id = $2`, tenantID, userID)
+		if err != nil {
+			return fmt.Errorf("Error removing SCIM user: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("Error removing SCIM user: %w", domain.ErrUserNotFound)
+		}
+		query := `DELETE FROM scim_group_members WHERE user_id = $2 AND group_id IN (SELECT id FROM scim_groups WHERE tenant_id = $1)`
+		if _, err := tx.Exec(ctx, query, tenantID, userID); err != nil {
+			return fmt.Errorf("Error removing SCIM user from groups: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *postgresSCIMRepository) CreateGroup(ctx context.Context, group *domain.SCIMGroup) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.CreateGroup", tracing.WithDBOperation("INSERT", "scim_groups"))
+	defer func() { tracing.End(span, err) }()
+
+	return pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		query := `INSERT INTO scim_groups (` + scimGroupColumns + `) VALUES ($1, $2, $3, $4, $5, $6)`
+		if _, err := tx.Exec(ctx, query, group.ID, group.TenantID, group.DisplayName, group.ExternalID, group.CreatedAt, group.UpdatedAt); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return fmt.Errorf("Error creating SCIM group: %w", domain.ErrSCIMGroupExists)
+			}
+			return fmt.Errorf("Error creating SCIM group: %w", err)
+		}
+		return insertGroupMembers(ctx, tx, group)
+	})
+}
+
+func (r *postgresSCIMRepository) FindGroup(ctx context.Context, tenantID, groupID string) (_ *domain.SCIMGroup, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.FindGroup", tracing.WithDBOperation("SELECT", "scim_groups"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + scimGroupColumns + ` FROM scim_groups WHERE tenant_id = $1 AND id = $2`
+	group := &domain.SCIMGroup{}
+	err = conn(ctx, r.db).QueryRow(ctx, query, tenantID, groupID).Scan(&group.ID, &group.TenantID, &group.DisplayName, &group.ExternalID, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for SCIM group: %w", domain.ErrSCIMGroupNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for SCIM group: %w", err)
+	}
+	if err := r.loadMembers(ctx, []*domain.SCIMGroup{group}); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (r *postgresSCIMRepository) ListGroups(ctx context.Context, tenantID, displayName string, limit, offset int) (_ []*domain.SCIMGroup, _ int, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.ListGroups", tracing.WithDBOperation("SELECT", "scim_groups"))
+	defer func() { tracing.End(span, err) }()
+
+	where := `WHERE tenant_id = $1 AND ($2 = '' OR display_name = $2)`
+	var total int
+	if err = conn(ctx, r.db).QueryRow(ctx, `SELECT count(*) FROM scim_groups `+where, tenantID, displayName).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("Error counting SCIM groups: %w", err)
+	}
+	query := `SELECT ` + scimGroupColumns + ` FROM scim_groups ` + where + ` ORDER BY created_at, id LIMIT $3 OFFSET $4`
+	rows, err := conn(ctx, r.db).Query(ctx, query, tenantID, displayName, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error listing SCIM groups: %w", err)
+	}
+	groups, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.SCIMGroup, error) {
+		group := &domain.SCIMGroup{}
+		err := row.Scan(&group.ID, &group.TenantID, &group.DisplayName, &group.ExternalID, &group.CreatedAt, &group.UpdatedAt)
+		return group, err
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error listing SCIM groups: %w", err)
+	}
+	if err := r.loadMembers(ctx, groups); err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+func (r *postgresSCIMRepository) UpdateGroup(ctx context.Context, group *domain.SCIMGroup) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.UpdateGroup", tracing.WithDBOperation("UPDATE", "scim_groups"))
+	defer func() { tracing.End(span, err) }()
+
+	return pgx.BeginFunc(ctx, conn(ctx, r.db), func(tx pgx.Tx) error {
+		query := `UPDATE scim_groups SET display_name = $3, external_id = $4, updated_at = $5 WHERE tenant_id = $1 AND id = $2`
+		tag, err := tx.Exec(ctx, query, group.TenantID, group.ID, group.DisplayName, group.ExternalID, group.UpdatedAt)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return fmt.Errorf("Error updating SCIM group: %w", domain.ErrSCIMGroupExists)
+			}
+			return fmt.Errorf("Error updating SCIM group: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("Error updating SCIM group: %w", domain.ErrSCIMGroupNotFound)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM scim_group_members WHERE group_id = $1`, group.ID); err != nil {
+			return fmt.Errorf("Error updating SCIM group members: %w", err)
+		}
+		return insertGroupMembers(ctx, tx, group)
+	})
+}
+
+func (r *postgresSCIMRepository) DeleteGroup(ctx context.Context, tenantID, groupID string) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.DeleteGroup", tracing.WithDBOperation("DELETE", "scim_groups"))
+	defer func() { tracing.End(span, err) }()
+
+	tag, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM scim_groups WHERE tenant_id = $1 AND id = $2`, tenantID, groupID)
+	if err != nil {
+		return fmt.Errorf("Error deleting SCIM group: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Error deleting SCIM group: %w", domain.ErrSCIMGroupNotFound)
+	}
+	return nil
+}
+
+func insertGroupMembers(ctx context.Context, tx pgx.Tx, group *domain.SCIMGroup) error {
+	if len(group.MemberIDs) == 0 {
+		return nil
+	}
+	query := `INSERT INTO scim_group_members (group_id, user_id) SELECT $1, unnest($2::text[])::uuid ON CONFLICT DO NOTHING`
+	if _, err := tx.Exec(ctx, query, group.ID, group.MemberIDs); err != nil {
+		return fmt.Errorf("Error saving SCIM group members: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresSCIMRepository) loadMembers(ctx context.Context, groups []*domain.SCIMGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	byID := make(map[string]*domain.SCIMGroup, len(groups))
+	ids := make([]string, 0, len(groups))
+	for _, group := range groups {
+		group.MemberIDs = []string{}
+		byID[group.ID] = group
+		ids = append(ids, group.ID)
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, `SELECT group_id, user_id FROM scim_group_members WHERE group_id = ANY($1::text[]::uuid[]) ORDER BY user_id`, ids)
+	if err != nil {
+		return fmt.Errorf("Error loading SCIM group members: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupID, userID string
+		if err := rows.Scan(&groupID, &userID); err != nil {
+			return fmt.Errorf("Error loading SCIM group members: %w", err)
+		}
+		byID[groupID].MemberIDs = append(byID[groupID].MemberIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Error loading SCIM group members: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type accessTokenRepository struct {
+	db *sql.DB
+}
+
+func NewAccessToken(db *sql.DB) repository.AccessTokenRepository {
+	return &accessTokenRepository{db: db}
+}
+
+func (r *accessTokenRepository) Create(ctx context.Context, token *domain.AccessToken) (err error) {
+	ctx, span := tracer.Start(ctx, "AccessTokenRepository.Create", tracing.WithSQLiteOperation("INSERT", "access_tokens"), tracing.WithUserID(token.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO access_tokens (token_hash, user_id, claims, issued_at, expires_at) VALUES (?, ?, ?, ?, ?)`
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, token.TokenHash, token.UserID, string(token.Claims), token.IssuedAt.UTC(), token.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("Error creating access token: %w", err)
+	}
+	return nil
+}
+
+func (r *accessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (_ *domain.AccessToken, err error) {
+	ctx, span := tracer.Start(ctx, "AccessTokenRepository.FindByHash", tracing.WithSQLiteOperation("SELECT", "access_tokens"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT token_hash, user_id, claims, issued_at, expires_at FROM access_tokens WHERE token_hash = ?`
+	token := &domain.AccessToken{}
+	var claims string
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash).Scan(&token.TokenHash, &token.UserID, &claims, &token.IssuedAt, &token.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error finding access token: %w", domain.ErrInvalidToken)
+		}
+		return nil, fmt.Errorf("Error finding access token: %w", err)
+	}
+	token.Claims = json.RawMessage(claims)
+	return token, nil
+}
+
+func (r *accessTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "AccessTokenRepository.DeleteExpired", tracing.WithSQLiteOperation("DELETE", "access_tokens"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM access_tokens WHERE expires_at < ?`, before.UTC()); err != nil {
+		return fmt.Errorf("Error deleting expired access tokens: %w", err)
+	}
+	return nil
+}
+
+func (r *accessTokenRepository) Revoke(ctx context.Context, tokenHash string) (err error) {
+	ctx, span := tracer.Start(ctx, "AccessTokenRepository.Revoke", tracing.WithSQLiteOperation("DELETE", "access_tokens"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM access_tokens WHERE token_hash = ?`, tokenHash); err != nil {
+		return fmt.Errorf("Error revoking access token: %w", err)
+	}
+	return nil
+}
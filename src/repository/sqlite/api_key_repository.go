@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const apiKeyColumns = `id, name, key_hash, created_at, expires_at, revoked_at`
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKey(db *sql.DB) repository.APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) (err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyRepository.Create", tracing.WithSQLiteOperation("INSERT", "api_keys"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO api_keys (id, name, key_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`
+	if _, err = conn(ctx, r.db).ExecContext(ctx, query, key.ID, key.Name, key.KeyHash, key.CreatedAt.UTC(), key.ExpiresAt.UTC()); err != nil {
+		return fmt.Errorf("Error creating API key: %w", err)
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (_ *domain.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyRepository.FindByHash", tracing.WithSQLiteOperation("SELECT", "api_keys"))
+	defer func() { tracing.End(span, err) }()
+
+	key, err := scanAPIKey(conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, keyHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for API key: %w", domain.ErrAPIKeyNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for API key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) (_ []*domain.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyRepository.List", tracing.WithSQLiteOperation("SELECT", "api_keys"))
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY name, created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing API keys: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyRepository.Revoke", tracing.WithSQLiteOperation("UPDATE", "api_keys"))
+	defer func() { tracing.End(span, err) }()
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, at.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("Error revoking API key: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("Error revoking API key: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("Error revoking API key: %w", domain.ErrAPIKeyNotFound)
+	}
+	return nil
+}
+
+func scanAPIKey(row scanner) (*domain.APIKey, error) {
+	key := &domain.APIKey{}
+	if err := row.Scan(&key.ID, &key.Name, &key.KeyHash, &key.CreatedAt, &key.ExpiresAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+type clientRepository struct {
+	db *sql.DB
+}
+
+func NewClient(db *sql.DB) repository.ClientRepository {
+	return &clientRepository{db: db}
+}
+
+func (r *clientRepository) Create(ctx context.Context, client *domain.Client) (err error) {
+	ctx, span := tracer.Start(ctx, "ClientRepository.Create", tracing.WithSQLiteOperation("INSERT", "clients"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO clients (id, name, secret_hash, created_at) VALUES (?, ?, ?, ?)`
+	if _, err = conn(ctx, r.db).ExecContext(ctx, query, client.ID, client.Name, client.SecretHash, client.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("Error creating client: %w", err)
+	}
+	return nil
+}
+
+func (r *clientRepository) FindByID(ctx context.Context, id string) (_ *domain.Client, err error) {
+	ctx, span := tracer.Start(ctx, "ClientRepository.FindByID", tracing.WithSQLiteOperation("SELECT", "clients"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT id, name, secret_hash, created_at FROM clients WHERE id = ?`
+	client := &domain.Client{}
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&client.ID, &client.Name, &client.SecretHash, &client.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for client by ID: %w", domain.ErrClientNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for client by ID: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const deviceAuthorizationColumns = `device_code_hash, user_code, client_id, status, COALESCE(user_id, ''), interval_seconds, created_at, expires_at, last_polled_at`
+
+type deviceAuthorizationRepository struct {
+	db *sql.DB
+}
+
+func NewDeviceAuthorization(db *sql.DB) repository.DeviceAuthorizationRepository {
+	return &deviceAuthorizationRepository{db: db}
+}
+
+func (r *deviceAuthorizationRepository) Create(ctx context.Context, auth *domain.DeviceAuthorization) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.Create", tracing.WithSQLiteOperation("INSERT", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO device_authorizations (device_code_hash, user_code, client_id, status, interval_seconds, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, auth.DeviceCodeHash, auth.UserCode, auth.ClientID, auth.Status, int(auth.Interval.Seconds()), auth.CreatedAt.UTC(), auth.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("Error creating device authorization: %w", err)
+	}
+	return nil
+}
+
+func (r *deviceAuthorizationRepository) FindByDeviceCode(ctx context.Context, deviceCodeHash string) (_ *domain.DeviceAuthorization, err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.FindByDeviceCode", tracing.WithSQLiteOperation("SELECT", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + deviceAuthorizationColumns + ` FROM device_authorizations WHERE device_code_hash = ?`
+	auth, err := scanDeviceAuthorization(conn(ctx, r.db).QueryRowContext(ctx, query, deviceCodeHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error finding device authorization: %w", domain.ErrDeviceCodeInvalid)
+		}
+		return nil, fmt.Errorf("Error finding device authorization: %w", err)
+	}
+	return auth, nil
+}
+
+func (r *deviceAuthorizationRepository) FindPendingByUserCode(ctx context.Context, userCode string, now time.Time) (_ *domain.DeviceAuthorization, err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.FindPendingByUserCode", tracing.WithSQLiteOperation("SELECT", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + deviceAuthorizationColumns + ` FROM device_authorizations
+		WHERE user_code = ? AND status = ? AND expires_at > ?`
+	auth, err := scanDeviceAuthorization(conn(ctx, r.db).QueryRowContext(ctx, query, userCode, domain.DeviceAuthorizationPending, now.UTC()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error finding device authorization: %w", domain.ErrUserCodeInvalid)
+		}
+		return nil, fmt.Errorf("Error finding device authorization: %w", err)
+	}
+	return auth, nil
+}
+
+func (r *deviceAuthorizationRepository) Decide(ctx context.Context, userCode, userID, status string, now time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.Decide", tracing.WithSQLiteOperation("UPDATE", "device_authorizations"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE device_authorizations SET status = ?, user_id = ?
+		WHERE user_code = ? AND status = ? AND expires_at > ?`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, status, userID, userCode, domain.DeviceAuthorizationPending, now.UTC())
+	if err != nil {
+		return fmt.Errorf("Error deciding device authorization: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("Error deciding device authorization: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("Error deciding device authorization: %w", domain.ErrUserCodeInvalid)
+	}
+	return nil
+}
+
+func (r *deviceAuthorizationRepository) RecordPoll(ctx context.Context, deviceCodeHash string, at time.Time, interval time.Duration) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.RecordPoll", tracing.WithSQLiteOperation("UPDATE", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE device_authorizations SET last_polled_at = ?, interval_seconds = ? WHERE device_code_hash = ?`
+	if _, err = conn(ctx, r.db).ExecContext(ctx, query, at.UTC(), int(interval.Seconds()), deviceCodeHash); err != nil {
+		return fmt.Errorf("Error recording device poll: %w", err)
+	}
+	return nil
+}
+
+func (r *deviceAuthorizationRepository) Delete(ctx context.Context, deviceCodeHash string) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.Delete", tracing.WithSQLiteOperation("DELETE", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM device_authorizations WHERE device_code_hash = ?`, deviceCodeHash)
+	if err != nil {
+		return fmt.Errorf("Error deleting device authorization: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("Error deleting device authorization: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("Error deleting device authorization: %w", domain.ErrDeviceCodeInvalid)
+	}
+	return nil
+}
+
+func (r *deviceAuthorizationRepository) DeleteExpired(ctx context.Context, before time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationRepository.DeleteExpired", tracing.WithSQLiteOperation("DELETE", "device_authorizations"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM device_authorizations WHERE expires_at < ?`, before.UTC()); err != nil {
+		return fmt.Errorf("Error deleting expired device authorizations: %w", err)
+	}
+	return nil
+}
+
+func scanDeviceAuthorization(row scanner) (*domain.DeviceAuthorization, error) {
+	auth := &domain.DeviceAuthorization{}
+	var interval int
+	if err := row.Scan(&auth.DeviceCodeHash, &auth.UserCode, &auth.ClientID, &auth.Status, &auth.UserID, &interval, &auth.CreatedAt, &auth.ExpiresAt, &auth.LastPolledAt); err != nil {
+		return nil, err
+	}
+	auth.Interval = time.Duration(interval) * time.Second
+	return auth, nil
+}
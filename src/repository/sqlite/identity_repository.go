@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const identityColumns = `provider, subject, user_id, email, created_at`
+
+type identityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentity(db *sql.DB) repository.IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+func (r *identityRepository) Create(ctx context.Context, identity *domain.Identity) (err error) {
+	ctx, span := tracer.Start(ctx, "IdentityRepository.Create", tracing.WithSQLiteOperation("INSERT", "user_identities"), tracing.WithUserID(identity.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO user_identities (` + identityColumns + `) VALUES (?, ?, ?, ?, ?)`
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt.UTC())
+	if err != nil {
+		if isUniqueViolation(err) || isConstraint(err, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+			return fmt.Errorf("Error creating identity: %w", domain.ErrIdentityAlreadyLinked)
+		}
+		return fmt.Errorf("Error creating identity: %w", err)
+	}
+	return nil
+}
+
+func (r *identityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (_ *domain.Identity, err error) {
+	ctx, span := tracer.Start(ctx, "IdentityRepository.FindByProviderSubject", tracing.WithSQLiteOperation("SELECT", "user_identities"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE provider = ? AND subject = ?`
+	identity, err := scanIdentity(conn(ctx, r.db).QueryRowContext(ctx, query, provider, subject))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for identity: %w", domain.ErrIdentityNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for identity: %w", err)
+	}
+	return identity, nil
+}
+
+func (r *identityRepository) ListByUser(ctx context.Context, userID string) (_ []*domain.Identity, err error) {
+	ctx, span := tracer.Start(ctx, "IdentityRepository.ListByUser", tracing.WithSQLiteOperation("SELECT", "user_identities"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE user_id = ? ORDER BY created_at, provider`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []*domain.Identity{}
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing identities: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing identities: %w", err)
+	}
+	return identities, nil
+}
+
+func scanIdentity(row scanner) (*domain.Identity, error) {
+	identity := &domain.Identity{}
+	if err := row.Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.CreatedAt); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
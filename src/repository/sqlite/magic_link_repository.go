@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type magicLinkRepository struct {
+	db *sql.DB
+}
+
+func NewMagicLink(db *sql.DB) repository.MagicLinkRepository {
+	return &magicLinkRepository{db: db}
+}
+
+func (r *magicLinkRepository) Create(ctx context.Context, link *domain.MagicLink) (err error) {
+	ctx, span := tracer.Start(ctx, "MagicLinkRepository.Create", tracing.WithSQLiteOperation("INSERT", "magic_links"), tracing.WithUserID(link.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO magic_links (token_hash, user_id, nonce_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`
+	if _, err = conn(ctx, r.db).ExecContext(ctx, query, link.TokenHash, link.UserID, link.NonceHash, link.CreatedAt.UTC(), link.ExpiresAt.UTC()); err != nil {
+		return fmt.Errorf("Error creating magic link: %w", err)
+	}
+	return nil
+}
+
+func (r *magicLinkRepository) Consume(ctx context.Context, tokenHash, nonceHash string, now time.Time) (_ *domain.MagicLink, err error) {
+	ctx, span := tracer.Start(ctx, "MagicLinkRepository.Consume", tracing.WithSQLiteOperation("UPDATE", "magic_links"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE magic_links SET used_at = ?3
+		WHERE token_hash = ?1 AND used_at IS NULL AND expires_at > ?3 AND (nonce_hash = '' OR nonce_hash = ?2)
+		RETURNING token_hash, user_id, nonce_hash, created_at, expires_at, used_at`
+	link := &domain.MagicLink{}
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash, nonceHash, now.UTC()).
+		Scan(&link.TokenHash, &link.UserID, &link.NonceHash, &link.CreatedAt, &link.ExpiresAt, &link.UsedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error consuming magic link: %w", domain.ErrMagicLinkInvalid)
+		}
+		return nil, fmt.Errorf("Error consuming magic link: %w", err)
+	}
+	return link, nil
+}
+
+func (r *magicLinkRepository) CountSince(ctx context.Context, userID string, since time.Time) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "MagicLinkRepository.CountSince", tracing.WithSQLiteOperation("SELECT", "magic_links"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	var count int
+	query := `SELECT count(*) FROM magic_links WHERE user_id = ? AND created_at >= ?`
+	if err = conn(ctx, r.db).QueryRowContext(ctx, query, userID, since.UTC()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("Error counting magic links: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const eventColumns = `id, type, data, created_at, dispatched_at`
+
+type outboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutbox(db *sql.DB) repository.OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Append(ctx context.Context, events ...*domain.Event) (err error) {
+	ctx, span := tracer.Start(ctx, "OutboxRepository.Append", tracing.WithSQLiteOperation("INSERT", "outbox_events"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO outbox_events (id, type, data, created_at) VALUES (?, ?, ?, ?)`
+	for _, event := range events {
+		if _, err = conn(ctx, r.db).ExecContext(ctx, query, event.ID, event.Type, string(event.Data), event.CreatedAt.UTC()); err != nil {
+			return fmt.Errorf("Error appending event: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *outboxRepository) FindByID(ctx context.Context, id string) (_ *domain.Event, err error) {
+	ctx, span := tracer.Start(ctx, "OutboxRepository.FindByID", tracing.WithSQLiteOperation("SELECT", "outbox_events"))
+	defer func() { tracing.End(span, err) }()
+
+	event, err := scanEvent(conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+eventColumns+` FROM outbox_events WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for event: %w", domain.ErrEventNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for event: %w", err)
+	}
+	return event, nil
+}
+
+func (r *outboxRepository) ListUndispatched(ctx context.Context, limit int) (_ []*domain.Event, err error) {
+	ctx, span := tracer.Start(ctx, "OutboxRepository.ListUndispatched", tracing.WithSQLiteOperation("SELECT", "outbox_events"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + eventColumns + ` FROM outbox_events WHERE dispatched_at IS NULL ORDER BY created_at, id LIMIT ?`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing undispatched events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*domain.Event{}
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing undispatched events: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing undispatched events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) MarkDispatched(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "OutboxRepository.MarkDispatched", tracing.WithSQLiteOperation("UPDATE", "outbox_events"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).ExecContext(ctx, `UPDATE outbox_events SET dispatched_at = ? WHERE id = ?`, at.UTC(), id); err != nil {
+		return fmt.Errorf("Error marking event as dispatched: %w", err)
+	}
+	return nil
+}
+
+func scanEvent(row scanner) (*domain.Event, error) {
+	event := &domain.Event{}
+	var data string
+	if err := row.Scan(&event.ID, &event.Type, &data, &event.CreatedAt, &event.DispatchedAt); err != nil {
+		return nil, err
+	}
+	event.Data = []byte(data)
+	return event, nil
+}
@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const personalAccessTokenColumns = `id, user_id, name, token_hash, scopes, created_at, expires_at, last_used_at, revoked_at`
+
+type personalAccessTokenRepository struct {
+	db *sql.DB
+}
+
+func NewPersonalAccessToken(db *sql.DB) repository.PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{db: db}
+}
+
+func (r *personalAccessTokenRepository) Create(ctx context.Context, token *domain.PersonalAccessToken) (err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenRepository.Create", tracing.WithSQLiteOperation("INSERT", "personal_access_tokens"), tracing.WithUserID(token.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, token.ID, token.UserID, token.Name, token.TokenHash, strings.Join(token.Scopes, " "), token.CreatedAt.UTC(), token.ExpiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("Error creating personal access token: %w", err)
+	}
+	return nil
+}
+
+func (r *personalAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (_ *domain.PersonalAccessToken, err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenRepository.FindByHash", tracing.WithSQLiteOperation("SELECT", "personal_access_tokens"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + personalAccessTokenColumns + ` FROM personal_access_tokens WHERE token_hash = ?`
+	token, err := scanPersonalAccessToken(conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for personal access token: %w", domain.ErrTokenNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for personal access token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *personalAccessTokenRepository) ListByUser(ctx context.Context, userID string) (_ []*domain.PersonalAccessToken, err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenRepository.ListByUser", tracing.WithSQLiteOperation("SELECT", "personal_access_tokens"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + personalAccessTokenColumns + ` FROM personal_access_tokens
+		WHERE user_id = ? AND revoked_at IS NULL ORDER BY created_at DESC, id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing personal access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*domain.PersonalAccessToken{}
+	for rows.Next() {
+		token, err := scanPersonalAccessToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing personal access tokens: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing personal access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *personalAccessTokenRepository) Revoke(ctx context.Context, userID, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenRepository.Revoke", tracing.WithSQLiteOperation("UPDATE", "personal_access_tokens"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE personal_access_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, at.UTC(), id, userID)
+	if err != nil {
+		return fmt.Errorf("Error revoking personal access token: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("Error revoking personal access token: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("Error revoking personal access token: %w", domain.ErrTokenNotFound)
+	}
+	return nil
+}
+
+func (r *personalAccessTokenRepository) MarkUsed(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenRepository.MarkUsed", tracing.WithSQLiteOperation("UPDATE", "personal_access_tokens"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).ExecContext(ctx, `UPDATE personal_access_tokens SET last_used_at = ? WHERE id = ?`, at.UTC(), id); err != nil {
+		return fmt.Errorf("Error recording personal access token use: %w", err)
+	}
+	return nil
+}
+
+func scanPersonalAccessToken(row scanner) (*domain.PersonalAccessToken, error) {
+	token := &domain.PersonalAccessToken{}
+	var scopes string
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, &scopes,
+		&token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = strings.Fields(scopes)
+	return token, nil
+}
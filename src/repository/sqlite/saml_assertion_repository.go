@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type samlAssertionRepository struct {
+	db *sql.DB
+}
+
+func NewSAMLAssertion(db *sql.DB) repository.SAMLAssertionRepository {
+	return &samlAssertionRepository{db: db}
+}
+
+func (r *samlAssertionRepository) Consume(ctx context.Context, provider, assertionID string, expiresAt time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "SAMLAssertionRepository.Consume", tracing.WithSQLiteOperation("INSERT", "saml_assertions"), tracing.WithProvider(provider))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO saml_assertions (provider, assertion_id, expires_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, provider, assertionID, expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("Error consuming SAML assertion: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("Error consuming SAML assertion %s: %w", assertionID, domain.ErrAssertionConsumed)
+	}
+	return nil
+}
+
+func (r *samlAssertionRepository) DeleteExpired(ctx context.Context, before time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "SAMLAssertionRepository.DeleteExpired", tracing.WithSQLiteOperation("DELETE", "saml_assertions"))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM saml_assertions WHERE expires_at < ?`, before.UTC()); err != nil {
+		return fmt.Errorf("Error deleting expired SAML assertions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	scimTenantColumns = `id, name, token_hash, created_at`
+	scimUserColumns   = `tenant_id, user_id, external_id, created_at, deactivated`
+	scimGroupColumns  = `id, tenant_id, display_name, external_id, created_at, updated_at`
+)
+
+type scimRepository struct {
+	db *sql.DB
+}
+
+func NewSCIM(db *sql.DB) repository.SCIMRepository {
+	return &scimRepository{db: db}
+}
+
+func (r *scimRepository) CreateTenant(ctx context.Context, tenant *domain.SCIMTenant) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.CreateTenant", tracing.WithSQLiteOperation("INSERT", "scim_tenants"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO scim_tenants (` + scimTenantColumns + `) VALUES (?, ?, ?, ?)`
+	if _, err = conn(ctx, r.db).ExecContext(ctx, query, tenant.ID, tenant.Name, tenant.TokenHash, tenant.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("Error creating SCIM tenant: %w", err)
+	}
+	return nil
+}
+
+func (r *scimRepository) FindTenantByTokenHash(ctx context.Context, tokenHash string) (_ *domain.SCIMTenant, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.FindTenantByTokenHash", tracing.WithSQLiteOperation("SELECT", "scim_tenants"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + scimTenantColumns + ` FROM scim_tenants WHERE token_hash = ?`
+	tenant, err := scanSCIMTenant(conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for SCIM tenant: %w", domain.ErrSCIMTenantNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for SCIM tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+func (r *scimRepository) ListTenants(ctx context.Context) (_ []*domain.SCIMTenant, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.ListTenants", tracing.WithSQLiteOperation("SELECT", "scim_tenants"))
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+scimTenantColumns+` FROM scim_tenants ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing SCIM tenants: %w", err)
+	}
+	defer rows.Close()
+
+	tenants := []*domain.SCIMTenant{}
+	for rows.Next() {
+		tenant, err := scanSCIMTenant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing SCIM tenants: %w", err)
+		}
+		tenants = append(tenants, tenant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing SCIM tenants: %w", err)
+	}
+	return tenants, nil
+}
+
+func (r *scimRepository) AddUser(ctx context.Context, user *domain.SCIMUser) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.AddUser", tracing.WithSQLiteOperation("INSERT", "scim_users"), tracing.WithUserID(user.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO scim_users (` + scimUserColumns + `) VALUES (?, ?, ?, ?, ?)`
+	if _, err = conn(ctx, r.db).ExecContext(ctx, query, user.TenantID, user.UserID, user.ExternalID, user.CreatedAt.UTC(), user.Deactivated); err != nil {
+		if isConstraint(err, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+			return fmt.Errorf("Error adding SCIM user: %w", domain.ErrSCIMUserExists)
+		}
+		return fmt.Errorf("Error adding SCIM user: %w", err)
+	}
+	return nil
+}
+
+func (r *scimRepository) FindUser(ctx context.Context, tenantID, userID string) (_ *domain.SCIMUser, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.FindUser", tracing.WithSQLiteOperation("SELECT", "scim_users"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + scimUserColumns + ` FROM scim_users WHERE tenant_id = ? AND user_id = ?`
+	user, err := scanSCIMUser(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for SCIM user: %w", domain.ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for SCIM user: %w", err)
+	}
+	return user, nil
+}
+
+func (r *scimRepository) ListUsers(ctx context.Context, tenantID string, limit, offset int) (_ []*domain.SCIMUser, _ int, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.ListUsers", tracing.WithSQLiteOperation("SELECT", "scim_users"))
+	defer func() { tracing.End(span, err) }()
+
+	var total int
+	if err = conn(ctx, r.db).QueryRowContext(ctx, `SELECT count(*) FROM scim_users WHERE tenant_id = ?`, tenantID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("Error counting SCIM users: %w", err)
+	}
+	query := `SELECT ` + scimUserColumns + ` FROM scim_users WHERE tenant_id = ? ORDER BY created_at, user_id LIMIT ? OFFSET ?`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error listing SCIM users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*domain.SCIMUser{}
+	for rows.Next() {
+		user, err := scanSCIMUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Error listing SCIM users: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("Error listing SCIM users: %w", err)
+	}
+	return users, total, nil
+}
+
+func (r *scimRepository) UpdateUser(ctx context.Context, user *domain.SCIMUser) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.UpdateUser", tracing.WithSQLiteOperation("UPDATE", "scim_users"), tracing.WithUserID(user.UserID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE scim_users SET external_id = ?, deactivated = ? WHERE tenant_id = ? AND user_id = ?`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, user.ExternalID, user.Deactivated, user.TenantID, user.UserID)
+	if err != nil {
+		return fmt.Errorf("Error updating SCIM user: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("Error updating SCIM user: %w", domain.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (r *scimRepository) RemoveUser(ctx context.Context, tenantID, userID string) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.RemoveUser", tracing.WithSQLiteOperation("DELETE", "scim_users"), tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM scim_users WHERE tenant_id = ? AND user_id = ?`, tenantID, userID)
+		if err != nil {
+			return fmt.Errorf("Error removing SCIM user: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("Error removing SCIM user: %w", domain.ErrUserNotFound)
+		}
+		query := `DELETE FROM scim_group_members WHERE user_id = ? AND group_id IN (SELECT id FROM scim_groups WHERE tenant_id = ?)`
+		if _, err := tx.ExecContext(ctx, query, userID, tenantID); err != nil {
+			return fmt.Errorf("Error removing SCIM user from groups: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *scimRepository) CreateGroup(ctx context.Context, group *domain.SCIMGroup) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.CreateGroup", tracing.WithSQLiteOperation("INSERT", "scim_groups"))
+	defer func() { tracing.End(span, err) }()
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO scim_groups (` + scimGroupColumns + `) VALUES (?, ?, ?, ?, ?, ?)`
+		_, err := tx.ExecContext(ctx, query, group.ID, group.TenantID, group.DisplayName, group.ExternalID, group.CreatedAt.UTC(), group.UpdatedAt.UTC())
+		if err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("Error creating SCIM group: %w", domain.ErrSCIMGroupExists)
+			}
+			return fmt.Errorf("Error creating SCIM group: %w", err)
+		}
+		return insertGroupMembers(ctx, tx, group)
+	})
+}
+
+func (r *scimRepository) FindGroup(ctx context.Context, tenantID, groupID string) (_ *domain.SCIMGroup, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.FindGroup", tracing.WithSQLiteOperation("SELECT", "scim_groups"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + scimGroupColumns + ` FROM scim_groups WHERE tenant_id = ? AND id = ?`
+	group, err := scanSCIMGroup(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, groupID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for SCIM group: %w", domain.ErrSCIMGroupNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for SCIM group: %w", err)
+	}
+	if err := r.loadMembers(ctx, []*domain.SCIMGroup{group}); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (r *scimRepository) ListGroups(ctx context.Context, tenantID, displayName string, limit, offset int) (_ []*domain.SCIMGroup, _ int, err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.ListGroups", tracing.WithSQLiteOperation("SELECT", "scim_groups"))
+	defer func() { tracing.End(span, err) }()
+
+	where := `WHERE tenant_id = ? AND (? = '' OR display_name = ?)`
+	var total int
+	if err = conn(ctx, r.db).QueryRowContext(ctx, `SELECT count(*) FROM scim_groups `+where, tenantID, displayName, displayName).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("Error counting SCIM groups: %w", err)
+	}
+	query := `SELECT ` + scimGroupColumns + ` FROM scim_groups ` + where + ` ORDER BY created_at, id LIMIT ? OFFSET ?`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, displayName, displayName, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Error listing SCIM groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []*domain.SCIMGroup{}
+	for rows.Next() {
+		group, err := scanSCIMGroup(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Error listing SCIM groups: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("Error listing SCIM groups: %w", err)
+	}
+	rows.Close()
+	if err := r.loadMembers(ctx, groups); err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+func (r *scimRepository) UpdateGroup(ctx context.Context, group *domain.SCIMGroup) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.UpdateGroup", tracing.WithSQLiteOperation("UPDATE", "scim_groups"))
+	defer func() { tracing.End(span, err) }()
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		query := `UPDATE scim_groups SET display_name = ?, external_id = ?, updated_at = ? WHERE tenant_id = ? AND id = ?`
+		result, err := tx.ExecContext(ctx, query, group.DisplayName, group.ExternalID, group.UpdatedAt.UTC(), group.TenantID, group.ID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("Error updating SCIM group: %w", domain.ErrSCIMGroupExists)
+			}
+			return fmt.Errorf("Error updating SCIM group: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("Error updating SCIM group: %w", domain.ErrSCIMGroupNotFound)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM scim_group_members WHERE group_id = ?`, group.ID); err != nil {
+			return fmt.Errorf("Error updating SCIM group members: %w", err)
+		}
+		return insertGroupMembers(ctx, tx, group)
+	})
+}
+
+func (r *scimRepository) DeleteGroup(ctx context.Context, tenantID, groupID string) (err error) {
+	ctx, span := tracer.Start(ctx, "SCIMRepository.DeleteGroup", tracing.WithSQLiteOperation("DELETE", "scim_groups"))
+	defer func() { tracing.End(span, err) }()
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM scim_groups WHERE tenant_id = ? AND id = ?`, tenantID, groupID)
+	if err != nil {
+		return fmt.Errorf("Error deleting SCIM group: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("Error deleting SCIM group: %w", domain.ErrSCIMGroupNotFound)
+	}
+	return nil
+}
+
+func (r *scimRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return inSavepoint(ctx, tx, fn)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Error starting transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertGroupMembers(ctx context.Context, tx *sql.Tx, group *domain.SCIMGroup) error {
+	for _, userID := range group.MemberIDs {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO scim_group_members (group_id, user_id) VALUES (?, ?)`, group.ID, userID); err != nil {
+			return fmt.Errorf("Error saving SCIM group members: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *scimRepository) loadMembers(ctx context.Context, groups []*domain.SCIMGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	byID := make(map[string]*domain.SCIMGroup, len(groups))
+	args := make([]any, 0, len(groups))
+	for _, group := range groups {
+		group.MemberIDs = []string{}
+		byID[group.ID] = group
+		args = append(args, group.ID)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	query := `SELECT group_id, user_id FROM scim_group_members WHERE group_id IN (` + placeholders + `) ORDER BY user_id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("Error loading SCIM group members: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupID, userID string
+		if err := rows.Scan(&groupID, &userID); err != nil {
+			return fmt.Errorf("Error loading SCIM group members: %w", err)
+		}
+		byID[groupID].MemberIDs = append(byID[groupID].MemberIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Error loading SCIM group members: %w", err)
+	}
+	return nil
+}
+
+func scanSCIMTenant(row scanner) (*domain.SCIMTenant, error) {
+	tenant := &domain.SCIMTenant{}
+	if err := row.Scan(&tenant.ID, &tenant.Name, &tenant.TokenHash, &tenant.CreatedAt); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func scanSCIMUser(row scanner) (*domain.SCIMUser, error) {
+	user := &domain.SCIMUser{}
+	if err := row.Scan(&user.TenantID, &user.UserID, &user.ExternalID, &user.CreatedAt, &user.Deactivated); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func scanSCIMGroup(row scanner) (*domain.SCIMGroup, error) {
+	group := &domain.SCIMGroup{}
+	if err := row.Scan(&group.ID, &group.TenantID, &group.DisplayName, &group.ExternalID, &group.CreatedAt, &group.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
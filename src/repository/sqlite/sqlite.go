@@ -0,0 +1,86 @@
+// Package sqlite stores users and clients in a single SQLite file, for tools
+// that embed the service as one binary without Postgres. It uses the pure Go
+// modernc.org/sqlite driver, so builds keep working with CGO disabled.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+var tracer = otel.Tracer("auth-service/src/repository/sqlite")
+
+// defaultPragmas let concurrent requests wait for the write lock instead of
+// failing with SQLITE_BUSY, and let readers proceed while a write is running.
+const defaultPragmas = "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+
+// immediateTxLock makes transactions take the write lock when they begin,
+// since one that reads before writing cannot wait for a lock it would have
+// to upgrade.
+const immediateTxLock = "_txlock=immediate"
+
+// Open opens the database file named by dsn, which may carry its own
+// _pragma query parameters to replace the defaults. Transactions begin
+// immediately unless dsn sets _txlock itself.
+func Open(ctx context.Context, dsn string) (*sql.DB, error) {
+	if !strings.Contains(dsn, "_pragma=") {
+		dsn = withParam(dsn, defaultPragmas)
+	}
+	if !strings.Contains(dsn, "_txlock=") {
+		dsn = withParam(dsn, immediateTxLock)
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	return db, nil
+}
+
+func withParam(dsn, param string) string {
+	if strings.Contains(dsn, "?") {
+		return dsn + "&" + param
+	}
+	return dsn + "?" + param
+}
+
+// isBusy reports whether err is SQLITE_BUSY or one of its extended codes:
+// the write lock stayed taken for longer than busy_timeout.
+func isBusy(err error) bool {
+	var sqliteErr *sqlitedriver.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+}
+
+// isUniqueViolation is the SQLite counterpart of Postgres' 23505.
+func isUniqueViolation(err error) bool {
+	return isConstraint(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE)
+}
+
+// isConstraint reports whether err is the given extended constraint code.
+// Unlike Postgres, SQLite reports primary key violations separately.
+func isConstraint(err error, code int) bool {
+	var sqliteErr *sqlitedriver.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == code
+}
+
+// utc stores every timestamp in UTC so the text representation SQLite keeps
+// sorts chronologically.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type txKey struct{}
+
+// querier is what sql.DB and sql.Tx have in common.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn returns the transaction carried by ctx, or db outside of one.
+func conn(ctx context.Context, db *sql.DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+type txManager struct {
+	db *sql.DB
+}
+
+func NewTxManager(db *sql.DB) repository.TxManager {
+	return &txManager{db: db}
+}
+
+const (
+	maxRetries     = 3
+	retryBaseDelay = 10 * time.Millisecond
+)
+
+// WithinTx runs fn again, like the Postgres manager does on a serialization
+// failure, when the write lock could not be had within busy_timeout.
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	ctx, span := tracer.Start(ctx, "TxManager.WithinTx")
+	defer func() { tracing.End(span, err) }()
+
+	for attempt := 0; ; attempt++ {
+		err = m.run(ctx, fn)
+		if err == nil || !isBusy(err) || attempt >= maxRetries {
+			return err
+		}
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("db.tx.attempt", attempt+1)))
+
+		delay := retryBaseDelay << attempt
+		delay += rand.N(delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (m *txManager) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Error starting transaction: %w", err)
+	}
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// inSavepoint runs fn inside the caller's transaction, undoing only fn's
+// writes when it fails so the caller can still handle the error and commit.
+func inSavepoint(ctx context.Context, tx *sql.Tx, fn func(tx *sql.Tx) error) error {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT nested`); err != nil {
+		return fmt.Errorf("Error starting savepoint: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.ExecContext(ctx, `ROLLBACK TO nested`)
+		tx.ExecContext(ctx, `RELEASE nested`)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `RELEASE nested`); err != nil {
+		return fmt.Errorf("Error releasing savepoint: %w", err)
+	}
+	return nil
+}
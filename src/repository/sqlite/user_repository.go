@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const userColumns = `id, name, email, password_hash, role, created_at, disabled_at, tokens_valid_after, email_verified_at`
+
+type userRepository struct {
+	db *sql.DB
+}
+
+func NewUser(db *sql.DB) repository.UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) Create(ctx context.Context, user *domain.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.Create", tracing.WithSQLiteOperation("INSERT", "users"))
+	defer func() { tracing.End(span, err) }()
+
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+	query := `INSERT INTO users (id, name, email, password_hash, role, created_at, email_verified_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, user.ID, user.Name, user.Email, user.PasswordHash, user.Role, user.CreatedAt.UTC(), utc(user.EmailVerifiedAt))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("Error creating user: %w", domain.ErrEmailAlreadyExists)
+		}
+		return fmt.Errorf("Error creating user: %w", err)
+	}
+	return nil
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (_ *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.FindByEmail", tracing.WithSQLiteOperation("SELECT", "users"), tracing.WithEmail(email))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE lower(email) = lower(?)`
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for user by email: %w", domain.ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for user by email: %w", err)
+	}
+	return user, nil
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id string) (_ *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.FindByID", tracing.WithSQLiteOperation("SELECT", "users"), tracing.WithUserID(id))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for user by ID: %w", domain.ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for user by ID: %w", err)
+	}
+	return user, nil
+}
+
+// FindByIDForUpdate needs no row lock: transactions take the database write
+// lock when they begin (see Open), so no other one can change the row.
+func (r *userRepository) FindByIDForUpdate(ctx context.Context, id string) (*domain.User, error) {
+	return r.FindByID(ctx, id)
+}
+
+func (r *userRepository) Update(ctx context.Context, user *domain.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.Update", tracing.WithSQLiteOperation("UPDATE", "users"), tracing.WithUserID(user.ID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE users SET name = ?, email = ?, password_hash = ?, role = ?, disabled_at = ?, tokens_valid_after = ?, email_verified_at = ? WHERE id = ?`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, user.Name, user.Email, user.PasswordHash, user.Role, utc(user.DisabledAt), utc(user.TokensValidAfter), utc(user.EmailVerifiedAt), user.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("Error updating user: %w", domain.ErrEmailAlreadyExists)
+		}
+		return fmt.Errorf("Error updating user: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Error updating user: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("Error updating user: %w", domain.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (r *userRepository) List(ctx context.Context, limit, offset int) (_ []*domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.List", tracing.WithSQLiteOperation("SELECT", "users"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY created_at, id LIMIT ? OFFSET ?`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*domain.User, 0, limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing users: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing users: %w", err)
+	}
+	return users, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (*domain.User, error) {
+	user := &domain.User{}
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.DisabledAt, &user.TokensValidAfter, &user.EmailVerifiedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
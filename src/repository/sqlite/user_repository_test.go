@@ -0,0 +1,212 @@
+package sqlite
+
+import (
+	sqlitemigrations "auth-service/database/sqlite"
+	"auth-service/src/domain"
+	"auth-service/src/migration"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/conformance"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SQLite Repository Suite")
+}
+
+// newTestDB opens a migrated database in a fresh temporary file, so every
+// spec starts from an empty schema without Docker.
+func newTestDB() *sql.DB {
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(GinkgoT().TempDir(), "auth.db"))
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(db.Close)
+
+	migrator, err := migration.NewSQLite(db, sqlitemigrations.Migrations, slog.Default())
+	Expect(err).NotTo(HaveOccurred())
+	Expect(migrator.Up(ctx)).To(Succeed())
+	return db
+}
+
+var _ = conformance.UserRepository(func() repository.UserRepository { return NewUser(newTestDB()) })
+
+var _ = conformance.MagicLinkRepository(func() (repository.UserRepository, repository.MagicLinkRepository) {
+	db := newTestDB()
+	return NewUser(db), NewMagicLink(db)
+})
+
+var _ = conformance.IdentityRepository(func() (repository.UserRepository, repository.IdentityRepository) {
+	db := newTestDB()
+	return NewUser(db), NewIdentity(db)
+})
+
+var _ = conformance.SCIMRepository(func() (repository.UserRepository, repository.SCIMRepository) {
+	db := newTestDB()
+	return NewUser(db), NewSCIM(db)
+})
+
+var _ = conformance.PersonalAccessTokenRepository(func() (repository.UserRepository, repository.PersonalAccessTokenRepository) {
+	db := newTestDB()
+	return NewUser(db), NewPersonalAccessToken(db)
+})
+
+var _ = conformance.APIKeyRepository(func() repository.APIKeyRepository { return NewAPIKey(newTestDB()) })
+
+var _ = conformance.TxManager(func() (repository.TxManager, repository.UserRepository, repository.SCIMRepository) {
+	db := newTestDB()
+	return NewTxManager(db), NewUser(db), NewSCIM(db)
+})
+
+var _ = conformance.OutboxRepository(func() (repository.UserRepository, repository.TxManager, repository.OutboxRepository) {
+	db := newTestDB()
+	return NewUser(db), NewTxManager(db), NewOutbox(db)
+})
+
+var _ = conformance.WebhookRepository(func() (repository.OutboxRepository, repository.WebhookRepository) {
+	db := newTestDB()
+	return NewOutbox(db), NewWebhook(db)
+})
+
+var _ = conformance.DeviceAuthorizationRepository(func() (repository.UserRepository, repository.ClientRepository, repository.DeviceAuthorizationRepository) {
+	db := newTestDB()
+	return NewUser(db), NewClient(db), NewDeviceAuthorization(db)
+})
+
+var _ = conformance.AccessTokenRepository(func() (repository.UserRepository, repository.AccessTokenRepository) {
+	db := newTestDB()
+	return NewUser(db), NewAccessToken(db)
+})
+
+var _ = conformance.SAMLAssertionRepository(func() repository.SAMLAssertionRepository {
+	return NewSAMLAssertion(newTestDB())
+})
+
+var _ = Describe("SQLite UserRepository", func() {
+	var db *sql.DB
+	var repo repository.UserRepository
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		db = newTestDB()
+		repo = NewUser(db)
+	})
+
+	It("should round-trip nullable timestamps", func() {
+		// Arrange
+		user := stubs.NewUserStub().Get()
+		Expect(repo.Create(ctx, user)).To(Succeed())
+		disabledAt := time.Now().Truncate(time.Second)
+		user.DisabledAt = &disabledAt
+
+		// Act
+		Expect(repo.Update(ctx, user)).To(Succeed())
+		found, err := repo.FindByID(ctx, user.ID)
+
+		// Assert
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.DisabledAt).NotTo(BeNil())
+		Expect(found.DisabledAt.Equal(disabledAt)).To(BeTrue())
+		Expect(found.TokensValidAfter).To(BeNil())
+	})
+
+	It("should map unique violations to ErrEmailAlreadyExists", func() {
+		// Arrange
+		Expect(repo.Create(ctx, stubs.NewUserStub().WithEmail("dup@example.com").Get())).To(Succeed())
+
+		// Act
+		err := repo.Create(ctx, stubs.NewUserStub().WithEmail("dup@example.com").Get())
+
+		// Assert
+		Expect(errors.Is(err, domain.ErrEmailAlreadyExists)).To(BeTrue())
+	})
+
+	It("should migrate down and up again", func() {
+		// Arrange
+		migrator, err := migration.NewSQLite(db, sqlitemigrations.Migrations, slog.Default())
+		Expect(err).NotTo(HaveOccurred())
+
+		// Act & Assert
+		Expect(migrator.Down(ctx, int(migrator.Latest()))).To(Succeed())
+		version, _, err := migrator.Version(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(BeZero())
+		Expect(migrator.Up(ctx)).To(Succeed())
+		version, _, err = migrator.Version(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal(migrator.Latest()))
+	})
+})
+
+var _ = Describe("SQLite TxManager", func() {
+	It("should serialize read-then-write transactions even with custom pragmas", func() {
+		// Arrange
+		ctx := context.Background()
+		dsn := filepath.Join(GinkgoT().TempDir(), "auth.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+		db, err := Open(ctx, dsn)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(db.Close)
+		migrator, err := migration.NewSQLite(db, sqlitemigrations.Migrations, slog.Default())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(migrator.Up(ctx)).To(Succeed())
+		users, tx := NewUser(db), NewTxManager(db)
+		user := stubs.NewUserStub().Get()
+		user.Name = "0"
+		Expect(users.Create(ctx, user)).To(Succeed())
+
+		// Act
+		const writers = 8
+		errs := make(chan error, writers)
+		for range writers {
+			go func() {
+				errs <- tx.WithinTx(ctx, func(ctx context.Context) error {
+					found, err := users.FindByIDForUpdate(ctx, user.ID)
+					if err != nil {
+						return err
+					}
+					n, _ := strconv.Atoi(found.Name)
+					found.Name = strconv.Itoa(n + 1)
+					return users.Update(ctx, found)
+				})
+			}()
+		}
+
+		// Assert
+		for range writers {
+			Expect(<-errs).To(Succeed())
+		}
+		found, err := users.FindByID(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.Name).To(Equal(strconv.Itoa(writers)))
+	})
+})
+
+var _ = Describe("SQLite ClientRepository", func() {
+	It("should store and find clients", func() {
+		// Arrange
+		ctx := context.Background()
+		repo := NewClient(newTestDB())
+		client := &domain.Client{ID: "cli_123", Name: "billing", SecretHash: "hash", CreatedAt: time.Now()}
+
+		// Act
+		Expect(repo.Create(ctx, client)).To(Succeed())
+		found, err := repo.FindByID(ctx, "cli_123")
+		_, missingErr := repo.FindByID(ctx, "cli_missing")
+
+		// Assert
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.Name).To(Equal("billing"))
+		Expect(errors.Is(missingErr, domain.ErrClientNotFound)).To(BeTrue())
+	})
+})
@@ -0,0 +1,194 @@
+package sqlite
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	webhookSubscriptionColumns = `id, url, events, secret, created_at`
+	webhookDeliveryColumns     = `id, event_id, subscription_id, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+)
+
+type webhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhook(db *sql.DB) repository.WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.CreateSubscription", tracing.WithSQLiteOperation("INSERT", "webhook_subscriptions"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO webhook_subscriptions (id, url, events, secret, created_at) VALUES (?, ?, ?, ?, ?)`
+	if _, err = conn(ctx, r.db).ExecContext(ctx, query, sub.ID, sub.URL, strings.Join(sub.Events, " "), sub.Secret, sub.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("Error creating webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) FindSubscription(ctx context.Context, id string) (_ *domain.WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.FindSubscription", tracing.WithSQLiteOperation("SELECT", "webhook_subscriptions"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = ?`
+	sub, err := scanWebhookSubscription(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for webhook subscription: %w", domain.ErrWebhookNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (r *webhookRepository) ListSubscriptions(ctx context.Context) (_ []*domain.WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.ListSubscriptions", tracing.WithSQLiteOperation("SELECT", "webhook_subscriptions"))
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []*domain.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing webhook subscriptions: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.DeleteSubscription", tracing.WithSQLiteOperation("DELETE", "webhook_subscriptions"))
+	defer func() { tracing.End(span, err) }()
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("Error deleting webhook subscription: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("Error deleting webhook subscription: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("Error deleting webhook subscription: %w", domain.ErrWebhookNotFound)
+	}
+	return nil
+}
+
+func (r *webhookRepository) EnqueueDelivery(ctx context.Context, delivery *domain.WebhookDelivery) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.EnqueueDelivery", tracing.WithSQLiteOperation("INSERT", "webhook_deliveries"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO webhook_deliveries (id, event_id, subscription_id, status, attempts, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (event_id, subscription_id) DO NOTHING`
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, delivery.ID, delivery.EventID, delivery.SubscriptionID, delivery.Status, delivery.Attempts,
+		delivery.NextAttemptAt.UTC(), delivery.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("Error enqueueing webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) ClaimDue(ctx context.Context, now, until time.Time, limit int) (_ []*domain.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.ClaimDue", tracing.WithSQLiteOperation("UPDATE", "webhook_deliveries"))
+	defer func() { tracing.End(span, err) }()
+
+	// A single UPDATE holds the write lock throughout, which is all the
+	// locking SQLite needs to hand each delivery to one dispatcher.
+	query := `UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN (
+			SELECT id FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at, id LIMIT ?
+		) RETURNING ` + webhookDeliveryColumns
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, until.UTC(), domain.DeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error claiming webhook deliveries: %w", err)
+	}
+	return collectWebhookDeliveries(rows, "Error claiming webhook deliveries")
+}
+
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.UpdateDelivery", tracing.WithSQLiteOperation("UPDATE", "webhook_deliveries"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, delivered_at = ? WHERE id = ?`
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UTC(), delivery.LastError, utc(delivery.DeliveredAt), delivery.ID)
+	if err != nil {
+		return fmt.Errorf("Error updating webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, subscriptionID, status string, limit int) (_ []*domain.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.ListDeliveries", tracing.WithSQLiteOperation("SELECT", "webhook_deliveries"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+		WHERE subscription_id = ? AND (? = '' OR status = ?) ORDER BY created_at DESC, id LIMIT ?`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, subscriptionID, status, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing webhook deliveries: %w", err)
+	}
+	return collectWebhookDeliveries(rows, "Error listing webhook deliveries")
+}
+
+func (r *webhookRepository) Redeliver(ctx context.Context, subscriptionID, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.Redeliver", tracing.WithSQLiteOperation("UPDATE", "webhook_deliveries"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?
+		WHERE id = ? AND subscription_id = ? AND status = ?`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, domain.DeliveryPending, at.UTC(), id, subscriptionID, domain.DeliveryDead)
+	if err != nil {
+		return fmt.Errorf("Error redelivering webhook: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("Error redelivering webhook: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("Error redelivering webhook: %w", domain.ErrDeliveryNotFound)
+	}
+	return nil
+}
+
+func collectWebhookDeliveries(rows *sql.Rows, errPrefix string) ([]*domain.WebhookDelivery, error) {
+	defer rows.Close()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for rows.Next() {
+		d := &domain.WebhookDelivery{}
+		err := rows.Scan(&d.ID, &d.EventID, &d.SubscriptionID, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", errPrefix, err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	return deliveries, nil
+}
+
+func scanWebhookSubscription(row scanner) (*domain.WebhookSubscription, error) {
+	sub := &domain.WebhookSubscription{}
+	var events string
+	if err := row.Scan(&sub.ID, &sub.URL, &events, &sub.Secret, &sub.CreatedAt); err != nil {
+		return nil, err
+	}
+	sub.Events = strings.Fields(events)
+	return sub, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TxManager runs a unit of work in one transaction. Repositories called with
+// the context handed to fn join that transaction instead of using their own
+// connection, so their writes commit or roll back together.
+type TxManager interface {
+	// WithinTx commits when fn returns nil and rolls back otherwise. Called
+	// inside another WithinTx it joins the outer transaction. fn may run more
+	// than once, so it must not have side effects outside the database.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// querier is what pgxpool.Pool and pgx.Tx have in common. Begin on a pgx.Tx
+// opens a savepoint, so a repository that needs its own transaction nests
+// inside the caller's.
+type querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// conn returns the transaction carried by ctx, or db outside of one.
+func conn(ctx context.Context, db *pgxpool.Pool) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+type txOptions struct {
+	isoLevel   pgx.TxIsoLevel
+	maxRetries int
+}
+
+type TxOption func(*txOptions)
+
+// WithIsolationLevel sets the isolation level of the transactions started by
+// the manager. The default is the server's, READ COMMITTED unless changed.
+func WithIsolationLevel(level pgx.TxIsoLevel) TxOption {
+	return func(o *txOptions) {
+		o.isoLevel = level
+	}
+}
+
+// WithMaxRetries sets how many times a transaction aborted by a serialization
+// failure or a deadlock is run again before the error is returned.
+func WithMaxRetries(n int) TxOption {
+	return func(o *txOptions) {
+		o.maxRetries = n
+	}
+}
+
+const retryBaseDelay = 10 * time.Millisecond
+
+type postgresTxManager struct {
+	db *pgxpool.Pool
+	txOptions
+}
+
+func NewTxManager(db *pgxpool.Pool, opts ...TxOption) TxManager {
+	o := txOptions{maxRetries: 3}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &postgresTxManager{db: db, txOptions: o}
+}
+
+func (m *postgresTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	ctx, span := tracer.Start(ctx, "TxManager.WithinTx")
+	defer func() { tracing.End(span, err) }()
+
+	for attempt := 0; ; attempt++ {
+		err = pgx.BeginTxFunc(ctx, m.db, pgx.TxOptions{IsoLevel: m.isoLevel}, func(tx pgx.Tx) error {
+			return fn(context.WithValue(ctx, txKey{}, tx))
+		})
+		if err == nil || !retryable(err) || attempt >= m.maxRetries {
+			return err
+		}
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("db.tx.attempt", attempt+1)))
+
+		// Conflicting transactions retried in lockstep tend to collide again.
+		delay := retryBaseDelay << attempt
+		delay += rand.N(delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryable reports whether err aborted the transaction for reasons that
+// running it again can fix: a serialization failure or a deadlock.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"auth-service/src/domain"
+	"auth-service/src/tracing"
 	"context"
 	"errors"
 	"fmt"
@@ -9,57 +10,153 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("auth-service/src/repository")
+
 type UserRepository interface {
 	Create(ctx context.Context, user *domain.User) error
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
 	FindByID(ctx context.Context, id string) (*domain.User, error)
+	// FindByIDForUpdate reads the user like FindByID and, inside a
+	// transaction, keeps other transactions from changing it until this one
+	// ends, so a read-then-write cannot interleave with another.
+	FindByIDForUpdate(ctx context.Context, id string) (*domain.User, error)
+	Update(ctx context.Context, user *domain.User) error
+	List(ctx context.Context, limit, offset int) ([]*domain.User, error)
 }
 
+const userColumns = `id, name, email, password_hash, role, created_at, disabled_at, tokens_valid_after, email_verified_at`
+
 type postgresUserRepository struct {
 	db *pgxpool.Pool
+	options
 }
 
-func NewUser(db *pgxpool.Pool) UserRepository {
-	return &postgresUserRepository{db: db}
+func NewUser(db *pgxpool.Pool, opts ...Option) UserRepository {
+	return &postgresUserRepository{db: db, options: newOptions(opts)}
 }
 
-func (r *postgresUserRepository) Create(ctx context.Context, user *domain.User) error {
-	query := `INSERT INTO users (id, name, email, password_hash, created_at) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.Exec(ctx, query, user.ID, user.Name, user.Email, user.PasswordHash, user.CreatedAt)
+func (r *postgresUserRepository) Create(ctx context.Context, user *domain.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.Create", tracing.WithDBOperation("INSERT", "users"))
+	defer func() { tracing.End(span, err) }()
+
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+	query := `INSERT INTO users (id, name, email, password_hash, role, created_at, email_verified_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = conn(ctx, r.db).Exec(ctx, query, user.ID, user.Name, user.Email, user.PasswordHash, user.Role, user.CreatedAt, user.EmailVerifiedAt)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			r.logger.DebugContext(ctx, "unique violation creating user", "constraint", pgErr.ConstraintName)
 			return fmt.Errorf("Error creating user: %w", domain.ErrEmailAlreadyExists)
 		}
+		r.logger.ErrorContext(ctx, "failed to insert user", "user_id", user.ID, "error", err)
 		return fmt.Errorf("Error creating user: %w", err)
 	}
 	return nil
 }
 
-func (r *postgresUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
-	query := `SELECT id, name, email, password_hash, created_at FROM users WHERE email = $1`
-	user := &domain.User{}
-	err := r.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.CreatedAt)
+func (r *postgresUserRepository) FindByEmail(ctx context.Context, email string) (_ *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.FindByEmail", tracing.WithDBOperation("SELECT", "users"), tracing.WithEmail(email))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE lower(email) = lower($1)`
+	user, err := scanUser(conn(ctx, r.db).QueryRow(ctx, query, email))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, fmt.Errorf("Error when searching for user by email: %w", domain.ErrUserNotFound)
 		}
+		r.logger.ErrorContext(ctx, "failed to query user by email", "error", err)
 		return nil, fmt.Errorf("Error when searching for user by email: %w", err)
 	}
 	return user, nil
 }
 
-func (r *postgresUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
-	query := `SELECT id, name, email, password_hash, created_at FROM users WHERE id = $1`
-	user := &domain.User{}
-	err := r.db.QueryRow(ctx, query, id).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.CreatedAt)
+func (r *postgresUserRepository) FindByID(ctx context.Context, id string) (_ *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.FindByID", tracing.WithDBOperation("SELECT", "users"), tracing.WithUserID(id))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	user, err := scanUser(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("Error when searching for user by ID: %w", domain.ErrUserNotFound)
+		}
+		r.logger.ErrorContext(ctx, "failed to query user by id", "user_id", id, "error", err)
+		return nil, fmt.Errorf("Error when searching for user by ID: %w", err)
+	}
+	return user, nil
+}
+
+func (r *postgresUserRepository) FindByIDForUpdate(ctx context.Context, id string) (_ *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.FindByIDForUpdate", tracing.WithDBOperation("SELECT", "users"), tracing.WithUserID(id))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 FOR UPDATE`
+	user, err := scanUser(conn(ctx, r.db).QueryRow(ctx, query, id))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, fmt.Errorf("Error when searching for user by ID: %w", domain.ErrUserNotFound)
 		}
+		r.logger.ErrorContext(ctx, "failed to lock user by id", "user_id", id, "error", err)
 		return nil, fmt.Errorf("Error when searching for user by ID: %w", err)
 	}
 	return user, nil
 }
+
+func (r *postgresUserRepository) Update(ctx context.Context, user *domain.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.Update", tracing.WithDBOperation("UPDATE", "users"), tracing.WithUserID(user.ID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE users SET name = $2, email = $3, password_hash = $4, role = $5, disabled_at = $6, tokens_valid_after = $7, email_verified_at = $8 WHERE id = $1`
+	tag, err := conn(ctx, r.db).Exec(ctx, query, user.ID, user.Name, user.Email, user.PasswordHash, user.Role, user.DisabledAt, user.TokensValidAfter, user.EmailVerifiedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("Error updating user: %w", domain.ErrEmailAlreadyExists)
+		}
+		r.logger.ErrorContext(ctx, "failed to update user", "user_id", user.ID, "error", err)
+		return fmt.Errorf("Error updating user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Error updating user: %w", domain.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (r *postgresUserRepository) List(ctx context.Context, limit, offset int) (_ []*domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserRepository.List", tracing.WithDBOperation("SELECT", "users"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY created_at, id LIMIT $1 OFFSET $2`
+	rows, err := conn(ctx, r.db).Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*domain.User, 0, limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing users: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error listing users: %w", err)
+	}
+	return users, nil
+}
+
+func scanUser(row pgx.Row) (*domain.User, error) {
+	user := &domain.User{}
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.DisabledAt, &user.TokensValidAfter, &user.EmailVerifiedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
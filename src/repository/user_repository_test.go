@@ -1,17 +1,25 @@
-package repository
+package repository_test
 
 import (
+	"auth-service/database"
 	"auth-service/src/domain"
+	"auth-service/src/migration"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/conformance"
 	"auth-service/src/test_artefacts/seeder"
 	"auth-service/src/test_artefacts/stubs"
 	"context"
 	"errors"
-	"os"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
@@ -52,24 +60,83 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).NotTo(HaveOccurred())
 
-	migration, err := os.ReadFile("../../database/000001_create_users_table.up.sql")
-	Expect(err).NotTo(HaveOccurred())
-	_, err = db.Exec(context.Background(), string(migration))
+	migrator, err := migration.New(db, database.Migrations, slog.Default())
 	Expect(err).NotTo(HaveOccurred())
+	Expect(migrator.Up(context.Background())).To(Succeed())
 })
 
 var _ = AfterSuite(func() {
 	Expect(pool.Purge(resource)).To(Succeed())
 })
 
+var _ = conformance.UserRepository(func() repository.UserRepository {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewUser(db)
+})
+
+var _ = conformance.MagicLinkRepository(func() (repository.UserRepository, repository.MagicLinkRepository) {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewUser(db), repository.NewMagicLink(db)
+})
+
+var _ = conformance.IdentityRepository(func() (repository.UserRepository, repository.IdentityRepository) {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewUser(db), repository.NewIdentity(db)
+})
+
+var _ = conformance.SCIMRepository(func() (repository.UserRepository, repository.SCIMRepository) {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewUser(db), repository.NewSCIM(db)
+})
+
+var _ = conformance.PersonalAccessTokenRepository(func() (repository.UserRepository, repository.PersonalAccessTokenRepository) {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewUser(db), repository.NewPersonalAccessToken(db)
+})
+
+var _ = conformance.APIKeyRepository(func() repository.APIKeyRepository {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewAPIKey(db)
+})
+
+var _ = conformance.TxManager(func() (repository.TxManager, repository.UserRepository, repository.SCIMRepository) {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewTxManager(db), repository.NewUser(db), repository.NewSCIM(db)
+})
+
+var _ = conformance.OutboxRepository(func() (repository.UserRepository, repository.TxManager, repository.OutboxRepository) {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewUser(db), repository.NewTxManager(db), repository.NewOutbox(db)
+})
+
+var _ = conformance.WebhookRepository(func() (repository.OutboxRepository, repository.WebhookRepository) {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewOutbox(db), repository.NewWebhook(db)
+})
+
+var _ = conformance.DeviceAuthorizationRepository(func() (repository.UserRepository, repository.ClientRepository, repository.DeviceAuthorizationRepository) {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewUser(db), repository.NewClient(db), repository.NewDeviceAuthorization(db)
+})
+
+var _ = conformance.AccessTokenRepository(func() (repository.UserRepository, repository.AccessTokenRepository) {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewUser(db), repository.NewAccessToken(db)
+})
+
+var _ = conformance.SAMLAssertionRepository(func() repository.SAMLAssertionRepository {
+	Expect(seeder.NewTestSeeder(db).TruncateTables(context.Background())).To(Succeed())
+	return repository.NewSAMLAssertion(db)
+})
+
 var _ = Describe("UserRepository", func() {
-	var userRepo UserRepository
+	var userRepo repository.UserRepository
 	var testSeeder *seeder.TestSeeder
 	var ctx context.Context
 
 	BeforeEach(func() {
 		ctx = context.Background()
-		userRepo = NewUser(db)
+		userRepo = repository.NewUser(db)
 		testSeeder = seeder.NewTestSeeder(db)
 
 		err := testSeeder.TruncateTables(ctx)
@@ -97,3 +164,112 @@ var _ = Describe("UserRepository", func() {
 		})
 	})
 })
+
+var _ = Describe("TxManager", func() {
+	var userRepo repository.UserRepository
+	var ctx context.Context
+	var user *domain.User
+
+	// rename reads the user and saves it under a new name, the read-modify-write
+	// that two concurrent transactions can't both commit under REPEATABLE READ.
+	rename := func(ctx context.Context, name string) error {
+		current, err := userRepo.FindByID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		current.Name = name
+		return userRepo.Update(ctx, current)
+	}
+
+	// conflict runs two renames that both read the user before either writes,
+	// so the second one to write fails with a serialization error at least
+	// once, and returns how many attempts they took in total.
+	conflict := func(tx repository.TxManager) (int32, []error) {
+		var attempts atomic.Int32
+		var read sync.WaitGroup
+		read.Add(2)
+		errs := make([]error, 2)
+		var done sync.WaitGroup
+		for i, name := range []string{"First", "Second"} {
+			done.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer done.Done()
+				errs[i] = tx.WithinTx(ctx, func(ctx context.Context) error {
+					if attempts.Add(1) > 2 {
+						return rename(ctx, name)
+					}
+					if _, err := userRepo.FindByID(ctx, user.ID); err != nil {
+						return err
+					}
+					read.Done()
+					read.Wait()
+					return rename(ctx, name)
+				})
+			}()
+		}
+		done.Wait()
+		return attempts.Load(), errs
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		userRepo = repository.NewUser(db)
+		Expect(seeder.NewTestSeeder(db).TruncateTables(ctx)).To(Succeed())
+		user = stubs.NewUserStub().WithEmail("tx-retry@example.com").Get()
+		Expect(userRepo.Create(ctx, user)).To(Succeed())
+	})
+
+	It("should retry a transaction aborted by a serialization failure", func() {
+		// Arrange
+		tx := repository.NewTxManager(db, repository.WithIsolationLevel(pgx.RepeatableRead))
+
+		// Act
+		attempts, errs := conflict(tx)
+
+		// Assert
+		Expect(errs).To(HaveEach(Succeed()))
+		Expect(attempts).To(BeNumerically(">", 2))
+	})
+
+	It("should return the serialization failure once the retries run out", func() {
+		// Arrange
+		tx := repository.NewTxManager(db, repository.WithIsolationLevel(pgx.RepeatableRead), repository.WithMaxRetries(0))
+
+		// Act
+		attempts, errs := conflict(tx)
+
+		// Assert
+		Expect(attempts).To(BeEquivalentTo(2))
+		var pgErr *pgconn.PgError
+		failed := errs[0]
+		if failed == nil {
+			failed = errs[1]
+		}
+		Expect(errors.As(failed, &pgErr)).To(BeTrue())
+		Expect(pgErr.Code).To(Equal("40001"))
+	})
+
+	It("should not retry other errors", func() {
+		// Arrange
+		tx := repository.NewTxManager(db)
+		errBoom := errors.New("boom")
+		attempts := 0
+
+		// Act
+		err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			attempts++
+			if err := rename(ctx, "Renamed"); err != nil {
+				return err
+			}
+			return errBoom
+		})
+
+		// Assert
+		Expect(err).To(MatchError(errBoom))
+		Expect(attempts).To(Equal(1))
+		found, findErr := userRepo.FindByID(ctx, user.ID)
+		Expect(findErr).NotTo(HaveOccurred())
+		Expect(found.Name).To(Equal(user.Name))
+	})
+})
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error
+	FindSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error)
+	// ListSubscriptions returns every subscription, oldest first.
+	ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error)
+	// DeleteSubscription also drops the deliveries queued for it.
+	DeleteSubscription(ctx context.Context, id string) error
+
+	// EnqueueDelivery does nothing when the event was already queued for the
+	// subscription, so dispatching an event twice is harmless.
+	EnqueueDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	// ClaimDue returns up to limit pending deliveries due at now and moves
+	// their next attempt to until, so that another dispatcher does not pick
+	// them up while they are in flight.
+	ClaimDue(ctx context.Context, now, until time.Time, limit int) ([]*domain.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	// ListDeliveries returns up to limit deliveries to a subscription, newest
+	// first, only those in status unless it is empty.
+	ListDeliveries(ctx context.Context, subscriptionID, status string, limit int) ([]*domain.WebhookDelivery, error)
+	// Redeliver queues a dead delivery again, due at. It returns
+	// domain.ErrDeliveryNotFound unless the delivery belongs to the
+	// subscription and is dead.
+	Redeliver(ctx context.Context, subscriptionID, id string, at time.Time) error
+}
+
+const (
+	webhookSubscriptionColumns = `id, url, events, secret, created_at`
+	webhookDeliveryColumns     = `id, event_id, subscription_id, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+)
+
+type postgresWebhookRepository struct {
+	db *pgxpool.Pool
+	options
+}
+
+func NewWebhook(db *pgxpool.Pool, opts ...Option) WebhookRepository {
+	return &postgresWebhookRepository{db: db, options: newOptions(opts)}
+}
+
+func (r *postgresWebhookRepository) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.CreateSubscription", tracing.WithDBOperation("INSERT", "webhook_subscriptions"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO webhook_subscriptions (id, url, events, secret, created_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err = conn(ctx, r.db).Exec(ctx, query, sub.ID, sub.URL, strings.Join(sub.Events, " "), sub.Secret, sub.CreatedAt); err != nil {
+		r.logger.ErrorContext(ctx, "failed to insert webhook subscription", "error", err)
+		return fmt.Errorf("Error creating webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepository) FindSubscription(ctx context.Context, id string) (_ *domain.WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.FindSubscription", tracing.WithDBOperation("SELECT", "webhook_subscriptions"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id::text = $1`
+	sub, err := scanWebhookSubscription(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("Error when searching for webhook subscription: %w", domain.ErrWebhookNotFound)
+		}
+		return nil, fmt.Errorf("Error when searching for webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (r *postgresWebhookRepository) ListSubscriptions(ctx context.Context) (_ []*domain.WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.ListSubscriptions", tracing.WithDBOperation("SELECT", "webhook_subscriptions"))
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := conn(ctx, r.db).Query(ctx, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing webhook subscriptions: %w", err)
+	}
+	subs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.WebhookSubscription, error) {
+		return scanWebhookSubscription(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *postgresWebhookRepository) DeleteSubscription(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.DeleteSubscription", tracing.WithDBOperation("DELETE", "webhook_subscriptions"))
+	defer func() { tracing.End(span, err) }()
+
+	tag, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id::text = $1`, id)
+	if err != nil {
+		return fmt.Errorf("Error deleting webhook subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Error deleting webhook subscription: %w", domain.ErrWebhookNotFound)
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepository) EnqueueDelivery(ctx context.Context, delivery *domain.WebhookDelivery) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.EnqueueDelivery", tracing.WithDBOperation("INSERT", "webhook_deliveries"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `INSERT INTO webhook_deliveries (id, event_id, subscription_id, status, attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (event_id, subscription_id) DO NOTHING`
+	_, err = conn(ctx, r.db).Exec(ctx, query, delivery.ID, delivery.EventID, delivery.SubscriptionID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.CreatedAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to enqueue webhook delivery", "event_id", delivery.EventID, "subscription_id", delivery.SubscriptionID, "error", err)
+		return fmt.Errorf("Error enqueueing webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepository) ClaimDue(ctx context.Context, now, until time.Time, limit int) (_ []*domain.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.ClaimDue", tracing.WithDBOperation("UPDATE", "webhook_deliveries"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE webhook_deliveries SET next_attempt_at = $2 WHERE id IN (
+			SELECT id FROM webhook_deliveries WHERE status = $3 AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id LIMIT $4 FOR UPDATE SKIP LOCKED
+		) RETURNING ` + webhookDeliveryColumns
+	rows, err := conn(ctx, r.db).Query(ctx, query, now, until, domain.DeliveryPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error claiming webhook deliveries: %w", err)
+	}
+	deliveries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.WebhookDelivery, error) {
+		return scanWebhookDelivery(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error claiming webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *postgresWebhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.UpdateDelivery", tracing.WithDBOperation("UPDATE", "webhook_deliveries"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE webhook_deliveries SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, delivered_at = $6 WHERE id = $1`
+	_, err = conn(ctx, r.db).Exec(ctx, query, delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastError, delivery.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("Error updating webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepository) ListDeliveries(ctx context.Context, subscriptionID, status string, limit int) (_ []*domain.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.ListDeliveries", tracing.WithDBOperation("SELECT", "webhook_deliveries"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+		WHERE subscription_id::text = $1 AND ($2 = '' OR status = $2) ORDER BY created_at DESC, id LIMIT $3`
+	rows, err := conn(ctx, r.db).Query(ctx, query, subscriptionID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing webhook deliveries: %w", err)
+	}
+	deliveries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*domain.WebhookDelivery, error) {
+		return scanWebhookDelivery(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *postgresWebhookRepository) Redeliver(ctx context.Context, subscriptionID, id string, at time.Time) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookRepository.Redeliver", tracing.WithDBOperation("UPDATE", "webhook_deliveries"))
+	defer func() { tracing.End(span, err) }()
+
+	query := `UPDATE webhook_deliveries SET status = $4, attempts = 0, next_attempt_at = $3
+		WHERE id::text = $1 AND subscription_id::text = $2 AND status = $5`
+	tag, err := conn(ctx, r.db).Exec(ctx, query, id, subscriptionID, at, domain.DeliveryPending, domain.DeliveryDead)
+	if err != nil {
+		return fmt.Errorf("Error redelivering webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Error redelivering webhook: %w", domain.ErrDeliveryNotFound)
+	}
+	return nil
+}
+
+func scanWebhookSubscription(row pgx.Row) (*domain.WebhookSubscription, error) {
+	sub := &domain.WebhookSubscription{}
+	var events string
+	if err := row.Scan(&sub.ID, &sub.URL, &events, &sub.Secret, &sub.CreatedAt); err != nil {
+		return nil, err
+	}
+	sub.Events = strings.Fields(events)
+	return sub, nil
+}
+
+func scanWebhookDelivery(row pgx.Row) (*domain.WebhookDelivery, error) {
+	d := &domain.WebhookDelivery{}
+	err := row.Scan(&d.ID, &d.EventID, &d.SubscriptionID, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
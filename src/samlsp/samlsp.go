@@ -0,0 +1,441 @@
+// Package samlsp is the service provider side of SAML 2.0 single sign-on:
+// it publishes our metadata, sends browsers to the identity provider with an
+// AuthnRequest and checks the signed assertion that comes back. Deciding
+// which local account an assertion maps to is up to the service layer.
+package samlsp
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crewjam/saml"
+)
+
+var (
+	// ErrInvalidResponse covers every reason to reject a response: bad
+	// signature, wrong audience or recipient, expired conditions, unknown
+	// request ID, or missing attributes. The wrapped message says which.
+	ErrInvalidResponse = errors.New("invalid SAML response")
+	// ErrReplayed means the assertion was already consumed.
+	ErrReplayed = errors.New("SAML assertion was already used")
+)
+
+const maxMetadataSize = 1 << 20
+
+// sweepInterval is how often consuming an assertion also deletes the IDs of
+// the expired ones.
+const sweepInterval = time.Minute
+
+// Attribute names tried, in order, when the matching Config field is
+// empty. They cover the defaults of Okta, Azure AD / Entra ID, Google and
+// Shibboleth-style IdPs.
+var (
+	defaultEmailAttributes = []string{
+		"email", "mail", "emailaddress",
+		"urn:oid:0.9.2342.19200300.100.1.3",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	}
+	defaultNameAttributes = []string{
+		"displayName", "name", "cn",
+		"urn:oid:2.16.840.1.113730.3.1.241",
+		"urn:oid:2.5.4.3",
+		"http://schemas.microsoft.com/identity/claims/displayname",
+	}
+	defaultGroupAttributes = []string{
+		"groups", "memberOf",
+		"http://schemas.microsoft.com/ws/2008/06/identity/claims/groups",
+	}
+)
+
+// Config describes one identity provider and how we appear to it.
+type Config struct {
+	Name string
+	// BaseURL is the public URL of this service. The SP entity ID is
+	// <BaseURL>/saml/<Name>/metadata and the ACS <BaseURL>/saml/<Name>/acs.
+	BaseURL string
+	// IDPMetadata is the IdP's metadata XML. When empty it is fetched from
+	// IDPMetadataURL on first use.
+	IDPMetadata    []byte
+	IDPMetadataURL string
+	// Key and Certificate identify us to the IdP; assertions encrypted for
+	// us are decrypted with Key.
+	Key         crypto.Signer
+	Certificate *x509.Certificate
+	// NameIDFormat is requested in the AuthnRequest; unspecified by default
+	// so the IdP picks its configured format.
+	NameIDFormat   string
+	EmailAttribute string
+	NameAttribute  string
+	GroupAttribute string
+	// SubjectAttribute holds a stable ID for the account. When empty, the
+	// NameID is used, which then must not be transient.
+	SubjectAttribute string
+	// GroupRoles maps group names, case-insensitively, to local roles. Admin
+	// wins when a user belongs to several mapped groups.
+	GroupRoles  map[string]string
+	DefaultRole string
+	// AllowIDPInitiated accepts responses no AuthnRequest of ours asked for.
+	AllowIDPInitiated bool
+	HTTPClient        *http.Client
+	// Assertions records the consumed assertion IDs. It must be shared by
+	// every replica for replays to be caught across them.
+	Assertions repository.SAMLAssertionRepository
+}
+
+type Provider struct {
+	cfg        Config
+	sp         saml.ServiceProvider
+	groupRoles map[string]string
+
+	mu        sync.Mutex
+	idp       *saml.EntityDescriptor
+	lastSweep time.Time
+}
+
+// New checks cfg and builds the provider. The IdP metadata, when given
+// inline, is parsed here so a typo fails at start-up.
+func New(cfg Config) (*Provider, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("SAML provider name is required")
+	}
+	if cfg.Key == nil || cfg.Certificate == nil {
+		return nil, fmt.Errorf("SAML provider %s needs a key and certificate", cfg.Name)
+	}
+	if cfg.Assertions == nil {
+		return nil, fmt.Errorf("SAML provider %s needs an assertion store", cfg.Name)
+	}
+	base, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("SAML provider %s has an invalid base URL %q", cfg.Name, cfg.BaseURL)
+	}
+	if cfg.NameIDFormat == "" {
+		cfg.NameIDFormat = string(saml.UnspecifiedNameIDFormat)
+	}
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = domain.RoleUser
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	p := &Provider{
+		cfg:        cfg,
+		groupRoles: make(map[string]string, len(cfg.GroupRoles)),
+	}
+	for group, role := range cfg.GroupRoles {
+		p.groupRoles[strings.ToLower(group)] = role
+	}
+
+	metadataURL := base.JoinPath("saml", cfg.Name, "metadata")
+	acsURL := base.JoinPath("saml", cfg.Name, "acs")
+	p.sp = saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		Key:               cfg.Key,
+		Certificate:       cfg.Certificate,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		AuthnNameIDFormat: saml.NameIDFormat(cfg.NameIDFormat),
+		AllowIDPInitiated: cfg.AllowIDPInitiated,
+		HTTPClient:        cfg.HTTPClient,
+	}
+	// An assertion without any AudienceRestriction would otherwise be
+	// accepted by every SP trusting the same IdP.
+	p.sp.ValidateAudienceRestriction = p.validateAudience
+
+	switch {
+	case len(cfg.IDPMetadata) > 0:
+		if p.idp, err = parseMetadata(cfg.IDPMetadata); err != nil {
+			return nil, fmt.Errorf("invalid IdP metadata for SAML provider %s: %w", cfg.Name, err)
+		}
+	case cfg.IDPMetadataURL == "":
+		return nil, fmt.Errorf("SAML provider %s needs IdP metadata or a metadata URL", cfg.Name)
+	}
+	return p, nil
+}
+
+// LoadKeyPair reads the SP certificate and private key from PEM files.
+func LoadKeyPair(certFile, keyFile string) (crypto.Signer, *x509.Certificate, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load SAML key pair: %w", err)
+	}
+	signer, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("SAML private key cannot sign")
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse SAML certificate: %w", err)
+	}
+	return signer, cert, nil
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// EntityID is how the IdP knows us; it doubles as the metadata URL.
+func (p *Provider) EntityID() string {
+	return p.sp.EntityID
+}
+
+// Metadata returns our SP metadata XML, for the IdP administrator. It does
+// not need the IdP, so it works before the IdP is reachable.
+func (p *Provider) Metadata() ([]byte, error) {
+	sp := p.sp
+	out, err := xml.MarshalIndent(sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SAML metadata: %w", err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// AuthnRequestURL returns the IdP URL to send the browser to and the ID of
+// the request, which Consume expects back in the response.
+func (p *Provider) AuthnRequestURL(ctx context.Context, relayState string) (string, string, error) {
+	sp, err := p.serviceProvider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	location := sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if location == "" {
+		return "", "", fmt.Errorf("IdP of SAML provider %s has no HTTP-Redirect SSO endpoint", p.cfg.Name)
+	}
+	req, err := sp.MakeAuthenticationRequest(location, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build SAML AuthnRequest: %w", err)
+	}
+	redirect, err := req.Redirect(url.QueryEscape(relayState), sp)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build SAML AuthnRequest: %w", err)
+	}
+	return redirect.String(), req.ID, nil
+}
+
+// Consume checks the base64 SAMLResponse posted to the ACS: the signature
+// against the IdP's certificate, issuer, destination and recipient, our
+// audience, the validity window, that it answers one of requestIDs, and
+// that the assertion was not seen before. It then maps the attributes to
+// an account.
+func (p *Provider) Consume(ctx context.Context, samlResponse string, requestIDs []string) (*domain.DirectoryAccount, error) {
+	sp, err := p.serviceProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(samlResponse), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not base64", ErrInvalidResponse)
+	}
+
+	assertion, err := sp.ParseXMLResponse(raw, requestIDs, sp.AcsURL)
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			err = invalid.PrivateErr
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if err := p.markConsumed(ctx, assertion); err != nil {
+		return nil, err
+	}
+	return p.account(assertion)
+}
+
+// serviceProvider completes the SP with the IdP metadata, fetching it the
+// first time when only a URL was configured.
+func (p *Provider) serviceProvider(ctx context.Context) (*saml.ServiceProvider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.idp == nil {
+		idp, err := p.fetchMetadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.idp = idp
+	}
+	sp := p.sp
+	sp.IDPMetadata = p.idp
+	return &sp, nil
+}
+
+func (p *Provider) fetchMetadata(ctx context.Context) (*saml.EntityDescriptor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.IDPMetadataURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IdP metadata URL for %s: %w", p.cfg.Name, err)
+	}
+	resp, err := p.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IdP metadata for %s: %w", p.cfg.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch IdP metadata for %s: status %d", p.cfg.Name, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IdP metadata for %s: %w", p.cfg.Name, err)
+	}
+	idp, err := parseMetadata(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IdP metadata for %s: %w", p.cfg.Name, err)
+	}
+	return idp, nil
+}
+
+// parseMetadata accepts a single EntityDescriptor or an EntitiesDescriptor,
+// from which the first IdP entity is taken.
+func parseMetadata(data []byte) (*saml.EntityDescriptor, error) {
+	var entity saml.EntityDescriptor
+	if err := xml.Unmarshal(data, &entity); err == nil && len(entity.IDPSSODescriptors) > 0 {
+		return &entity, nil
+	}
+	var entities saml.EntitiesDescriptor
+	if err := xml.Unmarshal(data, &entities); err != nil {
+		return nil, err
+	}
+	for i := range entities.EntityDescriptors {
+		if len(entities.EntityDescriptors[i].IDPSSODescriptors) > 0 {
+			return &entities.EntityDescriptors[i], nil
+		}
+	}
+	return nil, errors.New("no IDPSSODescriptor found")
+}
+
+func (p *Provider) validateAudience(assertion *saml.Assertion) error {
+	if assertion.Conditions == nil {
+		return errors.New("assertion has no conditions")
+	}
+	for _, restriction := range assertion.Conditions.AudienceRestrictions {
+		if restriction.Audience.Value == p.sp.EntityID {
+			return nil
+		}
+	}
+	return fmt.Errorf("assertion audience is not %q", p.sp.EntityID)
+}
+
+// markConsumed records the assertion ID until the assertion would expire
+// anyway. IDs that outlived their assertion are swept at most once per
+// sweepInterval.
+func (p *Provider) markConsumed(ctx context.Context, assertion *saml.Assertion) error {
+	now := saml.TimeNow()
+	expires := now.Add(saml.MaxIssueDelay)
+	if assertion.Conditions != nil && !assertion.Conditions.NotOnOrAfter.IsZero() {
+		expires = assertion.Conditions.NotOnOrAfter
+	}
+	expires = expires.Add(saml.MaxClockSkew)
+
+	p.mu.Lock()
+	due := now.Sub(p.lastSweep) >= sweepInterval
+	if due {
+		p.lastSweep = now
+	}
+	p.mu.Unlock()
+	if due {
+		if err := p.cfg.Assertions.DeleteExpired(ctx, now); err != nil {
+			return err
+		}
+	}
+
+	err := p.cfg.Assertions.Consume(ctx, p.cfg.Name, assertion.ID, expires)
+	if errors.Is(err, domain.ErrAssertionConsumed) {
+		return fmt.Errorf("Error consuming assertion %s: %w", assertion.ID, ErrReplayed)
+	}
+	return err
+}
+
+func (p *Provider) account(assertion *saml.Assertion) (*domain.DirectoryAccount, error) {
+	var nameID *saml.NameID
+	if assertion.Subject != nil {
+		nameID = assertion.Subject.NameID
+	}
+
+	subject := firstValue(assertion, p.cfg.SubjectAttribute, nil)
+	if subject == "" && nameID != nil && nameID.Format != string(saml.TransientNameIDFormat) {
+		subject = nameID.Value
+	}
+	if subject == "" {
+		return nil, fmt.Errorf("%w: no persistent subject in assertion", ErrInvalidResponse)
+	}
+
+	email := firstValue(assertion, p.cfg.EmailAttribute, defaultEmailAttributes)
+	if email == "" && nameID != nil && nameID.Format == string(saml.EmailAddressNameIDFormat) {
+		email = nameID.Value
+	}
+
+	return &domain.DirectoryAccount{
+		Subject: subject,
+		Email:   email,
+		Name:    firstValue(assertion, p.cfg.NameAttribute, defaultNameAttributes),
+		Role:    p.role(values(assertion, p.cfg.GroupAttribute, defaultGroupAttributes)),
+	}, nil
+}
+
+// role maps the groups to a role: admin wins over support, which wins over
+// user.
+func (p *Provider) role(groups []string) string {
+	role := p.cfg.DefaultRole
+	for _, group := range groups {
+		mapped, ok := p.groupRoles[strings.ToLower(group)]
+		if !ok {
+			continue
+		}
+		if mapped == domain.RoleAdmin {
+			return domain.RoleAdmin
+		}
+		if mapped == domain.RoleSupport || role != domain.RoleSupport {
+			role = mapped
+		}
+	}
+	return role
+}
+
+func firstValue(assertion *saml.Assertion, configured string, defaults []string) string {
+	for _, v := range values(assertion, configured, defaults) {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// values returns the values of the configured attribute or, when none is
+// configured, of the first default the assertion carries. Attributes match
+// on Name or FriendlyName, case-insensitively.
+func values(assertion *saml.Assertion, configured string, defaults []string) []string {
+	names := defaults
+	if configured != "" {
+		names = []string{configured}
+	}
+	for _, name := range names {
+		var found []string
+		for _, statement := range assertion.AttributeStatements {
+			for _, attr := range statement.Attributes {
+				if !strings.EqualFold(attr.Name, name) && !strings.EqualFold(attr.FriendlyName, name) {
+					continue
+				}
+				for _, v := range attr.Values {
+					found = append(found, v.Value)
+				}
+			}
+		}
+		if len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package samlsp
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository/memory"
+	"auth-service/src/test_artefacts/samlidp"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var alice = samlidp.User{
+	NameID: "00u-alice",
+	Email:  "alice@example.com",
+	Name:   "Alice Admin",
+	Groups: []string{"Everyone", "Shop-Admins"},
+}
+
+func newProvider(t *testing.T, idp *samlidp.IdP, opts ...func(*Config)) *Provider {
+	t.Helper()
+	key, cert := samlidp.KeyPair("shop.example.com")
+	cfg := Config{
+		Name:        "acme",
+		BaseURL:     "https://shop.example.com/",
+		IDPMetadata: idp.Metadata(),
+		Key:         key,
+		Certificate: cert,
+		GroupRoles:  map[string]string{"shop-admins": domain.RoleAdmin},
+		Assertions:  memory.NewSAMLAssertion(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	p, err := New(cfg)
+	require.NoError(t, err)
+	return p
+}
+
+// login runs the SP-initiated flow up to the POST to the ACS.
+func login(t *testing.T, p *Provider, idp *samlidp.IdP, user samlidp.User, edit ...func(*saml.Assertion)) (string, string) {
+	t.Helper()
+	authURL, requestID, err := p.AuthnRequestURL(context.Background(), "")
+	require.NoError(t, err)
+	metadata, err := p.Metadata()
+	require.NoError(t, err)
+	response, err := idp.Respond(authURL, metadata, user, edit...)
+	require.NoError(t, err)
+	return response, requestID
+}
+
+func TestMetadata_PublishesEntityIDAndACS(t *testing.T) {
+	// Arrange
+	p := newProvider(t, samlidp.New())
+
+	// Act
+	metadata, err := p.Metadata()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "https://shop.example.com/saml/acme/metadata", p.EntityID())
+	assert.Contains(t, string(metadata), `entityID="https://shop.example.com/saml/acme/metadata"`)
+	assert.Contains(t, string(metadata), `Location="https://shop.example.com/saml/acme/acs"`)
+	assert.Contains(t, string(metadata), "X509Certificate")
+}
+
+func TestAuthnRequestURL_RedirectsToIdP(t *testing.T) {
+	// Arrange
+	p := newProvider(t, samlidp.New())
+
+	// Act
+	authURL, requestID, err := p.AuthnRequestURL(context.Background(), "")
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(authURL, samlidp.SSOURL+"?SAMLRequest="))
+	assert.NotEmpty(t, requestID)
+}
+
+func TestConsume_MapsAttributesToAccount(t *testing.T) {
+	// Arrange
+	idp := samlidp.New()
+	p := newProvider(t, idp)
+	response, requestID := login(t, p, idp, alice)
+
+	// Act
+	account, err := p.Consume(context.Background(), response, []string{requestID})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, &domain.DirectoryAccount{
+		Subject: "00u-alice",
+		Email:   "alice@example.com",
+		Name:    "Alice Admin",
+		Role:    domain.RoleAdmin,
+	}, account)
+}
+
+func TestConsume_UsesConfiguredAttributes(t *testing.T) {
+	// Arrange
+	idp := samlidp.New()
+	p := newProvider(t, idp, func(cfg *Config) {
+		cfg.SubjectAttribute = "employeeId"
+		cfg.EmailAttribute = "workEmail"
+	})
+	user := samlidp.User{NameID: "transient", Email: "ignored@example.com", Attributes: map[string]string{
+		"employeeId": "E-42",
+		"workEmail":  "bob@example.com",
+	}}
+	response, requestID := login(t, p, idp, user)
+
+	// Act
+	account, err := p.Consume(context.Background(), response, []string{requestID})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "E-42", account.Subject)
+	assert.Equal(t, "bob@example.com", account.Email)
+	assert.Equal(t, domain.RoleUser, account.Role)
+}
+
+func TestConsume_RejectsInvalidResponses(t *testing.T) {
+	idp := samlidp.New()
+	tests := []struct {
+		name       string
+		idp        *samlidp.IdP
+		edit       func(*saml.Assertion)
+		requestIDs func(string) []string
+	}{
+		{
+			name: "signed by another key",
+			idp:  samlidp.New(),
+		},
+		{
+			name: "wrong audience",
+			edit: func(a *saml.Assertion) {
+				a.Conditions.AudienceRestrictions[0].Audience.Value = "https://other.example.com/saml/metadata"
+			},
+		},
+		{
+			name: "no audience",
+			edit: func(a *saml.Assertion) {
+				a.Conditions.AudienceRestrictions = nil
+			},
+		},
+		{
+			name: "expired",
+			edit: func(a *saml.Assertion) {
+				a.Conditions.NotOnOrAfter = time.Now().Add(-time.Hour)
+			},
+		},
+		{
+			name: "not yet valid",
+			edit: func(a *saml.Assertion) {
+				a.Conditions.NotBefore = time.Now().Add(time.Hour)
+			},
+		},
+		{
+			name:       "unsolicited",
+			requestIDs: func(string) []string { return []string{"id-someone-else"} },
+		},
+		{
+			name: "transient subject",
+			edit: func(a *saml.Assertion) {
+				a.Subject.NameID.Format = string(saml.TransientNameIDFormat)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			p := newProvider(t, idp)
+			signer := idp
+			if tt.idp != nil {
+				signer = tt.idp
+			}
+			var edits []func(*saml.Assertion)
+			if tt.edit != nil {
+				edits = append(edits, tt.edit)
+			}
+			response, requestID := login(t, p, signer, alice, edits...)
+			requestIDs := []string{requestID}
+			if tt.requestIDs != nil {
+				requestIDs = tt.requestIDs(requestID)
+			}
+
+			// Act
+			account, err := p.Consume(context.Background(), response, requestIDs)
+
+			// Assert
+			assert.ErrorIs(t, err, ErrInvalidResponse)
+			assert.Nil(t, account)
+		})
+	}
+}
+
+func TestConsume_RejectsReplayedAssertion(t *testing.T) {
+	// Arrange
+	idp := samlidp.New()
+	p := newProvider(t, idp)
+	response, requestID := login(t, p, idp, alice)
+	_, err := p.Consume(context.Background(), response, []string{requestID})
+	require.NoError(t, err)
+
+	// Act
+	_, err = p.Consume(context.Background(), response, []string{requestID})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrReplayed)
+}
+
+func TestConsume_RejectsAssertionReplayedOnAnotherReplica(t *testing.T) {
+	// Arrange
+	idp := samlidp.New()
+	first := newProvider(t, idp)
+	// Same configuration and assertion store, as on another replica.
+	second, err := New(first.cfg)
+	require.NoError(t, err)
+	response, requestID := login(t, first, idp, alice)
+	_, err = first.Consume(context.Background(), response, []string{requestID})
+	require.NoError(t, err)
+
+	// Act
+	_, err = second.Consume(context.Background(), response, []string{requestID})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrReplayed)
+}
+
+func TestConsume_RejectsGarbage(t *testing.T) {
+	// Arrange
+	p := newProvider(t, samlidp.New())
+
+	// Act
+	_, notBase64 := p.Consume(context.Background(), "%%%", nil)
+	_, notXML := p.Consume(context.Background(), base64.StdEncoding.EncodeToString([]byte("<nope")), nil)
+
+	// Assert
+	assert.ErrorIs(t, notBase64, ErrInvalidResponse)
+	assert.ErrorIs(t, notXML, ErrInvalidResponse)
+}
+
+func TestNew_FetchesMetadataOnFirstUse(t *testing.T) {
+	// Arrange
+	idp := samlidp.New()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(idp.Metadata())
+	}))
+	defer server.Close()
+	p := newProvider(t, idp, func(cfg *Config) {
+		cfg.IDPMetadata = nil
+		cfg.IDPMetadataURL = server.URL
+	})
+
+	// Act
+	_, _, first := p.AuthnRequestURL(context.Background(), "")
+	_, _, second := p.AuthnRequestURL(context.Background(), "")
+
+	// Assert
+	require.NoError(t, first)
+	require.NoError(t, second)
+	assert.Equal(t, 1, requests)
+}
+
+func TestNew_RejectsIncompleteConfig(t *testing.T) {
+	key, cert := samlidp.KeyPair("shop.example.com")
+	tests := map[string]Config{
+		"no name":      {BaseURL: "https://shop.example.com", IDPMetadataURL: "https://idp", Key: key, Certificate: cert},
+		"no key":       {Name: "acme", BaseURL: "https://shop.example.com", IDPMetadataURL: "https://idp"},
+		"no store":     {Name: "acme", BaseURL: "https://shop.example.com", IDPMetadataURL: "https://idp", Key: key, Certificate: cert},
+		"no base URL":  {Name: "acme", IDPMetadataURL: "https://idp", Key: key, Certificate: cert},
+		"no metadata":  {Name: "acme", BaseURL: "https://shop.example.com", Key: key, Certificate: cert},
+		"bad metadata": {Name: "acme", BaseURL: "https://shop.example.com", IDPMetadata: []byte("<x/>"), Key: key, Certificate: cert},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			_, err := New(cfg)
+
+			// Assert
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Filter is the single comparison the service supports, which is what
+// identity providers use to look up a resource before creating it.
+type Filter struct {
+	Attribute string
+	Value     string
+}
+
+// ParseFilter parses `attribute eq "value"`. The operator and the attribute
+// name are case-insensitive; the attribute is returned as written. An empty
+// filter returns nil.
+func ParseFilter(filter string) (*Filter, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return nil, nil
+	}
+	attr, rest, ok := strings.Cut(filter, " ")
+	if !ok {
+		return nil, fmt.Errorf("Error parsing filter %q: %w", filter, ErrInvalidFilter)
+	}
+	op, value, ok := strings.Cut(strings.TrimSpace(rest), " ")
+	if !ok || !strings.EqualFold(op, "eq") {
+		return nil, fmt.Errorf("Error parsing filter %q: %w", filter, ErrInvalidFilter)
+	}
+	var parsed string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(value)), &parsed); err != nil {
+		return nil, fmt.Errorf("Error parsing filter %q: %w", filter, ErrInvalidFilter)
+	}
+	return &Filter{Attribute: attr, Value: parsed}, nil
+}
+
+// Is reports whether the filter compares the given attribute.
+func (f *Filter) Is(attribute string) bool {
+	return strings.EqualFold(f.Attribute, attribute)
+}
@@ -0,0 +1,327 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is one entry of a PATCH request. Op is matched
+// case-insensitively because Azure AD sends "Replace" and "Add".
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply runs the operations against the user. Attributes the service does
+// not store, such as phone numbers or the enterprise extension, are
+// accepted and ignored so identity providers do not fail the whole sync.
+func (u *User) Apply(ops []PatchOperation) error {
+	before := *u
+	var beforeName Name
+	if u.Name != nil {
+		beforeName = *u.Name
+	}
+
+	for _, op := range ops {
+		if err := u.apply(op); err != nil {
+			return err
+		}
+	}
+
+	// The resource is read back with displayName and name.formatted both set
+	// to the stored name, so a change to the name parts alone must not be
+	// shadowed by those stale copies.
+	var afterName Name
+	if u.Name != nil {
+		afterName = *u.Name
+	}
+	if afterName != beforeName && u.DisplayName == before.DisplayName {
+		u.DisplayName = ""
+		if afterName.Formatted == beforeName.Formatted {
+			u.Name.Formatted = ""
+		}
+	}
+	return nil
+}
+
+func (u *User) apply(op PatchOperation) error {
+	kind, err := opKind(op)
+	if err != nil {
+		return err
+	}
+	path := attributePath(op.Path, UserSchema)
+	if path == "" {
+		return eachAttribute(kind, op.Value, func(name string, value json.RawMessage) error {
+			return u.set(attributePath(name, UserSchema), value)
+		})
+	}
+	if kind == "remove" {
+		return u.remove(path)
+	}
+	return u.set(path, op.Value)
+}
+
+func (u *User) set(path string, value json.RawMessage) error {
+	var err error
+	switch strings.ToLower(path) {
+	case "active":
+		var active bool
+		active, err = parseBool(value)
+		u.Active = &active
+	case "username":
+		u.UserName, err = parseString(value)
+	case "displayname":
+		u.DisplayName, err = parseString(value)
+	case "externalid":
+		u.ExternalID, err = parseString(value)
+	case "password":
+		u.Password, err = parseString(value)
+	case "name":
+		var name Name
+		if err := json.Unmarshal(value, &name); err != nil {
+			return fmt.Errorf("Error setting name: %w", ErrInvalidValue)
+		}
+		u.ensureName()
+		if name.Formatted != "" {
+			u.Name.Formatted = name.Formatted
+		}
+		if name.GivenName != "" {
+			u.Name.GivenName = name.GivenName
+		}
+		if name.FamilyName != "" {
+			u.Name.FamilyName = name.FamilyName
+		}
+	case "name.formatted":
+		u.ensureName()
+		u.Name.Formatted, err = parseString(value)
+	case "name.givenname":
+		u.ensureName()
+		u.Name.GivenName, err = parseString(value)
+	case "name.familyname":
+		u.ensureName()
+		u.Name.FamilyName, err = parseString(value)
+	}
+	if err != nil {
+		return fmt.Errorf("Error setting %s: %w", path, err)
+	}
+	return nil
+}
+
+func (u *User) remove(path string) error {
+	switch strings.ToLower(path) {
+	case "username":
+		return fmt.Errorf("Error removing userName, it is required: %w", ErrInvalidValue)
+	case "displayname":
+		u.DisplayName = ""
+	case "externalid":
+		u.ExternalID = ""
+	case "name":
+		u.Name = nil
+	case "name.formatted":
+		u.ensureName()
+		u.Name.Formatted = ""
+	case "name.givenname":
+		u.ensureName()
+		u.Name.GivenName = ""
+	case "name.familyname":
+		u.ensureName()
+		u.Name.FamilyName = ""
+	}
+	return nil
+}
+
+func (u *User) ensureName() {
+	if u.Name == nil {
+		u.Name = &Name{}
+	}
+}
+
+// Apply runs the operations against the group. Besides plain paths it
+// understands members[value eq "id"], which Azure AD and Okta use to remove
+// a single member.
+func (g *Group) Apply(ops []PatchOperation) error {
+	for _, op := range ops {
+		if err := g.apply(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Group) apply(op PatchOperation) error {
+	kind, err := opKind(op)
+	if err != nil {
+		return err
+	}
+	path := attributePath(op.Path, GroupSchema)
+	if path == "" {
+		return eachAttribute(kind, op.Value, func(name string, value json.RawMessage) error {
+			return g.set(kind, attributePath(name, GroupSchema), value)
+		})
+	}
+
+	if attr, filter, ok := strings.Cut(path, "["); ok {
+		if !strings.EqualFold(attr, "members") || !strings.HasSuffix(filter, "]") || kind != "remove" {
+			return fmt.Errorf("Error applying %s to %s: %w", op.Op, path, ErrInvalidPath)
+		}
+		f, err := ParseFilter(strings.TrimSuffix(filter, "]"))
+		if err != nil || f == nil || !f.Is("value") {
+			return fmt.Errorf("Error applying %s to %s: %w", op.Op, path, ErrInvalidPath)
+		}
+		g.removeMembers([]string{f.Value})
+		return nil
+	}
+
+	if kind == "remove" {
+		switch strings.ToLower(path) {
+		case "members":
+			if isEmpty(op.Value) {
+				g.Members = []Member{}
+				return nil
+			}
+			members, err := parseMembers(op.Value)
+			if err != nil {
+				return err
+			}
+			ids := make([]string, 0, len(members))
+			for _, m := range members {
+				ids = append(ids, m.Value)
+			}
+			g.removeMembers(ids)
+		case "displayname":
+			return fmt.Errorf("Error removing displayName, it is required: %w", ErrInvalidValue)
+		case "externalid":
+			g.ExternalID = ""
+		}
+		return nil
+	}
+	return g.set(kind, path, op.Value)
+}
+
+func (g *Group) set(kind, path string, value json.RawMessage) error {
+	var err error
+	switch strings.ToLower(path) {
+	case "displayname":
+		g.DisplayName, err = parseString(value)
+	case "externalid":
+		g.ExternalID, err = parseString(value)
+	case "members":
+		var members []Member
+		if members, err = parseMembers(value); err != nil {
+			return err
+		}
+		if kind == "replace" {
+			g.Members = members
+		} else {
+			g.Members = append(g.Members, members...)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("Error setting %s: %w", path, err)
+	}
+	return nil
+}
+
+func (g *Group) removeMembers(ids []string) {
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := []Member{}
+	for _, m := range g.Members {
+		if !drop[m.Value] {
+			kept = append(kept, m)
+		}
+	}
+	g.Members = kept
+}
+
+func opKind(op PatchOperation) (string, error) {
+	kind := strings.ToLower(op.Op)
+	switch kind {
+	case "add", "replace", "remove":
+		return kind, nil
+	default:
+		return "", fmt.Errorf("Error applying PATCH operation %q: %w", op.Op, ErrInvalidSyntax)
+	}
+}
+
+// eachAttribute handles an operation without a path, whose value is an
+// object of attributes to set.
+func eachAttribute(kind string, value json.RawMessage, fn func(name string, value json.RawMessage) error) error {
+	if kind == "remove" {
+		return fmt.Errorf("Error applying remove without a path: %w", ErrInvalidPath)
+	}
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(value, &attrs); err != nil {
+		return fmt.Errorf("Error applying %s without a path: %w", kind, ErrInvalidValue)
+	}
+	for name, v := range attrs {
+		if err := fn(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attributePath strips the core schema URN some clients prefix paths with.
+// Paths of other schemas keep their URN and are ignored by the callers.
+func attributePath(path, schema string) string {
+	path = strings.TrimSpace(path)
+	if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+		return path[len(schema)+1:]
+	}
+	return path
+}
+
+// parseBool accepts a JSON boolean or, as Azure AD sends it, the strings
+// "True" and "False".
+func parseBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		switch strings.ToLower(s) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, ErrInvalidValue
+}
+
+func parseString(value json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", ErrInvalidValue
+	}
+	return s, nil
+}
+
+// parseMembers accepts a list of members or, leniently, a single one.
+func parseMembers(value json.RawMessage) ([]Member, error) {
+	var members []Member
+	if err := json.Unmarshal(value, &members); err == nil {
+		return members, nil
+	}
+	var member Member
+	if err := json.Unmarshal(value, &member); err != nil || member.Value == "" {
+		return nil, fmt.Errorf("Error reading members: %w", ErrInvalidValue)
+	}
+	return []Member{member}, nil
+}
+
+func isEmpty(value json.RawMessage) bool {
+	v := strings.TrimSpace(string(value))
+	return v == "" || v == "null" || v == "[]"
+}
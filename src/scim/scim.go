@@ -0,0 +1,146 @@
+// Package scim holds the SCIM 2.0 (RFC 7643/7644) wire format: resources,
+// list and error responses, filters and PATCH operations. It knows nothing
+// about storage; the service maps these resources onto accounts.
+package scim
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	UserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	GroupSchema                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	PatchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	// ContentType is what SCIM clients send and expect back.
+	ContentType = "application/scim+json"
+)
+
+var (
+	ErrInvalidFilter = errors.New("unsupported filter, only 'attribute eq \"value\"' is supported")
+	ErrInvalidPath   = errors.New("unsupported PATCH path")
+	ErrInvalidValue  = errors.New("invalid attribute value")
+	ErrInvalidSyntax = errors.New("invalid SCIM request")
+)
+
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	// Password is write-only and never returned.
+	Password string `json:"password,omitempty"`
+	Meta     *Meta  `json:"meta,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// FullName picks the best display name the client sent: displayName, then
+// name.formatted, then the given and family names.
+func (u *User) FullName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name == nil {
+		return ""
+	}
+	if u.Name.Formatted != "" {
+		return u.Name.Formatted
+	}
+	switch {
+	case u.Name.GivenName != "" && u.Name.FamilyName != "":
+		return u.Name.GivenName + " " + u.Name.FamilyName
+	case u.Name.GivenName != "":
+		return u.Name.GivenName
+	default:
+		return u.Name.FamilyName
+	}
+}
+
+// IsActive treats a missing active attribute as true, as clients that do
+// not manage it expect.
+func (u *User) IsActive() bool {
+	return u.Active == nil || *u.Active
+}
+
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// MemberIDs returns the member values without duplicates, in order.
+func (g *Group) MemberIDs() []string {
+	ids := []string{}
+	seen := make(map[string]bool, len(g.Members))
+	for _, m := range g.Members {
+		if m.Value != "" && !seen[m.Value] {
+			seen[m.Value] = true
+			ids = append(ids, m.Value)
+		}
+	}
+	return ids
+}
+
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location,omitempty"`
+}
+
+type ListResponse[T any] struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []T      `json:"Resources"`
+}
+
+func NewListResponse[T any](resources []T, total, startIndex int) *ListResponse[T] {
+	if resources == nil {
+		resources = []T{}
+	}
+	return &ListResponse[T]{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// Error is the SCIM error body. Status is a string, as RFC 7644 requires.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
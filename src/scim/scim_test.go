@@ -0,0 +1,176 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ops(t *testing.T, raw string) []PatchOperation {
+	t.Helper()
+	var req PatchRequest
+	require.NoError(t, json.Unmarshal([]byte(raw), &req))
+	return req.Operations
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   *Filter
+		err    bool
+	}{
+		{filter: "", want: nil},
+		{filter: `userName eq "ana@example.com"`, want: &Filter{Attribute: "userName", Value: "ana@example.com"}},
+		{filter: `displayName EQ "Sales \"EU\""`, want: &Filter{Attribute: "displayName", Value: `Sales "EU"`}},
+		{filter: `userName co "ana"`, err: true},
+		{filter: `userName eq ana`, err: true},
+		{filter: `userName`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			// Act
+			got, err := ParseFilter(tt.filter)
+
+			// Assert
+			if tt.err {
+				assert.True(t, errors.Is(err, ErrInvalidFilter))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUserApply_AzureStyleDeactivation(t *testing.T) {
+	// Arrange
+	active := true
+	user := &User{UserName: "ana@example.com", Active: &active}
+
+	// Act
+	err := user.Apply(ops(t, `{"Operations":[{"op":"Replace","path":"active","value":"False"}]}`))
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, user.IsActive())
+}
+
+func TestUserApply_WithoutPath(t *testing.T) {
+	// Arrange
+	user := &User{UserName: "ana@example.com"}
+
+	// Act
+	err := user.Apply(ops(t, `{"Operations":[{"op":"replace","value":{
+		"active": false,
+		"userName": "ana.silva@example.com",
+		"urn:ietf:params:scim:schemas:core:2.0:User:externalId": "ext-1",
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department": "Sales"
+	}}]}`))
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, user.IsActive())
+	assert.Equal(t, "ana.silva@example.com", user.UserName)
+	assert.Equal(t, "ext-1", user.ExternalID)
+}
+
+func TestUserApply_NamePartsReplaceStoredName(t *testing.T) {
+	// Arrange
+	user := &User{UserName: "ana@example.com", DisplayName: "Ana", Name: &Name{Formatted: "Ana"}}
+
+	// Act
+	err := user.Apply(ops(t, `{"Operations":[
+		{"op":"replace","path":"name.givenName","value":"Ana"},
+		{"op":"replace","path":"name.familyName","value":"Silva"}
+	]}`))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "Ana Silva", user.FullName())
+}
+
+func TestUserApply_IgnoresUnknownAttributes(t *testing.T) {
+	// Arrange
+	user := &User{UserName: "ana@example.com"}
+
+	// Act
+	err := user.Apply(ops(t, `{"Operations":[
+		{"op":"add","path":"phoneNumbers[type eq \"work\"].value","value":"555"},
+		{"op":"replace","path":"emails[type eq \"work\"].value","value":"other@example.com"}
+	]}`))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, &User{UserName: "ana@example.com"}, user)
+}
+
+func TestUserApply_Errors(t *testing.T) {
+	tests := map[string]struct {
+		patch string
+		err   error
+	}{
+		"unknown op":          {patch: `{"Operations":[{"op":"move","path":"active"}]}`, err: ErrInvalidSyntax},
+		"remove userName":     {patch: `{"Operations":[{"op":"remove","path":"userName"}]}`, err: ErrInvalidValue},
+		"remove without path": {patch: `{"Operations":[{"op":"remove"}]}`, err: ErrInvalidPath},
+		"non boolean active":  {patch: `{"Operations":[{"op":"replace","path":"active","value":"maybe"}]}`, err: ErrInvalidValue},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			user := &User{UserName: "ana@example.com"}
+
+			// Act
+			err := user.Apply(ops(t, tt.patch))
+
+			// Assert
+			assert.True(t, errors.Is(err, tt.err), "got %v", err)
+		})
+	}
+}
+
+func TestGroupApply_Members(t *testing.T) {
+	// Arrange
+	group := &Group{DisplayName: "Engineering", Members: []Member{{Value: "u1"}, {Value: "u2"}}}
+
+	// Act
+	err := group.Apply(ops(t, `{"Operations":[
+		{"op":"add","path":"members","value":[{"value":"u3"},{"value":"u1"}]},
+		{"op":"remove","path":"members[value eq \"u2\"]"},
+		{"op":"Replace","path":"displayName","value":"Platform"}
+	]}`))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "Platform", group.DisplayName)
+	assert.Equal(t, []string{"u1", "u3"}, group.MemberIDs())
+}
+
+func TestGroupApply_ReplaceAndClearMembers(t *testing.T) {
+	// Arrange
+	group := &Group{DisplayName: "Engineering", Members: []Member{{Value: "u1"}}}
+
+	// Act
+	replaceErr := group.Apply(ops(t, `{"Operations":[{"op":"replace","value":{"members":[{"value":"u2"}]}}]}`))
+	replaced := group.MemberIDs()
+	clearErr := group.Apply(ops(t, `{"Operations":[{"op":"remove","path":"members"}]}`))
+
+	// Assert
+	require.NoError(t, replaceErr)
+	assert.Equal(t, []string{"u2"}, replaced)
+	require.NoError(t, clearErr)
+	assert.Empty(t, group.MemberIDs())
+}
+
+func TestGroupApply_RejectsFilteredPathsOtherThanMemberRemoval(t *testing.T) {
+	// Arrange
+	group := &Group{DisplayName: "Engineering"}
+
+	// Act
+	err := group.Apply(ops(t, `{"Operations":[{"op":"replace","path":"members[value eq \"u1\"]","value":{}}]}`))
+
+	// Assert
+	assert.True(t, errors.Is(err, ErrInvalidPath))
+}
@@ -0,0 +1,38 @@
+// Package secret generates and hashes the high-entropy credentials the
+// service hands out (client secrets, API keys, one-time links). Since they are
+// random rather than user-chosen, a fast SHA-256 is enough to store them.
+package secret
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+const defaultBytes = 32
+
+// Generate returns a URL-safe random string of n bytes of entropy, with an
+// optional recognizable prefix such as "ask_".
+func Generate(prefix string, n int) (string, error) {
+	if n <= 0 {
+		n = defaultBytes
+	}
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func Hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Equal compares a plaintext value with a stored hash in constant time.
+func Equal(value, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(value)), []byte(hash)) == 1
+}
@@ -0,0 +1,29 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate_PrefixAndUniqueness(t *testing.T) {
+	// Act
+	a, err := Generate("ask_", 0)
+	require.NoError(t, err)
+	b, err := Generate("ask_", 0)
+	require.NoError(t, err)
+
+	// Assert
+	assert.True(t, strings.HasPrefix(a, "ask_"))
+	assert.NotEqual(t, a, b)
+	assert.Len(t, strings.TrimPrefix(a, "ask_"), 43)
+}
+
+func TestEqual(t *testing.T) {
+	hash := Hash("s3cr3t")
+
+	assert.True(t, Equal("s3cr3t", hash))
+	assert.False(t, Equal("other", hash))
+}
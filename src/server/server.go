@@ -3,50 +3,278 @@ package server
 import (
 	"auth-service/src/api"
 	"auth-service/src/config"
+	"auth-service/src/domain"
+	"auth-service/src/health"
+	"auth-service/src/logging"
+	"auth-service/src/metrics"
 	"auth-service/src/service"
-	"log"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 type Server struct {
-	cfg     *config.Config
-	service service.UserService
+	cfg        *config.Config
+	service    service.UserService
+	magicLinks service.MagicLinkService
+	social     service.SocialLoginService
+	scim       service.SCIMService
+	saml       service.SAMLService
+	tokens     service.PersonalAccessTokenService
+	apiKeys    service.APIKeyService
+	webhooks   service.WebhookService
+	exchange   service.TokenExchangeService
+	devices    service.DeviceAuthorizationService
+	health     *health.Checker
+	metrics    *metrics.Prometheus
+	logger     *slog.Logger
 }
 
-func NewServer(cfg *config.Config, userService service.UserService) *Server {
-	return &Server{
+type Option func(*Server)
+
+// WithMagicLinks enables the passwordless sign-in endpoints.
+func WithMagicLinks(svc service.MagicLinkService) Option {
+	return func(s *Server) {
+		s.magicLinks = svc
+	}
+}
+
+// WithSocialLogin enables sign-in through the configured external providers.
+func WithSocialLogin(svc service.SocialLoginService) Option {
+	return func(s *Server) {
+		s.social = svc
+	}
+}
+
+// WithSCIM enables the SCIM 2.0 provisioning API.
+func WithSCIM(svc service.SCIMService) Option {
+	return func(s *Server) {
+		s.scim = svc
+	}
+}
+
+// WithSAML enables single sign-on through the configured SAML IdPs.
+func WithSAML(svc service.SAMLService) Option {
+	return func(s *Server) {
+		s.saml = svc
+	}
+}
+
+// WithPersonalAccessTokens enables the token management endpoints and lets
+// protected routes accept personal access tokens.
+func WithPersonalAccessTokens(svc service.PersonalAccessTokenService) Option {
+	return func(s *Server) {
+		s.tokens = svc
+	}
+}
+
+// WithAPIKeys authenticates internal callers with named, hashed keys and
+// serves the endpoints administrators use to manage them.
+func WithAPIKeys(svc service.APIKeyService) Option {
+	return func(s *Server) {
+		s.apiKeys = svc
+	}
+}
+
+// WithWebhooks serves the endpoints administrators use to manage webhook
+// subscriptions.
+func WithWebhooks(svc service.WebhookService) Option {
+	return func(s *Server) {
+		s.webhooks = svc
+	}
+}
+
+// WithTokenExchange enables the token endpoint for impersonation and
+// delegation through token exchange.
+func WithTokenExchange(svc service.TokenExchangeService) Option {
+	return func(s *Server) {
+		s.exchange = svc
+	}
+}
+
+// WithDeviceAuthorization enables sign-in of smart TVs and CLIs through the
+// device authorization grant.
+func WithDeviceAuthorization(svc service.DeviceAuthorizationService) Option {
+	return func(s *Server) {
+		s.devices = svc
+	}
+}
+
+func NewServer(cfg *config.Config, userService service.UserService, checker *health.Checker, prom *metrics.Prometheus, logger *slog.Logger, opts ...Option) *Server {
+	s := &Server{
 		cfg:     cfg,
 		service: userService,
+		health:  checker,
+		metrics: prom,
+		logger:  logger,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *Server) Run() {
+func (s *Server) Router() http.Handler {
 	router := chi.NewRouter()
-	router.Use(middleware.Logger)
+	router.Use(middleware.RequestID)
 	router.Use(middleware.Recoverer)
+	router.Use(s.metrics.Middleware)
 
-	apiHandler := api.NewHandler(s.service, s.cfg)
+	apiHandler := api.NewHandler(s.service, s.cfg, api.WithLogger(s.logger), api.WithMagicLinks(s.magicLinks), api.WithSocialLogin(s.social), api.WithSCIM(s.scim), api.WithSAML(s.saml), api.WithPersonalAccessTokens(s.tokens), api.WithAPIKeys(s.apiKeys), api.WithWebhooks(s.webhooks), api.WithTokenExchange(s.exchange), api.WithDeviceAuthorization(s.devices))
+	router.Use(apiHandler.CORSMiddleware)
 
-	// --- Configuração das Rotas ---
-	// Rotas Públicas
-	router.Post("/register", apiHandler.HandleRegister)
-	router.Post("/login", apiHandler.HandleLogin)
+	// Probes e métricas ficam fora do logger para não poluir os logs a cada poucos segundos.
+	router.Get("/healthz", s.health.HandleLiveness)
+	router.Get("/readyz", s.health.HandleReadiness)
+	router.Method(http.MethodGet, "/metrics", s.metrics.Handler())
 
-	// Rotas Protegidas
-	router.Group(func(r chi.Router) {
-		r.Use(apiHandler.APIKeyAuthMiddleware)
-		r.Post("/auth/validate", apiHandler.HandleAuthValidate)
-	})
-	router.Group(func(r chi.Router) {
-		r.Use(apiHandler.JWTAuthMiddleware)
-		r.Get("/profile", apiHandler.HandleGetProfile)
+	router.Group(func(router chi.Router) {
+		router.Use(tracing.Middleware)
+		router.Use(logging.Middleware(s.logger))
+
+		// --- Configuração das Rotas ---
+		// Rotas Públicas
+		router.Group(func(r chi.Router) {
+			r.Use(apiHandler.RateLimitMiddleware)
+			r.Post("/register", apiHandler.HandleRegister)
+			r.Post("/login", apiHandler.HandleLogin)
+			if s.magicLinks != nil {
+				r.Post("/login/magic-link", apiHandler.HandleMagicLinkRequest)
+				r.Post("/login/magic-link/verify", apiHandler.HandleMagicLinkVerify)
+			}
+			if s.social != nil {
+				r.Get("/auth/{provider}/start", apiHandler.HandleSocialLoginStart)
+				r.Get("/auth/{provider}/callback", apiHandler.HandleSocialLoginCallback)
+			}
+			if s.saml != nil {
+				r.Get("/saml/{idp}/metadata", apiHandler.HandleSAMLMetadata)
+				r.Get("/saml/{idp}/login", apiHandler.HandleSAMLLogin)
+				r.Post("/saml/{idp}/acs", apiHandler.HandleSAMLACS)
+			}
+			if s.exchange != nil || s.devices != nil {
+				r.Post("/oauth/token", apiHandler.HandleToken)
+			}
+			r.Post("/oauth/revoke", apiHandler.HandleRevoke)
+			if s.devices != nil {
+				r.Post("/oauth/device_authorization", apiHandler.HandleDeviceAuthorization)
+			}
+		})
+
+		// Rotas Protegidas
+		router.Group(func(r chi.Router) {
+			r.Use(apiHandler.APIKeyAuthMiddleware)
+			r.Post("/auth/validate", apiHandler.HandleAuthValidate)
+		})
+		router.Group(func(r chi.Router) {
+			r.Use(apiHandler.JWTAuthMiddleware)
+			r.With(apiHandler.RequireScope(domain.ScopeProfileRead)).Get("/profile", apiHandler.HandleGetProfile)
+			if s.tokens != nil {
+				r.With(apiHandler.RequireScope(domain.ScopeTokensRead)).Get("/profile/tokens", apiHandler.HandleListPersonalAccessTokens)
+				r.With(apiHandler.RequireScope(domain.ScopeTokensWrite)).Post("/profile/tokens", apiHandler.HandleCreatePersonalAccessToken)
+				r.With(apiHandler.RequireScope(domain.ScopeTokensWrite)).Delete("/profile/tokens/{id}", apiHandler.HandleRevokePersonalAccessToken)
+			}
+			if s.devices != nil {
+				r.With(apiHandler.RequireSession).Get("/device", apiHandler.HandleDeviceLookup)
+				r.With(apiHandler.RequireSession).Post("/device/approve", apiHandler.HandleDeviceApprove)
+				r.With(apiHandler.RequireSession).Post("/device/deny", apiHandler.HandleDeviceDeny)
+			}
+		})
+		if s.apiKeys != nil || s.webhooks != nil {
+			router.Route("/admin", func(r chi.Router) {
+				r.Use(apiHandler.JWTAuthMiddleware)
+				r.Use(apiHandler.RequireAdmin)
+				if s.apiKeys != nil {
+					r.Get("/api-keys", apiHandler.HandleListAPIKeys)
+					r.Post("/api-keys", apiHandler.HandleCreateAPIKey)
+					r.Delete("/api-keys/{id}", apiHandler.HandleRevokeAPIKey)
+				}
+				if s.webhooks != nil {
+					r.Get("/webhooks", apiHandler.HandleListWebhooks)
+					r.Post("/webhooks", apiHandler.HandleCreateWebhook)
+					r.Delete("/webhooks/{id}", apiHandler.HandleDeleteWebhook)
+					r.Get("/webhooks/{id}/deliveries", apiHandler.HandleListWebhookDeliveries)
+					r.Post("/webhooks/{id}/deliveries/{deliveryID}/retry", apiHandler.HandleRedeliverWebhook)
+				}
+			})
+		}
+		if s.scim != nil {
+			router.Route(api.SCIMBasePath, func(r chi.Router) {
+				r.Use(apiHandler.SCIMAuthMiddleware)
+				r.Get("/ServiceProviderConfig", apiHandler.HandleSCIMServiceProviderConfig)
+				r.Get("/Users", apiHandler.HandleSCIMListUsers)
+				r.Post("/Users", apiHandler.HandleSCIMCreateUser)
+				r.Get("/Users/{id}", apiHandler.HandleSCIMGetUser)
+				r.Put("/Users/{id}", apiHandler.HandleSCIMReplaceUser)
+				r.Patch("/Users/{id}", apiHandler.HandleSCIMPatchUser)
+				r.Delete("/Users/{id}", apiHandler.HandleSCIMDeleteUser)
+				r.Get("/Groups", apiHandler.HandleSCIMListGroups)
+				r.Post("/Groups", apiHandler.HandleSCIMCreateGroup)
+				r.Get("/Groups/{id}", apiHandler.HandleSCIMGetGroup)
+				r.Put("/Groups/{id}", apiHandler.HandleSCIMReplaceGroup)
+				r.Patch("/Groups/{id}", apiHandler.HandleSCIMPatchGroup)
+				r.Delete("/Groups/{id}", apiHandler.HandleSCIMDeleteGroup)
+			})
+		}
 	})
 
-	log.Printf("Servidor de Autenticação iniciado em %s", s.cfg.ListenAddr)
-	if err := http.ListenAndServe(s.cfg.ListenAddr, router); err != nil {
-		log.Fatalf("Falha ao iniciar o servidor: %v", err)
+	return router
+}
+
+func (s *Server) Run() {
+	httpServer := &http.Server{
+		Addr:              s.cfg.ListenAddr,
+		Handler:           s.Router(),
+		ReadTimeout:       s.cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: s.cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      s.cfg.HTTP.WriteTimeout,
+		IdleTimeout:       s.cfg.HTTP.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(s.logger.Handler(), slog.LevelWarn),
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		s.logger.Info("auth server started", "addr", s.cfg.ListenAddr)
+		serverErr <- httpServer.ListenAndServe()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("failed to start server", "error", err)
+			os.Exit(1)
+		}
+		return
+	case sig := <-stop:
+		s.logger.Info("shutdown signal received, draining", "signal", sig.String())
+	}
+
+	// Dá tempo para os balanceadores perceberem o /readyz falhando antes de parar de aceitar conexões.
+	s.health.MarkShuttingDown()
+	time.Sleep(s.cfg.HTTP.ShutdownDrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		s.logger.Error("failed to shut down server", "error", err)
+	}
+	// Os links pedidos antes do sinal ainda são enviados.
+	if s.magicLinks != nil {
+		if err := s.magicLinks.Shutdown(ctx); err != nil {
+			s.logger.Error("failed to send pending magic links", "error", err)
+		}
 	}
+	s.logger.Info("server stopped")
 }
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/metrics"
+	"auth-service/src/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// accountLinker maps accounts vouched for by a directory, a SAML IdP or a
+// social provider onto local users, through identities keyed by the
+// provider's subject.
+type accountLinker struct {
+	provider        string
+	users           repository.UserRepository
+	identities      repository.IdentityRepository
+	policy          AuthProviderPolicy
+	lowercaseEmails bool
+	metrics         metrics.Recorder
+	logger          *slog.Logger
+	// keepProfile leaves the local role and name alone, for providers with
+	// no say over them.
+	keepProfile bool
+}
+
+// resolve returns the local user for account, linking or provisioning one
+// as the policy allows, and refreshes its role and name unless keepProfile
+// is set.
+func (l *accountLinker) resolve(ctx context.Context, account *domain.DirectoryAccount) (*domain.User, error) {
+	identity, err := l.identities.FindByProviderSubject(ctx, l.provider, account.Subject)
+	switch {
+	case err == nil:
+		user, err := l.users.FindByIDForUpdate(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return l.sync(ctx, user, account)
+	case errors.Is(err, domain.ErrIdentityNotFound):
+		return l.link(ctx, account)
+	default:
+		return nil, err
+	}
+}
+
+func (l *accountLinker) link(ctx context.Context, account *domain.DirectoryAccount) (*domain.User, error) {
+	if account.EmailUnverified {
+		return nil, fmt.Errorf("Error linking %s account %s: %w", l.provider, account.Subject, domain.ErrEmailNotVerified)
+	}
+	email, err := domain.NormalizeEmail(account.Email, l.lowercaseEmails)
+	if err != nil {
+		return nil, fmt.Errorf("Error linking %s account %s without a valid email: %w", l.provider, account.Subject, domain.ErrInvalidCredentials)
+	}
+
+	user, err := l.users.FindByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !l.policy.LinkExistingEmail {
+			return nil, fmt.Errorf("Error linking %s account %s: %w", l.provider, account.Subject, domain.ErrAccountLinkRequired)
+		}
+		if user, err = l.sync(ctx, user, account); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, domain.ErrUserNotFound):
+		if !l.policy.Provision {
+			return nil, fmt.Errorf("Error linking %s account %s: %w", l.provider, account.Subject, domain.ErrInvalidCredentials)
+		}
+		now := time.Now().UTC()
+		user = &domain.User{
+			ID:        uuid.NewString(),
+			Name:      displayName(account, email),
+			Email:     email,
+			Role:      account.Role,
+			CreatedAt: now,
+		}
+		if account.EmailVerified {
+			user.EmailVerifiedAt = &now
+		}
+		if err := l.users.Create(ctx, user); err != nil {
+			return nil, err
+		}
+		l.metrics.UserRegistered()
+		l.logger.InfoContext(ctx, "account provisioned", "provider", l.provider, "user_id", user.ID, "role", user.Role)
+	default:
+		return nil, err
+	}
+
+	err = l.identities.Create(ctx, &domain.Identity{
+		UserID:    user.ID,
+		Provider:  l.provider,
+		Subject:   account.Subject,
+		Email:     email,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.logger.InfoContext(ctx, "external identity linked", "provider", l.provider, "user_id", user.ID)
+	return user, nil
+}
+
+// sync applies the directory's role and name, so removing someone from an
+// admin group takes effect on their next login, and records a verified
+// email the first time the provider vouches for it.
+func (l *accountLinker) sync(ctx context.Context, user *domain.User, account *domain.DirectoryAccount) (*domain.User, error) {
+	changed := false
+	if name := displayName(account, user.Email); !l.keepProfile && (user.Role != account.Role || user.Name != name) {
+		l.logger.InfoContext(ctx, "account updated from directory", "provider", l.provider, "user_id", user.ID, "role", account.Role)
+		user.Role = account.Role
+		user.Name = name
+		changed = true
+	}
+	if l.provesEmail(user, account) {
+		now := time.Now().UTC()
+		user.EmailVerifiedAt = &now
+		changed = true
+	}
+	if !changed {
+		return user, nil
+	}
+	if err := l.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// provesEmail reports whether account newly shows that the user owns their
+// address: the provider verified it, and it is the address on the account.
+func (l *accountLinker) provesEmail(user *domain.User, account *domain.DirectoryAccount) bool {
+	if !account.EmailVerified || user.EmailVerifiedAt != nil {
+		return false
+	}
+	email, err := domain.NormalizeEmail(account.Email, l.lowercaseEmails)
+	return err == nil && strings.EqualFold(email, user.Email)
+}
+
+func displayName(account *domain.DirectoryAccount, email string) string {
+	if account.Name != "" {
+		return account.Name
+	}
+	return email
+}
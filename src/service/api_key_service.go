@@ -0,0 +1,163 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/secret"
+	"auth-service/src/tracing"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// APIKeyService authenticates the services calling the internal endpoints
+// and manages their keys.
+type APIKeyService interface {
+	// Authenticate returns the key matching the presented one, or
+	// domain.ErrInvalidAPIKey when none is active.
+	Authenticate(ctx context.Context, key string) (*domain.APIKey, error)
+	// Create returns the key itself, which is never shown again, next to its
+	// stored record. A nil expiresAt uses the default lifetime.
+	Create(ctx context.Context, name string, expiresAt *time.Time) (string, *domain.APIKey, error)
+	// List returns the keys stored in the database; keys from the
+	// configuration file are not included.
+	List(ctx context.Context) ([]*domain.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+}
+
+type apiKeyService struct {
+	keys       repository.APIKeyRepository
+	static     []*domain.APIKey
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+	logger     *slog.Logger
+}
+
+type APIKeyOption func(*apiKeyService)
+
+// WithStaticAPIKeys adds keys that live outside the database, such as the
+// ones listed in the configuration file. Only their KeyHash is compared.
+func WithStaticAPIKeys(keys ...*domain.APIKey) APIKeyOption {
+	return func(s *apiKeyService) {
+		s.static = append(s.static, keys...)
+	}
+}
+
+// WithAPIKeyTTL sets the lifetime of keys created without an expiry and the
+// longest lifetime an administrator may ask for.
+func WithAPIKeyTTL(defaultTTL, maxTTL time.Duration) APIKeyOption {
+	return func(s *apiKeyService) {
+		s.defaultTTL = defaultTTL
+		s.maxTTL = maxTTL
+	}
+}
+
+func WithAPIKeyLogger(logger *slog.Logger) APIKeyOption {
+	return func(s *apiKeyService) {
+		s.logger = logger
+	}
+}
+
+func NewAPIKeyService(keys repository.APIKeyRepository, opts ...APIKeyOption) APIKeyService {
+	s := &apiKeyService{
+		keys:       keys,
+		defaultTTL: 90 * 24 * time.Hour,
+		maxTTL:     2 * 365 * 24 * time.Hour,
+		logger:     slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, key string) (_ *domain.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	if key == "" {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	hash := secret.Hash(key)
+	// Every static key is compared, in constant time, so the time taken
+	// does not tell which one matched.
+	var match *domain.APIKey
+	for _, candidate := range s.static {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(candidate.KeyHash)) == 1 && match == nil {
+			match = candidate
+		}
+	}
+	if match == nil && strings.HasPrefix(key, domain.APIKeyPrefix) {
+		found, err := s.keys.FindByHash(ctx, hash)
+		if err != nil && !errors.Is(err, domain.ErrAPIKeyNotFound) {
+			return nil, err
+		}
+		if found != nil && secret.Equal(key, found.KeyHash) {
+			match = found
+		}
+	}
+	if match == nil {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	if !match.Active(time.Now().UTC()) {
+		s.logger.WarnContext(ctx, "expired or revoked API key used", "key_id", match.ID, "caller", match.Name)
+		return nil, domain.ErrInvalidAPIKey
+	}
+	span.SetAttributes(attribute.String("auth.caller", match.Name))
+	return match, nil
+}
+
+func (s *apiKeyService) Create(ctx context.Context, name string, expiresAt *time.Time) (key string, apiKey *domain.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, domain.ErrAPIKeyNameRequired
+	}
+	now := time.Now().UTC()
+	expiry := now.Add(s.defaultTTL)
+	if expiresAt != nil {
+		expiry = expiresAt.UTC()
+	}
+	if !expiry.After(now) || expiry.After(now.Add(s.maxTTL)) {
+		return "", nil, domain.ErrInvalidExpiry
+	}
+
+	key, err = secret.Generate(domain.APIKeyPrefix, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	apiKey = &domain.APIKey{
+		ID:        uuid.NewString(),
+		Name:      name,
+		KeyHash:   secret.Hash(key),
+		CreatedAt: now,
+		ExpiresAt: expiry,
+	}
+	if err := s.keys.Create(ctx, apiKey); err != nil {
+		return "", nil, fmt.Errorf("Error creating API key: %w", err)
+	}
+	return key, apiKey, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context) (_ []*domain.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.List")
+	defer func() { tracing.End(span, err) }()
+
+	return s.keys.List(ctx)
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.Revoke")
+	defer func() { tracing.End(span, err) }()
+
+	return s.keys.Revoke(ctx, id, time.Now().UTC())
+}
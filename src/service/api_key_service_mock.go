@@ -0,0 +1,36 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type APIKeyServiceMock struct {
+	mock.Mock
+}
+
+func (m *APIKeyServiceMock) Authenticate(ctx context.Context, key string) (*domain.APIKey, error) {
+	args := m.Called(ctx, key)
+	apiKey, _ := args.Get(0).(*domain.APIKey)
+	return apiKey, args.Error(1)
+}
+
+func (m *APIKeyServiceMock) Create(ctx context.Context, name string, expiresAt *time.Time) (string, *domain.APIKey, error) {
+	args := m.Called(ctx, name, expiresAt)
+	apiKey, _ := args.Get(1).(*domain.APIKey)
+	return args.String(0), apiKey, args.Error(2)
+}
+
+func (m *APIKeyServiceMock) List(ctx context.Context) ([]*domain.APIKey, error) {
+	args := m.Called(ctx)
+	keys, _ := args.Get(0).([]*domain.APIKey)
+	return keys, args.Error(1)
+}
+
+func (m *APIKeyServiceMock) Revoke(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository/memory"
+	"auth-service/src/secret"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("APIKeyService", func() {
+	var apiKeys APIKeyService
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		apiKeys = NewAPIKeyService(memory.NewAPIKey(),
+			WithAPIKeyTTL(24*time.Hour, 30*24*time.Hour),
+			WithStaticAPIKeys(
+				&domain.APIKey{ID: "config:billing", Name: "billing", KeyHash: secret.Hash("billing-key"), ExpiresAt: time.Now().Add(time.Hour)},
+				&domain.APIKey{ID: "config:legacy", Name: "legacy", KeyHash: secret.Hash("expired-key"), ExpiresAt: time.Now().Add(-time.Hour)},
+			))
+	})
+
+	Context("when minting a key", func() {
+		It("should return a prefixed key that identifies its caller", func() {
+			// Act
+			key, apiKey, err := apiKeys.Create(ctx, " orders ", nil)
+			caller, authErr := apiKeys.Authenticate(ctx, key)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.HasPrefix(key, domain.APIKeyPrefix)).To(BeTrue())
+			Expect(apiKey.Name).To(Equal("orders"))
+			Expect(apiKey.KeyHash).To(Equal(secret.Hash(key)))
+			Expect(apiKey.ExpiresAt).To(BeTemporally("~", time.Now().Add(24*time.Hour), time.Minute))
+			Expect(authErr).NotTo(HaveOccurred())
+			Expect(caller.ID).To(Equal(apiKey.ID))
+		})
+
+		It("should require a name and an expiry within the allowed lifetime", func() {
+			// Arrange
+			past := time.Now().Add(-time.Minute)
+			tooFar := time.Now().Add(31 * 24 * time.Hour)
+
+			// Act
+			_, _, nameErr := apiKeys.Create(ctx, "", nil)
+			_, _, pastErr := apiKeys.Create(ctx, "orders", &past)
+			_, _, farErr := apiKeys.Create(ctx, "orders", &tooFar)
+
+			// Assert
+			Expect(errors.Is(nameErr, domain.ErrAPIKeyNameRequired)).To(BeTrue())
+			Expect(errors.Is(pastErr, domain.ErrInvalidExpiry)).To(BeTrue())
+			Expect(errors.Is(farErr, domain.ErrInvalidExpiry)).To(BeTrue())
+		})
+	})
+
+	Context("when rotating", func() {
+		It("should accept the old and new keys until the old one is revoked", func() {
+			// Arrange
+			oldKey, oldAPIKey, err := apiKeys.Create(ctx, "orders", nil)
+			Expect(err).NotTo(HaveOccurred())
+			newKey, _, err := apiKeys.Create(ctx, "orders", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			_, oldErr := apiKeys.Authenticate(ctx, oldKey)
+			_, newErr := apiKeys.Authenticate(ctx, newKey)
+			Expect(apiKeys.Revoke(ctx, oldAPIKey.ID)).To(Succeed())
+			_, revokedErr := apiKeys.Authenticate(ctx, oldKey)
+
+			// Assert
+			Expect(oldErr).NotTo(HaveOccurred())
+			Expect(newErr).NotTo(HaveOccurred())
+			Expect(errors.Is(revokedErr, domain.ErrInvalidAPIKey)).To(BeTrue())
+		})
+	})
+
+	Context("when authenticating", func() {
+		It("should accept active static keys and reject expired or unknown ones", func() {
+			// Act
+			caller, err := apiKeys.Authenticate(ctx, "billing-key")
+			_, expiredErr := apiKeys.Authenticate(ctx, "expired-key")
+			_, unknownErr := apiKeys.Authenticate(ctx, domain.APIKeyPrefix+"unknown")
+			_, emptyErr := apiKeys.Authenticate(ctx, "")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(caller.Name).To(Equal("billing"))
+			Expect(errors.Is(expiredErr, domain.ErrInvalidAPIKey)).To(BeTrue())
+			Expect(errors.Is(unknownErr, domain.ErrInvalidAPIKey)).To(BeTrue())
+			Expect(errors.Is(emptyErr, domain.ErrInvalidAPIKey)).To(BeTrue())
+		})
+	})
+})
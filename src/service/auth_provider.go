@@ -0,0 +1,63 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+)
+
+// AuthProvider checks a login and password against an external directory
+// such as LDAP. It returns domain.ErrInvalidCredentials when the directory
+// rejects them; any other error means the directory could not be asked.
+type AuthProvider interface {
+	Name() string
+	Authenticate(ctx context.Context, login, password string) (*domain.DirectoryAccount, error)
+}
+
+// AuthProviderPolicy decides how directory accounts relate to local ones.
+type AuthProviderPolicy struct {
+	// Exclusive skips the local password check, so only the directory can
+	// sign users in. Otherwise the local check runs when the directory
+	// rejects the credentials or is unreachable.
+	Exclusive bool
+	// Provision creates a local account the first time a directory user
+	// signs in.
+	Provision bool
+	// LinkExistingEmail attaches a directory user to a local account with
+	// the same email instead of refusing the login.
+	LinkExistingEmail bool
+}
+
+type authProvider struct {
+	provider   AuthProvider
+	identities repository.IdentityRepository
+	policy     AuthProviderPolicy
+}
+
+// WithAuthProvider makes Login try the directory before the local bcrypt
+// check. Directory accounts are linked to local users through identities,
+// and their role and name are refreshed from the directory on every login.
+func WithAuthProvider(provider AuthProvider, identities repository.IdentityRepository, policy AuthProviderPolicy) Option {
+	return func(s *userService) {
+		s.authProvider = &authProvider{provider: provider, identities: identities, policy: policy}
+	}
+}
+
+// directoryLogin returns the local user for a login the directory accepted.
+func (s *userService) directoryLogin(ctx context.Context, login, password string) (*domain.User, error) {
+	p := s.authProvider
+	account, err := p.provider.Authenticate(ctx, login, password)
+	if err != nil {
+		return nil, err
+	}
+	linker := &accountLinker{
+		provider:        p.provider.Name(),
+		users:           s.repo,
+		identities:      p.identities,
+		policy:          p.policy,
+		lowercaseEmails: s.lowercaseEmails,
+		metrics:         s.metrics,
+		logger:          s.logger,
+	}
+	return linker.resolve(ctx, account)
+}
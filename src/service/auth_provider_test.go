@@ -0,0 +1,160 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/ldapauth"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"auth-service/src/test_artefacts/ldapserver"
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UserService with an LDAP auth provider", func() {
+	const (
+		serviceDN = "cn=auth-service,ou=services,dc=example,dc=com"
+		adminsDN  = "cn=shop-admins,ou=groups,dc=example,dc=com"
+		aliceDN   = "uid=alice,ou=people,dc=example,dc=com"
+	)
+
+	var dir *ldapserver.Server
+	var users repository.UserRepository
+	var identities repository.IdentityRepository
+	var ctx context.Context
+
+	newService := func(policy AuthProviderPolicy) UserService {
+		provider := ldapauth.New(ldapauth.Config{
+			URL:          dir.URL,
+			BindDN:       serviceDN,
+			BindPassword: "service-secret",
+			BaseDN:       "dc=example,dc=com",
+			GroupRoles:   map[string]string{adminsDN: domain.RoleAdmin},
+		})
+		return NewUserService(users, "test-secret", WithAuthProvider(provider, identities, policy))
+	}
+
+	addAlice := func(groups ...string) {
+		dir.Add(aliceDN, map[string][]string{
+			"objectClass":       {"person"},
+			"uid":               {"alice"},
+			"mail":              {"alice@example.com"},
+			"displayName":       {"Alice"},
+			"memberOf":          groups,
+			ldapserver.Password: {"directory-password"},
+		})
+	}
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		dir, err = ldapserver.Start()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(dir.Close)
+		dir.Add(serviceDN, map[string][]string{"objectClass": {"account"}, ldapserver.Password: {"service-secret"}})
+		addAlice(adminsDN)
+
+		users = memory.NewUser()
+		identities = memory.NewIdentity()
+	})
+
+	Context("when provisioning is enabled", func() {
+		It("should create the account just in time with the mapped role", func() {
+			// Arrange
+			svc := newService(AuthProviderPolicy{Provision: true})
+
+			// Act
+			token, err := svc.Login(ctx, "alice", "directory-password", "")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			claims, err := svc.ValidateToken(ctx, token)
+			Expect(err).NotTo(HaveOccurred())
+
+			user, err := users.FindByEmail(ctx, "alice@example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims["sub"]).To(Equal(user.ID))
+			Expect(user.Role).To(Equal(domain.RoleAdmin))
+			Expect(user.PasswordHash).To(BeEmpty())
+			linked, err := identities.ListByUser(ctx, user.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(linked).To(HaveLen(1))
+			Expect(linked[0].Provider).To(Equal("ldap"))
+		})
+
+		It("should refresh the role from the directory on every login", func() {
+			// Arrange
+			svc := newService(AuthProviderPolicy{Provision: true})
+			_, err := svc.Login(ctx, "alice", "directory-password", "")
+			Expect(err).NotTo(HaveOccurred())
+			addAlice()
+
+			// Act
+			_, err = svc.Login(ctx, "alice@example.com", "directory-password", "")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			user, err := users.FindByEmail(ctx, "alice@example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.Role).To(Equal(domain.RoleUser))
+		})
+	})
+
+	Context("when provisioning is disabled", func() {
+		It("should reject directory users without a local account", func() {
+			_, err := newService(AuthProviderPolicy{}).Login(ctx, "alice", "directory-password", "")
+			Expect(errors.Is(err, domain.ErrInvalidCredentials)).To(BeTrue())
+		})
+	})
+
+	Context("when a local account already uses the directory email", func() {
+		BeforeEach(func() {
+			_, err := NewUserService(users, "test-secret").Register(ctx, "Local Alice", "alice@example.com", "local-password")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should require linking unless the policy allows it", func() {
+			_, err := newService(AuthProviderPolicy{Provision: true}).Login(ctx, "alice", "directory-password", "")
+			Expect(errors.Is(err, domain.ErrAccountLinkRequired)).To(BeTrue())
+		})
+
+		It("should link the account when the policy allows it", func() {
+			// Act
+			_, err := newService(AuthProviderPolicy{LinkExistingEmail: true}).Login(ctx, "alice", "directory-password", "")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			user, err := users.FindByEmail(ctx, "alice@example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.Role).To(Equal(domain.RoleAdmin))
+			Expect(user.Name).To(Equal("Alice"))
+		})
+
+		It("should fall back to the local password unless the directory is exclusive", func() {
+			// Act
+			_, fallbackErr := newService(AuthProviderPolicy{}).Login(ctx, "alice@example.com", "local-password", "")
+			_, exclusiveErr := newService(AuthProviderPolicy{Exclusive: true}).Login(ctx, "alice@example.com", "local-password", "")
+
+			// Assert
+			Expect(fallbackErr).NotTo(HaveOccurred())
+			Expect(errors.Is(exclusiveErr, domain.ErrInvalidCredentials)).To(BeTrue())
+		})
+	})
+
+	Context("when the directory is unreachable", func() {
+		It("should still accept local passwords", func() {
+			// Arrange
+			_, err := NewUserService(users, "test-secret").Register(ctx, "Local", "local@example.com", "local-password")
+			Expect(err).NotTo(HaveOccurred())
+			dir.Close()
+
+			// Act
+			_, err = newService(AuthProviderPolicy{Provision: true}).Login(ctx, "local@example.com", "local-password", "")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/secret"
+	"auth-service/src/tracing"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+)
+
+// slowDownStep is how much longer a device must wait after polling too fast,
+// as RFC 8628 requires.
+const slowDownStep = 5 * time.Second
+
+// DeviceAuthorizationService implements the OAuth 2.0 Device Authorization
+// Grant (RFC 8628), which signs in smart TVs and CLIs that cannot show a
+// login form.
+type DeviceAuthorizationService interface {
+	// Authorize starts a sign-in for a registered client. The device code is
+	// returned here only; the device shows the user code and polls with the
+	// device code.
+	Authorize(ctx context.Context, clientID string) (string, *domain.DeviceAuthorization, error)
+	// Lookup returns the pending request behind a user code, with the client
+	// that made it, so the user can check what they are about to approve.
+	Lookup(ctx context.Context, userCode string) (*domain.DeviceAuthorization, *domain.Client, error)
+	// Approve lets the device that shows userCode sign in as userID.
+	Approve(ctx context.Context, userCode, userID string) error
+	// Deny refuses the request, and the device stops polling.
+	Deny(ctx context.Context, userCode, userID string) error
+	// Poll returns a session JWT once the user approved, and otherwise the
+	// error telling the device whether to keep polling.
+	Poll(ctx context.Context, clientID, deviceCode string) (string, error)
+}
+
+type deviceAuthorizationService struct {
+	users    UserService
+	clients  repository.ClientRepository
+	auths    repository.DeviceAuthorizationRepository
+	ttl      time.Duration
+	interval time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+type DeviceAuthorizationOption func(*deviceAuthorizationService)
+
+// WithDeviceAuthorizationTTL sets how long the user has to approve a device.
+func WithDeviceAuthorizationTTL(ttl time.Duration) DeviceAuthorizationOption {
+	return func(s *deviceAuthorizationService) {
+		s.ttl = ttl
+	}
+}
+
+// WithDeviceAuthorizationInterval sets how long devices wait between polls
+// until they poll too fast.
+func WithDeviceAuthorizationInterval(interval time.Duration) DeviceAuthorizationOption {
+	return func(s *deviceAuthorizationService) {
+		s.interval = interval
+	}
+}
+
+func WithDeviceAuthorizationLogger(logger *slog.Logger) DeviceAuthorizationOption {
+	return func(s *deviceAuthorizationService) {
+		s.logger = logger
+	}
+}
+
+func NewDeviceAuthorizationService(users UserService, clients repository.ClientRepository, auths repository.DeviceAuthorizationRepository, opts ...DeviceAuthorizationOption) DeviceAuthorizationService {
+	s := &deviceAuthorizationService{
+		users:    users,
+		clients:  clients,
+		auths:    auths,
+		ttl:      10 * time.Minute,
+		interval: 5 * time.Second,
+		logger:   slog.Default(),
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *deviceAuthorizationService) Authorize(ctx context.Context, clientID string) (deviceCode string, auth *domain.DeviceAuthorization, err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationService.Authorize", tracing.WithClient(clientID))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.clients.FindByID(ctx, clientID); err != nil {
+		return "", nil, err
+	}
+
+	now := s.now().UTC().Truncate(time.Second)
+	// Expired requests are kept for one more TTL, so a device polling late
+	// still hears expired_token rather than invalid_grant.
+	if err := s.auths.DeleteExpired(ctx, now.Add(-s.ttl)); err != nil {
+		s.logger.WarnContext(ctx, "failed to delete expired device authorizations", "error", err)
+	}
+
+	deviceCode, err = secret.Generate(domain.DeviceCodePrefix, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	userCode, err := generateUserCode()
+	if err != nil {
+		return "", nil, err
+	}
+	auth = &domain.DeviceAuthorization{
+		DeviceCodeHash: secret.Hash(deviceCode),
+		UserCode:       userCode,
+		ClientID:       clientID,
+		Status:         domain.DeviceAuthorizationPending,
+		Interval:       s.interval,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(s.ttl),
+	}
+	if err := s.auths.Create(ctx, auth); err != nil {
+		return "", nil, err
+	}
+	s.logger.InfoContext(ctx, "device authorization started", "client_id", clientID, "expires_at", auth.ExpiresAt)
+	return deviceCode, auth, nil
+}
+
+func (s *deviceAuthorizationService) Lookup(ctx context.Context, userCode string) (auth *domain.DeviceAuthorization, client *domain.Client, err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationService.Lookup")
+	defer func() { tracing.End(span, err) }()
+
+	auth, err = s.auths.FindPendingByUserCode(ctx, domain.NormalizeUserCode(userCode), s.now().UTC())
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err = s.clients.FindByID(ctx, auth.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return auth, client, nil
+}
+
+func (s *deviceAuthorizationService) Approve(ctx context.Context, userCode, userID string) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationService.Approve", tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	return s.decide(ctx, userCode, userID, domain.DeviceAuthorizationApproved)
+}
+
+func (s *deviceAuthorizationService) Deny(ctx context.Context, userCode, userID string) (err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationService.Deny", tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	return s.decide(ctx, userCode, userID, domain.DeviceAuthorizationDenied)
+}
+
+func (s *deviceAuthorizationService) decide(ctx context.Context, userCode, userID, status string) error {
+	if err := s.auths.Decide(ctx, domain.NormalizeUserCode(userCode), userID, status, s.now().UTC()); err != nil {
+		if errors.Is(err, domain.ErrUserCodeInvalid) {
+			s.logger.InfoContext(ctx, "device authorization not decided", "reason", "invalid user code", "user_id", userID)
+		}
+		return err
+	}
+	s.logger.InfoContext(ctx, "device authorization decided", "status", status, "user_id", userID)
+	return nil
+}
+
+// Poll answers in the order RFC 8628 implies: an expired code is reported
+// as such whatever happened to it, a decision is delivered however fast the
+// device polls, and only a still pending code is subject to slow_down.
+func (s *deviceAuthorizationService) Poll(ctx context.Context, clientID, deviceCode string) (token string, err error) {
+	ctx, span := tracer.Start(ctx, "DeviceAuthorizationService.Poll", tracing.WithClient(clientID))
+	defer func() { tracing.End(span, err) }()
+
+	if deviceCode == "" {
+		return "", domain.ErrDeviceCodeInvalid
+	}
+	hash := secret.Hash(deviceCode)
+	auth, err := s.auths.FindByDeviceCode(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	if auth.ClientID != clientID {
+		s.logger.WarnContext(ctx, "device poll rejected", "reason", "device code issued to another client", "client_id", clientID)
+		return "", fmt.Errorf("Error polling device authorization: %w", domain.ErrDeviceCodeInvalid)
+	}
+
+	now := s.now().UTC()
+	if !now.Before(auth.ExpiresAt) {
+		return "", domain.ErrDeviceCodeExpired
+	}
+	switch auth.Status {
+	case domain.DeviceAuthorizationApproved:
+		return s.redeem(ctx, auth)
+	case domain.DeviceAuthorizationDenied:
+		if err := s.auths.Delete(ctx, hash); err != nil && !errors.Is(err, domain.ErrDeviceCodeInvalid) {
+			return "", err
+		}
+		return "", domain.ErrAccessDenied
+	}
+
+	interval := auth.Interval
+	tooFast := auth.LastPolledAt != nil && now.Sub(*auth.LastPolledAt) < interval
+	if tooFast {
+		interval += slowDownStep
+	}
+	if err := s.auths.RecordPoll(ctx, hash, now, interval); err != nil {
+		return "", err
+	}
+	if tooFast {
+		return "", domain.ErrSlowDown
+	}
+	return "", domain.ErrAuthorizationPending
+}
+
+// redeem deletes the approved authorization before issuing the token, so
+// two concurrent polls cannot both receive one.
+func (s *deviceAuthorizationService) redeem(ctx context.Context, auth *domain.DeviceAuthorization) (string, error) {
+	if err := s.auths.Delete(ctx, auth.DeviceCodeHash); err != nil {
+		return "", err
+	}
+	token, err := s.users.IssueClientToken(ctx, auth.UserID, auth.ClientID)
+	if err != nil {
+		return "", err
+	}
+	s.logger.InfoContext(ctx, "device signed in", "client_id", auth.ClientID, "user_id", auth.UserID)
+	return token, nil
+}
+
+// generateUserCode draws every character uniformly from UserCodeAlphabet.
+func generateUserCode() (string, error) {
+	max := big.NewInt(int64(len(domain.UserCodeAlphabet)))
+	code := make([]byte, domain.UserCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+		code[i] = domain.UserCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
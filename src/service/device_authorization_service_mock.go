@@ -0,0 +1,40 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type DeviceAuthorizationServiceMock struct {
+	mock.Mock
+}
+
+func (m *DeviceAuthorizationServiceMock) Authorize(ctx context.Context, clientID string) (string, *domain.DeviceAuthorization, error) {
+	args := m.Called(ctx, clientID)
+	auth, _ := args.Get(1).(*domain.DeviceAuthorization)
+	return args.String(0), auth, args.Error(2)
+}
+
+func (m *DeviceAuthorizationServiceMock) Lookup(ctx context.Context, userCode string) (*domain.DeviceAuthorization, *domain.Client, error) {
+	args := m.Called(ctx, userCode)
+	auth, _ := args.Get(0).(*domain.DeviceAuthorization)
+	client, _ := args.Get(1).(*domain.Client)
+	return auth, client, args.Error(2)
+}
+
+func (m *DeviceAuthorizationServiceMock) Approve(ctx context.Context, userCode, userID string) error {
+	args := m.Called(ctx, userCode, userID)
+	return args.Error(0)
+}
+
+func (m *DeviceAuthorizationServiceMock) Deny(ctx context.Context, userCode, userID string) error {
+	args := m.Called(ctx, userCode, userID)
+	return args.Error(0)
+}
+
+func (m *DeviceAuthorizationServiceMock) Poll(ctx context.Context, clientID, deviceCode string) (string, error) {
+	args := m.Called(ctx, clientID, deviceCode)
+	return args.String(0), args.Error(1)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/jwt"
+	"auth-service/src/repository/memory"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeviceAuthorizationService", func() {
+	var devices DeviceAuthorizationService
+	var users UserService
+	var ctx context.Context
+	var now time.Time
+	var user *domain.User
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Now().UTC().Truncate(time.Second)
+		userRepo := memory.NewUser()
+		clients := memory.NewClient()
+		Expect(clients.Create(ctx, &domain.Client{ID: "tv-app", Name: "Smart TV", SecretHash: "unused", CreatedAt: now})).To(Succeed())
+		users = NewUserService(userRepo, "test-secret")
+		devices = NewDeviceAuthorizationService(users, clients, memory.NewDeviceAuthorization(),
+			WithDeviceAuthorizationTTL(10*time.Minute),
+			WithDeviceAuthorizationInterval(5*time.Second),
+		)
+		devices.(*deviceAuthorizationService).now = func() time.Time { return now }
+
+		var err error
+		user, err = users.Register(ctx, "Viewer", "viewer@example.com", "password123")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should sign the device in once the user approves the code it shows", func() {
+		// Arrange
+		deviceCode, auth, err := devices.Authorize(ctx, "tv-app")
+		Expect(err).NotTo(HaveOccurred())
+		typed := strings.ToLower(domain.FormatUserCode(auth.UserCode))
+
+		// Act
+		_, pendingErr := devices.Poll(ctx, "tv-app", deviceCode)
+		pending, client, lookupErr := devices.Lookup(ctx, typed)
+		approveErr := devices.Approve(ctx, typed, user.ID)
+		now = now.Add(5 * time.Second)
+		token, err := devices.Poll(ctx, "tv-app", deviceCode)
+		_, replayErr := devices.Poll(ctx, "tv-app", deviceCode)
+
+		// Assert
+		Expect(strings.HasPrefix(deviceCode, domain.DeviceCodePrefix)).To(BeTrue())
+		Expect(auth.UserCode).To(MatchRegexp("^[" + domain.UserCodeAlphabet + "]{8}$"))
+		Expect(auth.ExpiresAt).To(Equal(now.Add(-5 * time.Second).Add(10 * time.Minute)))
+		Expect(errors.Is(pendingErr, domain.ErrAuthorizationPending)).To(BeTrue())
+		Expect(lookupErr).NotTo(HaveOccurred())
+		Expect(pending.UserCode).To(Equal(auth.UserCode))
+		Expect(client.Name).To(Equal("Smart TV"))
+		Expect(approveErr).NotTo(HaveOccurred())
+		Expect(err).NotTo(HaveOccurred())
+		claims, err := users.ValidateToken(ctx, token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims["sub"]).To(Equal(user.ID))
+		Expect(jwt.HasAudience(claims, "tv-app")).To(BeTrue())
+		Expect(errors.Is(replayErr, domain.ErrDeviceCodeInvalid)).To(BeTrue())
+	})
+
+	It("should ask a device that polls too fast to slow down for good", func() {
+		// Arrange
+		deviceCode, _, err := devices.Authorize(ctx, "tv-app")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Act
+		_, firstErr := devices.Poll(ctx, "tv-app", deviceCode)
+		now = now.Add(2 * time.Second)
+		_, fastErr := devices.Poll(ctx, "tv-app", deviceCode)
+		now = now.Add(7 * time.Second)
+		_, stillFastErr := devices.Poll(ctx, "tv-app", deviceCode)
+		now = now.Add(15 * time.Second)
+		_, patientErr := devices.Poll(ctx, "tv-app", deviceCode)
+
+		// Assert
+		Expect(errors.Is(firstErr, domain.ErrAuthorizationPending)).To(BeTrue())
+		Expect(errors.Is(fastErr, domain.ErrSlowDown)).To(BeTrue())
+		Expect(errors.Is(stillFastErr, domain.ErrSlowDown)).To(BeTrue())
+		Expect(errors.Is(patientErr, domain.ErrAuthorizationPending)).To(BeTrue())
+	})
+
+	It("should tell the device when the user denied it", func() {
+		// Arrange
+		deviceCode, auth, err := devices.Authorize(ctx, "tv-app")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Act
+		denyErr := devices.Deny(ctx, auth.UserCode, user.ID)
+		approveErr := devices.Approve(ctx, auth.UserCode, user.ID)
+		_, pollErr := devices.Poll(ctx, "tv-app", deviceCode)
+
+		// Assert
+		Expect(denyErr).NotTo(HaveOccurred())
+		Expect(errors.Is(approveErr, domain.ErrUserCodeInvalid)).To(BeTrue())
+		Expect(errors.Is(pollErr, domain.ErrAccessDenied)).To(BeTrue())
+	})
+
+	It("should refuse expired codes, unknown clients and codes issued to another client", func() {
+		// Arrange
+		deviceCode, auth, err := devices.Authorize(ctx, "tv-app")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Act
+		_, _, clientErr := devices.Authorize(ctx, "unknown")
+		_, otherClientErr := devices.Poll(ctx, "cli", deviceCode)
+		now = now.Add(11 * time.Minute)
+		approveErr := devices.Approve(ctx, auth.UserCode, user.ID)
+		_, expiredErr := devices.Poll(ctx, "tv-app", deviceCode)
+
+		// Assert
+		Expect(errors.Is(clientErr, domain.ErrClientNotFound)).To(BeTrue())
+		Expect(errors.Is(otherClientErr, domain.ErrDeviceCodeInvalid)).To(BeTrue())
+		Expect(errors.Is(approveErr, domain.ErrUserCodeInvalid)).To(BeTrue())
+		Expect(errors.Is(expiredErr, domain.ErrDeviceCodeExpired)).To(BeTrue())
+	})
+})
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/mailer"
+	"auth-service/src/repository"
+	"auth-service/src/secret"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MagicLinkService signs shoppers in through single-use links sent by email.
+type MagicLinkService interface {
+	// Request emails a sign-in link when the address belongs to an active
+	// account. The lookup and the email happen in the background, so neither
+	// the result nor the time it takes tells callers which emails are
+	// registered. A non-empty nonce binds the link to the requesting browser.
+	Request(ctx context.Context, email, nonce string) error
+	// Verify redeems a link and returns the token Login would mint for
+	// audience. The first redemption also marks the user's email as
+	// verified.
+	Verify(ctx context.Context, token, nonce, audience string) (string, error)
+	// Shutdown stops taking requests and waits until the links already
+	// requested are sent, or until ctx ends.
+	Shutdown(ctx context.Context) error
+}
+
+type magicLinkService struct {
+	users      UserService
+	userRepo   repository.UserRepository
+	links      repository.MagicLinkRepository
+	mailer     mailer.Mailer
+	linkURL    string
+	ttl        time.Duration
+	maxPerHour int
+	logger     *slog.Logger
+	workers    int
+	queueSize  int
+	// run hands the work of a request to the workers; tests run it inline.
+	run func(func()) error
+
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan func()
+	working sync.WaitGroup
+}
+
+type MagicLinkOption func(*magicLinkService)
+
+func WithMagicLinkTTL(ttl time.Duration) MagicLinkOption {
+	return func(s *magicLinkService) {
+		s.ttl = ttl
+	}
+}
+
+// WithMagicLinkRateLimit caps how many links one account receives per hour.
+func WithMagicLinkRateLimit(maxPerHour int) MagicLinkOption {
+	return func(s *magicLinkService) {
+		s.maxPerHour = maxPerHour
+	}
+}
+
+// WithMagicLinkWorkers sets how many links are sent at once and how many
+// requests may wait for a worker. A request that finds the queue full is
+// rejected with domain.ErrRateLimited.
+func WithMagicLinkWorkers(workers, queueSize int) MagicLinkOption {
+	return func(s *magicLinkService) {
+		s.workers = workers
+		s.queueSize = queueSize
+	}
+}
+
+func WithMagicLinkLogger(logger *slog.Logger) MagicLinkOption {
+	return func(s *magicLinkService) {
+		s.logger = logger
+	}
+}
+
+// NewMagicLinkService builds links by adding a token query parameter to
+// linkURL, the page of the storefront that calls the verify endpoint.
+func NewMagicLinkService(users UserService, userRepo repository.UserRepository, links repository.MagicLinkRepository, m mailer.Mailer, linkURL string, opts ...MagicLinkOption) MagicLinkService {
+	s := &magicLinkService{
+		users:      users,
+		userRepo:   userRepo,
+		links:      links,
+		mailer:     m,
+		linkURL:    linkURL,
+		ttl:        15 * time.Minute,
+		maxPerHour: 5,
+		logger:     slog.Default(),
+		workers:    4,
+		queueSize:  100,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.run = s.enqueue
+	s.queue = make(chan func(), s.queueSize)
+	for range s.workers {
+		s.working.Add(1)
+		go s.work()
+	}
+	return s
+}
+
+func (s *magicLinkService) Request(ctx context.Context, email, nonce string) (err error) {
+	ctx, span := tracer.Start(ctx, "MagicLinkService.Request", tracing.WithEmail(email))
+	defer func() { tracing.End(span, err) }()
+
+	email, err = domain.NormalizeEmail(email, false)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithoutCancel(ctx)
+	return s.run(func() {
+		if err := s.send(ctx, email, nonce); err != nil {
+			s.logger.ErrorContext(ctx, "failed to send magic link", "error", err)
+		}
+	})
+}
+
+func (s *magicLinkService) enqueue(fn func()) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return fmt.Errorf("Error queueing magic link: shutting down: %w", domain.ErrRateLimited)
+	}
+	select {
+	case s.queue <- fn:
+		return nil
+	default:
+		return fmt.Errorf("Error queueing magic link: %d links already waiting: %w", s.queueSize, domain.ErrRateLimited)
+	}
+}
+
+func (s *magicLinkService) work() {
+	defer s.working.Done()
+	for fn := range s.queue {
+		fn()
+	}
+}
+
+func (s *magicLinkService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.working.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Error draining magic links: %w", ctx.Err())
+	}
+}
+
+// send does the work of Request once the caller has its answer.
+func (s *magicLinkService) send(ctx context.Context, email, nonce string) (err error) {
+	ctx, span := tracer.Start(ctx, "MagicLinkService.send")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			s.logger.InfoContext(ctx, "magic link not sent", "reason", "unknown email")
+			return nil
+		}
+		return err
+	}
+	span.SetAttributes(attribute.String("enduser.id", user.ID))
+	if user.Disabled() {
+		s.logger.InfoContext(ctx, "magic link not sent", "reason", "account disabled", "user_id", user.ID)
+		return nil
+	}
+
+	now := time.Now().UTC()
+	sent, err := s.links.CountSince(ctx, user.ID, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if sent >= s.maxPerHour {
+		s.logger.WarnContext(ctx, "magic link not sent", "reason", "rate limited", "user_id", user.ID)
+		return nil
+	}
+
+	token, err := secret.Generate("ml_", 0)
+	if err != nil {
+		return err
+	}
+	link := &domain.MagicLink{
+		TokenHash: secret.Hash(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+	if nonce != "" {
+		link.NonceHash = secret.Hash(nonce)
+	}
+	if err := s.links.Create(ctx, link); err != nil {
+		return err
+	}
+
+	href, err := s.buildLink(token)
+	if err != nil {
+		return err
+	}
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to sign in. It expires in %d minutes and works only once.\n\n%s\n\nIf you did not ask for it, you can ignore this email.\n",
+			user.Name, int(s.ttl.Minutes()), href),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("Error sending magic link to user %s: %w", user.ID, err)
+	}
+	s.logger.InfoContext(ctx, "magic link sent", "user_id", user.ID)
+	return nil
+}
+
+func (s *magicLinkService) Verify(ctx context.Context, token, nonce, audience string) (jwtToken string, err error) {
+	ctx, span := tracer.Start(ctx, "MagicLinkService.Verify")
+	defer func() { tracing.End(span, err) }()
+
+	if token == "" {
+		return "", domain.ErrMagicLinkInvalid
+	}
+	nonceHash := ""
+	if nonce != "" {
+		nonceHash = secret.Hash(nonce)
+	}
+	link, err := s.links.Consume(ctx, secret.Hash(token), nonceHash, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, domain.ErrMagicLinkInvalid) {
+			s.logger.InfoContext(ctx, "magic link rejected")
+		}
+		return "", err
+	}
+	span.SetAttributes(attribute.String("enduser.id", link.UserID))
+
+	jwtToken, err = s.users.IssueToken(ctx, link.UserID, audience)
+	if err != nil {
+		return "", err
+	}
+	if err := s.markEmailVerified(ctx, link.UserID); err != nil {
+		return "", err
+	}
+	s.logger.InfoContext(ctx, "magic link redeemed", "user_id", link.UserID)
+	return jwtToken, nil
+}
+
+// markEmailVerified records that redeeming a link proved the user owns
+// their address. Only the first proof is kept, so the user repository
+// publishes user.email_verified once.
+func (s *magicLinkService) markEmailVerified(ctx context.Context, userID string) error {
+	user, err := s.userRepo.FindByIDForUpdate(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	user.EmailVerifiedAt = &now
+	return s.userRepo.Update(ctx, user)
+}
+
+func (s *magicLinkService) buildLink(token string) (string, error) {
+	u, err := url.Parse(s.linkURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid magic link URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
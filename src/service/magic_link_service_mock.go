@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MagicLinkServiceMock struct {
+	mock.Mock
+}
+
+func (m *MagicLinkServiceMock) Request(ctx context.Context, email, nonce string) error {
+	args := m.Called(ctx, email, nonce)
+	return args.Error(0)
+}
+
+func (m *MagicLinkServiceMock) Verify(ctx context.Context, token, nonce, audience string) (string, error) {
+	args := m.Called(ctx, token, nonce, audience)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MagicLinkServiceMock) Shutdown(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/mailer"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("MagicLinkService", func() {
+	var magicLinks MagicLinkService
+	var userService UserService
+	var sentMail *mailer.MailerMock
+	var outbox repository.OutboxRepository
+	var ctx context.Context
+
+	// linkToken extracts the token from the last email sent.
+	linkToken := func() string {
+		msg := sentMail.Calls[len(sentMail.Calls)-1].Arguments.Get(1).(mailer.Message)
+		for _, field := range strings.Fields(msg.Body) {
+			if u, err := url.Parse(field); err == nil && u.Query().Get("token") != "" {
+				return u.Query().Get("token")
+			}
+		}
+		Fail("no link in email body")
+		return ""
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		outbox = memory.NewOutbox()
+		users := repository.NewOutboxUser(memory.NewUser(), memory.NewTxManager(), outbox)
+		userService = NewUserService(users, "test-secret")
+		sentMail = new(mailer.MailerMock)
+		sentMail.On("Send", mock.Anything, mock.Anything).Return(nil)
+		magicLinks = NewMagicLinkService(userService, users, memory.NewMagicLink(), sentMail, "https://shop.example.com/login?from=email",
+			WithMagicLinkRateLimit(2))
+		magicLinks.(*magicLinkService).run = func(fn func()) error { fn(); return nil }
+
+		_, err := userService.Register(ctx, "Shopper", "shopper@example.com", "password123")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when the email belongs to an account", func() {
+		It("should email a link that can be exchanged once for a token", func() {
+			// Act
+			Expect(magicLinks.Request(ctx, "Shopper@Example.com", "")).To(Succeed())
+			token := linkToken()
+			jwtToken, err := magicLinks.Verify(ctx, token, "", "")
+			_, replayErr := magicLinks.Verify(ctx, token, "", "")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			claims, err := userService.ValidateToken(ctx, jwtToken)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims["email"]).To(Equal("shopper@example.com"))
+			Expect(errors.Is(replayErr, domain.ErrMagicLinkInvalid)).To(BeTrue())
+		})
+	})
+
+	Context("when links are redeemed", func() {
+		It("should verify the email and publish user.email_verified once", func() {
+			// Arrange
+			Expect(magicLinks.Request(ctx, "shopper@example.com", "")).To(Succeed())
+			first := linkToken()
+			Expect(magicLinks.Request(ctx, "shopper@example.com", "")).To(Succeed())
+			second := linkToken()
+
+			// Act
+			_, firstErr := magicLinks.Verify(ctx, first, "", "")
+			_, secondErr := magicLinks.Verify(ctx, second, "", "")
+
+			// Assert
+			Expect(firstErr).NotTo(HaveOccurred())
+			Expect(secondErr).NotTo(HaveOccurred())
+			profile, err := userService.Login(ctx, "shopper@example.com", "password123", "")
+			Expect(err).NotTo(HaveOccurred())
+			claims, err := userService.ValidateToken(ctx, profile)
+			Expect(err).NotTo(HaveOccurred())
+			user, err := userService.GetProfile(ctx, claims["sub"].(string))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.EmailVerifiedAt).NotTo(BeNil())
+			events, err := outbox.ListUndispatched(ctx, 10)
+			Expect(err).NotTo(HaveOccurred())
+			types := make([]string, 0, len(events))
+			for _, event := range events {
+				types = append(types, event.Type)
+			}
+			Expect(types).To(ConsistOf(domain.EventUserRegistered, domain.EventUserEmailVerified))
+		})
+	})
+
+	Context("when the link is bound to a browser", func() {
+		It("should require the same nonce", func() {
+			// Arrange
+			Expect(magicLinks.Request(ctx, "shopper@example.com", "browser-nonce")).To(Succeed())
+			token := linkToken()
+
+			// Act
+			_, wrongErr := magicLinks.Verify(ctx, token, "", "")
+			_, err := magicLinks.Verify(ctx, token, "browser-nonce", "")
+
+			// Assert
+			Expect(errors.Is(wrongErr, domain.ErrMagicLinkInvalid)).To(BeTrue())
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when the email is unknown", func() {
+		It("should succeed without sending anything", func() {
+			Expect(magicLinks.Request(ctx, "nobody@example.com", "")).To(Succeed())
+			sentMail.AssertNotCalled(GinkgoT(), "Send", mock.Anything, mock.Anything)
+		})
+	})
+
+	Context("when the mailer fails", func() {
+		It("should answer as it does for an unknown email", func() {
+			// Arrange
+			failing := new(mailer.MailerMock)
+			failing.On("Send", mock.Anything, mock.Anything).Return(errors.New("smtp unavailable"))
+			users := memory.NewUser()
+			_, err := NewUserService(users, "test-secret").Register(ctx, "Shopper", "shopper@example.com", "password123")
+			Expect(err).NotTo(HaveOccurred())
+			service := NewMagicLinkService(userService, users, memory.NewMagicLink(), failing, "https://shop.example.com/login")
+			service.(*magicLinkService).run = func(fn func()) error { fn(); return nil }
+
+			// Act
+			err = service.Request(ctx, "shopper@example.com", "")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			failing.AssertNumberOfCalls(GinkgoT(), "Send", 1)
+		})
+	})
+
+	Context("when the same email asks too often", func() {
+		It("should stop sending links for the hour", func() {
+			for i := 0; i < 3; i++ {
+				Expect(magicLinks.Request(ctx, "shopper@example.com", "")).To(Succeed())
+			}
+			sentMail.AssertNumberOfCalls(GinkgoT(), "Send", 2)
+		})
+	})
+
+	Context("when links are sent in the background", func() {
+		It("should turn requests away once the queue is full and send the queued ones on shutdown", func() {
+			// Arrange
+			started := make(chan struct{}, 2)
+			release := make(chan struct{})
+			slow := new(mailer.MailerMock)
+			slow.On("Send", mock.Anything, mock.Anything).
+				Run(func(mock.Arguments) { started <- struct{}{}; <-release }).
+				Return(nil)
+			users := memory.NewUser()
+			_, err := NewUserService(users, "test-secret").Register(ctx, "Shopper", "shopper@example.com", "password123")
+			Expect(err).NotTo(HaveOccurred())
+			service := NewMagicLinkService(userService, users, memory.NewMagicLink(), slow, "https://shop.example.com/login",
+				WithMagicLinkWorkers(1, 1))
+			Expect(service.Request(ctx, "shopper@example.com", "")).To(Succeed())
+			<-started
+
+			// Act
+			queuedErr := service.Request(ctx, "shopper@example.com", "")
+			rejectedErr := service.Request(ctx, "shopper@example.com", "")
+			close(release)
+			shutdownErr := service.Shutdown(ctx)
+			afterErr := service.Request(ctx, "shopper@example.com", "")
+
+			// Assert
+			Expect(queuedErr).NotTo(HaveOccurred())
+			Expect(errors.Is(rejectedErr, domain.ErrRateLimited)).To(BeTrue())
+			Expect(shutdownErr).NotTo(HaveOccurred())
+			Expect(errors.Is(afterErr, domain.ErrRateLimited)).To(BeTrue())
+			slow.AssertNumberOfCalls(GinkgoT(), "Send", 2)
+		})
+	})
+})
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/secret"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PersonalAccessTokenService manages the long-lived tokens users create for
+// scripts and integrations.
+type PersonalAccessTokenService interface {
+	// Create returns the token itself, which is never shown again, next to
+	// its stored record. A nil expiresAt uses the default lifetime.
+	Create(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (string, *domain.PersonalAccessToken, error)
+	List(ctx context.Context, userID string) ([]*domain.PersonalAccessToken, error)
+	Revoke(ctx context.Context, userID, id string) error
+	// Authenticate resolves a presented token to its user. Like session
+	// JWTs, tokens stop working when the user is disabled or has their
+	// tokens revoked; it also records when the token was last used.
+	Authenticate(ctx context.Context, token string) (*domain.User, *domain.PersonalAccessToken, error)
+}
+
+type personalAccessTokenService struct {
+	users      repository.UserRepository
+	tokens     repository.PersonalAccessTokenRepository
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+	logger     *slog.Logger
+}
+
+// lastUsedPrecision throttles MarkUsed so a busy token does not write on
+// every request.
+const lastUsedPrecision = time.Minute
+
+type PersonalAccessTokenOption func(*personalAccessTokenService)
+
+// WithPersonalAccessTokenTTL sets the lifetime of tokens created without an
+// expiry and the longest lifetime a caller may ask for.
+func WithPersonalAccessTokenTTL(defaultTTL, maxTTL time.Duration) PersonalAccessTokenOption {
+	return func(s *personalAccessTokenService) {
+		s.defaultTTL = defaultTTL
+		s.maxTTL = maxTTL
+	}
+}
+
+func WithPersonalAccessTokenLogger(logger *slog.Logger) PersonalAccessTokenOption {
+	return func(s *personalAccessTokenService) {
+		s.logger = logger
+	}
+}
+
+func NewPersonalAccessTokenService(users repository.UserRepository, tokens repository.PersonalAccessTokenRepository, opts ...PersonalAccessTokenOption) PersonalAccessTokenService {
+	s := &personalAccessTokenService{
+		users:      users,
+		tokens:     tokens,
+		defaultTTL: 90 * 24 * time.Hour,
+		maxTTL:     365 * 24 * time.Hour,
+		logger:     slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *personalAccessTokenService) Create(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (token string, pat *domain.PersonalAccessToken, err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenService.Create", tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, domain.ErrTokenNameRequired
+	}
+	if len(scopes) == 0 {
+		return "", nil, domain.ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !domain.ValidScope(scope) {
+			return "", nil, fmt.Errorf("Error creating personal access token: %w: %q", domain.ErrInvalidScope, scope)
+		}
+	}
+	now := time.Now().UTC()
+	expiry := now.Add(s.defaultTTL)
+	if expiresAt != nil {
+		expiry = expiresAt.UTC()
+	}
+	if !expiry.After(now) || expiry.After(now.Add(s.maxTTL)) {
+		return "", nil, domain.ErrInvalidExpiry
+	}
+
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if user.Disabled() {
+		return "", nil, domain.ErrAccountDisabled
+	}
+
+	token, err = secret.Generate(domain.PersonalAccessTokenPrefix, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	pat = &domain.PersonalAccessToken{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		Name:      name,
+		TokenHash: secret.Hash(token),
+		Scopes:    slices.Compact(scopes),
+		CreatedAt: now,
+		ExpiresAt: expiry,
+	}
+	if err := s.tokens.Create(ctx, pat); err != nil {
+		return "", nil, err
+	}
+	s.logger.InfoContext(ctx, "personal access token created", "user_id", user.ID, "token_id", pat.ID, "scopes", pat.Scopes)
+	return token, pat, nil
+}
+
+func (s *personalAccessTokenService) List(ctx context.Context, userID string) (_ []*domain.PersonalAccessToken, err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenService.List", tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	return s.tokens.ListByUser(ctx, userID)
+}
+
+func (s *personalAccessTokenService) Revoke(ctx context.Context, userID, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenService.Revoke", tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.tokens.Revoke(ctx, userID, id, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "personal access token revoked", "user_id", userID, "token_id", id)
+	return nil
+}
+
+func (s *personalAccessTokenService) Authenticate(ctx context.Context, token string) (_ *domain.User, _ *domain.PersonalAccessToken, err error) {
+	ctx, span := tracer.Start(ctx, "PersonalAccessTokenService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	if !strings.HasPrefix(token, domain.PersonalAccessTokenPrefix) {
+		return nil, nil, domain.ErrInvalidToken
+	}
+	pat, err := s.tokens.FindByHash(ctx, secret.Hash(token))
+	if err != nil {
+		if errors.Is(err, domain.ErrTokenNotFound) {
+			return nil, nil, domain.ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+	span.SetAttributes(attribute.String("enduser.id", pat.UserID))
+	now := time.Now().UTC()
+	if pat.RevokedAt != nil || pat.Expired(now) {
+		s.logger.DebugContext(ctx, "personal access token rejected", "token_id", pat.ID, "reason", "revoked or expired")
+		return nil, nil, domain.ErrInvalidToken
+	}
+
+	user, err := s.users.FindByID(ctx, pat.UserID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, nil, domain.ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+	if user.Disabled() {
+		return nil, nil, domain.ErrAccountDisabled
+	}
+	if user.TokensValidAfter != nil && pat.CreatedAt.Before(*user.TokensValidAfter) {
+		return nil, nil, domain.ErrTokenRevoked
+	}
+
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) >= lastUsedPrecision {
+		if err := s.tokens.MarkUsed(ctx, pat.ID, now); err != nil {
+			s.logger.WarnContext(ctx, "failed to record personal access token use", "token_id", pat.ID, "error", err)
+		} else {
+			pat.LastUsedAt = &now
+		}
+	}
+	return user, pat, nil
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type PersonalAccessTokenServiceMock struct {
+	mock.Mock
+}
+
+func (m *PersonalAccessTokenServiceMock) Create(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (string, *domain.PersonalAccessToken, error) {
+	args := m.Called(ctx, userID, name, scopes, expiresAt)
+	token, _ := args.Get(1).(*domain.PersonalAccessToken)
+	return args.String(0), token, args.Error(2)
+}
+
+func (m *PersonalAccessTokenServiceMock) List(ctx context.Context, userID string) ([]*domain.PersonalAccessToken, error) {
+	args := m.Called(ctx, userID)
+	tokens, _ := args.Get(0).([]*domain.PersonalAccessToken)
+	return tokens, args.Error(1)
+}
+
+func (m *PersonalAccessTokenServiceMock) Revoke(ctx context.Context, userID, id string) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *PersonalAccessTokenServiceMock) Authenticate(ctx context.Context, token string) (*domain.User, *domain.PersonalAccessToken, error) {
+	args := m.Called(ctx, token)
+	user, _ := args.Get(0).(*domain.User)
+	pat, _ := args.Get(1).(*domain.PersonalAccessToken)
+	return user, pat, args.Error(2)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"auth-service/src/secret"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PersonalAccessTokenService", func() {
+	var tokens PersonalAccessTokenService
+	var userService UserService
+	var tokenRepo repository.PersonalAccessTokenRepository
+	var user *domain.User
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		users := memory.NewUser()
+		tokenRepo = memory.NewPersonalAccessToken()
+		userService = NewUserService(users, "test-secret")
+		tokens = NewPersonalAccessTokenService(users, tokenRepo, WithPersonalAccessTokenTTL(24*time.Hour, 30*24*time.Hour))
+
+		var err error
+		user, err = userService.Register(ctx, "Developer", "dev@example.com", "password123")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when creating a token", func() {
+		It("should return a prefixed token that authenticates as the user", func() {
+			// Act
+			token, pat, err := tokens.Create(ctx, user.ID, " ci ", []string{domain.ScopeProfileRead}, nil)
+			authUser, authPAT, authErr := tokens.Authenticate(ctx, token)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.HasPrefix(token, domain.PersonalAccessTokenPrefix)).To(BeTrue())
+			Expect(pat.Name).To(Equal("ci"))
+			Expect(pat.TokenHash).NotTo(ContainSubstring(token))
+			Expect(pat.ExpiresAt).To(BeTemporally("~", time.Now().Add(24*time.Hour), time.Minute))
+			Expect(authErr).NotTo(HaveOccurred())
+			Expect(authUser.ID).To(Equal(user.ID))
+			Expect(authPAT.Scopes).To(Equal([]string{domain.ScopeProfileRead}))
+		})
+
+		It("should reject a missing name, unknown scopes and bad expiries", func() {
+			// Arrange
+			past := time.Now().Add(-time.Minute)
+			tooFar := time.Now().Add(31 * 24 * time.Hour)
+
+			// Act
+			_, _, nameErr := tokens.Create(ctx, user.ID, "", []string{domain.ScopeProfileRead}, nil)
+			_, _, noScopeErr := tokens.Create(ctx, user.ID, "ci", nil, nil)
+			_, _, scopeErr := tokens.Create(ctx, user.ID, "ci", []string{"admin"}, nil)
+			_, _, pastErr := tokens.Create(ctx, user.ID, "ci", []string{domain.ScopeProfileRead}, &past)
+			_, _, farErr := tokens.Create(ctx, user.ID, "ci", []string{domain.ScopeProfileRead}, &tooFar)
+
+			// Assert
+			Expect(errors.Is(nameErr, domain.ErrTokenNameRequired)).To(BeTrue())
+			Expect(errors.Is(noScopeErr, domain.ErrInvalidScope)).To(BeTrue())
+			Expect(errors.Is(scopeErr, domain.ErrInvalidScope)).To(BeTrue())
+			Expect(errors.Is(pastErr, domain.ErrInvalidExpiry)).To(BeTrue())
+			Expect(errors.Is(farErr, domain.ErrInvalidExpiry)).To(BeTrue())
+		})
+	})
+
+	Context("when authenticating", func() {
+		It("should reject unknown, revoked and expired tokens", func() {
+			// Arrange
+			revoked, revokedPAT, err := tokens.Create(ctx, user.ID, "old", []string{domain.ScopeProfileRead}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tokens.Revoke(ctx, user.ID, revokedPAT.ID)).To(Succeed())
+			expired := domain.PersonalAccessTokenPrefix + "expired"
+			Expect(tokenRepo.Create(ctx, &domain.PersonalAccessToken{
+				ID: "expired", UserID: user.ID, Name: "expired", TokenHash: secret.Hash(expired),
+				CreatedAt: time.Now().Add(-2 * time.Hour), ExpiresAt: time.Now().Add(-time.Hour),
+			})).To(Succeed())
+
+			// Act
+			_, _, unknownErr := tokens.Authenticate(ctx, domain.PersonalAccessTokenPrefix+"unknown")
+			_, _, jwtErr := tokens.Authenticate(ctx, "eyJhbGciOi.not.pat")
+			_, _, revokedErr := tokens.Authenticate(ctx, revoked)
+			_, _, expiredErr := tokens.Authenticate(ctx, expired)
+
+			// Assert
+			Expect(errors.Is(unknownErr, domain.ErrInvalidToken)).To(BeTrue())
+			Expect(errors.Is(jwtErr, domain.ErrInvalidToken)).To(BeTrue())
+			Expect(errors.Is(revokedErr, domain.ErrInvalidToken)).To(BeTrue())
+			Expect(errors.Is(expiredErr, domain.ErrInvalidToken)).To(BeTrue())
+		})
+
+		It("should stop accepting tokens once the user is disabled or has tokens revoked", func() {
+			// Arrange
+			token, _, err := tokens.Create(ctx, user.ID, "ci", []string{domain.ScopeProfileRead}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			time.Sleep(1100 * time.Millisecond)
+			Expect(userService.RevokeTokens(ctx, user.ID)).To(Succeed())
+
+			// Act
+			_, _, revokedErr := tokens.Authenticate(ctx, token)
+			Expect(userService.DisableUser(ctx, user.ID)).To(Succeed())
+			_, _, disabledErr := tokens.Authenticate(ctx, token)
+
+			// Assert
+			Expect(errors.Is(revokedErr, domain.ErrTokenRevoked)).To(BeTrue())
+			Expect(errors.Is(disabledErr, domain.ErrAccountDisabled)).To(BeTrue())
+		})
+
+		It("should record when the token was last used", func() {
+			// Arrange
+			token, _, err := tokens.Create(ctx, user.ID, "ci", []string{domain.ScopeProfileRead}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			_, _, err = tokens.Authenticate(ctx, token)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			list, err := tokens.List(ctx, user.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(1))
+			Expect(list[0].LastUsedAt).NotTo(BeNil())
+		})
+	})
+
+	Context("when revoking", func() {
+		It("should only revoke the user's own tokens", func() {
+			// Arrange
+			_, pat, err := tokens.Create(ctx, user.ID, "ci", []string{domain.ScopeProfileRead}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			other, err := userService.Register(ctx, "Other", "other@example.com", "password123")
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			otherErr := tokens.Revoke(ctx, other.ID, pat.ID)
+			err = tokens.Revoke(ctx, user.ID, pat.ID)
+
+			// Assert
+			Expect(errors.Is(otherErr, domain.ErrTokenNotFound)).To(BeTrue())
+			Expect(err).NotTo(HaveOccurred())
+			list, err := tokens.List(ctx, user.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/metrics"
+	"auth-service/src/repository"
+	"auth-service/src/samlsp"
+	"auth-service/src/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SAMLService signs users in through SAML 2.0 identity providers, mapping
+// each asserted subject to a local account.
+type SAMLService interface {
+	// Metadata returns our SP metadata for the named IdP.
+	Metadata(ctx context.Context, provider string) ([]byte, error)
+	// Start returns the IdP URL to send the browser to, and the values the
+	// caller must keep (in a cookie) until the response is posted back,
+	// among them the audience the token will be minted for.
+	Start(ctx context.Context, provider, audience string) (string, *PendingSAMLLogin, error)
+	// Consume checks the posted SAMLResponse and returns the token Login
+	// would mint. pending is what Start returned for this browser, or nil
+	// for an IdP-initiated login, which gets the default audience.
+	Consume(ctx context.Context, provider, samlResponse string, pending *PendingSAMLLogin) (string, error)
+}
+
+// PendingSAMLLogin binds a response to the AuthnRequest of this browser.
+type PendingSAMLLogin struct {
+	Provider  string `json:"provider"`
+	RequestID string `json:"requestId"`
+	Audience  string `json:"audience,omitempty"`
+}
+
+// SAMLConnection is one IdP and how its accounts map to local ones.
+type SAMLConnection struct {
+	Provider *samlsp.Provider
+	Policy   AuthProviderPolicy
+}
+
+type samlService struct {
+	users       UserService
+	userRepo    repository.UserRepository
+	identities  repository.IdentityRepository
+	connections map[string]SAMLConnection
+	metrics     metrics.Recorder
+	logger      *slog.Logger
+	// lowercaseEmails mirrors the UserService setting for accounts created here.
+	lowercaseEmails bool
+}
+
+type SAMLOption func(*samlService)
+
+func WithSAMLLogger(logger *slog.Logger) SAMLOption {
+	return func(s *samlService) {
+		s.logger = logger
+	}
+}
+
+func WithSAMLMetrics(recorder metrics.Recorder) SAMLOption {
+	return func(s *samlService) {
+		s.metrics = recorder
+	}
+}
+
+func WithSAMLLowercaseEmails(lowercase bool) SAMLOption {
+	return func(s *samlService) {
+		s.lowercaseEmails = lowercase
+	}
+}
+
+func NewSAMLService(users UserService, userRepo repository.UserRepository, identities repository.IdentityRepository, connections []SAMLConnection, opts ...SAMLOption) SAMLService {
+	s := &samlService{
+		users:       users,
+		userRepo:    userRepo,
+		identities:  identities,
+		connections: make(map[string]SAMLConnection, len(connections)),
+		metrics:     metrics.NewNoop(),
+		logger:      slog.Default(),
+	}
+	for _, c := range connections {
+		s.connections[c.Provider.Name()] = c
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *samlService) Metadata(ctx context.Context, providerName string) ([]byte, error) {
+	conn, ok := s.connections[providerName]
+	if !ok {
+		return nil, fmt.Errorf("Error reading SAML metadata for %q: %w", providerName, domain.ErrUnknownProvider)
+	}
+	return conn.Provider.Metadata()
+}
+
+func (s *samlService) Start(ctx context.Context, providerName, audience string) (authURL string, pending *PendingSAMLLogin, err error) {
+	ctx, span := tracer.Start(ctx, "SAMLService.Start", tracing.WithProvider(providerName))
+	defer func() { tracing.End(span, err) }()
+
+	conn, ok := s.connections[providerName]
+	if !ok {
+		return "", nil, fmt.Errorf("Error starting SAML sign-in with %q: %w", providerName, domain.ErrUnknownProvider)
+	}
+	authURL, requestID, err := conn.Provider.AuthnRequestURL(ctx, "")
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to start SAML login", "provider", providerName, "error", err)
+		return "", nil, fmt.Errorf("Error starting SAML sign-in with %s: %w", providerName, domain.ErrSAMLLoginFailed)
+	}
+	return authURL, &PendingSAMLLogin{Provider: providerName, RequestID: requestID, Audience: audience}, nil
+}
+
+func (s *samlService) Consume(ctx context.Context, providerName, samlResponse string, pending *PendingSAMLLogin) (token string, err error) {
+	ctx, span := tracer.Start(ctx, "SAMLService.Consume", tracing.WithProvider(providerName))
+	defer func() { tracing.End(span, err) }()
+
+	conn, ok := s.connections[providerName]
+	if !ok {
+		return "", fmt.Errorf("Error completing SAML sign-in with %q: %w", providerName, domain.ErrUnknownProvider)
+	}
+	if samlResponse == "" {
+		return "", fmt.Errorf("Error completing SAML sign-in with %s: %w", providerName, domain.ErrSAMLLoginFailed)
+	}
+	var requestIDs []string
+	audience := ""
+	if pending != nil && pending.Provider == providerName {
+		requestIDs = []string{pending.RequestID}
+		audience = pending.Audience
+	}
+
+	account, err := conn.Provider.Consume(ctx, samlResponse, requestIDs)
+	if err != nil {
+		if errors.Is(err, samlsp.ErrInvalidResponse) || errors.Is(err, samlsp.ErrReplayed) {
+			s.logger.WarnContext(ctx, "SAML login rejected", "provider", providerName, "error", err)
+			return "", fmt.Errorf("Error completing SAML sign-in with %s: %w", providerName, domain.ErrSAMLLoginFailed)
+		}
+		s.logger.ErrorContext(ctx, "SAML login failed", "provider", providerName, "error", err)
+		return "", err
+	}
+
+	linker := &accountLinker{
+		provider:        "saml:" + providerName,
+		users:           s.userRepo,
+		identities:      s.identities,
+		policy:          conn.Policy,
+		lowercaseEmails: s.lowercaseEmails,
+		metrics:         s.metrics,
+		logger:          s.logger,
+	}
+	user, err := linker.resolve(ctx, account)
+	if err != nil {
+		s.logger.InfoContext(ctx, "SAML login rejected", "provider", providerName, "error", err)
+		return "", err
+	}
+	span.SetAttributes(attribute.String("enduser.id", user.ID))
+	return s.users.IssueToken(ctx, user.ID, audience)
+}
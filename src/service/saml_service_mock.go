@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SAMLServiceMock struct {
+	mock.Mock
+}
+
+func (m *SAMLServiceMock) Metadata(ctx context.Context, provider string) ([]byte, error) {
+	args := m.Called(ctx, provider)
+	metadata, _ := args.Get(0).([]byte)
+	return metadata, args.Error(1)
+}
+
+func (m *SAMLServiceMock) Start(ctx context.Context, provider, audience string) (string, *PendingSAMLLogin, error) {
+	args := m.Called(ctx, provider, audience)
+	pending, _ := args.Get(1).(*PendingSAMLLogin)
+	return args.String(0), pending, args.Error(2)
+}
+
+func (m *SAMLServiceMock) Consume(ctx context.Context, provider, samlResponse string, pending *PendingSAMLLogin) (string, error) {
+	args := m.Called(ctx, provider, samlResponse, pending)
+	return args.String(0), args.Error(1)
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"auth-service/src/samlsp"
+	"auth-service/src/test_artefacts/samlidp"
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SAMLService", func() {
+	var idp *samlidp.IdP
+	var userService UserService
+	var users repository.UserRepository
+	var identities repository.IdentityRepository
+	var ctx context.Context
+
+	alice := samlidp.User{NameID: "00u-alice", Email: "Alice@Example.com", Name: "Alice", Groups: []string{"shop-admins"}}
+
+	newService := func(policy AuthProviderPolicy) SAMLService {
+		key, cert := samlidp.KeyPair("auth.example.com")
+		provider, err := samlsp.New(samlsp.Config{
+			Name:        "acme",
+			BaseURL:     "https://auth.example.com",
+			IDPMetadata: idp.Metadata(),
+			Key:         key,
+			Certificate: cert,
+			GroupRoles:  map[string]string{"shop-admins": domain.RoleAdmin},
+			Assertions:  memory.NewSAMLAssertion(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return NewSAMLService(userService, users, identities, []SAMLConnection{{Provider: provider, Policy: policy}})
+	}
+
+	// signIn runs the whole browser round trip against the test IdP.
+	signIn := func(svc SAMLService, user samlidp.User) (string, error) {
+		authURL, pending, err := svc.Start(ctx, "acme", "")
+		Expect(err).NotTo(HaveOccurred())
+		metadata, err := svc.Metadata(ctx, "acme")
+		Expect(err).NotTo(HaveOccurred())
+		response, err := idp.Respond(authURL, metadata, user)
+		Expect(err).NotTo(HaveOccurred())
+		return svc.Consume(ctx, "acme", response, pending)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		idp = samlidp.New()
+		users = memory.NewUser()
+		identities = memory.NewIdentity()
+		userService = NewUserService(users, "test-secret")
+	})
+
+	Context("when provisioning is enabled", func() {
+		It("should create the account with the mapped role and reuse it on the next login", func() {
+			// Arrange
+			svc := newService(AuthProviderPolicy{Provision: true})
+
+			// Act
+			first, err := signIn(svc, alice)
+			Expect(err).NotTo(HaveOccurred())
+			second, err := signIn(svc, alice)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Assert
+			firstClaims, err := userService.ValidateToken(ctx, first)
+			Expect(err).NotTo(HaveOccurred())
+			secondClaims, err := userService.ValidateToken(ctx, second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secondClaims["sub"]).To(Equal(firstClaims["sub"]))
+
+			user, err := users.FindByID(ctx, firstClaims["sub"].(string))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.Email).To(Equal("Alice@example.com"))
+			Expect(user.Role).To(Equal(domain.RoleAdmin))
+
+			identity, err := identities.FindByProviderSubject(ctx, "saml:acme", "00u-alice")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(identity.UserID).To(Equal(user.ID))
+		})
+
+		It("should demote the account once the IdP drops the admin group", func() {
+			// Arrange
+			svc := newService(AuthProviderPolicy{Provision: true})
+			_, err := signIn(svc, alice)
+			Expect(err).NotTo(HaveOccurred())
+			demoted := alice
+			demoted.Groups = nil
+
+			// Act
+			token, err := signIn(svc, demoted)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			claims, err := userService.ValidateToken(ctx, token)
+			Expect(err).NotTo(HaveOccurred())
+			user, err := users.FindByID(ctx, claims["sub"].(string))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.Role).To(Equal(domain.RoleUser))
+		})
+	})
+
+	Context("when a local account already uses the email", func() {
+		BeforeEach(func() {
+			_, err := userService.Register(ctx, "Alice", "alice@example.com", "password123")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should refuse to link unless the policy allows it", func() {
+			// Arrange
+			svc := newService(AuthProviderPolicy{Provision: true})
+
+			// Act
+			_, err := signIn(svc, alice)
+
+			// Assert
+			Expect(errors.Is(err, domain.ErrAccountLinkRequired)).To(BeTrue())
+		})
+
+		It("should link to the existing account when the policy allows it", func() {
+			// Arrange
+			svc := newService(AuthProviderPolicy{LinkExistingEmail: true})
+
+			// Act
+			token, err := signIn(svc, alice)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			claims, err := userService.ValidateToken(ctx, token)
+			Expect(err).NotTo(HaveOccurred())
+			existing, err := users.FindByEmail(ctx, "alice@example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims["sub"]).To(Equal(existing.ID))
+		})
+	})
+
+	It("should not create accounts when provisioning is disabled", func() {
+		// Arrange
+		svc := newService(AuthProviderPolicy{})
+
+		// Act
+		_, err := signIn(svc, alice)
+
+		// Assert
+		Expect(errors.Is(err, domain.ErrInvalidCredentials)).To(BeTrue())
+		_, err = users.FindByEmail(ctx, "alice@example.com")
+		Expect(errors.Is(err, domain.ErrUserNotFound)).To(BeTrue())
+	})
+
+	It("should reject a response meant for another browser's request", func() {
+		// Arrange
+		svc := newService(AuthProviderPolicy{Provision: true})
+		authURL, _, err := svc.Start(ctx, "acme", "")
+		Expect(err).NotTo(HaveOccurred())
+		metadata, err := svc.Metadata(ctx, "acme")
+		Expect(err).NotTo(HaveOccurred())
+		response, err := idp.Respond(authURL, metadata, alice)
+		Expect(err).NotTo(HaveOccurred())
+		_, otherPending, err := svc.Start(ctx, "acme", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		// Act
+		_, err = svc.Consume(ctx, "acme", response, otherPending)
+
+		// Assert
+		Expect(errors.Is(err, domain.ErrSAMLLoginFailed)).To(BeTrue())
+	})
+
+	It("should reject a replayed response", func() {
+		// Arrange
+		svc := newService(AuthProviderPolicy{Provision: true})
+		authURL, pending, err := svc.Start(ctx, "acme", "")
+		Expect(err).NotTo(HaveOccurred())
+		metadata, err := svc.Metadata(ctx, "acme")
+		Expect(err).NotTo(HaveOccurred())
+		response, err := idp.Respond(authURL, metadata, alice)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = svc.Consume(ctx, "acme", response, pending)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Act
+		_, err = svc.Consume(ctx, "acme", response, pending)
+
+		// Assert
+		Expect(errors.Is(err, domain.ErrSAMLLoginFailed)).To(BeTrue())
+	})
+
+	It("should reject unknown IdPs", func() {
+		// Arrange
+		svc := newService(AuthProviderPolicy{Provision: true})
+
+		// Act
+		_, _, err := svc.Start(ctx, "other", "")
+
+		// Assert
+		Expect(errors.Is(err, domain.ErrUnknownProvider)).To(BeTrue())
+	})
+})
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/scim"
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SCIMServiceMock struct {
+	mock.Mock
+}
+
+func (m *SCIMServiceMock) Authenticate(ctx context.Context, token string) (*domain.SCIMTenant, error) {
+	args := m.Called(ctx, token)
+	tenant, _ := args.Get(0).(*domain.SCIMTenant)
+	return tenant, args.Error(1)
+}
+
+func (m *SCIMServiceMock) CreateTenant(ctx context.Context, name string) (*domain.SCIMTenant, string, error) {
+	args := m.Called(ctx, name)
+	tenant, _ := args.Get(0).(*domain.SCIMTenant)
+	return tenant, args.String(1), args.Error(2)
+}
+
+func (m *SCIMServiceMock) ListTenants(ctx context.Context) ([]*domain.SCIMTenant, error) {
+	args := m.Called(ctx)
+	tenants, _ := args.Get(0).([]*domain.SCIMTenant)
+	return tenants, args.Error(1)
+}
+
+func (m *SCIMServiceMock) CreateUser(ctx context.Context, tenantID string, user *scim.User) (*scim.User, error) {
+	args := m.Called(ctx, tenantID, user)
+	created, _ := args.Get(0).(*scim.User)
+	return created, args.Error(1)
+}
+
+func (m *SCIMServiceMock) GetUser(ctx context.Context, tenantID, userID string) (*scim.User, error) {
+	args := m.Called(ctx, tenantID, userID)
+	user, _ := args.Get(0).(*scim.User)
+	return user, args.Error(1)
+}
+
+func (m *SCIMServiceMock) ListUsers(ctx context.Context, tenantID string, filter *scim.Filter, startIndex, count int) (*scim.ListResponse[*scim.User], error) {
+	args := m.Called(ctx, tenantID, filter, startIndex, count)
+	list, _ := args.Get(0).(*scim.ListResponse[*scim.User])
+	return list, args.Error(1)
+}
+
+func (m *SCIMServiceMock) ReplaceUser(ctx context.Context, tenantID, userID string, user *scim.User) (*scim.User, error) {
+	args := m.Called(ctx, tenantID, userID, user)
+	replaced, _ := args.Get(0).(*scim.User)
+	return replaced, args.Error(1)
+}
+
+func (m *SCIMServiceMock) PatchUser(ctx context.Context, tenantID, userID string, ops []scim.PatchOperation) (*scim.User, error) {
+	args := m.Called(ctx, tenantID, userID, ops)
+	user, _ := args.Get(0).(*scim.User)
+	return user, args.Error(1)
+}
+
+func (m *SCIMServiceMock) DeleteUser(ctx context.Context, tenantID, userID string) error {
+	args := m.Called(ctx, tenantID, userID)
+	return args.Error(0)
+}
+
+func (m *SCIMServiceMock) CreateGroup(ctx context.Context, tenantID string, group *scim.Group) (*scim.Group, error) {
+	args := m.Called(ctx, tenantID, group)
+	created, _ := args.Get(0).(*scim.Group)
+	return created, args.Error(1)
+}
+
+func (m *SCIMServiceMock) GetGroup(ctx context.Context, tenantID, groupID string) (*scim.Group, error) {
+	args := m.Called(ctx, tenantID, groupID)
+	group, _ := args.Get(0).(*scim.Group)
+	return group, args.Error(1)
+}
+
+func (m *SCIMServiceMock) ListGroups(ctx context.Context, tenantID string, filter *scim.Filter, startIndex, count int) (*scim.ListResponse[*scim.Group], error) {
+	args := m.Called(ctx, tenantID, filter, startIndex, count)
+	list, _ := args.Get(0).(*scim.ListResponse[*scim.Group])
+	return list, args.Error(1)
+}
+
+func (m *SCIMServiceMock) ReplaceGroup(ctx context.Context, tenantID, groupID string, group *scim.Group) (*scim.Group, error) {
+	args := m.Called(ctx, tenantID, groupID, group)
+	replaced, _ := args.Get(0).(*scim.Group)
+	return replaced, args.Error(1)
+}
+
+func (m *SCIMServiceMock) PatchGroup(ctx context.Context, tenantID, groupID string, ops []scim.PatchOperation) (*scim.Group, error) {
+	args := m.Called(ctx, tenantID, groupID, ops)
+	group, _ := args.Get(0).(*scim.Group)
+	return group, args.Error(1)
+}
+
+func (m *SCIMServiceMock) DeleteGroup(ctx context.Context, tenantID, groupID string) error {
+	args := m.Called(ctx, tenantID, groupID)
+	return args.Error(0)
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"auth-service/src/scim"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SCIMService", func() {
+	var userService UserService
+	var users repository.UserRepository
+	var svc SCIMService
+	var tenant, otherTenant *domain.SCIMTenant
+	var token string
+	var ctx context.Context
+
+	newUser := func(userName string) *scim.User {
+		return &scim.User{
+			Schemas:    []string{scim.UserSchema},
+			UserName:   userName,
+			ExternalID: "ext-" + userName,
+			Name:       &scim.Name{GivenName: "Ana", FamilyName: "Silva"},
+		}
+	}
+
+	patch := func(raw string) []scim.PatchOperation {
+		var req scim.PatchRequest
+		Expect(json.Unmarshal([]byte(raw), &req)).To(Succeed())
+		return req.Operations
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		users = memory.NewUser()
+		userService = NewUserService(users, "test-secret")
+		svc = NewSCIMService(userService, users, memory.NewSCIM(), memory.NewTxManager())
+
+		var err error
+		tenant, token, err = svc.CreateTenant(ctx, "acme")
+		Expect(err).NotTo(HaveOccurred())
+		otherTenant, _, err = svc.CreateTenant(ctx, "globex")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when authenticating", func() {
+		It("should accept only the tenant's token", func() {
+			// Act
+			found, err := svc.Authenticate(ctx, token)
+			_, wrongErr := svc.Authenticate(ctx, "scim_wrong")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.ID).To(Equal(tenant.ID))
+			Expect(errors.Is(wrongErr, domain.ErrInvalidToken)).To(BeTrue())
+		})
+	})
+
+	Context("when provisioning a user", func() {
+		It("should create a passwordless account owned by the tenant", func() {
+			// Act
+			created, err := svc.CreateUser(ctx, tenant.ID, newUser("Ana@Example.com"))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created.UserName).To(Equal("Ana@example.com"))
+			Expect(created.DisplayName).To(Equal("Ana Silva"))
+			Expect(created.ExternalID).To(Equal("ext-Ana@Example.com"))
+			Expect(created.IsActive()).To(BeTrue())
+
+			account, err := users.FindByID(ctx, created.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(account.PasswordHash).To(BeEmpty())
+			_, otherErr := svc.GetUser(ctx, otherTenant.ID, created.ID)
+			Expect(errors.Is(otherErr, domain.ErrUserNotFound)).To(BeTrue())
+		})
+
+		It("should let the user log in with the password the tenant set", func() {
+			// Arrange
+			resource := newUser("ana@example.com")
+			resource.Password = "correct-horse"
+
+			// Act
+			_, err := svc.CreateUser(ctx, tenant.ID, resource)
+			Expect(err).NotTo(HaveOccurred())
+			_, loginErr := userService.Login(ctx, "ana@example.com", "correct-horse", "")
+
+			// Assert
+			Expect(loginErr).NotTo(HaveOccurred())
+		})
+
+		It("should report a conflict for an email that is already registered", func() {
+			// Arrange
+			_, err := svc.CreateUser(ctx, tenant.ID, newUser("ana@example.com"))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = userService.Register(ctx, "Bob", "bob@example.com", "password123")
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			_, retryErr := svc.CreateUser(ctx, tenant.ID, newUser("ana@example.com"))
+			_, takenErr := svc.CreateUser(ctx, tenant.ID, newUser("bob@example.com"))
+
+			// Assert
+			Expect(errors.Is(retryErr, domain.ErrSCIMUserExists)).To(BeTrue())
+			Expect(errors.Is(takenErr, domain.ErrEmailAlreadyExists)).To(BeTrue())
+		})
+	})
+
+	Context("when listing users", func() {
+		BeforeEach(func() {
+			for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+				_, err := svc.CreateUser(ctx, tenant.ID, newUser(email))
+				Expect(err).NotTo(HaveOccurred())
+				time.Sleep(time.Millisecond)
+			}
+			_, err := svc.CreateUser(ctx, otherTenant.ID, newUser("d@example.com"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should page through the tenant's users", func() {
+			// Act
+			page, err := svc.ListUsers(ctx, tenant.ID, nil, 2, 2)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(page.TotalResults).To(Equal(3))
+			Expect(page.StartIndex).To(Equal(2))
+			Expect(page.ItemsPerPage).To(Equal(2))
+			Expect(page.Resources[0].UserName).To(Equal("b@example.com"))
+			Expect(page.Resources[1].UserName).To(Equal("c@example.com"))
+		})
+
+		It("should filter on userName within the tenant", func() {
+			// Act
+			found, err := svc.ListUsers(ctx, tenant.ID, &scim.Filter{Attribute: "userName", Value: "B@example.com"}, 1, 100)
+			foreign, foreignErr := svc.ListUsers(ctx, tenant.ID, &scim.Filter{Attribute: "userName", Value: "d@example.com"}, 1, 100)
+			_, badErr := svc.ListUsers(ctx, tenant.ID, &scim.Filter{Attribute: "emails", Value: "a@example.com"}, 1, 100)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.TotalResults).To(Equal(1))
+			Expect(found.Resources[0].UserName).To(Equal("b@example.com"))
+			Expect(foreignErr).NotTo(HaveOccurred())
+			Expect(foreign.TotalResults).To(BeZero())
+			Expect(errors.Is(badErr, scim.ErrInvalidFilter)).To(BeTrue())
+		})
+	})
+
+	Context("when updating a user", func() {
+		var userID string
+		var session string
+
+		BeforeEach(func() {
+			resource := newUser("ana@example.com")
+			resource.Password = "correct-horse"
+			created, err := svc.CreateUser(ctx, tenant.ID, resource)
+			Expect(err).NotTo(HaveOccurred())
+			userID = created.ID
+			session, err = userService.Login(ctx, "ana@example.com", "correct-horse", "")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should disable the account and revoke its sessions when deactivated", func() {
+			// Act
+			updated, err := svc.PatchUser(ctx, tenant.ID, userID, patch(`{"Operations":[{"op":"Replace","path":"active","value":"False"}]}`))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.IsActive()).To(BeFalse())
+			_, validateErr := userService.ValidateToken(ctx, session)
+			Expect(errors.Is(validateErr, domain.ErrAccountDisabled)).To(BeTrue())
+			_, loginErr := userService.Login(ctx, "ana@example.com", "correct-horse", "")
+			Expect(errors.Is(loginErr, domain.ErrAccountDisabled)).To(BeTrue())
+		})
+
+		It("should let the account log in again when reactivated", func() {
+			// Arrange
+			_, err := svc.PatchUser(ctx, tenant.ID, userID, patch(`{"Operations":[{"op":"replace","path":"active","value":false}]}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			updated, err := svc.PatchUser(ctx, tenant.ID, userID, patch(`{"Operations":[{"op":"replace","path":"active","value":true}]}`))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.IsActive()).To(BeTrue())
+			_, loginErr := userService.Login(ctx, "ana@example.com", "correct-horse", "")
+			Expect(loginErr).NotTo(HaveOccurred())
+		})
+
+		It("should not re-enable an account it did not deactivate", func() {
+			// Arrange
+			Expect(userService.DisableUser(ctx, userID)).To(Succeed())
+			_, err := svc.PatchUser(ctx, tenant.ID, userID, patch(`{"Operations":[{"op":"replace","path":"active","value":false}]}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			updated, err := svc.PatchUser(ctx, tenant.ID, userID, patch(`{"Operations":[{"op":"replace","path":"active","value":true}]}`))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.IsActive()).To(BeFalse())
+			_, loginErr := userService.Login(ctx, "ana@example.com", "correct-horse", "")
+			Expect(errors.Is(loginErr, domain.ErrAccountDisabled)).To(BeTrue())
+		})
+
+		It("should rename the account and change its email on replace", func() {
+			// Arrange
+			resource := newUser("ana.silva@example.com")
+			resource.DisplayName = "Ana Maria Silva"
+			resource.ExternalID = "new-ext"
+
+			// Act
+			updated, err := svc.ReplaceUser(ctx, tenant.ID, userID, resource)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.UserName).To(Equal("ana.silva@example.com"))
+			Expect(updated.DisplayName).To(Equal("Ana Maria Silva"))
+			Expect(updated.ExternalID).To(Equal("new-ext"))
+			_, loginErr := userService.Login(ctx, "ana.silva@example.com", "correct-horse", "")
+			Expect(loginErr).NotTo(HaveOccurred())
+		})
+
+		It("should not touch users of another tenant", func() {
+			// Act
+			_, err := svc.PatchUser(ctx, otherTenant.ID, userID, patch(`{"Operations":[{"op":"replace","path":"active","value":false}]}`))
+
+			// Assert
+			Expect(errors.Is(err, domain.ErrUserNotFound)).To(BeTrue())
+			account, _ := users.FindByID(ctx, userID)
+			Expect(account.Disabled()).To(BeFalse())
+		})
+	})
+
+	Context("when deprovisioning a user", func() {
+		It("should disable the account, revoke its sessions and hide it from the tenant", func() {
+			// Arrange
+			resource := newUser("ana@example.com")
+			resource.Password = "correct-horse"
+			created, err := svc.CreateUser(ctx, tenant.ID, resource)
+			Expect(err).NotTo(HaveOccurred())
+			session, err := userService.Login(ctx, "ana@example.com", "correct-horse", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			err = svc.DeleteUser(ctx, tenant.ID, created.ID)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			account, err := users.FindByID(ctx, created.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(account.Disabled()).To(BeTrue())
+			_, validateErr := userService.ValidateToken(ctx, session)
+			Expect(validateErr).To(HaveOccurred())
+			_, getErr := svc.GetUser(ctx, tenant.ID, created.ID)
+			Expect(errors.Is(getErr, domain.ErrUserNotFound)).To(BeTrue())
+		})
+	})
+
+	Context("when managing groups", func() {
+		var ana, bob string
+
+		BeforeEach(func() {
+			created, err := svc.CreateUser(ctx, tenant.ID, newUser("ana@example.com"))
+			Expect(err).NotTo(HaveOccurred())
+			ana = created.ID
+			created, err = svc.CreateUser(ctx, tenant.ID, newUser("bob@example.com"))
+			Expect(err).NotTo(HaveOccurred())
+			bob = created.ID
+		})
+
+		It("should create a group and patch its members", func() {
+			// Arrange
+			group, err := svc.CreateGroup(ctx, tenant.ID, &scim.Group{DisplayName: "Engineering", Members: []scim.Member{{Value: ana}}})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			updated, err := svc.PatchGroup(ctx, tenant.ID, group.ID, patch(`{"Operations":[
+				{"op":"add","path":"members","value":[{"value":"`+bob+`"}]},
+				{"op":"remove","path":"members[value eq \"`+ana+`\"]"}
+			]}`))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.MemberIDs()).To(Equal([]string{bob}))
+		})
+
+		It("should reject members the tenant did not provision", func() {
+			// Arrange
+			foreign, err := svc.CreateUser(ctx, otherTenant.ID, newUser("carol@example.com"))
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			_, err = svc.CreateGroup(ctx, tenant.ID, &scim.Group{DisplayName: "Engineering", Members: []scim.Member{{Value: foreign.ID}}})
+
+			// Assert
+			Expect(errors.Is(err, scim.ErrInvalidValue)).To(BeTrue())
+		})
+
+		It("should find groups by display name", func() {
+			// Arrange
+			_, err := svc.CreateGroup(ctx, tenant.ID, &scim.Group{DisplayName: "Engineering"})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = svc.CreateGroup(ctx, tenant.ID, &scim.Group{DisplayName: "Sales"})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			found, err := svc.ListGroups(ctx, tenant.ID, &scim.Filter{Attribute: "displayName", Value: "Sales"}, 1, 100)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.TotalResults).To(Equal(1))
+			Expect(found.Resources[0].DisplayName).To(Equal("Sales"))
+		})
+
+		It("should drop a deprovisioned user from its groups", func() {
+			// Arrange
+			group, err := svc.CreateGroup(ctx, tenant.ID, &scim.Group{DisplayName: "Engineering", Members: []scim.Member{{Value: ana}, {Value: bob}}})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			Expect(svc.DeleteUser(ctx, tenant.ID, ana)).To(Succeed())
+			found, err := svc.GetGroup(ctx, tenant.ID, group.ID)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.MemberIDs()).To(Equal([]string{bob}))
+		})
+	})
+})
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/metrics"
+	"auth-service/src/repository"
+	"auth-service/src/secret"
+	"auth-service/src/social"
+	"auth-service/src/tracing"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SocialLoginService signs users in through external providers and maps each
+// external identity to a local account.
+type SocialLoginService interface {
+	// Start returns the provider URL to send the browser to, and the values
+	// the caller must keep (in a cookie) until the callback, among them the
+	// audience the token will be minted for.
+	Start(ctx context.Context, provider, audience string) (string, *PendingLogin, error)
+	// Callback redeems the authorization code and returns the token Login
+	// would mint. pending is what Start returned for this browser.
+	Callback(ctx context.Context, provider, code, state string, pending *PendingLogin) (string, error)
+}
+
+// PendingLogin is the per-attempt secret state between Start and Callback.
+type PendingLogin struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	Audience string `json:"audience,omitempty"`
+}
+
+type socialLoginService struct {
+	users      UserService
+	userRepo   repository.UserRepository
+	identities repository.IdentityRepository
+	providers  map[string]social.Provider
+	metrics    metrics.Recorder
+	logger     *slog.Logger
+	// lowercaseEmails mirrors the UserService setting for accounts created here.
+	lowercaseEmails bool
+}
+
+type SocialLoginOption func(*socialLoginService)
+
+func WithSocialLoginLogger(logger *slog.Logger) SocialLoginOption {
+	return func(s *socialLoginService) {
+		s.logger = logger
+	}
+}
+
+func WithSocialLoginMetrics(recorder metrics.Recorder) SocialLoginOption {
+	return func(s *socialLoginService) {
+		s.metrics = recorder
+	}
+}
+
+func WithSocialLoginLowercaseEmails(lowercase bool) SocialLoginOption {
+	return func(s *socialLoginService) {
+		s.lowercaseEmails = lowercase
+	}
+}
+
+func NewSocialLoginService(users UserService, userRepo repository.UserRepository, identities repository.IdentityRepository, providers []social.Provider, opts ...SocialLoginOption) SocialLoginService {
+	s := &socialLoginService{
+		users:      users,
+		userRepo:   userRepo,
+		identities: identities,
+		providers:  make(map[string]social.Provider, len(providers)),
+		metrics:    metrics.NewNoop(),
+		logger:     slog.Default(),
+	}
+	for _, p := range providers {
+		s.providers[p.Name()] = p
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *socialLoginService) Start(ctx context.Context, providerName, audience string) (authURL string, pending *PendingLogin, err error) {
+	ctx, span := tracer.Start(ctx, "SocialLoginService.Start", tracing.WithProvider(providerName))
+	defer func() { tracing.End(span, err) }()
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", nil, fmt.Errorf("Error starting sign-in with %q: %w", providerName, domain.ErrUnknownProvider)
+	}
+
+	pending = &PendingLogin{Provider: providerName, Audience: audience}
+	for _, value := range []*string{&pending.State, &pending.Verifier, &pending.Nonce} {
+		if *value, err = secret.Generate("", 0); err != nil {
+			return "", nil, err
+		}
+	}
+	authURL, err = provider.AuthCodeURL(ctx, pending.State, pending.Verifier, pending.Nonce)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to start social login", "provider", providerName, "error", err)
+		return "", nil, fmt.Errorf("Error starting sign-in with %s: %w", providerName, domain.ErrSocialLoginFailed)
+	}
+	return authURL, pending, nil
+}
+
+func (s *socialLoginService) Callback(ctx context.Context, providerName, code, state string, pending *PendingLogin) (token string, err error) {
+	ctx, span := tracer.Start(ctx, "SocialLoginService.Callback", tracing.WithProvider(providerName))
+	defer func() { tracing.End(span, err) }()
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", fmt.Errorf("Error completing sign-in with %q: %w", providerName, domain.ErrUnknownProvider)
+	}
+	if code == "" || pending == nil || pending.Provider != providerName ||
+		subtle.ConstantTimeCompare([]byte(state), []byte(pending.State)) != 1 {
+		s.logger.InfoContext(ctx, "social login rejected", "provider", providerName, "reason", "state mismatch")
+		return "", fmt.Errorf("Error completing sign-in with %s: %w", providerName, domain.ErrSocialLoginFailed)
+	}
+
+	external, err := provider.Exchange(ctx, code, pending.Verifier, pending.Nonce)
+	if err != nil {
+		s.logger.WarnContext(ctx, "social login rejected", "provider", providerName, "error", err)
+		return "", fmt.Errorf("Error completing sign-in with %s: %w", providerName, domain.ErrSocialLoginFailed)
+	}
+
+	// Social accounts carry no role, so new users get the default one and
+	// linked users keep theirs. An existing account is only linked when the
+	// provider is trusted to auto-link; otherwise the user must sign in
+	// locally first.
+	linker := &accountLinker{
+		provider:        external.Provider,
+		users:           s.userRepo,
+		identities:      s.identities,
+		policy:          AuthProviderPolicy{Provision: true, LinkExistingEmail: provider.AutoLinkVerifiedEmail()},
+		lowercaseEmails: s.lowercaseEmails,
+		metrics:         s.metrics,
+		logger:          s.logger,
+		keepProfile:     true,
+	}
+	user, err := linker.resolve(ctx, &domain.DirectoryAccount{
+		Subject:         external.Subject,
+		Email:           external.Email,
+		Name:            external.Name,
+		Role:            domain.RoleUser,
+		EmailUnverified: !external.EmailVerified,
+		EmailVerified:   external.EmailVerified,
+	})
+	if err != nil {
+		s.logger.InfoContext(ctx, "social login rejected", "provider", providerName, "error", err)
+		return "", err
+	}
+	span.SetAttributes(attribute.String("enduser.id", user.ID))
+	return s.users.IssueToken(ctx, user.ID, pending.Audience)
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SocialLoginServiceMock struct {
+	mock.Mock
+}
+
+func (m *SocialLoginServiceMock) Start(ctx context.Context, provider, audience string) (string, *PendingLogin, error) {
+	args := m.Called(ctx, provider, audience)
+	pending, _ := args.Get(1).(*PendingLogin)
+	return args.String(0), pending, args.Error(2)
+}
+
+func (m *SocialLoginServiceMock) Callback(ctx context.Context, provider, code, state string, pending *PendingLogin) (string, error) {
+	args := m.Called(ctx, provider, code, state, pending)
+	return args.String(0), args.Error(1)
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"auth-service/src/social"
+	"auth-service/src/test_artefacts/idp"
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SocialLoginService", func() {
+	var stub *idp.Server
+	var userService UserService
+	var users repository.UserRepository
+	var identities repository.IdentityRepository
+	var ctx context.Context
+
+	newService := func(autoLink bool) SocialLoginService {
+		provider, err := social.New(social.Config{
+			Name: "stub", Type: "oidc", Issuer: stub.URL,
+			ClientID: idp.ClientID, ClientSecret: idp.ClientSecret,
+			RedirectURL:           "https://auth.example.com/auth/stub/callback",
+			AutoLinkVerifiedEmail: autoLink,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return NewSocialLoginService(userService, users, identities, []social.Provider{provider})
+	}
+
+	// signIn runs the whole browser round trip against the stub IdP.
+	signIn := func(svc SocialLoginService) (string, error) {
+		authURL, pending, err := svc.Start(ctx, "stub", "")
+		Expect(err).NotTo(HaveOccurred())
+		code, state, err := stub.Authorize(authURL)
+		Expect(err).NotTo(HaveOccurred())
+		return svc.Callback(ctx, "stub", code, state, pending)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		stub = idp.New()
+		DeferCleanup(stub.Close)
+		users = memory.NewUser()
+		identities = memory.NewIdentity()
+		userService = NewUserService(users, "test-secret")
+	})
+
+	Context("when no account uses the verified email", func() {
+		It("should create an account and sign into it again on the next login", func() {
+			// Arrange
+			svc := newService(false)
+
+			// Act
+			first, err := signIn(svc)
+			Expect(err).NotTo(HaveOccurred())
+			second, err := signIn(svc)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Assert
+			firstClaims, err := userService.ValidateToken(ctx, first)
+			Expect(err).NotTo(HaveOccurred())
+			secondClaims, err := userService.ValidateToken(ctx, second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(firstClaims["email"]).To(Equal("stub@example.com"))
+			Expect(secondClaims["sub"]).To(Equal(firstClaims["sub"]))
+
+			linked, err := identities.ListByUser(ctx, firstClaims["sub"].(string))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(linked).To(HaveLen(1))
+			Expect(linked[0].Subject).To(Equal("stub-user-1"))
+			user, err := users.FindByEmail(ctx, "stub@example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.EmailVerifiedAt).NotTo(BeNil())
+		})
+
+		It("should leave the new account without a usable password", func() {
+			// Arrange
+			svc := newService(false)
+			_, err := signIn(svc)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			_, err = userService.Login(ctx, "stub@example.com", "", "")
+
+			// Assert
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when an account already uses the verified email", func() {
+		var existing *domain.User
+
+		BeforeEach(func() {
+			var err error
+			existing, err = userService.Register(ctx, "Local", "Stub@Example.com", "password123")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should require the user to link it when auto-linking is off", func() {
+			// Act
+			_, err := signIn(newService(false))
+
+			// Assert
+			Expect(errors.Is(err, domain.ErrAccountLinkRequired)).To(BeTrue())
+			linked, _ := identities.ListByUser(ctx, existing.ID)
+			Expect(linked).To(BeEmpty())
+		})
+
+		It("should link it when the provider is trusted to auto-link", func() {
+			// Act
+			token, err := signIn(newService(true))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			claims, err := userService.ValidateToken(ctx, token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims["sub"]).To(Equal(existing.ID))
+			user, err := users.FindByEmail(ctx, "stub@example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.EmailVerifiedAt).NotTo(BeNil())
+		})
+	})
+
+	Context("when the provider has not verified the email", func() {
+		It("should neither create nor link an account", func() {
+			// Arrange
+			stub.SetUser(idp.User{Subject: "unverified", Email: "someone@example.com"})
+
+			// Act
+			_, err := signIn(newService(true))
+
+			// Assert
+			Expect(errors.Is(err, domain.ErrEmailNotVerified)).To(BeTrue())
+			_, err = users.FindByEmail(ctx, "someone@example.com")
+			Expect(errors.Is(err, domain.ErrUserNotFound)).To(BeTrue())
+		})
+	})
+
+	Context("when the callback does not match the login attempt", func() {
+		It("should reject a different state", func() {
+			// Arrange
+			svc := newService(false)
+			authURL, pending, err := svc.Start(ctx, "stub", "")
+			Expect(err).NotTo(HaveOccurred())
+			code, _, err := stub.Authorize(authURL)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			_, err = svc.Callback(ctx, "stub", code, "forged-state", pending)
+
+			// Assert
+			Expect(errors.Is(err, domain.ErrSocialLoginFailed)).To(BeTrue())
+		})
+
+		It("should reject a callback without a pending login", func() {
+			_, err := newService(false).Callback(ctx, "stub", "code", "state", nil)
+			Expect(errors.Is(err, domain.ErrSocialLoginFailed)).To(BeTrue())
+		})
+	})
+
+	It("should reject unknown providers", func() {
+		svc := newService(false)
+
+		_, _, err := svc.Start(ctx, "myspace", "")
+		Expect(errors.Is(err, domain.ErrUnknownProvider)).To(BeTrue())
+
+		_, err = svc.Callback(ctx, "myspace", "code", "state", &PendingLogin{Provider: "myspace"})
+		Expect(errors.Is(err, domain.ErrUnknownProvider)).To(BeTrue())
+	})
+})
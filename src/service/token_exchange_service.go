@@ -0,0 +1,205 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/jwt"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TokenExchangeService implements OAuth 2.0 token exchange (RFC 8693). The
+// tokens it issues act as a user on someone else's behalf, record that
+// someone in the act claim and expire quickly.
+type TokenExchangeService interface {
+	// Exchange lets support staff impersonate a customer, with their own
+	// session as the actor token, or lets an allowed internal service act on
+	// behalf of the user whose token it received.
+	Exchange(ctx context.Context, req domain.TokenExchange) (*domain.ExchangedToken, error)
+}
+
+type tokenExchangeService struct {
+	users     UserService
+	userRepo  repository.UserRepository
+	tokens    jwt.TokenIssuer
+	ttl       time.Duration
+	delegates []string
+	logger    *slog.Logger
+	now       func() time.Time
+}
+
+type TokenExchangeOption func(*tokenExchangeService)
+
+// WithTokenExchangeTTL caps the lifetime of exchanged tokens. They never
+// outlive the token they were exchanged for either.
+func WithTokenExchangeTTL(ttl time.Duration) TokenExchangeOption {
+	return func(s *tokenExchangeService) {
+		s.ttl = ttl
+	}
+}
+
+// WithTokenExchangeDelegates names the internal API keys whose services may
+// act on behalf of users. Without it no service can.
+func WithTokenExchangeDelegates(names ...string) TokenExchangeOption {
+	return func(s *tokenExchangeService) {
+		s.delegates = names
+	}
+}
+
+func WithTokenExchangeLogger(logger *slog.Logger) TokenExchangeOption {
+	return func(s *tokenExchangeService) {
+		s.logger = logger
+	}
+}
+
+func NewTokenExchangeService(users UserService, userRepo repository.UserRepository, tokens jwt.TokenIssuer, opts ...TokenExchangeOption) TokenExchangeService {
+	s := &tokenExchangeService{
+		users:    users,
+		userRepo: userRepo,
+		tokens:   tokens,
+		ttl:      15 * time.Minute,
+		logger:   slog.Default(),
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *tokenExchangeService) Exchange(ctx context.Context, req domain.TokenExchange) (token *domain.ExchangedToken, err error) {
+	ctx, span := tracer.Start(ctx, "TokenExchangeService.Exchange")
+	defer func() { tracing.End(span, err) }()
+
+	if !exchangeableTokenType(req.SubjectToken, req.SubjectTokenType) || !exchangeableTokenType(req.ActorToken, req.ActorTokenType) {
+		return nil, domain.ErrInvalidExchange
+	}
+	switch {
+	case req.Caller != nil:
+		span.SetAttributes(attribute.String("auth.caller", req.Caller.Name))
+		token, err = s.delegate(ctx, req)
+	case req.ActorToken != "":
+		token, err = s.impersonate(ctx, req)
+	default:
+		return nil, domain.ErrInvalidExchange
+	}
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("enduser.id", token.UserID))
+	return token, nil
+}
+
+// delegate issues a token for the subject token's user acting through the
+// calling service. Exchanging an already exchanged token keeps its actors,
+// so the chain shows every hop.
+func (s *tokenExchangeService) delegate(ctx context.Context, req domain.TokenExchange) (*domain.ExchangedToken, error) {
+	if req.SubjectToken == "" || req.ActorToken != "" || req.RequestedSubject != "" {
+		return nil, domain.ErrInvalidExchange
+	}
+	if !slices.Contains(s.delegates, req.Caller.Name) {
+		s.logger.WarnContext(ctx, "token exchange refused", "reason", "service may not act on behalf of users", "caller", req.Caller.Name)
+		return nil, domain.ErrExchangeNotPermitted
+	}
+
+	claims, err := s.users.ValidateToken(ctx, req.SubjectToken)
+	if err != nil {
+		s.logger.InfoContext(ctx, "token exchange refused", "reason", "invalid subject token", "caller", req.Caller.Name, "error", err)
+		return nil, fmt.Errorf("Error validating subject token: %w", domain.ErrInvalidGrant)
+	}
+	previous, err := jwt.Actor(claims)
+	if err != nil {
+		return nil, fmt.Errorf("Error validating subject token: %w", domain.ErrInvalidGrant)
+	}
+	userID, _ := claims["sub"].(string)
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := &domain.Actor{Subject: req.Caller.Name, Type: domain.ActorService, Actor: previous}
+	token, err := s.issue(ctx, user, actor, jwt.ExpiresAt(claims), req.Audience)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "token exchanged on behalf of user", "caller", req.Caller.Name, "user_id", user.ID, "actors", len(actor.Chain()), "expires_at", token.ExpiresAt)
+	return token, nil
+}
+
+// impersonate issues a token for the requested user acting through the
+// actor token's user, who needs PermissionImpersonate. Administrators and
+// other impersonators cannot be impersonated, and an exchanged token cannot
+// be used to impersonate anyone.
+func (s *tokenExchangeService) impersonate(ctx context.Context, req domain.TokenExchange) (*domain.ExchangedToken, error) {
+	if req.RequestedSubject == "" || req.SubjectToken != "" {
+		return nil, domain.ErrInvalidExchange
+	}
+
+	claims, err := s.users.ValidateToken(ctx, req.ActorToken)
+	if err != nil {
+		s.logger.InfoContext(ctx, "token exchange refused", "reason", "invalid actor token", "error", err)
+		return nil, fmt.Errorf("Error validating actor token: %w", domain.ErrInvalidGrant)
+	}
+	actorID, _ := claims["sub"].(string)
+	if previous, err := jwt.Actor(claims); err != nil || previous != nil {
+		s.logger.WarnContext(ctx, "token exchange refused", "reason", "actor token was itself exchanged", "actor_id", actorID, "user_id", req.RequestedSubject)
+		return nil, domain.ErrExchangeNotPermitted
+	}
+	agent, err := s.userRepo.FindByID(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !agent.Can(domain.PermissionImpersonate) {
+		s.logger.WarnContext(ctx, "token exchange refused", "reason", "actor may not impersonate", "actor_id", agent.ID, "user_id", req.RequestedSubject)
+		return nil, domain.ErrExchangeNotPermitted
+	}
+
+	user, err := s.userRepo.FindByID(ctx, req.RequestedSubject)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == agent.ID || user.Role == domain.RoleAdmin || user.Can(domain.PermissionImpersonate) {
+		s.logger.WarnContext(ctx, "token exchange refused", "reason", "user may not be impersonated", "actor_id", agent.ID, "user_id", user.ID)
+		return nil, domain.ErrExchangeNotPermitted
+	}
+	if user.Disabled() {
+		return nil, domain.ErrAccountDisabled
+	}
+
+	actor := &domain.Actor{Subject: agent.ID, Type: domain.ActorUser, Email: agent.Email}
+	token, err := s.issue(ctx, user, actor, jwt.ExpiresAt(claims), req.Audience)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "user impersonated", "actor_id", agent.ID, "user_id", user.ID, "expires_at", token.ExpiresAt)
+	return token, nil
+}
+
+// issue signs the token for audience, expiring after the configured TTL or
+// with the exchanged token, whichever comes first.
+func (s *tokenExchangeService) issue(ctx context.Context, user *domain.User, actor *domain.Actor, notAfter time.Time, audience string) (*domain.ExchangedToken, error) {
+	expiresAt := s.now().Add(s.ttl).Truncate(time.Second)
+	if !notAfter.IsZero() && notAfter.Before(expiresAt) {
+		expiresAt = notAfter
+	}
+	accessToken, err := s.tokens.Issue(ctx, user, actor, expiresAt, jwt.ForAudience(audience))
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ExchangedToken{AccessToken: accessToken, ExpiresAt: expiresAt, UserID: user.ID, Actor: actor}, nil
+}
+
+// exchangeableTokenType accepts the JWT and access token types for a token
+// that was sent, and an empty type only for one that was not.
+func exchangeableTokenType(token, tokenType string) bool {
+	if token == "" {
+		return true
+	}
+	return tokenType == domain.TokenTypeAccessToken || tokenType == domain.TokenTypeJWT
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type TokenExchangeServiceMock struct {
+	mock.Mock
+}
+
+func (m *TokenExchangeServiceMock) Exchange(ctx context.Context, req domain.TokenExchange) (*domain.ExchangedToken, error) {
+	args := m.Called(ctx, req)
+	token, _ := args.Get(0).(*domain.ExchangedToken)
+	return token, args.Error(1)
+}
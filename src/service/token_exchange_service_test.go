@@ -0,0 +1,167 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/jwt"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TokenExchangeService", func() {
+	var exchange TokenExchangeService
+	var users UserService
+	var repo repository.UserRepository
+	var keys jwt.KeyProvider
+	var ctx context.Context
+	var agent, customer, admin *domain.User
+	orders := &domain.APIKey{ID: "key-orders", Name: "orders"}
+
+	newUser := func(email, role string) *domain.User {
+		user := stubs.NewUserStub().WithEmail(email).Get()
+		user.Role = role
+		Expect(repo.Create(ctx, user)).To(Succeed())
+		return user
+	}
+
+	sessionOf := func(user *domain.User) string {
+		token, err := jwt.CreateToken(user, keys, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		return token
+	}
+
+	impersonation := func(actor *domain.User, userID string) domain.TokenExchange {
+		return domain.TokenExchange{ActorToken: sessionOf(actor), ActorTokenType: domain.TokenTypeAccessToken, RequestedSubject: userID}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		repo = memory.NewUser()
+		keys = jwt.NewStaticKeys("test-secret")
+		users = NewUserService(repo, "test-secret")
+		exchange = NewTokenExchangeService(users, repo, jwt.NewJWTIssuer(keys),
+			WithTokenExchangeTTL(10*time.Minute),
+			WithTokenExchangeDelegates("orders"),
+		)
+		agent = newUser("agent@example.com", domain.RoleSupport)
+		customer = newUser("customer@example.com", domain.RoleUser)
+		admin = newUser("admin@example.com", domain.RoleAdmin)
+	})
+
+	Context("when support staff impersonate a customer", func() {
+		It("should issue a short-lived token for the customer that names the agent", func() {
+			// Act
+			token, err := exchange.Exchange(ctx, impersonation(agent, customer.ID))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token.ExpiresAt).To(BeTemporally("~", time.Now().Add(10*time.Minute), 2*time.Second))
+			claims, err := users.ValidateToken(ctx, token.AccessToken)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims["sub"]).To(Equal(customer.ID))
+			actor, err := jwt.Actor(claims)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actor).To(Equal(&domain.Actor{Subject: agent.ID, Type: domain.ActorUser, Email: agent.Email}))
+		})
+
+		It("should name only the requested audience", func() {
+			// Arrange
+			audiences := jwt.WithAudience("storefront", "orders")
+			exchange = NewTokenExchangeService(users, repo, jwt.NewJWTIssuer(keys, audiences))
+			req := impersonation(agent, customer.ID)
+			req.Audience = "orders"
+
+			// Act
+			token, err := exchange.Exchange(ctx, req)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			claims, err := jwt.ValidateToken(token.AccessToken, keys, audiences)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims["aud"]).To(Equal([]interface{}{"orders"}))
+		})
+
+		It("should refuse users without the permission and protected targets", func() {
+			// Arrange
+			otherAgent := newUser("other-agent@example.com", domain.RoleSupport)
+
+			// Act
+			_, byCustomerErr := exchange.Exchange(ctx, impersonation(customer, agent.ID))
+			_, adminErr := exchange.Exchange(ctx, impersonation(agent, admin.ID))
+			_, agentErr := exchange.Exchange(ctx, impersonation(agent, otherAgent.ID))
+			_, missingErr := exchange.Exchange(ctx, impersonation(agent, "unknown"))
+
+			// Assert
+			Expect(errors.Is(byCustomerErr, domain.ErrExchangeNotPermitted)).To(BeTrue())
+			Expect(errors.Is(adminErr, domain.ErrExchangeNotPermitted)).To(BeTrue())
+			Expect(errors.Is(agentErr, domain.ErrExchangeNotPermitted)).To(BeTrue())
+			Expect(errors.Is(missingErr, domain.ErrUserNotFound)).To(BeTrue())
+		})
+
+		It("should stop accepting the token once the agent is disabled", func() {
+			// Arrange
+			token, err := exchange.Exchange(ctx, impersonation(agent, customer.ID))
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			Expect(users.DisableUser(ctx, agent.ID)).To(Succeed())
+			_, validateErr := users.ValidateToken(ctx, token.AccessToken)
+
+			// Assert
+			Expect(errors.Is(validateErr, domain.ErrAccountDisabled)).To(BeTrue())
+		})
+
+		It("should not let an impersonation token impersonate again", func() {
+			// Arrange
+			token, err := exchange.Exchange(ctx, impersonation(agent, customer.ID))
+			Expect(err).NotTo(HaveOccurred())
+			other := newUser("other@example.com", domain.RoleUser)
+
+			// Act
+			_, err = exchange.Exchange(ctx, domain.TokenExchange{ActorToken: token.AccessToken, ActorTokenType: domain.TokenTypeJWT, RequestedSubject: other.ID})
+
+			// Assert
+			Expect(errors.Is(err, domain.ErrExchangeNotPermitted)).To(BeTrue())
+		})
+	})
+
+	Context("when a service acts on behalf of a user", func() {
+		It("should add the service in front of the existing actors", func() {
+			// Arrange
+			impersonated, err := exchange.Exchange(ctx, impersonation(agent, customer.ID))
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			token, err := exchange.Exchange(ctx, domain.TokenExchange{SubjectToken: impersonated.AccessToken, SubjectTokenType: domain.TokenTypeAccessToken, Caller: orders})
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token.UserID).To(Equal(customer.ID))
+			Expect(token.ExpiresAt).To(Equal(impersonated.ExpiresAt))
+			Expect(token.Actor.Chain()).To(HaveLen(2))
+			Expect(token.Actor.Subject).To(Equal("orders"))
+			Expect(token.Actor.Type).To(Equal(domain.ActorService))
+			Expect(token.Actor.Actor.Subject).To(Equal(agent.ID))
+		})
+
+		It("should refuse services that are not allowed to delegate and bad subject tokens", func() {
+			// Act
+			_, callerErr := exchange.Exchange(ctx, domain.TokenExchange{SubjectToken: sessionOf(customer), SubjectTokenType: domain.TokenTypeJWT, Caller: &domain.APIKey{Name: "billing"}})
+			_, tokenErr := exchange.Exchange(ctx, domain.TokenExchange{SubjectToken: "not-a-token", SubjectTokenType: domain.TokenTypeJWT, Caller: orders})
+			_, typeErr := exchange.Exchange(ctx, domain.TokenExchange{SubjectToken: sessionOf(customer), SubjectTokenType: "urn:ietf:params:oauth:token-type:saml2", Caller: orders})
+			_, missingErr := exchange.Exchange(ctx, domain.TokenExchange{Caller: orders})
+
+			// Assert
+			Expect(errors.Is(callerErr, domain.ErrExchangeNotPermitted)).To(BeTrue())
+			Expect(errors.Is(tokenErr, domain.ErrInvalidGrant)).To(BeTrue())
+			Expect(errors.Is(typeErr, domain.ErrInvalidExchange)).To(BeTrue())
+			Expect(errors.Is(missingErr, domain.ErrInvalidExchange)).To(BeTrue())
+		})
+	})
+})
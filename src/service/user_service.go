@@ -3,40 +3,155 @@ package service
 import (
 	"auth-service/src/domain"
 	"auth-service/src/jwt"
+	"auth-service/src/metrics"
 	"auth-service/src/repository"
+	"auth-service/src/tracing"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var tracer = otel.Tracer("auth-service/src/service")
+
 type UserService interface {
 	Register(ctx context.Context, name, email, password string) (*domain.User, error)
-	Login(ctx context.Context, email, password string) (string, error)
+	// Login checks the credentials and mints a token for audience, which
+	// must be one of the configured audiences; empty picks the first.
+	Login(ctx context.Context, email, password, audience string) (string, error)
 	GetProfile(ctx context.Context, userID string) (*domain.User, error)
-	ValidateToken(tokenString string) (map[string]interface{}, error)
+	ValidateToken(ctx context.Context, tokenString string) (map[string]interface{}, error)
+
+	CreateUser(ctx context.Context, name, email, password, role string) (*domain.User, error)
+	ListUsers(ctx context.Context, limit, offset int) ([]*domain.User, error)
+	SetPassword(ctx context.Context, userID, password string) error
+	DisableUser(ctx context.Context, userID string) error
+	RevokeTokens(ctx context.Context, userID string) error
+	// RevokeAccessToken revokes a single token. Only opaque tokens can be
+	// revoked this way; others are domain.ErrUnsupportedTokenType.
+	RevokeAccessToken(ctx context.Context, token string) error
+	// IssueToken mints a token for audience like Login, without a password
+	// check.
+	IssueToken(ctx context.Context, userID, audience string) (string, error)
+	// IssueClientToken mints a token like IssueToken for the default
+	// audience whose aud also names the OAuth client it was issued to.
+	IssueClientToken(ctx context.Context, userID, clientID string) (string, error)
 }
 
 type userService struct {
-	repo      repository.UserRepository
-	jwtSecret string
+	repo       repository.UserRepository
+	tokens     jwt.TokenIssuer
+	metrics    metrics.Recorder
+	logger     *slog.Logger
+	tokenTTL   time.Duration
+	bcryptCost int
+	// lowercaseEmails also lowercases the local part of stored addresses.
+	lowercaseEmails bool
+	// authProvider, when set, is asked before the local password check.
+	authProvider *authProvider
+}
+
+type Option func(*userService)
+
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(s *userService) {
+		s.tokenTTL = ttl
+	}
+}
+
+func WithBcryptCost(cost int) Option {
+	return func(s *userService) {
+		s.bcryptCost = cost
+	}
 }
 
-func NewUserService(repo repository.UserRepository, jwtSecret string) UserService {
-	return &userService{repo: repo, jwtSecret: jwtSecret}
+// WithLowercaseEmails stores addresses fully lowercased instead of only
+// lowercasing the domain.
+func WithLowercaseEmails(enabled bool) Option {
+	return func(s *userService) {
+		s.lowercaseEmails = enabled
+	}
+}
+
+// WithSigningKeys replaces the single JWT secret with a rotating key set.
+func WithSigningKeys(keys jwt.KeyProvider) Option {
+	return func(s *userService) {
+		s.tokens = jwt.NewJWTIssuer(keys)
+	}
+}
+
+// WithTokenIssuer sets the format, keys and claims of the tokens the
+// service mints and accepts.
+func WithTokenIssuer(tokens jwt.TokenIssuer) Option {
+	return func(s *userService) {
+		s.tokens = tokens
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *userService) {
+		s.logger = logger
+	}
+}
+
+func WithMetrics(recorder metrics.Recorder) Option {
+	return func(s *userService) {
+		s.metrics = recorder
+	}
+}
+
+func NewUserService(repo repository.UserRepository, jwtSecret string, opts ...Option) UserService {
+	s := &userService{
+		repo:       repo,
+		tokens:     jwt.NewJWTIssuer(jwt.NewStaticKeys(jwtSecret)),
+		metrics:    metrics.NewNoop(),
+		logger:     slog.Default(),
+		tokenTTL:   24 * time.Hour,
+		bcryptCost: bcrypt.DefaultCost,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *userService) Register(ctx context.Context, name, email, password string) (user *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Register", tracing.WithEmail(email))
+	defer func() { tracing.End(span, err) }()
+
+	return s.createUser(ctx, name, email, password, domain.RoleUser)
+}
+
+func (s *userService) CreateUser(ctx context.Context, name, email, password, role string) (user *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.CreateUser", tracing.WithEmail(email), trace.WithAttributes(attribute.String("enduser.role", role)))
+	defer func() { tracing.End(span, err) }()
+
+	if !domain.ValidRole(role) {
+		return nil, domain.ErrInvalidRole
+	}
+	return s.createUser(ctx, name, email, password, role)
 }
 
-func (s *userService) Register(ctx context.Context, name, email, password string) (*domain.User, error) {
+func (s *userService) createUser(ctx context.Context, name, email, password, role string) (*domain.User, error) {
 	if name == "" || email == "" || password == "" {
 		return nil, domain.ErrParametersMissing
 	}
 	if len(password) < 8 {
 		return nil, domain.ErrPasswordTooShort
 	}
+	email, err := domain.NormalizeEmail(email, s.lowercaseEmails)
+	if err != nil {
+		return nil, err
+	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hashPassword(ctx, password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", domain.ErrFailedHashingPassword)
 	}
@@ -46,30 +161,322 @@ func (s *userService) Register(ctx context.Context, name, email, password string
 		Name:         name,
 		Email:        email,
 		PasswordHash: string(hashedPassword),
+		Role:         role,
 		CreatedAt:    time.Now().UTC(),
 	}
 
 	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, err
 	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("enduser.id", user.ID))
+	s.metrics.UserRegistered()
+	s.logger.InfoContext(ctx, "user registered", "user_id", user.ID)
 	return user, nil
 }
 
-func (s *userService) Login(ctx context.Context, email, password string) (string, error) {
-	user, err := s.repo.FindByEmail(ctx, email)
+func (s *userService) Login(ctx context.Context, email, password, audience string) (token string, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.Login", tracing.WithEmail(email))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.authenticate(ctx, email, password)
+	if err != nil {
+		s.metrics.LoginAttempted(metrics.LoginInvalidCredentials)
+		return "", err
+	}
+	span.SetAttributes(attribute.String("enduser.id", user.ID))
+
+	if user.Disabled() {
+		s.metrics.LoginAttempted(metrics.LoginLocked)
+		s.logger.InfoContext(ctx, "login failed", "reason", "account disabled", "user_id", user.ID)
+		return "", domain.ErrAccountDisabled
+	}
+
+	if err := awaitRevocation(ctx, user); err != nil {
+		return "", err
+	}
+	token, err = s.tokens.Issue(ctx, user, nil, time.Now().Add(s.tokenTTL), jwt.ForAudience(audience))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to issue token", "user_id", user.ID, "error", err)
+		return "", err
+	}
+	s.metrics.LoginAttempted(metrics.LoginSuccess)
+	s.logger.InfoContext(ctx, "login succeeded", "user_id", user.ID)
+	return token, nil
+}
+
+// authenticate checks the credentials against the directory, when one is
+// configured, and then against the local password hash.
+func (s *userService) authenticate(ctx context.Context, login, password string) (*domain.User, error) {
+	if s.authProvider != nil {
+		name := s.authProvider.provider.Name()
+		user, err := s.directoryLogin(ctx, login, password)
+		switch {
+		case err == nil:
+			return user, nil
+		case errors.Is(err, domain.ErrAccountLinkRequired):
+			s.logger.InfoContext(ctx, "login failed", "reason", "directory account not linked", "provider", name, "error", err)
+			return nil, err
+		case errors.Is(err, domain.ErrInvalidCredentials):
+			s.logger.InfoContext(ctx, "directory rejected login", "provider", name, "error", err)
+		default:
+			s.logger.ErrorContext(ctx, "directory login failed", "provider", name, "error", err)
+		}
+		if s.authProvider.policy.Exclusive {
+			return nil, domain.ErrInvalidCredentials
+		}
+	}
+
+	user, err := s.findByEmail(ctx, login)
 	if err != nil {
-		return "", domain.ErrInvalidCredentials
+		s.logger.InfoContext(ctx, "login failed", "reason", "user lookup failed", "error", err)
+		return nil, domain.ErrInvalidCredentials
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", domain.ErrInvalidCredentials
+	if err := s.comparePassword(ctx, user.PasswordHash, password); err != nil {
+		s.logger.InfoContext(ctx, "login failed", "reason", "password mismatch", "user_id", user.ID)
+		return nil, domain.ErrInvalidCredentials
 	}
-	return jwt.CreateToken(user, s.jwtSecret)
+	return user, nil
+}
+
+// findByEmail looks an account up by the normalized form of email; a
+// malformed address simply matches no account.
+func (s *userService) findByEmail(ctx context.Context, email string) (*domain.User, error) {
+	normalized, err := domain.NormalizeEmail(email, s.lowercaseEmails)
+	if err != nil {
+		return nil, fmt.Errorf("Error when searching for user by email: %w", domain.ErrUserNotFound)
+	}
+	return s.repo.FindByEmail(ctx, normalized)
 }
 
-func (s *userService) GetProfile(ctx context.Context, userID string) (*domain.User, error) {
+func (s *userService) GetProfile(ctx context.Context, userID string) (user *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetProfile", tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.FindByID(ctx, userID)
 }
 
-func (s *userService) ValidateToken(tokenString string) (map[string]interface{}, error) {
-	return jwt.ValidateToken(tokenString, s.jwtSecret)
+func (s *userService) ValidateToken(ctx context.Context, tokenString string) (claims map[string]interface{}, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.ValidateToken")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err = s.tokens.Validate(ctx, tokenString)
+	if err == nil {
+		err = s.checkRevocation(ctx, claims)
+	}
+	switch {
+	case err == nil:
+		s.metrics.TokenValidated(metrics.TokenValid)
+	case errors.Is(err, domain.ErrTokenExpired):
+		s.metrics.TokenValidated(metrics.TokenExpired)
+	case errors.Is(err, domain.ErrTokenRevoked), errors.Is(err, domain.ErrAccountDisabled):
+		s.metrics.TokenValidated(metrics.TokenRevoked)
+	default:
+		s.metrics.TokenValidated(metrics.TokenInvalid)
+		s.logger.DebugContext(ctx, "token rejected", "error", err)
+	}
+	span.SetAttributes(attribute.Bool("auth.token.valid", err == nil))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkRevocation rejects tokens whose subject, or any user in their actor
+// chain, has since been disabled or has had their tokens revoked.
+func (s *userService) checkRevocation(ctx context.Context, claims map[string]interface{}) error {
+	userID, _ := claims["sub"].(string)
+	if err := s.checkUserRevocation(ctx, userID, claims); err != nil {
+		return err
+	}
+	actor, err := jwt.Actor(claims)
+	if err != nil {
+		return err
+	}
+	for _, a := range actor.Chain() {
+		if a.Type != domain.ActorUser {
+			continue
+		}
+		if err := s.checkUserRevocation(ctx, a.Subject, claims); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *userService) checkUserRevocation(ctx context.Context, userID string, claims map[string]interface{}) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return domain.ErrInvalidToken
+		}
+		return err
+	}
+	if user.Disabled() {
+		return domain.ErrAccountDisabled
+	}
+	if user.TokensValidAfter != nil && jwt.IssuedAt(claims).Before(*user.TokensValidAfter) {
+		return domain.ErrTokenRevoked
+	}
+	return nil
+}
+
+func (s *userService) ListUsers(ctx context.Context, limit, offset int) (users []*domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.ListUsers")
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.List(ctx, limit, offset)
+}
+
+// SetPassword replaces the password and revokes every token issued with the old one.
+func (s *userService) SetPassword(ctx context.Context, userID, password string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.SetPassword", tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	if len(password) < 8 {
+		return domain.ErrPasswordTooShort
+	}
+	user, err := s.repo.FindByIDForUpdate(ctx, userID)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := s.hashPassword(ctx, password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", domain.ErrFailedHashingPassword)
+	}
+	user.PasswordHash = string(hashedPassword)
+	user.TokensValidAfter = revocationTime()
+	if err := s.repo.Update(ctx, user); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "password changed", "user_id", user.ID)
+	return nil
+}
+
+func (s *userService) DisableUser(ctx context.Context, userID string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.DisableUser", tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.repo.FindByIDForUpdate(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Disabled() {
+		return nil
+	}
+	now := time.Now().UTC()
+	user.DisabledAt = &now
+	user.TokensValidAfter = revocationTime()
+	if err := s.repo.Update(ctx, user); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "user disabled", "user_id", user.ID)
+	return nil
+}
+
+func (s *userService) RevokeTokens(ctx context.Context, userID string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.RevokeTokens", tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.repo.FindByIDForUpdate(ctx, userID)
+	if err != nil {
+		return err
+	}
+	user.TokensValidAfter = revocationTime()
+	if err := s.repo.Update(ctx, user); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "tokens revoked", "user_id", user.ID)
+	return nil
+}
+
+func (s *userService) RevokeAccessToken(ctx context.Context, token string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.RevokeAccessToken")
+	defer func() { tracing.End(span, err) }()
+
+	revoker, ok := s.tokens.(jwt.Revoker)
+	if !ok {
+		return fmt.Errorf("Error revoking token: %w", domain.ErrUnsupportedTokenType)
+	}
+	if err := revoker.Revoke(ctx, token); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "access token revoked")
+	return nil
+}
+
+// IssueToken mints a token without a password check, for operators and
+// the passwordless logins.
+func (s *userService) IssueToken(ctx context.Context, userID, audience string) (token string, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.IssueToken", tracing.WithUserID(userID))
+	defer func() { tracing.End(span, err) }()
+
+	return s.issueToken(ctx, userID, jwt.ForAudience(audience))
+}
+
+func (s *userService) IssueClientToken(ctx context.Context, userID, clientID string) (token string, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.IssueClientToken", tracing.WithUserID(userID), tracing.WithClient(clientID))
+	defer func() { tracing.End(span, err) }()
+
+	return s.issueToken(ctx, userID, jwt.ForAudience(""), jwt.AddAudience(clientID))
+}
+
+func (s *userService) issueToken(ctx context.Context, userID string, opts ...jwt.Option) (string, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user.Disabled() {
+		return "", domain.ErrAccountDisabled
+	}
+	if err := awaitRevocation(ctx, user); err != nil {
+		return "", err
+	}
+	return s.tokens.Issue(ctx, user, nil, time.Now().Add(s.tokenTTL), opts...)
+}
+
+// revocationTime is rounded up to the next whole second because iat has
+// second precision: every token minted up to now, even earlier in the same
+// second, has an iat before it.
+func revocationTime() *time.Time {
+	t := time.Now().UTC().Truncate(time.Second).Add(time.Second)
+	return &t
+}
+
+// awaitRevocation holds back a token for user until a revocation made in
+// the current second takes effect; minted earlier, its iat would fall
+// before the cutoff and the token would be refused.
+func awaitRevocation(ctx context.Context, user *domain.User) error {
+	if user.TokensValidAfter == nil {
+		return nil
+	}
+	wait := time.Until(*user.TokensValidAfter)
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+func (s *userService) hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := tracer.Start(ctx, "bcrypt.GenerateFromPassword", tracing.WithBcryptCost(s.bcryptCost))
+	defer span.End()
+
+	start := time.Now()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.bcryptCost)
+	s.metrics.PasswordHashed(metrics.HashGenerate, time.Since(start))
+	return hash, err
+}
+
+func (s *userService) comparePassword(ctx context.Context, hash, password string) error {
+	_, span := tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
+	start := time.Now()
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	s.metrics.PasswordHashed(metrics.HashCompare, time.Since(start))
+	return err
 }
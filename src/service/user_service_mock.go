@@ -19,8 +19,8 @@ func (m *UserServiceMock) Register(ctx context.Context, name, email, password st
 	return nil, args.Error(1)
 }
 
-func (m *UserServiceMock) Login(ctx context.Context, email, password string) (string, error) {
-	args := m.Called(ctx, email, password)
+func (m *UserServiceMock) Login(ctx context.Context, email, password, audience string) (string, error) {
+	args := m.Called(ctx, email, password, audience)
 	return args.String(0), args.Error(1)
 }
 
@@ -32,10 +32,56 @@ func (m *UserServiceMock) GetProfile(ctx context.Context, userID string) (*domai
 	return nil, args.Error(1)
 }
 
-func (m *UserServiceMock) ValidateToken(tokenString string) (map[string]interface{}, error) {
-	args := m.Called(tokenString)
+func (m *UserServiceMock) ValidateToken(ctx context.Context, tokenString string) (map[string]interface{}, error) {
+	args := m.Called(ctx, tokenString)
 	if claims, ok := args.Get(0).(map[string]interface{}); ok {
 		return claims, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
+
+func (m *UserServiceMock) CreateUser(ctx context.Context, name, email, password, role string) (*domain.User, error) {
+	args := m.Called(ctx, name, email, password, role)
+	if user, ok := args.Get(0).(*domain.User); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *UserServiceMock) ListUsers(ctx context.Context, limit, offset int) ([]*domain.User, error) {
+	args := m.Called(ctx, limit, offset)
+	if users, ok := args.Get(0).([]*domain.User); ok {
+		return users, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *UserServiceMock) SetPassword(ctx context.Context, userID, password string) error {
+	args := m.Called(ctx, userID, password)
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) DisableUser(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) RevokeTokens(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) RevokeAccessToken(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *UserServiceMock) IssueToken(ctx context.Context, userID, audience string) (string, error) {
+	args := m.Called(ctx, userID, audience)
+	return args.String(0), args.Error(1)
+}
+
+func (m *UserServiceMock) IssueClientToken(ctx context.Context, userID, clientID string) (string, error) {
+	args := m.Called(ctx, userID, clientID)
+	return args.String(0), args.Error(1)
+}
@@ -1,14 +1,20 @@
 package service
 
 import (
+	"auth-service/database"
+	"auth-service/src/cache"
 	"auth-service/src/domain"
+	"auth-service/src/jwt"
+	"auth-service/src/metrics"
+	"auth-service/src/migration"
 	"auth-service/src/repository"
 	"auth-service/src/test_artefacts/seeder"
 	"auth-service/src/test_artefacts/stubs"
 	"context"
 	"errors"
-	"os"
+	"log/slog"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -16,6 +22,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestUserService(t *testing.T) {
@@ -53,10 +60,9 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).NotTo(HaveOccurred())
 
-	migration, err := os.ReadFile("../../database/000001_create_users_table.up.sql")
-	Expect(err).NotTo(HaveOccurred())
-	_, err = db.Exec(context.Background(), string(migration))
+	migrator, err := migration.New(db, database.Migrations, slog.Default())
 	Expect(err).NotTo(HaveOccurred())
+	Expect(migrator.Up(context.Background())).To(Succeed())
 })
 
 var _ = AfterSuite(func() {
@@ -95,5 +101,198 @@ var _ = Describe("UserService", func() {
 				Expect(errors.Is(err, domain.ErrEmailAlreadyExists)).To(BeTrue())
 			})
 		})
+
+		Context("when the email is malformed", func() {
+			It("should return an ErrInvalidEmail error", func() {
+				// Act
+				_, err := userService.Register(ctx, "Some User", "not-an-email", "password123")
+
+				// Assert
+				Expect(errors.Is(err, domain.ErrInvalidEmail)).To(BeTrue())
+			})
+		})
+
+		Context("when the email has mixed case and padding", func() {
+			It("should store the normalized address and log in with any casing", func() {
+				// Act
+				user, err := userService.Register(ctx, "Some User", "  Bob@Example.COM ", "password123")
+				Expect(err).NotTo(HaveOccurred())
+				_, loginErr := userService.Login(ctx, "bob@example.com", "password123", "")
+
+				// Assert
+				Expect(user.Email).To(Equal("Bob@example.com"))
+				Expect(loginErr).NotTo(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Logging in", func() {
+		var recorder *metrics.RecorderMock
+
+		BeforeEach(func() {
+			recorder = new(metrics.RecorderMock)
+			recorder.On("PasswordHashed", mock.Anything, mock.Anything).Return()
+			userService = NewUserService(repository.NewUser(db), "test-secret", WithMetrics(recorder))
+		})
+
+		Context("when the password is wrong", func() {
+			It("should record an invalid_credentials login", func() {
+				// Arrange
+				recorder.On("UserRegistered").Return()
+				recorder.On("LoginAttempted", metrics.LoginInvalidCredentials).Return()
+				_, err := userService.Register(ctx, "Login User", "login@example.com", "password123")
+				Expect(err).NotTo(HaveOccurred())
+
+				// Act
+				_, err = userService.Login(ctx, "login@example.com", "wrong-password", "")
+
+				// Assert
+				Expect(errors.Is(err, domain.ErrInvalidCredentials)).To(BeTrue())
+				recorder.AssertCalled(GinkgoT(), "UserRegistered")
+				recorder.AssertCalled(GinkgoT(), "LoginAttempted", metrics.LoginInvalidCredentials)
+				recorder.AssertCalled(GinkgoT(), "PasswordHashed", metrics.HashCompare, mock.Anything)
+			})
+		})
+
+		Context("when the credentials are correct", func() {
+			It("should record a successful login", func() {
+				// Arrange
+				recorder.On("UserRegistered").Return()
+				recorder.On("LoginAttempted", metrics.LoginSuccess).Return()
+				_, err := userService.Register(ctx, "Login User", "login@example.com", "password123")
+				Expect(err).NotTo(HaveOccurred())
+
+				// Act
+				token, err := userService.Login(ctx, "login@example.com", "password123", "")
+
+				// Assert
+				Expect(err).NotTo(HaveOccurred())
+				Expect(token).NotTo(BeEmpty())
+				recorder.AssertCalled(GinkgoT(), "LoginAttempted", metrics.LoginSuccess)
+			})
+		})
+	})
+
+	Describe("Administering accounts", func() {
+		var user *domain.User
+
+		BeforeEach(func() {
+			var err error
+			user, err = userService.Register(ctx, "Admin Target", "target@example.com", "password123")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the user is disabled", func() {
+			It("should reject logins and previously issued tokens", func() {
+				// Arrange
+				token, err := userService.Login(ctx, "target@example.com", "password123", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				// Act
+				err = userService.DisableUser(ctx, user.ID)
+
+				// Assert
+				Expect(err).NotTo(HaveOccurred())
+				_, err = userService.Login(ctx, "target@example.com", "password123", "")
+				Expect(errors.Is(err, domain.ErrAccountDisabled)).To(BeTrue())
+				_, err = userService.ValidateToken(ctx, token)
+				Expect(errors.Is(err, domain.ErrAccountDisabled)).To(BeTrue())
+			})
+		})
+
+		Context("when the user's tokens are revoked", func() {
+			It("should reject tokens issued before the revocation", func() {
+				// Arrange
+				token, err := userService.IssueToken(ctx, user.ID, "")
+				Expect(err).NotTo(HaveOccurred())
+
+				// Act
+				err = userService.RevokeTokens(ctx, user.ID)
+
+				// Assert
+				Expect(err).NotTo(HaveOccurred())
+				_, err = userService.ValidateToken(ctx, token)
+				Expect(errors.Is(err, domain.ErrTokenRevoked)).To(BeTrue())
+
+				freshToken, err := userService.IssueToken(ctx, user.ID, "")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = userService.ValidateToken(ctx, freshToken)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when a single token is revoked", func() {
+			It("should reject only that token", func() {
+				// Arrange
+				userService = NewUserService(repository.NewUser(db), "test-secret",
+					WithTokenIssuer(jwt.NewOpaqueIssuer(repository.NewAccessToken(db), cache.NewLRU(10), time.Minute, slog.Default())))
+				token, err := userService.IssueToken(ctx, user.ID, "")
+				Expect(err).NotTo(HaveOccurred())
+				other, err := userService.IssueToken(ctx, user.ID, "")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = userService.ValidateToken(ctx, token)
+				Expect(err).NotTo(HaveOccurred())
+
+				// Act
+				err = userService.RevokeAccessToken(ctx, token)
+
+				// Assert
+				Expect(err).NotTo(HaveOccurred())
+				_, err = userService.ValidateToken(ctx, token)
+				Expect(errors.Is(err, domain.ErrInvalidToken)).To(BeTrue())
+				_, err = userService.ValidateToken(ctx, other)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should refuse tokens that carry their own claims", func() {
+				// Arrange
+				token, err := userService.IssueToken(ctx, user.ID, "")
+				Expect(err).NotTo(HaveOccurred())
+
+				// Act
+				err = userService.RevokeAccessToken(ctx, token)
+
+				// Assert
+				Expect(errors.Is(err, domain.ErrUnsupportedTokenType)).To(BeTrue())
+			})
+		})
+
+		Context("when the user repository is cached", func() {
+			It("should keep the password of a user it disables or revokes", func() {
+				// Arrange
+				userService = NewUserService(repository.NewCachedUser(repository.NewUser(db), cache.NewLRU(10), time.Minute), "test-secret")
+				token, err := userService.IssueToken(ctx, user.ID, "")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = userService.ValidateToken(ctx, token)
+				Expect(err).NotTo(HaveOccurred())
+
+				// Act
+				err = userService.RevokeTokens(ctx, user.ID)
+
+				// Assert
+				Expect(err).NotTo(HaveOccurred())
+				_, err = userService.ValidateToken(ctx, token)
+				Expect(errors.Is(err, domain.ErrTokenRevoked)).To(BeTrue())
+				_, err = userService.Login(ctx, "target@example.com", "password123", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(userService.DisableUser(ctx, user.ID)).To(Succeed())
+				_, err = userService.Login(ctx, "target@example.com", "password123", "")
+				Expect(errors.Is(err, domain.ErrAccountDisabled)).To(BeTrue())
+			})
+		})
+
+		Context("when an operator sets a new password", func() {
+			It("should accept only the new password", func() {
+				// Act
+				err := userService.SetPassword(ctx, user.ID, "new-password-456")
+
+				// Assert
+				Expect(err).NotTo(HaveOccurred())
+				_, err = userService.Login(ctx, "target@example.com", "password123", "")
+				Expect(errors.Is(err, domain.ErrInvalidCredentials)).To(BeTrue())
+				_, err = userService.Login(ctx, "target@example.com", "new-password-456", "")
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
 	})
 })
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/secret"
+	"auth-service/src/tracing"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// deliveryListLimit caps how many deliveries an administrator sees at once.
+const deliveryListLimit = 100
+
+// WebhookService manages the subscriptions the dispatcher delivers events
+// to, and the deliveries that ended up dead.
+type WebhookService interface {
+	// Create returns the signing secret, which is never shown again, next
+	// to the subscription.
+	Create(ctx context.Context, endpoint string, events []string) (string, *domain.WebhookSubscription, error)
+	List(ctx context.Context) ([]*domain.WebhookSubscription, error)
+	Delete(ctx context.Context, id string) error
+	// ListDeliveries returns the latest deliveries to a subscription, only
+	// those in status unless it is empty.
+	ListDeliveries(ctx context.Context, subscriptionID, status string) ([]*domain.WebhookDelivery, error)
+	// Redeliver queues a dead delivery for an immediate attempt.
+	Redeliver(ctx context.Context, subscriptionID, deliveryID string) error
+}
+
+type webhookService struct {
+	webhooks repository.WebhookRepository
+	logger   *slog.Logger
+}
+
+type WebhookOption func(*webhookService)
+
+func WithWebhookLogger(logger *slog.Logger) WebhookOption {
+	return func(s *webhookService) {
+		s.logger = logger
+	}
+}
+
+func NewWebhookService(webhooks repository.WebhookRepository, opts ...WebhookOption) WebhookService {
+	s := &webhookService{webhooks: webhooks, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *webhookService) Create(ctx context.Context, endpoint string, events []string) (signingSecret string, sub *domain.WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", nil, domain.ErrInvalidWebhookURL
+	}
+	if len(events) == 0 {
+		return "", nil, domain.ErrInvalidEventType
+	}
+	var types []string
+	for _, eventType := range events {
+		if !domain.ValidEventType(eventType) {
+			return "", nil, domain.ErrInvalidEventType
+		}
+		if !slices.Contains(types, eventType) {
+			types = append(types, eventType)
+		}
+	}
+
+	signingSecret, err = secret.Generate(domain.WebhookSecretPrefix, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	sub = &domain.WebhookSubscription{
+		ID:        uuid.NewString(),
+		URL:       u.String(),
+		Events:    types,
+		Secret:    signingSecret,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.webhooks.CreateSubscription(ctx, sub); err != nil {
+		return "", nil, fmt.Errorf("Error creating webhook subscription: %w", err)
+	}
+	return signingSecret, sub, nil
+}
+
+func (s *webhookService) List(ctx context.Context) (_ []*domain.WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.List")
+	defer func() { tracing.End(span, err) }()
+
+	return s.webhooks.ListSubscriptions(ctx)
+}
+
+func (s *webhookService) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	return s.webhooks.DeleteSubscription(ctx, id)
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, subscriptionID, status string) (_ []*domain.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.webhooks.FindSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.webhooks.ListDeliveries(ctx, subscriptionID, status, deliveryListLimit)
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, subscriptionID, deliveryID string) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Redeliver")
+	defer func() { tracing.End(span, err) }()
+
+	return s.webhooks.Redeliver(ctx, subscriptionID, deliveryID, time.Now().UTC())
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type WebhookServiceMock struct {
+	mock.Mock
+}
+
+func (m *WebhookServiceMock) Create(ctx context.Context, endpoint string, events []string) (string, *domain.WebhookSubscription, error) {
+	args := m.Called(ctx, endpoint, events)
+	sub, _ := args.Get(1).(*domain.WebhookSubscription)
+	return args.String(0), sub, args.Error(2)
+}
+
+func (m *WebhookServiceMock) List(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	args := m.Called(ctx)
+	subs, _ := args.Get(0).([]*domain.WebhookSubscription)
+	return subs, args.Error(1)
+}
+
+func (m *WebhookServiceMock) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *WebhookServiceMock) ListDeliveries(ctx context.Context, subscriptionID, status string) ([]*domain.WebhookDelivery, error) {
+	args := m.Called(ctx, subscriptionID, status)
+	deliveries, _ := args.Get(0).([]*domain.WebhookDelivery)
+	return deliveries, args.Error(1)
+}
+
+func (m *WebhookServiceMock) Redeliver(ctx context.Context, subscriptionID, deliveryID string) error {
+	args := m.Called(ctx, subscriptionID, deliveryID)
+	return args.Error(0)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebhookService", func() {
+	var webhooks WebhookService
+	var repo repository.WebhookRepository
+	var outbox repository.OutboxRepository
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		repo = memory.NewWebhook()
+		outbox = memory.NewOutbox()
+		webhooks = NewWebhookService(repo)
+	})
+
+	Context("when subscribing", func() {
+		It("should return a signing secret and keep each event type once", func() {
+			// Act
+			signingSecret, sub, err := webhooks.Create(ctx, "https://orders.example.com/hooks",
+				[]string{domain.EventUserRegistered, domain.EventUserEmailVerified, domain.EventUserDeleted, domain.EventUserRegistered})
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.HasPrefix(signingSecret, domain.WebhookSecretPrefix)).To(BeTrue())
+			Expect(sub.Secret).To(Equal(signingSecret))
+			Expect(sub.Events).To(Equal([]string{domain.EventUserRegistered, domain.EventUserEmailVerified, domain.EventUserDeleted}))
+			list, err := webhooks.List(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(1))
+		})
+
+		It("should reject URLs that are not absolute http(s) and unknown events", func() {
+			// Act
+			_, _, relativeErr := webhooks.Create(ctx, "/hooks", []string{domain.EventUserRegistered})
+			_, _, schemeErr := webhooks.Create(ctx, "ftp://orders.example.com", []string{domain.EventUserRegistered})
+			_, _, noEventsErr := webhooks.Create(ctx, "https://orders.example.com", nil)
+			_, _, unknownErr := webhooks.Create(ctx, "https://orders.example.com", []string{"order.placed"})
+
+			// Assert
+			Expect(errors.Is(relativeErr, domain.ErrInvalidWebhookURL)).To(BeTrue())
+			Expect(errors.Is(schemeErr, domain.ErrInvalidWebhookURL)).To(BeTrue())
+			Expect(errors.Is(noEventsErr, domain.ErrInvalidEventType)).To(BeTrue())
+			Expect(errors.Is(unknownErr, domain.ErrInvalidEventType)).To(BeTrue())
+		})
+	})
+
+	Context("when handling dead deliveries", func() {
+		It("should list them and queue them again", func() {
+			// Arrange
+			_, sub, err := webhooks.Create(ctx, "https://orders.example.com/hooks", []string{domain.EventPasswordChanged})
+			Expect(err).NotTo(HaveOccurred())
+			delivery := &domain.WebhookDelivery{
+				ID:             uuid.NewString(),
+				EventID:        uuid.NewString(),
+				SubscriptionID: sub.ID,
+				Status:         domain.DeliveryPending,
+				CreatedAt:      time.Now().UTC(),
+			}
+			Expect(repo.EnqueueDelivery(ctx, delivery)).To(Succeed())
+			delivery.Status = domain.DeliveryDead
+			delivery.Attempts = 10
+			Expect(repo.UpdateDelivery(ctx, delivery)).To(Succeed())
+
+			// Act
+			dead, listErr := webhooks.ListDeliveries(ctx, sub.ID, domain.DeliveryDead)
+			redeliverErr := webhooks.Redeliver(ctx, sub.ID, delivery.ID)
+			_, unknownErr := webhooks.ListDeliveries(ctx, uuid.NewString(), "")
+
+			// Assert
+			Expect(listErr).NotTo(HaveOccurred())
+			Expect(dead).To(HaveLen(1))
+			Expect(redeliverErr).NotTo(HaveOccurred())
+			pending, err := webhooks.ListDeliveries(ctx, sub.ID, domain.DeliveryPending)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(HaveLen(1))
+			Expect(pending[0].Attempts).To(BeZero())
+			Expect(errors.Is(unknownErr, domain.ErrWebhookNotFound)).To(BeTrue())
+		})
+	})
+
+	Context("when accounts change", func() {
+		It("should record an event for registration, password change and disabling", func() {
+			// Arrange
+			users := NewUserService(repository.NewOutboxUser(memory.NewUser(), memory.NewTxManager(), outbox), "secret", WithBcryptCost(4))
+			user, err := users.Register(ctx, "Ada", "ada@example.com", "password123")
+			Expect(err).NotTo(HaveOccurred())
+
+			// Act
+			Expect(users.SetPassword(ctx, user.ID, "another-password")).To(Succeed())
+			Expect(users.RevokeTokens(ctx, user.ID)).To(Succeed())
+			Expect(users.DisableUser(ctx, user.ID)).To(Succeed())
+
+			// Assert
+			events, err := outbox.ListUndispatched(ctx, 10)
+			Expect(err).NotTo(HaveOccurred())
+			types := make([]string, 0, len(events))
+			for _, event := range events {
+				types = append(types, event.Type)
+			}
+			Expect(types).To(ConsistOf(domain.EventUserRegistered, domain.EventPasswordChanged, domain.EventUserDeleted))
+		})
+	})
+})
@@ -0,0 +1,116 @@
+package social
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// githubProvider uses GitHub's OAuth2 flow, which has no ID token; the
+// identity comes from the REST API instead.
+type githubProvider struct {
+	cfg    Config
+	oauth  *oauth2.Config
+	apiURL string
+}
+
+func newGitHub(cfg Config) *githubProvider {
+	authURL, tokenURL, apiURL := cfg.AuthURL, cfg.TokenURL, cfg.APIURL
+	if authURL == "" {
+		authURL = "https://github.com/login/oauth/authorize"
+	}
+	if tokenURL == "" {
+		tokenURL = "https://github.com/login/oauth/access_token"
+	}
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"read:user", "user:email"}
+	}
+	return &githubProvider{
+		cfg: cfg,
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     oauth2.Endpoint{AuthURL: authURL, TokenURL: tokenURL},
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       scopes,
+		},
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+	}
+}
+
+func (p *githubProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *githubProvider) AutoLinkVerifiedEmail() bool {
+	return p.cfg.AutoLinkVerifiedEmail
+}
+
+func (p *githubProvider) AuthCodeURL(ctx context.Context, state, verifier, nonce string) (string, error) {
+	return p.oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code with %s: %w", p.cfg.Name, err)
+	}
+	client := p.oauth.Client(ctx, token)
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.get(ctx, client, "/user", &user); err != nil {
+		return nil, err
+	}
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, client, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{Provider: p.cfg.Name, Subject: strconv.FormatInt(user.ID, 10), Name: user.Name}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email, identity.EmailVerified = e.Email, e.Verified
+		}
+	}
+	return identity, nil
+}
+
+func (p *githubProvider) get(ctx context.Context, client *http.Client, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s%s: %w", p.cfg.Name, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s%s returned status %d", p.cfg.Name, path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response from %s%s: %w", p.cfg.Name, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Package social signs users in through external OpenID Connect and OAuth2
+// providers such as Google, Microsoft and GitHub. It only talks to the
+// provider; deciding which local account an identity maps to is up to the
+// service layer.
+package social
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNonceMismatch = errors.New("id token nonce does not match the login attempt")
+
+// Identity is what a provider tells us about the person who signed in.
+type Identity struct {
+	Provider string
+	// Subject is the provider's stable ID for the account.
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type Provider interface {
+	Name() string
+	// AutoLinkVerifiedEmail allows signing into an existing local account
+	// whose email matches one the provider has verified.
+	AutoLinkVerifiedEmail() bool
+	// AuthCodeURL is where the browser is sent to sign in. The verifier is
+	// sent as an S256 PKCE challenge.
+	AuthCodeURL(ctx context.Context, state, verifier, nonce string) (string, error)
+	// Exchange redeems the authorization code returned to the callback.
+	Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error)
+}
+
+// Config describes one provider. Type is "oidc" (discovered from Issuer) or
+// "github". AuthURL, TokenURL and APIURL override the GitHub endpoints, for
+// GitHub Enterprise.
+type Config struct {
+	Name                  string
+	Type                  string
+	Issuer                string
+	ClientID              string
+	ClientSecret          string
+	Scopes                []string
+	RedirectURL           string
+	AutoLinkVerifiedEmail bool
+	AuthURL               string
+	TokenURL              string
+	APIURL                string
+}
+
+// New builds the provider described by cfg.
+func New(cfg Config) (Provider, error) {
+	switch cfg.Type {
+	case "oidc":
+		return newOIDC(cfg), nil
+	case "github":
+		return newGitHub(cfg), nil
+	default:
+		return nil, errors.New("unsupported provider type " + cfg.Type)
+	}
+}
@@ -0,0 +1,129 @@
+package social
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"auth-service/src/test_artefacts/idp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func login(t *testing.T, stub *idp.Server, p Provider, nonce string) (*Identity, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "verifier-0123456789-0123456789-0123456789", nonce)
+	require.NoError(t, err)
+
+	code, state, err := stub.Authorize(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "state-1", state)
+	return p.Exchange(ctx, code, "verifier-0123456789-0123456789-0123456789", nonce)
+}
+
+func TestOIDC_Exchange(t *testing.T) {
+	// Arrange
+	stub := idp.New()
+	defer stub.Close()
+	p, err := New(Config{Name: "stub", Type: "oidc", Issuer: stub.URL, ClientID: idp.ClientID, ClientSecret: idp.ClientSecret, RedirectURL: "http://localhost/auth/stub/callback"})
+	require.NoError(t, err)
+
+	// Act
+	identity, err := login(t, stub, p, "nonce-1")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Provider: "stub", Subject: "stub-user-1", Email: "stub@example.com", EmailVerified: true, Name: "Stub User"}, identity)
+}
+
+func TestOIDC_AuthCodeURLUsesPKCEAndNonce(t *testing.T) {
+	// Arrange
+	stub := idp.New()
+	defer stub.Close()
+	p, _ := New(Config{Name: "stub", Type: "oidc", Issuer: stub.URL, ClientID: idp.ClientID, ClientSecret: idp.ClientSecret})
+
+	// Act
+	authURL, err := p.AuthCodeURL(context.Background(), "s", "verifier-0123456789-0123456789-0123456789", "n")
+
+	// Assert
+	require.NoError(t, err)
+	u, _ := url.Parse(authURL)
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+	assert.NotEmpty(t, u.Query().Get("code_challenge"))
+	assert.Equal(t, "n", u.Query().Get("nonce"))
+}
+
+func TestOIDC_RejectsWrongVerifier(t *testing.T) {
+	// Arrange
+	stub := idp.New()
+	defer stub.Close()
+	p, _ := New(Config{Name: "stub", Type: "oidc", Issuer: stub.URL, ClientID: idp.ClientID, ClientSecret: idp.ClientSecret})
+	authURL, err := p.AuthCodeURL(context.Background(), "s", "verifier-0123456789-0123456789-0123456789", "n")
+	require.NoError(t, err)
+	code, _, err := stub.Authorize(authURL)
+	require.NoError(t, err)
+
+	// Act
+	_, err = p.Exchange(context.Background(), code, "another-verifier-0123456789-0123456789", "n")
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestOIDC_RejectsNonceMismatch(t *testing.T) {
+	// Arrange
+	stub := idp.New()
+	defer stub.Close()
+	p, _ := New(Config{Name: "stub", Type: "oidc", Issuer: stub.URL, ClientID: idp.ClientID, ClientSecret: idp.ClientSecret})
+	authURL, err := p.AuthCodeURL(context.Background(), "s", "verifier-0123456789-0123456789-0123456789", "sent-nonce")
+	require.NoError(t, err)
+	code, _, err := stub.Authorize(authURL)
+	require.NoError(t, err)
+
+	// Act
+	_, err = p.Exchange(context.Background(), code, "verifier-0123456789-0123456789-0123456789", "expected-nonce")
+
+	// Assert
+	assert.True(t, errors.Is(err, ErrNonceMismatch))
+}
+
+func TestOIDC_UnverifiedEmail(t *testing.T) {
+	// Arrange
+	stub := idp.New()
+	defer stub.Close()
+	stub.SetUser(idp.User{Subject: "u2", Email: "u2@example.com", EmailVerified: false})
+	p, _ := New(Config{Name: "stub", Type: "oidc", Issuer: stub.URL, ClientID: idp.ClientID, ClientSecret: idp.ClientSecret})
+
+	// Act
+	identity, err := login(t, stub, p, "n")
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, identity.EmailVerified)
+}
+
+func TestGitHub_Exchange(t *testing.T) {
+	// Arrange
+	stub := idp.New()
+	defer stub.Close()
+	p, err := New(Config{
+		Name: "github", Type: "github", ClientID: idp.ClientID, ClientSecret: idp.ClientSecret,
+		AuthURL: stub.URL + "/authorize", TokenURL: stub.URL + "/token", APIURL: stub.URL + "/api",
+	})
+	require.NoError(t, err)
+
+	// Act
+	identity, err := login(t, stub, p, "")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Provider: "github", Subject: "4242", Email: "stub@example.com", EmailVerified: true, Name: "Stub User"}, identity)
+}
+
+func TestNew_UnsupportedType(t *testing.T) {
+	_, err := New(Config{Name: "x", Type: "saml"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// AccessTokenRepository registers the shared specs. newRepos must return
+// empty repositories backed by the same store, since tokens reference users.
+func AccessTokenRepository(newRepos func() (repository.UserRepository, repository.AccessTokenRepository)) bool {
+	return Describe("AccessTokenRepository conformance", func() {
+		var tokens repository.AccessTokenRepository
+		var user *domain.User
+		var ctx context.Context
+		var now time.Time
+
+		newToken := func(tokenHash string, expiresAt time.Time) *domain.AccessToken {
+			return &domain.AccessToken{
+				TokenHash: tokenHash,
+				UserID:    user.ID,
+				Claims:    json.RawMessage(`{"sub":"` + user.ID + `","role":"admin"}`),
+				IssuedAt:  now,
+				ExpiresAt: expiresAt,
+			}
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			now = time.Now().UTC().Truncate(time.Millisecond)
+			var users repository.UserRepository
+			users, tokens = newRepos()
+			user = stubs.NewUserStub().Get()
+			Expect(users.Create(ctx, user)).To(Succeed())
+		})
+
+		It("should find a token by its hash", func() {
+			// Arrange
+			Expect(tokens.Create(ctx, newToken("hash-1", now.Add(time.Hour)))).To(Succeed())
+
+			// Act
+			found, err := tokens.FindByHash(ctx, "hash-1")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.UserID).To(Equal(user.ID))
+			Expect(found.Claims).To(MatchJSON(`{"sub":"` + user.ID + `","role":"admin"}`))
+			Expect(found.IssuedAt.Equal(now)).To(BeTrue())
+			Expect(found.ExpiresAt.Equal(now.Add(time.Hour))).To(BeTrue())
+		})
+
+		It("should report an unknown hash as an invalid token", func() {
+			// Act
+			_, err := tokens.FindByHash(ctx, "missing")
+
+			// Assert
+			Expect(errors.Is(err, domain.ErrInvalidToken)).To(BeTrue())
+		})
+
+		It("should reject a duplicate hash", func() {
+			// Arrange
+			Expect(tokens.Create(ctx, newToken("hash-1", now.Add(time.Hour)))).To(Succeed())
+
+			// Act
+			err := tokens.Create(ctx, newToken("hash-1", now.Add(time.Hour)))
+
+			// Assert
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should revoke a token and accept revoking it again", func() {
+			// Arrange
+			Expect(tokens.Create(ctx, newToken("hash-1", now.Add(time.Hour)))).To(Succeed())
+			Expect(tokens.Create(ctx, newToken("hash-2", now.Add(time.Hour)))).To(Succeed())
+
+			// Act
+			err := tokens.Revoke(ctx, "hash-1")
+			again := tokens.Revoke(ctx, "hash-1")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(again).NotTo(HaveOccurred())
+			_, revokedErr := tokens.FindByHash(ctx, "hash-1")
+			Expect(errors.Is(revokedErr, domain.ErrInvalidToken)).To(BeTrue())
+			_, otherErr := tokens.FindByHash(ctx, "hash-2")
+			Expect(otherErr).NotTo(HaveOccurred())
+		})
+
+		It("should delete only the tokens that expired", func() {
+			// Arrange
+			Expect(tokens.Create(ctx, newToken("hash-old", now.Add(-time.Minute)))).To(Succeed())
+			Expect(tokens.Create(ctx, newToken("hash-new", now.Add(time.Hour)))).To(Succeed())
+
+			// Act
+			err := tokens.DeleteExpired(ctx, now)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			_, oldErr := tokens.FindByHash(ctx, "hash-old")
+			Expect(errors.Is(oldErr, domain.ErrInvalidToken)).To(BeTrue())
+			_, newErr := tokens.FindByHash(ctx, "hash-new")
+			Expect(newErr).NotTo(HaveOccurred())
+		})
+	})
+}
@@ -0,0 +1,98 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// APIKeyRepository registers the shared specs. newRepo must return an empty
+// repository.
+func APIKeyRepository(newRepo func() repository.APIKeyRepository) bool {
+	return Describe("APIKeyRepository conformance", func() {
+		var keys repository.APIKeyRepository
+		var ctx context.Context
+		var now time.Time
+
+		newKey := func(name, keyHash string, createdAt time.Time) *domain.APIKey {
+			return &domain.APIKey{
+				ID:        uuid.NewString(),
+				Name:      name,
+				KeyHash:   keyHash,
+				CreatedAt: createdAt,
+				ExpiresAt: createdAt.Add(30 * 24 * time.Hour),
+			}
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			now = time.Now().UTC().Truncate(time.Millisecond)
+			keys = newRepo()
+		})
+
+		It("should find a key by its hash", func() {
+			// Arrange
+			key := newKey("orders", "hash-1", now)
+			Expect(keys.Create(ctx, key)).To(Succeed())
+
+			// Act
+			found, err := keys.FindByHash(ctx, "hash-1")
+			_, missingErr := keys.FindByHash(ctx, "missing")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.ID).To(Equal(key.ID))
+			Expect(found.Name).To(Equal("orders"))
+			Expect(found.ExpiresAt.Equal(key.ExpiresAt)).To(BeTrue())
+			Expect(found.RevokedAt).To(BeNil())
+			Expect(errors.Is(missingErr, domain.ErrAPIKeyNotFound)).To(BeTrue())
+		})
+
+		It("should list every key by name and age, revoked ones included", func() {
+			// Arrange
+			newer := newKey("orders", "hash-1", now)
+			older := newKey("orders", "hash-2", now.Add(-time.Hour))
+			billing := newKey("billing", "hash-3", now)
+			for _, key := range []*domain.APIKey{newer, older, billing} {
+				Expect(keys.Create(ctx, key)).To(Succeed())
+			}
+			Expect(keys.Revoke(ctx, older.ID, now)).To(Succeed())
+
+			// Act
+			list, err := keys.List(ctx)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(3))
+			Expect(list[0].ID).To(Equal(billing.ID))
+			Expect(list[1].ID).To(Equal(older.ID))
+			Expect(list[1].RevokedAt).NotTo(BeNil())
+			Expect(list[2].ID).To(Equal(newer.ID))
+		})
+
+		It("should revoke a key only once", func() {
+			// Arrange
+			key := newKey("orders", "hash-1", now)
+			Expect(keys.Create(ctx, key)).To(Succeed())
+
+			// Act
+			err := keys.Revoke(ctx, key.ID, now)
+			againErr := keys.Revoke(ctx, key.ID, now)
+			missingErr := keys.Revoke(ctx, uuid.NewString(), now)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.Is(againErr, domain.ErrAPIKeyNotFound)).To(BeTrue())
+			Expect(errors.Is(missingErr, domain.ErrAPIKeyNotFound)).To(BeTrue())
+			found, err := keys.FindByHash(ctx, "hash-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.RevokedAt).NotTo(BeNil())
+		})
+	})
+}
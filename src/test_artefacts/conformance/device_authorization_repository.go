@@ -0,0 +1,161 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// DeviceAuthorizationRepository registers the shared specs. newRepos must
+// return empty repositories backed by the same store, since authorizations
+// reference clients and users.
+func DeviceAuthorizationRepository(newRepos func() (repository.UserRepository, repository.ClientRepository, repository.DeviceAuthorizationRepository)) bool {
+	return Describe("DeviceAuthorizationRepository conformance", func() {
+		var auths repository.DeviceAuthorizationRepository
+		var user *domain.User
+		var ctx context.Context
+		var now time.Time
+
+		newAuthorization := func(deviceCodeHash, userCode string) *domain.DeviceAuthorization {
+			return &domain.DeviceAuthorization{
+				DeviceCodeHash: deviceCodeHash,
+				UserCode:       userCode,
+				ClientID:       "tv-app",
+				Status:         domain.DeviceAuthorizationPending,
+				Interval:       5 * time.Second,
+				CreatedAt:      now,
+				ExpiresAt:      now.Add(10 * time.Minute),
+			}
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			now = time.Now().UTC().Truncate(time.Millisecond)
+			var users repository.UserRepository
+			var clients repository.ClientRepository
+			users, clients, auths = newRepos()
+			user = stubs.NewUserStub().Get()
+			Expect(users.Create(ctx, user)).To(Succeed())
+			Expect(clients.Create(ctx, &domain.Client{ID: "tv-app", Name: "TV app", SecretHash: "unused", CreatedAt: now})).To(Succeed())
+		})
+
+		It("should find a pending authorization by either code", func() {
+			// Arrange
+			Expect(auths.Create(ctx, newAuthorization("hash-1", "BCDFGHJK"))).To(Succeed())
+
+			// Act
+			byDevice, deviceErr := auths.FindByDeviceCode(ctx, "hash-1")
+			byUser, userErr := auths.FindPendingByUserCode(ctx, "BCDFGHJK", now)
+
+			// Assert
+			Expect(deviceErr).NotTo(HaveOccurred())
+			Expect(userErr).NotTo(HaveOccurred())
+			Expect(byDevice).To(Equal(byUser))
+			Expect(byDevice.ClientID).To(Equal("tv-app"))
+			Expect(byDevice.Status).To(Equal(domain.DeviceAuthorizationPending))
+			Expect(byDevice.UserID).To(BeEmpty())
+			Expect(byDevice.Interval).To(Equal(5 * time.Second))
+			Expect(byDevice.ExpiresAt.Equal(now.Add(10 * time.Minute))).To(BeTrue())
+			Expect(byDevice.LastPolledAt).To(BeNil())
+		})
+
+		It("should not find unknown or expired user codes", func() {
+			// Arrange
+			Expect(auths.Create(ctx, newAuthorization("hash-1", "BCDFGHJK"))).To(Succeed())
+
+			// Act
+			_, unknownErr := auths.FindPendingByUserCode(ctx, "ZZZZZZZZ", now)
+			_, expiredErr := auths.FindPendingByUserCode(ctx, "BCDFGHJK", now.Add(time.Hour))
+			_, deviceErr := auths.FindByDeviceCode(ctx, "missing")
+
+			// Assert
+			Expect(errors.Is(unknownErr, domain.ErrUserCodeInvalid)).To(BeTrue())
+			Expect(errors.Is(expiredErr, domain.ErrUserCodeInvalid)).To(BeTrue())
+			Expect(errors.Is(deviceErr, domain.ErrDeviceCodeInvalid)).To(BeTrue())
+		})
+
+		It("should let a pending authorization be decided only once", func() {
+			// Arrange
+			Expect(auths.Create(ctx, newAuthorization("hash-1", "BCDFGHJK"))).To(Succeed())
+
+			// Act
+			err := auths.Decide(ctx, "BCDFGHJK", user.ID, domain.DeviceAuthorizationApproved, now)
+			againErr := auths.Decide(ctx, "BCDFGHJK", user.ID, domain.DeviceAuthorizationDenied, now)
+			_, pendingErr := auths.FindPendingByUserCode(ctx, "BCDFGHJK", now)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.Is(againErr, domain.ErrUserCodeInvalid)).To(BeTrue())
+			Expect(errors.Is(pendingErr, domain.ErrUserCodeInvalid)).To(BeTrue())
+			found, err := auths.FindByDeviceCode(ctx, "hash-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.Status).To(Equal(domain.DeviceAuthorizationApproved))
+			Expect(found.UserID).To(Equal(user.ID))
+		})
+
+		It("should not decide an expired authorization", func() {
+			// Arrange
+			Expect(auths.Create(ctx, newAuthorization("hash-1", "BCDFGHJK"))).To(Succeed())
+
+			// Act
+			err := auths.Decide(ctx, "BCDFGHJK", user.ID, domain.DeviceAuthorizationApproved, now.Add(time.Hour))
+
+			// Assert
+			Expect(errors.Is(err, domain.ErrUserCodeInvalid)).To(BeTrue())
+		})
+
+		It("should record polls and the interval the device must keep", func() {
+			// Arrange
+			Expect(auths.Create(ctx, newAuthorization("hash-1", "BCDFGHJK"))).To(Succeed())
+			polledAt := now.Add(3 * time.Second)
+
+			// Act
+			err := auths.RecordPoll(ctx, "hash-1", polledAt, 10*time.Second)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			found, err := auths.FindByDeviceCode(ctx, "hash-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.LastPolledAt).NotTo(BeNil())
+			Expect(found.LastPolledAt.Equal(polledAt)).To(BeTrue())
+			Expect(found.Interval).To(Equal(10 * time.Second))
+		})
+
+		It("should delete an authorization exactly once", func() {
+			// Arrange
+			Expect(auths.Create(ctx, newAuthorization("hash-1", "BCDFGHJK"))).To(Succeed())
+
+			// Act
+			err := auths.Delete(ctx, "hash-1")
+			againErr := auths.Delete(ctx, "hash-1")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.Is(againErr, domain.ErrDeviceCodeInvalid)).To(BeTrue())
+		})
+
+		It("should delete only the authorizations that expired before the cutoff", func() {
+			// Arrange
+			old := newAuthorization("hash-old", "BCDFGHJK")
+			old.ExpiresAt = now.Add(-time.Hour)
+			Expect(auths.Create(ctx, old)).To(Succeed())
+			Expect(auths.Create(ctx, newAuthorization("hash-new", "LMNPQRST"))).To(Succeed())
+
+			// Act
+			err := auths.DeleteExpired(ctx, now)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			_, oldErr := auths.FindByDeviceCode(ctx, "hash-old")
+			Expect(errors.Is(oldErr, domain.ErrDeviceCodeInvalid)).To(BeTrue())
+			_, newErr := auths.FindByDeviceCode(ctx, "hash-new")
+			Expect(newErr).NotTo(HaveOccurred())
+		})
+	})
+}
@@ -0,0 +1,83 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// IdentityRepository registers the shared specs. newRepos must return empty
+// repositories backed by the same store, since identities reference users.
+func IdentityRepository(newRepos func() (repository.UserRepository, repository.IdentityRepository)) bool {
+	return Describe("IdentityRepository conformance", func() {
+		var identities repository.IdentityRepository
+		var user *domain.User
+		var ctx context.Context
+
+		newIdentity := func(provider, subject string) *domain.Identity {
+			return &domain.Identity{
+				UserID:    user.ID,
+				Provider:  provider,
+				Subject:   subject,
+				Email:     user.Email,
+				CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+			}
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			var users repository.UserRepository
+			users, identities = newRepos()
+			user = stubs.NewUserStub().Get()
+			Expect(users.Create(ctx, user)).To(Succeed())
+		})
+
+		It("should find an identity by provider and subject", func() {
+			// Arrange
+			Expect(identities.Create(ctx, newIdentity("google", "sub-1"))).To(Succeed())
+
+			// Act
+			found, err := identities.FindByProviderSubject(ctx, "google", "sub-1")
+			_, otherProviderErr := identities.FindByProviderSubject(ctx, "github", "sub-1")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.UserID).To(Equal(user.ID))
+			Expect(found.Email).To(Equal(user.Email))
+			Expect(errors.Is(otherProviderErr, domain.ErrIdentityNotFound)).To(BeTrue())
+		})
+
+		It("should reject linking the same external account twice", func() {
+			// Arrange
+			Expect(identities.Create(ctx, newIdentity("google", "sub-1"))).To(Succeed())
+
+			// Act
+			err := identities.Create(ctx, newIdentity("google", "sub-1"))
+
+			// Assert
+			Expect(errors.Is(err, domain.ErrIdentityAlreadyLinked)).To(BeTrue())
+		})
+
+		It("should list every identity of a user", func() {
+			// Arrange
+			Expect(identities.Create(ctx, newIdentity("google", "sub-1"))).To(Succeed())
+			Expect(identities.Create(ctx, newIdentity("github", "42"))).To(Succeed())
+
+			// Act
+			list, err := identities.ListByUser(ctx, user.ID)
+			empty, emptyErr := identities.ListByUser(ctx, "00000000-0000-0000-0000-000000000000")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(2))
+			Expect(emptyErr).NotTo(HaveOccurred())
+			Expect(empty).To(BeEmpty())
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// MagicLinkRepository registers the shared specs. newRepos must return empty
+// repositories backed by the same store, since links reference users.
+func MagicLinkRepository(newRepos func() (repository.UserRepository, repository.MagicLinkRepository)) bool {
+	return Describe("MagicLinkRepository conformance", func() {
+		var links repository.MagicLinkRepository
+		var user *domain.User
+		var ctx context.Context
+		var now time.Time
+
+		newLink := func(tokenHash, nonceHash string) *domain.MagicLink {
+			return &domain.MagicLink{
+				TokenHash: tokenHash,
+				UserID:    user.ID,
+				NonceHash: nonceHash,
+				CreatedAt: now,
+				ExpiresAt: now.Add(15 * time.Minute),
+			}
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			now = time.Now().UTC().Truncate(time.Millisecond)
+			var users repository.UserRepository
+			users, links = newRepos()
+			user = stubs.NewUserStub().Get()
+			Expect(users.Create(ctx, user)).To(Succeed())
+		})
+
+		It("should consume a link exactly once", func() {
+			// Arrange
+			Expect(links.Create(ctx, newLink("hash-1", ""))).To(Succeed())
+
+			// Act
+			consumed, err := links.Consume(ctx, "hash-1", "", now.Add(time.Minute))
+			_, replayErr := links.Consume(ctx, "hash-1", "", now.Add(2*time.Minute))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(consumed.UserID).To(Equal(user.ID))
+			Expect(consumed.UsedAt).NotTo(BeNil())
+			Expect(errors.Is(replayErr, domain.ErrMagicLinkInvalid)).To(BeTrue())
+		})
+
+		It("should reject unknown and expired links", func() {
+			// Arrange
+			Expect(links.Create(ctx, newLink("hash-1", ""))).To(Succeed())
+
+			// Act
+			_, unknownErr := links.Consume(ctx, "missing", "", now)
+			_, expiredErr := links.Consume(ctx, "hash-1", "", now.Add(time.Hour))
+
+			// Assert
+			Expect(errors.Is(unknownErr, domain.ErrMagicLinkInvalid)).To(BeTrue())
+			Expect(errors.Is(expiredErr, domain.ErrMagicLinkInvalid)).To(BeTrue())
+		})
+
+		It("should only accept a bound link with its nonce, without burning it", func() {
+			// Arrange
+			Expect(links.Create(ctx, newLink("hash-1", "nonce-hash"))).To(Succeed())
+
+			// Act
+			_, wrongErr := links.Consume(ctx, "hash-1", "other", now)
+			_, missingErr := links.Consume(ctx, "hash-1", "", now)
+			_, err := links.Consume(ctx, "hash-1", "nonce-hash", now)
+
+			// Assert
+			Expect(errors.Is(wrongErr, domain.ErrMagicLinkInvalid)).To(BeTrue())
+			Expect(errors.Is(missingErr, domain.ErrMagicLinkInvalid)).To(BeTrue())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should count the links issued since a point in time", func() {
+			// Arrange
+			old := newLink("hash-old", "")
+			old.CreatedAt = now.Add(-2 * time.Hour)
+			Expect(links.Create(ctx, old)).To(Succeed())
+			Expect(links.Create(ctx, newLink("hash-1", ""))).To(Succeed())
+			Expect(links.Create(ctx, newLink("hash-2", ""))).To(Succeed())
+
+			// Act
+			count, err := links.CountSince(ctx, user.ID, now.Add(-time.Hour))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(2))
+		})
+	})
+}
@@ -0,0 +1,152 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// OutboxRepository registers the shared specs for the outbox and for the
+// events repository.NewOutboxUser records. newRepos must return empty
+// repositories backed by the same store.
+func OutboxRepository(newRepos func() (repository.UserRepository, repository.TxManager, repository.OutboxRepository)) bool {
+	return Describe("OutboxRepository conformance", func() {
+		var outbox repository.OutboxRepository
+		var users repository.UserRepository
+		var ctx context.Context
+		var now time.Time
+
+		newEvent := func(eventType string, createdAt time.Time) *domain.Event {
+			return &domain.Event{ID: uuid.NewString(), Type: eventType, Data: json.RawMessage(`{"userId":"42"}`), CreatedAt: createdAt}
+		}
+
+		eventTypes := func() []string {
+			events, err := outbox.ListUndispatched(ctx, 100)
+			Expect(err).NotTo(HaveOccurred())
+			types := make([]string, 0, len(events))
+			for _, event := range events {
+				types = append(types, event.Type)
+			}
+			return types
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			now = time.Now().UTC().Truncate(time.Millisecond)
+			var plain repository.UserRepository
+			var tx repository.TxManager
+			plain, tx, outbox = newRepos()
+			users = repository.NewOutboxUser(plain, tx, outbox)
+		})
+
+		It("should list undispatched events oldest first", func() {
+			// Arrange
+			newer := newEvent(domain.EventUserRegistered, now)
+			older := newEvent(domain.EventPasswordChanged, now.Add(-time.Minute))
+			dispatched := newEvent(domain.EventUserDeleted, now.Add(-time.Hour))
+			Expect(outbox.Append(ctx, newer, older, dispatched)).To(Succeed())
+			Expect(outbox.MarkDispatched(ctx, dispatched.ID, now)).To(Succeed())
+
+			// Act
+			events, err := outbox.ListUndispatched(ctx, 10)
+			limited, limitedErr := outbox.ListUndispatched(ctx, 1)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].ID).To(Equal(older.ID))
+			Expect(events[1].ID).To(Equal(newer.ID))
+			Expect(events[1].Data).To(MatchJSON(`{"userId":"42"}`))
+			Expect(limitedErr).NotTo(HaveOccurred())
+			Expect(limited).To(HaveLen(1))
+		})
+
+		It("should find an event by ID", func() {
+			// Arrange
+			event := newEvent(domain.EventUserRegistered, now)
+			Expect(outbox.Append(ctx, event)).To(Succeed())
+			Expect(outbox.MarkDispatched(ctx, event.ID, now)).To(Succeed())
+
+			// Act
+			found, err := outbox.FindByID(ctx, event.ID)
+			_, missingErr := outbox.FindByID(ctx, uuid.NewString())
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.Type).To(Equal(domain.EventUserRegistered))
+			Expect(found.CreatedAt.Equal(now)).To(BeTrue())
+			Expect(found.DispatchedAt).NotTo(BeNil())
+			Expect(errors.Is(missingErr, domain.ErrEventNotFound)).To(BeTrue())
+		})
+
+		It("should record user.registered with the user it creates", func() {
+			// Arrange
+			user := stubs.NewUserStub().WithEmail("ada@example.com").Get()
+
+			// Act
+			err := users.Create(ctx, user)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			events, err := outbox.ListUndispatched(ctx, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal(domain.EventUserRegistered))
+			var data domain.UserEventData
+			Expect(json.Unmarshal(events[0].Data, &data)).To(Succeed())
+			Expect(data.UserID).To(Equal(user.ID))
+			Expect(data.Email).To(Equal("ada@example.com"))
+		})
+
+		It("should record password and disable changes but not other updates", func() {
+			// Arrange
+			user := stubs.NewUserStub().Get()
+			Expect(users.Create(ctx, user)).To(Succeed())
+			events, err := outbox.ListUndispatched(ctx, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outbox.MarkDispatched(ctx, events[0].ID, now)).To(Succeed())
+
+			// Act
+			user.Name = "Renamed"
+			renameErr := users.Update(ctx, user)
+			renamed := eventTypes()
+			user.PasswordHash = "new-hash"
+			passwordErr := users.Update(ctx, user)
+			user.DisabledAt = &now
+			disableErr := users.Update(ctx, user)
+			againErr := users.Update(ctx, user)
+
+			// Assert
+			Expect(renameErr).NotTo(HaveOccurred())
+			Expect(renamed).To(BeEmpty())
+			Expect(passwordErr).NotTo(HaveOccurred())
+			Expect(disableErr).NotTo(HaveOccurred())
+			Expect(againErr).NotTo(HaveOccurred())
+			Expect(eventTypes()).To(ConsistOf(domain.EventPasswordChanged, domain.EventUserDeleted))
+		})
+
+		It("should record nothing when the user write fails", func() {
+			// Arrange
+			Expect(users.Create(ctx, stubs.NewUserStub().WithEmail("taken@example.com").Get())).To(Succeed())
+			before := eventTypes()
+			missing := stubs.NewUserStub().Get()
+
+			// Act
+			duplicateErr := users.Create(ctx, stubs.NewUserStub().WithEmail("taken@example.com").Get())
+			missingErr := users.Update(ctx, missing)
+
+			// Assert
+			Expect(errors.Is(duplicateErr, domain.ErrEmailAlreadyExists)).To(BeTrue())
+			Expect(errors.Is(missingErr, domain.ErrUserNotFound)).To(BeTrue())
+			Expect(eventTypes()).To(Equal(before))
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// PersonalAccessTokenRepository registers the shared specs. newRepos must
+// return empty repositories backed by the same store, since tokens reference
+// users.
+func PersonalAccessTokenRepository(newRepos func() (repository.UserRepository, repository.PersonalAccessTokenRepository)) bool {
+	return Describe("PersonalAccessTokenRepository conformance", func() {
+		var tokens repository.PersonalAccessTokenRepository
+		var users repository.UserRepository
+		var user *domain.User
+		var ctx context.Context
+		var now time.Time
+
+		newToken := func(userID, tokenHash string, createdAt time.Time) *domain.PersonalAccessToken {
+			return &domain.PersonalAccessToken{
+				ID:        uuid.NewString(),
+				UserID:    userID,
+				Name:      "ci",
+				TokenHash: tokenHash,
+				Scopes:    []string{domain.ScopeProfileRead, domain.ScopeTokensRead},
+				CreatedAt: createdAt,
+				ExpiresAt: createdAt.Add(24 * time.Hour),
+			}
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			now = time.Now().UTC().Truncate(time.Millisecond)
+			users, tokens = newRepos()
+			user = stubs.NewUserStub().Get()
+			Expect(users.Create(ctx, user)).To(Succeed())
+		})
+
+		It("should find a token by its hash", func() {
+			// Arrange
+			token := newToken(user.ID, "hash-1", now)
+			Expect(tokens.Create(ctx, token)).To(Succeed())
+
+			// Act
+			found, err := tokens.FindByHash(ctx, "hash-1")
+			_, missingErr := tokens.FindByHash(ctx, "missing")
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.ID).To(Equal(token.ID))
+			Expect(found.UserID).To(Equal(user.ID))
+			Expect(found.Name).To(Equal("ci"))
+			Expect(found.Scopes).To(Equal(token.Scopes))
+			Expect(found.ExpiresAt.Equal(token.ExpiresAt)).To(BeTrue())
+			Expect(found.LastUsedAt).To(BeNil())
+			Expect(found.RevokedAt).To(BeNil())
+			Expect(errors.Is(missingErr, domain.ErrTokenNotFound)).To(BeTrue())
+		})
+
+		It("should list the user's tokens newest first, without revoked ones", func() {
+			// Arrange
+			other := stubs.NewUserStub().WithEmail("other@example.com").Get()
+			Expect(users.Create(ctx, other)).To(Succeed())
+			older := newToken(user.ID, "hash-1", now.Add(-time.Hour))
+			newer := newToken(user.ID, "hash-2", now)
+			revoked := newToken(user.ID, "hash-3", now)
+			for _, token := range []*domain.PersonalAccessToken{older, newer, revoked, newToken(other.ID, "hash-4", now)} {
+				Expect(tokens.Create(ctx, token)).To(Succeed())
+			}
+			Expect(tokens.Revoke(ctx, user.ID, revoked.ID, now)).To(Succeed())
+
+			// Act
+			list, err := tokens.ListByUser(ctx, user.ID)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(2))
+			Expect(list[0].ID).To(Equal(newer.ID))
+			Expect(list[1].ID).To(Equal(older.ID))
+		})
+
+		It("should only revoke the owner's active tokens", func() {
+			// Arrange
+			token := newToken(user.ID, "hash-1", now)
+			Expect(tokens.Create(ctx, token)).To(Succeed())
+
+			// Act
+			otherErr := tokens.Revoke(ctx, uuid.NewString(), token.ID, now)
+			err := tokens.Revoke(ctx, user.ID, token.ID, now)
+			againErr := tokens.Revoke(ctx, user.ID, token.ID, now)
+			missingErr := tokens.Revoke(ctx, user.ID, uuid.NewString(), now)
+
+			// Assert
+			Expect(errors.Is(otherErr, domain.ErrTokenNotFound)).To(BeTrue())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.Is(againErr, domain.ErrTokenNotFound)).To(BeTrue())
+			Expect(errors.Is(missingErr, domain.ErrTokenNotFound)).To(BeTrue())
+			found, err := tokens.FindByHash(ctx, "hash-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.RevokedAt).NotTo(BeNil())
+		})
+
+		It("should record when a token was last used", func() {
+			// Arrange
+			token := newToken(user.ID, "hash-1", now)
+			Expect(tokens.Create(ctx, token)).To(Succeed())
+			usedAt := now.Add(time.Minute)
+
+			// Act
+			err := tokens.MarkUsed(ctx, token.ID, usedAt)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			found, err := tokens.FindByHash(ctx, "hash-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.LastUsedAt).NotTo(BeNil())
+			Expect(found.LastUsedAt.Equal(usedAt)).To(BeTrue())
+		})
+	})
+}
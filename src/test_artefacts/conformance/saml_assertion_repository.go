@@ -0,0 +1,64 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// SAMLAssertionRepository registers the shared specs. newRepo must return an
+// empty repository.
+func SAMLAssertionRepository(newRepo func() repository.SAMLAssertionRepository) bool {
+	return Describe("SAMLAssertionRepository conformance", func() {
+		var assertions repository.SAMLAssertionRepository
+		var ctx context.Context
+		var now time.Time
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			now = time.Now().UTC().Truncate(time.Millisecond)
+			assertions = newRepo()
+		})
+
+		It("should consume an assertion only once", func() {
+			// Act
+			err := assertions.Consume(ctx, "acme", "id-1", now.Add(time.Hour))
+			replayErr := assertions.Consume(ctx, "acme", "id-1", now.Add(time.Hour))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.Is(replayErr, domain.ErrAssertionConsumed)).To(BeTrue())
+		})
+
+		It("should keep the IDs of each provider apart", func() {
+			// Arrange
+			Expect(assertions.Consume(ctx, "acme", "id-1", now.Add(time.Hour))).To(Succeed())
+
+			// Act
+			err := assertions.Consume(ctx, "globex", "id-1", now.Add(time.Hour))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should delete only the IDs that expired", func() {
+			// Arrange
+			Expect(assertions.Consume(ctx, "acme", "id-old", now.Add(-time.Minute))).To(Succeed())
+			Expect(assertions.Consume(ctx, "acme", "id-new", now.Add(time.Hour))).To(Succeed())
+
+			// Act
+			err := assertions.DeleteExpired(ctx, now)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(assertions.Consume(ctx, "acme", "id-old", now.Add(time.Hour))).To(Succeed())
+			newErr := assertions.Consume(ctx, "acme", "id-new", now.Add(time.Hour))
+			Expect(errors.Is(newErr, domain.ErrAssertionConsumed)).To(BeTrue())
+		})
+	})
+}
@@ -0,0 +1,238 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// SCIMRepository registers the shared specs. newRepos must return empty
+// repositories backed by the same store, since SCIM users reference users.
+func SCIMRepository(newRepos func() (repository.UserRepository, repository.SCIMRepository)) bool {
+	return Describe("SCIMRepository conformance", func() {
+		var users repository.UserRepository
+		var scim repository.SCIMRepository
+		var tenant, otherTenant *domain.SCIMTenant
+		var ctx context.Context
+
+		newTenant := func(name string) *domain.SCIMTenant {
+			t := &domain.SCIMTenant{
+				ID:        uuid.NewString(),
+				Name:      name,
+				TokenHash: uuid.NewString(),
+				CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+			}
+			Expect(scim.CreateTenant(ctx, t)).To(Succeed())
+			return t
+		}
+
+		provision := func(t *domain.SCIMTenant, email string) *domain.User {
+			user := stubs.NewUserStub().WithEmail(email).Get()
+			Expect(users.Create(ctx, user)).To(Succeed())
+			Expect(scim.AddUser(ctx, &domain.SCIMUser{
+				TenantID:   t.ID,
+				UserID:     user.ID,
+				ExternalID: "ext-" + email,
+				CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+			})).To(Succeed())
+			return user
+		}
+
+		newGroup := func(t *domain.SCIMTenant, name string, members ...string) *domain.SCIMGroup {
+			now := time.Now().UTC().Truncate(time.Millisecond)
+			return &domain.SCIMGroup{
+				ID:          uuid.NewString(),
+				TenantID:    t.ID,
+				DisplayName: name,
+				MemberIDs:   members,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			users, scim = newRepos()
+			tenant = newTenant("acme")
+			otherTenant = newTenant("globex")
+		})
+
+		It("should find a tenant by the hash of its token", func() {
+			// Act
+			found, err := scim.FindTenantByTokenHash(ctx, tenant.TokenHash)
+			_, missingErr := scim.FindTenantByTokenHash(ctx, "unknown")
+			tenants, listErr := scim.ListTenants(ctx)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.ID).To(Equal(tenant.ID))
+			Expect(found.Name).To(Equal("acme"))
+			Expect(errors.Is(missingErr, domain.ErrSCIMTenantNotFound)).To(BeTrue())
+			Expect(listErr).NotTo(HaveOccurred())
+			Expect(tenants).To(HaveLen(2))
+		})
+
+		It("should scope provisioned users to their tenant", func() {
+			// Arrange
+			user := provision(tenant, "ana@example.com")
+
+			// Act
+			found, err := scim.FindUser(ctx, tenant.ID, user.ID)
+			_, otherErr := scim.FindUser(ctx, otherTenant.ID, user.ID)
+			dupErr := scim.AddUser(ctx, &domain.SCIMUser{TenantID: tenant.ID, UserID: user.ID, CreatedAt: time.Now().UTC()})
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.ExternalID).To(Equal("ext-ana@example.com"))
+			Expect(errors.Is(otherErr, domain.ErrUserNotFound)).To(BeTrue())
+			Expect(errors.Is(dupErr, domain.ErrSCIMUserExists)).To(BeTrue())
+		})
+
+		It("should page through users and report the total", func() {
+			// Arrange
+			first := provision(tenant, "a@example.com")
+			time.Sleep(2 * time.Millisecond)
+			second := provision(tenant, "b@example.com")
+			time.Sleep(2 * time.Millisecond)
+			provision(tenant, "c@example.com")
+			provision(otherTenant, "d@example.com")
+
+			// Act
+			firstPage, total, err := scim.ListUsers(ctx, tenant.ID, 2, 0)
+			lastPage, _, lastErr := scim.ListUsers(ctx, tenant.ID, 2, 2)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(total).To(Equal(3))
+			Expect(firstPage).To(HaveLen(2))
+			Expect(firstPage[0].UserID).To(Equal(first.ID))
+			Expect(firstPage[1].UserID).To(Equal(second.ID))
+			Expect(lastErr).NotTo(HaveOccurred())
+			Expect(lastPage).To(HaveLen(1))
+		})
+
+		It("should update the external ID and deactivated flag of a user", func() {
+			// Arrange
+			user := provision(tenant, "ana@example.com")
+
+			// Act
+			err := scim.UpdateUser(ctx, &domain.SCIMUser{TenantID: tenant.ID, UserID: user.ID, ExternalID: "new-id", Deactivated: true})
+			found, _ := scim.FindUser(ctx, tenant.ID, user.ID)
+			missingErr := scim.UpdateUser(ctx, &domain.SCIMUser{TenantID: otherTenant.ID, UserID: user.ID})
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.ExternalID).To(Equal("new-id"))
+			Expect(found.Deactivated).To(BeTrue())
+			Expect(errors.Is(missingErr, domain.ErrUserNotFound)).To(BeTrue())
+		})
+
+		It("should store groups with their members", func() {
+			// Arrange
+			ana := provision(tenant, "ana@example.com")
+			bob := provision(tenant, "bob@example.com")
+			group := newGroup(tenant, "Engineering", ana.ID, bob.ID)
+
+			// Act
+			err := scim.CreateGroup(ctx, group)
+			found, findErr := scim.FindGroup(ctx, tenant.ID, group.ID)
+			_, otherErr := scim.FindGroup(ctx, otherTenant.ID, group.ID)
+			dupErr := scim.CreateGroup(ctx, newGroup(tenant, "Engineering"))
+			otherTenantErr := scim.CreateGroup(ctx, newGroup(otherTenant, "Engineering"))
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(findErr).NotTo(HaveOccurred())
+			Expect(found.DisplayName).To(Equal("Engineering"))
+			Expect(found.MemberIDs).To(ConsistOf(ana.ID, bob.ID))
+			Expect(errors.Is(otherErr, domain.ErrSCIMGroupNotFound)).To(BeTrue())
+			Expect(errors.Is(dupErr, domain.ErrSCIMGroupExists)).To(BeTrue())
+			Expect(otherTenantErr).NotTo(HaveOccurred())
+		})
+
+		It("should filter groups by display name", func() {
+			// Arrange
+			Expect(scim.CreateGroup(ctx, newGroup(tenant, "Engineering"))).To(Succeed())
+			Expect(scim.CreateGroup(ctx, newGroup(tenant, "Sales"))).To(Succeed())
+			Expect(scim.CreateGroup(ctx, newGroup(otherTenant, "Sales"))).To(Succeed())
+
+			// Act
+			all, total, err := scim.ListGroups(ctx, tenant.ID, "", 10, 0)
+			sales, salesTotal, salesErr := scim.ListGroups(ctx, tenant.ID, "Sales", 10, 0)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(total).To(Equal(2))
+			Expect(all).To(HaveLen(2))
+			Expect(salesErr).NotTo(HaveOccurred())
+			Expect(salesTotal).To(Equal(1))
+			Expect(sales[0].DisplayName).To(Equal("Sales"))
+			Expect(sales[0].MemberIDs).To(BeEmpty())
+		})
+
+		It("should replace the members when a group is updated", func() {
+			// Arrange
+			ana := provision(tenant, "ana@example.com")
+			bob := provision(tenant, "bob@example.com")
+			group := newGroup(tenant, "Engineering", ana.ID)
+			Expect(scim.CreateGroup(ctx, group)).To(Succeed())
+
+			// Act
+			group.DisplayName = "Platform"
+			group.MemberIDs = []string{bob.ID}
+			err := scim.UpdateGroup(ctx, group)
+			found, _ := scim.FindGroup(ctx, tenant.ID, group.ID)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.DisplayName).To(Equal("Platform"))
+			Expect(found.MemberIDs).To(Equal([]string{bob.ID}))
+		})
+
+		It("should drop a removed user from the tenant's groups", func() {
+			// Arrange
+			ana := provision(tenant, "ana@example.com")
+			bob := provision(tenant, "bob@example.com")
+			group := newGroup(tenant, "Engineering", ana.ID, bob.ID)
+			Expect(scim.CreateGroup(ctx, group)).To(Succeed())
+
+			// Act
+			err := scim.RemoveUser(ctx, tenant.ID, ana.ID)
+			_, findErr := scim.FindUser(ctx, tenant.ID, ana.ID)
+			found, _ := scim.FindGroup(ctx, tenant.ID, group.ID)
+			againErr := scim.RemoveUser(ctx, tenant.ID, ana.ID)
+			account, accountErr := users.FindByID(ctx, ana.ID)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.Is(findErr, domain.ErrUserNotFound)).To(BeTrue())
+			Expect(found.MemberIDs).To(Equal([]string{bob.ID}))
+			Expect(errors.Is(againErr, domain.ErrUserNotFound)).To(BeTrue())
+			Expect(accountErr).NotTo(HaveOccurred())
+			Expect(account.ID).To(Equal(ana.ID))
+		})
+
+		It("should delete a group", func() {
+			// Arrange
+			group := newGroup(tenant, "Engineering")
+			Expect(scim.CreateGroup(ctx, group)).To(Succeed())
+
+			// Act
+			otherErr := scim.DeleteGroup(ctx, otherTenant.ID, group.ID)
+			err := scim.DeleteGroup(ctx, tenant.ID, group.ID)
+			_, findErr := scim.FindGroup(ctx, tenant.ID, group.ID)
+
+			// Assert
+			Expect(errors.Is(otherErr, domain.ErrSCIMGroupNotFound)).To(BeTrue())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.Is(findErr, domain.ErrSCIMGroupNotFound)).To(BeTrue())
+		})
+	})
+}
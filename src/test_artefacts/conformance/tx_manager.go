@@ -0,0 +1,171 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// TxManager registers the shared specs for stores that can roll back.
+// newRepos must return empty repositories backed by the same store as the
+// manager.
+func TxManager(newRepos func() (repository.TxManager, repository.UserRepository, repository.SCIMRepository)) bool {
+	return Describe("TxManager conformance", func() {
+		var tx repository.TxManager
+		var users repository.UserRepository
+		var scim repository.SCIMRepository
+		var ctx context.Context
+		errBoom := errors.New("boom")
+
+		newTenant := func() *domain.SCIMTenant {
+			return &domain.SCIMTenant{
+				ID:        uuid.NewString(),
+				Name:      "acme",
+				TokenHash: uuid.NewString(),
+				CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+			}
+		}
+
+		exists := func(user *domain.User) bool {
+			_, err := users.FindByID(ctx, user.ID)
+			if errors.Is(err, domain.ErrUserNotFound) {
+				return false
+			}
+			Expect(err).NotTo(HaveOccurred())
+			return true
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			tx, users, scim = newRepos()
+		})
+
+		It("should commit the writes of several repositories together", func() {
+			// Arrange
+			user := stubs.NewUserStub().WithEmail("tx-commit@example.com").Get()
+			tenant := newTenant()
+
+			// Act
+			err := tx.WithinTx(ctx, func(ctx context.Context) error {
+				if err := users.Create(ctx, user); err != nil {
+					return err
+				}
+				return scim.CreateTenant(ctx, tenant)
+			})
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists(user)).To(BeTrue())
+			_, findErr := scim.FindTenantByTokenHash(ctx, tenant.TokenHash)
+			Expect(findErr).NotTo(HaveOccurred())
+		})
+
+		It("should roll back every write when the unit of work fails", func() {
+			// Arrange
+			user := stubs.NewUserStub().WithEmail("tx-rollback@example.com").Get()
+			tenant := newTenant()
+
+			// Act
+			err := tx.WithinTx(ctx, func(ctx context.Context) error {
+				if err := users.Create(ctx, user); err != nil {
+					return err
+				}
+				if err := scim.CreateTenant(ctx, tenant); err != nil {
+					return err
+				}
+				return errBoom
+			})
+
+			// Assert
+			Expect(err).To(MatchError(errBoom))
+			Expect(exists(user)).To(BeFalse())
+			_, findErr := scim.FindTenantByTokenHash(ctx, tenant.TokenHash)
+			Expect(findErr).To(MatchError(domain.ErrSCIMTenantNotFound))
+		})
+
+		It("should see its own uncommitted writes", func() {
+			// Arrange
+			user := stubs.NewUserStub().WithEmail("tx-read@example.com").Get()
+			var found *domain.User
+
+			// Act
+			err := tx.WithinTx(ctx, func(ctx context.Context) error {
+				if err := users.Create(ctx, user); err != nil {
+					return err
+				}
+				var err error
+				found, err = users.FindByEmail(ctx, user.Email)
+				return err
+			})
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.ID).To(Equal(user.ID))
+		})
+
+		It("should join the outer transaction when nested", func() {
+			// Arrange
+			inner := stubs.NewUserStub().WithEmail("tx-inner@example.com").Get()
+			outer := stubs.NewUserStub().WithEmail("tx-outer@example.com").Get()
+
+			// Act
+			err := tx.WithinTx(ctx, func(ctx context.Context) error {
+				if err := users.Create(ctx, outer); err != nil {
+					return err
+				}
+				if err := tx.WithinTx(ctx, func(ctx context.Context) error {
+					return users.Create(ctx, inner)
+				}); err != nil {
+					return err
+				}
+				return errBoom
+			})
+
+			// Assert
+			Expect(err).To(MatchError(errBoom))
+			Expect(exists(outer)).To(BeFalse())
+			Expect(exists(inner)).To(BeFalse())
+		})
+
+		It("should undo only a repository's own failed write and keep the transaction usable", func() {
+			// Arrange
+			tenant := newTenant()
+			Expect(scim.CreateTenant(ctx, tenant)).To(Succeed())
+			member := stubs.NewUserStub().WithEmail("tx-member@example.com").Get()
+			Expect(users.Create(ctx, member)).To(Succeed())
+			now := time.Now().UTC().Truncate(time.Millisecond)
+			// The group row and the first member are written before the
+			// unknown member fails the insert.
+			group := &domain.SCIMGroup{
+				ID:          uuid.NewString(),
+				TenantID:    tenant.ID,
+				DisplayName: "admins",
+				MemberIDs:   []string{member.ID, uuid.NewString()},
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			user := stubs.NewUserStub().WithEmail("tx-after@example.com").Get()
+			var groupErr error
+
+			// Act
+			err := tx.WithinTx(ctx, func(ctx context.Context) error {
+				groupErr = scim.CreateGroup(ctx, group)
+				return users.Create(ctx, user)
+			})
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groupErr).To(HaveOccurred())
+			Expect(exists(user)).To(BeTrue())
+			_, findErr := scim.FindGroup(ctx, tenant.ID, group.ID)
+			Expect(findErr).To(MatchError(domain.ErrSCIMGroupNotFound))
+		})
+	})
+}
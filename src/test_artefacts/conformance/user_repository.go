@@ -0,0 +1,192 @@
+// Package conformance holds Ginkgo specs that every repository
+// implementation must pass, so the Postgres and in-memory stores cannot drift.
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/test_artefacts/seeder"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// UserRepository registers the shared specs. newRepo must return an empty
+// repository each time it is called.
+func UserRepository(newRepo func() repository.UserRepository) bool {
+	return Describe("UserRepository conformance", func() {
+		var repo repository.UserRepository
+		var ctx context.Context
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			repo = newRepo()
+		})
+
+		Describe("Create and find", func() {
+			It("should return the stored user by ID and by email", func() {
+				// Arrange
+				user := stubs.NewUserStub().Get()
+				verifiedAt := time.Now().UTC().Truncate(time.Second)
+				user.EmailVerifiedAt = &verifiedAt
+
+				// Act
+				Expect(repo.Create(ctx, user)).To(Succeed())
+				byID, errByID := repo.FindByID(ctx, user.ID)
+				byEmail, errByEmail := repo.FindByEmail(ctx, user.Email)
+				forUpdate, errForUpdate := repo.FindByIDForUpdate(ctx, user.ID)
+
+				// Assert
+				Expect(errByID).NotTo(HaveOccurred())
+				Expect(errByEmail).NotTo(HaveOccurred())
+				Expect(errForUpdate).NotTo(HaveOccurred())
+				Expect(byID.Email).To(Equal(user.Email))
+				Expect(byEmail.ID).To(Equal(user.ID))
+				Expect(forUpdate.Email).To(Equal(user.Email))
+				Expect(byID.Role).To(Equal(domain.RoleUser))
+				Expect(byID.Disabled()).To(BeFalse())
+				Expect(byID.EmailVerifiedAt).NotTo(BeNil())
+				Expect(byID.EmailVerifiedAt.Equal(verifiedAt)).To(BeTrue())
+			})
+
+			It("should wrap ErrUserNotFound for unknown users", func() {
+				_, err := repo.FindByID(ctx, "00000000-0000-0000-0000-000000000000")
+				Expect(errors.Is(err, domain.ErrUserNotFound)).To(BeTrue())
+
+				_, err = repo.FindByIDForUpdate(ctx, "00000000-0000-0000-0000-000000000000")
+				Expect(errors.Is(err, domain.ErrUserNotFound)).To(BeTrue())
+
+				_, err = repo.FindByEmail(ctx, "nobody@example.com")
+				Expect(errors.Is(err, domain.ErrUserNotFound)).To(BeTrue())
+			})
+
+			It("should reject a duplicate email with ErrEmailAlreadyExists", func() {
+				// Arrange
+				Expect(repo.Create(ctx, stubs.NewUserStub().WithEmail("duplicate@example.com").Get())).To(Succeed())
+
+				// Act
+				err := repo.Create(ctx, stubs.NewUserStub().WithEmail("duplicate@example.com").Get())
+
+				// Assert
+				Expect(errors.Is(err, domain.ErrEmailAlreadyExists)).To(BeTrue())
+			})
+		})
+
+		Describe("Update", func() {
+			It("should persist changed fields", func() {
+				// Arrange
+				user := stubs.NewUserStub().Get()
+				Expect(repo.Create(ctx, user)).To(Succeed())
+				disabledAt := time.Now().UTC().Truncate(time.Second)
+
+				// Act
+				user.Name = "Renamed"
+				user.Role = domain.RoleAdmin
+				user.DisabledAt = &disabledAt
+				user.TokensValidAfter = &disabledAt
+				user.EmailVerifiedAt = &disabledAt
+				err := repo.Update(ctx, user)
+
+				// Assert
+				Expect(err).NotTo(HaveOccurred())
+				stored, err := repo.FindByID(ctx, user.ID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stored.Name).To(Equal("Renamed"))
+				Expect(stored.Role).To(Equal(domain.RoleAdmin))
+				Expect(stored.DisabledAt).NotTo(BeNil())
+				Expect(stored.DisabledAt.Equal(disabledAt)).To(BeTrue())
+				Expect(stored.TokensValidAfter.Equal(disabledAt)).To(BeTrue())
+				Expect(stored.EmailVerifiedAt).NotTo(BeNil())
+				Expect(stored.EmailVerifiedAt.Equal(disabledAt)).To(BeTrue())
+			})
+
+			It("should wrap ErrUserNotFound for unknown users", func() {
+				err := repo.Update(ctx, stubs.NewUserStub().Get())
+				Expect(errors.Is(err, domain.ErrUserNotFound)).To(BeTrue())
+			})
+
+			It("should reject taking another user's email", func() {
+				// Arrange
+				first := stubs.NewUserStub().WithEmail("first@example.com").Get()
+				second := stubs.NewUserStub().WithEmail("second@example.com").Get()
+				Expect(repo.Create(ctx, first)).To(Succeed())
+				Expect(repo.Create(ctx, second)).To(Succeed())
+
+				// Act
+				second.Email = "first@example.com"
+				err := repo.Update(ctx, second)
+
+				// Assert
+				Expect(errors.Is(err, domain.ErrEmailAlreadyExists)).To(BeTrue())
+			})
+		})
+
+		Describe("List", func() {
+			It("should page through users ordered by creation time", func() {
+				// Arrange
+				base := time.Now().UTC().Truncate(time.Second)
+				var ids []string
+				for i := 0; i < 5; i++ {
+					user := stubs.NewUserStub().
+						WithEmail(fmt.Sprintf("user%d@example.com", i)).
+						WithCreatedAt(base.Add(time.Duration(i) * time.Minute)).
+						Get()
+					Expect(repo.Create(ctx, user)).To(Succeed())
+					ids = append(ids, user.ID)
+				}
+
+				// Act
+				page, err := repo.List(ctx, 2, 2)
+
+				// Assert
+				Expect(err).NotTo(HaveOccurred())
+				Expect(page).To(HaveLen(2))
+				Expect(page[0].ID).To(Equal(ids[2]))
+				Expect(page[1].ID).To(Equal(ids[3]))
+
+				rest, err := repo.List(ctx, 10, 4)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rest).To(HaveLen(1))
+			})
+		})
+
+		Describe("Email identity", func() {
+			It("should treat emails that differ only by case as the same account", func() {
+				// Arrange
+				Expect(repo.Create(ctx, stubs.NewUserStub().WithEmail("Bob@Example.com").Get())).To(Succeed())
+
+				// Act
+				err := repo.Create(ctx, stubs.NewUserStub().WithEmail("bob@example.com").Get())
+				found, findErr := repo.FindByEmail(ctx, "BOB@EXAMPLE.COM")
+
+				// Assert
+				Expect(errors.Is(err, domain.ErrEmailAlreadyExists)).To(BeTrue())
+				Expect(findErr).NotTo(HaveOccurred())
+				Expect(found.Email).To(Equal("Bob@Example.com"))
+			})
+		})
+
+		Describe("Isolation", func() {
+			It("should not let callers mutate stored users through returned values", func() {
+				// Arrange
+				user := stubs.NewUserStub().Get()
+				Expect(repo.Create(ctx, user)).To(Succeed())
+
+				// Act
+				found, err := repo.FindByID(ctx, user.ID)
+				Expect(err).NotTo(HaveOccurred())
+				found.Name = strings.ToUpper(found.Name) + "!"
+
+				// Assert
+				again, err := repo.FindByID(ctx, user.ID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(again.Name).To(Equal(user.Name))
+			})
+		})
+	})
+}
@@ -0,0 +1,178 @@
+package conformance
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// WebhookRepository registers the shared specs. newRepos must return empty
+// repositories backed by the same store, since deliveries reference events.
+func WebhookRepository(newRepos func() (repository.OutboxRepository, repository.WebhookRepository)) bool {
+	return Describe("WebhookRepository conformance", func() {
+		var outbox repository.OutboxRepository
+		var webhooks repository.WebhookRepository
+		var ctx context.Context
+		var now time.Time
+
+		newSubscription := func(createdAt time.Time, events ...string) *domain.WebhookSubscription {
+			sub := &domain.WebhookSubscription{
+				ID:        uuid.NewString(),
+				URL:       "https://orders.example.com/hooks",
+				Events:    events,
+				Secret:    "whsec_test",
+				CreatedAt: createdAt,
+			}
+			Expect(webhooks.CreateSubscription(ctx, sub)).To(Succeed())
+			return sub
+		}
+
+		newDelivery := func(sub *domain.WebhookSubscription, due time.Time) *domain.WebhookDelivery {
+			event := &domain.Event{ID: uuid.NewString(), Type: domain.EventUserRegistered, Data: json.RawMessage(`{}`), CreatedAt: now}
+			Expect(outbox.Append(ctx, event)).To(Succeed())
+			delivery := &domain.WebhookDelivery{
+				ID:             uuid.NewString(),
+				EventID:        event.ID,
+				SubscriptionID: sub.ID,
+				Status:         domain.DeliveryPending,
+				NextAttemptAt:  due,
+				CreatedAt:      now,
+			}
+			Expect(webhooks.EnqueueDelivery(ctx, delivery)).To(Succeed())
+			return delivery
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			now = time.Now().UTC().Truncate(time.Millisecond)
+			outbox, webhooks = newRepos()
+		})
+
+		It("should store, list and delete subscriptions", func() {
+			// Arrange
+			newer := newSubscription(now, domain.EventUserRegistered, domain.EventUserDeleted)
+			older := newSubscription(now.Add(-time.Hour), domain.EventPasswordChanged)
+
+			// Act
+			found, findErr := webhooks.FindSubscription(ctx, newer.ID)
+			list, listErr := webhooks.ListSubscriptions(ctx)
+			deleteErr := webhooks.DeleteSubscription(ctx, older.ID)
+			againErr := webhooks.DeleteSubscription(ctx, older.ID)
+			_, missingErr := webhooks.FindSubscription(ctx, older.ID)
+
+			// Assert
+			Expect(findErr).NotTo(HaveOccurred())
+			Expect(found.URL).To(Equal("https://orders.example.com/hooks"))
+			Expect(found.Events).To(Equal([]string{domain.EventUserRegistered, domain.EventUserDeleted}))
+			Expect(found.Secret).To(Equal("whsec_test"))
+			Expect(listErr).NotTo(HaveOccurred())
+			Expect(list).To(HaveLen(2))
+			Expect(list[0].ID).To(Equal(older.ID))
+			Expect(list[1].ID).To(Equal(newer.ID))
+			Expect(deleteErr).NotTo(HaveOccurred())
+			Expect(errors.Is(againErr, domain.ErrWebhookNotFound)).To(BeTrue())
+			Expect(errors.Is(missingErr, domain.ErrWebhookNotFound)).To(BeTrue())
+		})
+
+		It("should queue an event once per subscription", func() {
+			// Arrange
+			sub := newSubscription(now, domain.EventUserRegistered)
+			delivery := newDelivery(sub, now)
+			duplicate := *delivery
+			duplicate.ID = uuid.NewString()
+
+			// Act
+			err := webhooks.EnqueueDelivery(ctx, &duplicate)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			deliveries, err := webhooks.ListDeliveries(ctx, sub.ID, "", 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deliveries).To(HaveLen(1))
+			Expect(deliveries[0].ID).To(Equal(delivery.ID))
+		})
+
+		It("should claim due deliveries until the lease runs out", func() {
+			// Arrange
+			sub := newSubscription(now, domain.EventUserRegistered)
+			due := newDelivery(sub, now.Add(-time.Second))
+			newDelivery(sub, now.Add(time.Hour))
+			lease := now.Add(time.Minute)
+
+			// Act
+			claimed, err := webhooks.ClaimDue(ctx, now, lease, 10)
+			again, againErr := webhooks.ClaimDue(ctx, now, lease, 10)
+			expired, expiredErr := webhooks.ClaimDue(ctx, lease, lease.Add(time.Minute), 10)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimed).To(HaveLen(1))
+			Expect(claimed[0].ID).To(Equal(due.ID))
+			Expect(claimed[0].EventID).To(Equal(due.EventID))
+			Expect(claimed[0].NextAttemptAt.Equal(lease)).To(BeTrue())
+			Expect(againErr).NotTo(HaveOccurred())
+			Expect(again).To(BeEmpty())
+			Expect(expiredErr).NotTo(HaveOccurred())
+			Expect(expired).To(HaveLen(1))
+		})
+
+		It("should keep dead deliveries out of the queue until redelivered", func() {
+			// Arrange
+			sub := newSubscription(now, domain.EventUserRegistered)
+			delivery := newDelivery(sub, now)
+			delivered := newDelivery(sub, now)
+			delivery.Status = domain.DeliveryDead
+			delivery.Attempts = 5
+			delivery.LastError = "receiver returned 500"
+			Expect(webhooks.UpdateDelivery(ctx, delivery)).To(Succeed())
+			delivered.Status = domain.DeliveryDelivered
+			delivered.DeliveredAt = &now
+			Expect(webhooks.UpdateDelivery(ctx, delivered)).To(Succeed())
+
+			// Act
+			claimed, claimErr := webhooks.ClaimDue(ctx, now, now.Add(time.Minute), 10)
+			dead, listErr := webhooks.ListDeliveries(ctx, sub.ID, domain.DeliveryDead, 10)
+			redeliverErr := webhooks.Redeliver(ctx, sub.ID, delivery.ID, now)
+			againErr := webhooks.Redeliver(ctx, sub.ID, delivery.ID, now)
+			deliveredErr := webhooks.Redeliver(ctx, sub.ID, delivered.ID, now)
+			requeued, requeueErr := webhooks.ClaimDue(ctx, now, now.Add(time.Minute), 10)
+
+			// Assert
+			Expect(claimErr).NotTo(HaveOccurred())
+			Expect(claimed).To(BeEmpty())
+			Expect(listErr).NotTo(HaveOccurred())
+			Expect(dead).To(HaveLen(1))
+			Expect(dead[0].Attempts).To(Equal(5))
+			Expect(dead[0].LastError).To(Equal("receiver returned 500"))
+			Expect(redeliverErr).NotTo(HaveOccurred())
+			Expect(errors.Is(againErr, domain.ErrDeliveryNotFound)).To(BeTrue())
+			Expect(errors.Is(deliveredErr, domain.ErrDeliveryNotFound)).To(BeTrue())
+			Expect(requeueErr).NotTo(HaveOccurred())
+			Expect(requeued).To(HaveLen(1))
+			Expect(requeued[0].ID).To(Equal(delivery.ID))
+			Expect(requeued[0].Attempts).To(BeZero())
+		})
+
+		It("should drop the deliveries of a deleted subscription", func() {
+			// Arrange
+			sub := newSubscription(now, domain.EventUserRegistered)
+			newDelivery(sub, now)
+
+			// Act
+			err := webhooks.DeleteSubscription(ctx, sub.ID)
+
+			// Assert
+			Expect(err).NotTo(HaveOccurred())
+			claimed, err := webhooks.ClaimDue(ctx, now, now.Add(time.Minute), 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claimed).To(BeEmpty())
+		})
+	})
+}
@@ -0,0 +1,179 @@
+// Package ldapserver runs a small in-process LDAP directory for tests. It
+// supports simple binds and searches with and/or/not, equality and presence
+// filters, which is all the ldapauth package needs.
+package ldapserver
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/jimlambrt/gldap"
+)
+
+// Password is the attribute holding an entry's bind password.
+const Password = "userPassword"
+
+type Server struct {
+	URL string
+
+	srv     *gldap.Server
+	mu      sync.RWMutex
+	entries map[string]map[string][]string
+	binds   int
+}
+
+// Start listens on a random local port; stop it with Close.
+func Start() (*Server, error) {
+	srv, err := gldap.NewServer()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{srv: srv, entries: make(map[string]map[string][]string)}
+
+	mux, err := gldap.NewMux()
+	if err != nil {
+		return nil, err
+	}
+	if err := mux.Bind(s.bind); err != nil {
+		return nil, err
+	}
+	if err := mux.Search(s.search); err != nil {
+		return nil, err
+	}
+	if err := srv.Router(mux); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	go srv.Run(addr)
+	for deadline := time.Now().Add(5 * time.Second); !srv.Ready(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("ldap server did not start on %s", addr)
+		}
+	}
+	s.URL = "ldap://" + addr
+	return s, nil
+}
+
+func (s *Server) Close() {
+	s.srv.Stop()
+}
+
+// Add stores an entry, replacing any entry with the same DN.
+func (s *Server) Add(dn string, attributes map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[strings.ToLower(dn)] = attributes
+}
+
+// Binds counts the successful binds so far.
+func (s *Server) Binds() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.binds
+}
+
+func (s *Server) bind(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+	defer w.Write(resp)
+
+	m, err := r.GetSimpleBindMessage()
+	if err != nil || m.AuthChoice != gldap.SimpleAuthChoice || m.Password == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[strings.ToLower(m.UserName)]
+	if ok && len(entry[Password]) > 0 && entry[Password][0] == string(m.Password) {
+		s.binds++
+		resp.SetResultCode(gldap.ResultSuccess)
+	}
+}
+
+func (s *Server) search(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultOperationsError))
+	defer w.Write(resp)
+
+	m, err := r.GetSearchMessage()
+	if err != nil {
+		return
+	}
+	filter, err := ldap.CompileFilter(m.Filter)
+	if err != nil {
+		resp.SetResultCode(gldap.ResultFilterError)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	base := strings.ToLower(m.BaseDN)
+	for dn, attributes := range s.entries {
+		if dn != base && !strings.HasSuffix(dn, ","+base) {
+			continue
+		}
+		if !matches(filter, attributes) {
+			continue
+		}
+		entry := r.NewSearchResponseEntry(dn)
+		for name, values := range attributes {
+			if name != Password {
+				entry.AddAttribute(name, values)
+			}
+		}
+		w.Write(entry)
+	}
+	resp.SetResultCode(gldap.ResultSuccess)
+}
+
+func matches(filter *ber.Packet, attributes map[string][]string) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matches(child, attributes) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matches(child, attributes) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !matches(filter.Children[0], attributes)
+	case ldap.FilterPresent:
+		return len(lookup(attributes, filter.Data.String())) > 0
+	case ldap.FilterEqualityMatch:
+		want := filter.Children[1].Value.(string)
+		for _, value := range lookup(attributes, filter.Children[0].Value.(string)) {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// lookup finds an attribute regardless of case, as LDAP does.
+func lookup(attributes map[string][]string, name string) []string {
+	for key, values := range attributes {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
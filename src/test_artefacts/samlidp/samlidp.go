@@ -0,0 +1,156 @@
+// Package samlidp is a SAML 2.0 identity provider for tests. It signs
+// assertions with a key generated on start, so tests need neither fixtures
+// nor network access. Every AuthnRequest is answered at once as the given
+// user.
+package samlidp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/crewjam/saml"
+)
+
+const (
+	EntityID = "https://idp.example.test/metadata"
+	SSOURL   = "https://idp.example.test/sso"
+)
+
+// User is the account the IdP signs in as. Attributes are sent as extra
+// attributes next to the standard mail, cn and groups ones.
+type User struct {
+	NameID     string
+	Email      string
+	Name       string
+	Groups     []string
+	Attributes map[string]string
+}
+
+type IdP struct {
+	idp *saml.IdentityProvider
+}
+
+// New returns an IdP with a fresh key; two IdPs from New share the entity
+// ID but not the key, which is how tests forge a response.
+func New() *IdP {
+	key, cert := KeyPair("idp.example.test")
+	metadataURL, _ := url.Parse(EntityID)
+	ssoURL, _ := url.Parse(SSOURL)
+	return &IdP{idp: &saml.IdentityProvider{
+		Key:         key,
+		Signer:      key,
+		Certificate: cert,
+		MetadataURL: *metadataURL,
+		SSOURL:      *ssoURL,
+	}}
+}
+
+// KeyPair generates an RSA key and a self-signed certificate for it.
+func KeyPair(commonName string) (*rsa.PrivateKey, *x509.Certificate) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return key, cert
+}
+
+// Metadata returns the IdP metadata XML the SP is configured with.
+func (p *IdP) Metadata() []byte {
+	out, err := xml.Marshal(p.idp.Metadata())
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// Respond plays the IdP's part once the browser reaches authURL: it signs in
+// as user and returns the base64 SAMLResponse the browser would post to the
+// SP. edit, when given, changes the assertion before it is signed.
+func (p *IdP) Respond(authURL string, spMetadata []byte, user User, edit ...func(*saml.Assertion)) (string, error) {
+	var sp saml.EntityDescriptor
+	if err := xml.Unmarshal(spMetadata, &sp); err != nil {
+		return "", fmt.Errorf("invalid SP metadata: %w", err)
+	}
+	idp := *p.idp
+	idp.ServiceProviderProvider = staticSP{metadata: &sp}
+
+	req, err := saml.NewIdpAuthnRequest(&idp, httptest.NewRequest(http.MethodGet, authURL, nil))
+	if err != nil {
+		return "", err
+	}
+	if err := req.Validate(); err != nil {
+		return "", err
+	}
+
+	session := &saml.Session{
+		ID:             "session-1",
+		CreateTime:     time.Now(),
+		ExpireTime:     time.Now().Add(time.Hour),
+		NameID:         user.NameID,
+		NameIDFormat:   string(saml.PersistentNameIDFormat),
+		UserEmail:      user.Email,
+		UserCommonName: user.Name,
+	}
+	if len(user.Groups) > 0 {
+		groups := saml.Attribute{Name: "groups", NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:basic"}
+		for _, g := range user.Groups {
+			groups.Values = append(groups.Values, saml.AttributeValue{Type: "xs:string", Value: g})
+		}
+		session.CustomAttributes = append(session.CustomAttributes, groups)
+	}
+	for name, value := range user.Attributes {
+		session.CustomAttributes = append(session.CustomAttributes, saml.Attribute{
+			Name:       name,
+			NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:basic",
+			Values:     []saml.AttributeValue{{Type: "xs:string", Value: value}},
+		})
+	}
+
+	if err := (saml.DefaultAssertionMaker{}).MakeAssertion(req, session); err != nil {
+		return "", err
+	}
+	for _, fn := range edit {
+		fn(req.Assertion)
+	}
+	form, err := req.PostBinding()
+	if err != nil {
+		return "", err
+	}
+	return form.SAMLResponse, nil
+}
+
+type staticSP struct {
+	metadata *saml.EntityDescriptor
+}
+
+func (s staticSP) GetServiceProvider(_ *http.Request, id string) (*saml.EntityDescriptor, error) {
+	if id != s.metadata.EntityID {
+		return nil, os.ErrNotExist
+	}
+	return s.metadata, nil
+}
@@ -20,6 +20,7 @@ func NewUserStub() *UserStub {
 			Name:         f.Person().Name(),
 			Email:        f.Internet().Email(),
 			PasswordHash: "hashed-password",
+			Role:         domain.RoleUser,
 			CreatedAt:    time.Now(),
 		},
 	}
@@ -30,6 +31,11 @@ func (s *UserStub) WithEmail(email string) *UserStub {
 	return s
 }
 
+func (s *UserStub) WithCreatedAt(createdAt time.Time) *UserStub {
+	s.user.CreatedAt = createdAt
+	return s
+}
+
 func (s *UserStub) Get() *domain.User {
 	return s.user
 }
@@ -16,12 +16,12 @@ func NewTestSeeder(db *pgxpool.Pool) *TestSeeder {
 }
 
 func (s *TestSeeder) InsertUser(ctx context.Context, user *domain.User) error {
-	query := `INSERT INTO users (id, name, email, password_hash, created_at) VALUES ($1, $2, $3, $4, $5)`
-	_, err := s.db.Exec(ctx, query, user.ID, user.Name, user.Email, user.PasswordHash, user.CreatedAt)
+	query := `INSERT INTO users (id, name, email, password_hash, role, created_at, disabled_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := s.db.Exec(ctx, query, user.ID, user.Name, user.Email, user.PasswordHash, user.Role, user.CreatedAt, user.DisabledAt)
 	return err
 }
 
 func (s *TestSeeder) TruncateTables(ctx context.Context) error {
-	_, err := s.db.Exec(ctx, "TRUNCATE TABLE users RESTART IDENTITY")
+	_, err := s.db.Exec(ctx, "TRUNCATE TABLE users, clients, magic_links, user_identities, scim_tenants, scim_users, scim_groups, scim_group_members, personal_access_tokens, api_keys, outbox_events, webhook_subscriptions, webhook_deliveries, device_authorizations, access_tokens, saml_assertions RESTART IDENTITY")
 	return err
 }
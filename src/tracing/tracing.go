@@ -0,0 +1,179 @@
+package tracing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// emailKey keys the email hashes put on spans. Setup sets it before any
+// span is started; while it is empty no email attribute is recorded.
+var emailKey []byte
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The OTLP exporter reads its endpoint and headers from the
+// standard OTEL_EXPORTER_OTLP_* environment variables. emailHashKey keys
+// the enduser.email_hash attribute; without it spans carry no email at all.
+func Setup(ctx context.Context, exporter, serviceName, emailHashKey string) (func(context.Context) error, error) {
+	emailKey = []byte(emailHashKey)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(exporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spanExporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Middleware continues the trace from an incoming traceparent header and
+// opens a server span named after the chi route pattern.
+func Middleware(next http.Handler) http.Handler {
+	tracer := otel.Tracer("auth-service/src/api")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+r.URL.Path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// EmailHash identifies an email in span attributes without exposing it. It
+// is an HMAC under the configured key, so a list of addresses cannot be
+// hashed to find whose spans these are.
+func EmailHash(email string) attribute.KeyValue {
+	mac := hmac.New(sha256.New, emailKey)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return attribute.String("enduser.email_hash", hex.EncodeToString(mac.Sum(nil)[:8]))
+}
+
+// WithEmail adds EmailHash, or nothing when no key is configured.
+func WithEmail(email string) trace.SpanStartOption {
+	if len(emailKey) == 0 {
+		return trace.WithAttributes()
+	}
+	return trace.WithAttributes(EmailHash(email))
+}
+
+func WithUserID(userID string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("enduser.id", userID))
+}
+
+// WithProvider names the external identity provider a social login uses.
+func WithProvider(provider string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("auth.provider", provider))
+}
+
+// WithSCIMTenant names the SCIM tenant a provisioning request acts for.
+func WithSCIMTenant(tenantID string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("scim.tenant.id", tenantID))
+}
+
+// WithWebhook names the subscription and the event a webhook delivery carries.
+func WithWebhook(subscriptionID, eventType string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("webhook.subscription.id", subscriptionID), attribute.String("webhook.event.type", eventType))
+}
+
+// WithClient names the OAuth client a request was made for.
+func WithClient(clientID string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("oauth.client.id", clientID))
+}
+
+func WithBcryptCost(cost int) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.Int("bcrypt.cost", cost))
+}
+
+// WithDBOperation describes a repository span following the database semantic conventions.
+func WithDBOperation(operation, table string) trace.SpanStartOption {
+	return withDBOperation(semconv.DBSystemNamePostgreSQL, operation, table)
+}
+
+// WithSQLiteOperation is WithDBOperation for the SQLite backend.
+func WithSQLiteOperation(operation, table string) trace.SpanStartOption {
+	return withDBOperation(semconv.DBSystemNameSQLite, operation, table)
+}
+
+func withDBOperation(system attribute.KeyValue, operation, table string) trace.SpanStartOption {
+	return trace.WithAttributes(
+		system,
+		semconv.DBOperationName(operation),
+		semconv.DBCollectionName(table),
+	)
+}
+
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func End(span trace.Span, err error) {
+	RecordError(span, err)
+	span.End()
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func TestMiddleware_ContinuesIncomingTraceparent(t *testing.T) {
+	// Arrange
+	recorder := setupRecorder(t)
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	var handlerSpan trace.SpanContext
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /users/{id}", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), handlerSpan.SpanID())
+}
+
+func TestEmailHash_IsKeyed(t *testing.T) {
+	// Arrange
+	t.Cleanup(func() { emailKey = nil })
+	emailKey = []byte("first-key-0123456789")
+	first := EmailHash("Someone@Example.com")
+	emailKey = []byte("second-key-0123456789")
+
+	// Act
+	second := EmailHash("Someone@Example.com")
+
+	// Assert
+	unkeyed := sha256.Sum256([]byte("someone@example.com"))
+	assert.NotContains(t, first.Value.AsString(), "example")
+	assert.NotEqual(t, hex.EncodeToString(unkeyed[:8]), first.Value.AsString())
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, second, EmailHash(" someone@example.com "))
+}
+
+func TestWithEmail_OmittedWithoutKey(t *testing.T) {
+	// Arrange
+	emailKey = nil
+
+	// Act
+	cfg := trace.NewSpanStartConfig(WithEmail("someone@example.com"))
+
+	// Assert
+	assert.Empty(t, cfg.Attributes())
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), "zipkin", "auth-service", "")
+
+	assert.ErrorContains(t, err, "unknown trace exporter")
+}
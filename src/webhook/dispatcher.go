@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/tracing"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("auth-service/src/webhook")
+
+// maxErrorLength bounds the receiver response kept with a failed delivery.
+const maxErrorLength = 512
+
+// Dispatcher moves events from the outbox to the subscribed URLs. Each run
+// first queues a delivery per interested subscription for the events not
+// dispatched yet, then attempts the deliveries that are due. A failed
+// delivery is retried with exponential backoff until it reaches the maximum
+// number of attempts and is dead-lettered.
+//
+// Delivery is at least once: receivers should use the Webhook-Id header,
+// which is the event ID, to ignore duplicates.
+type Dispatcher struct {
+	outbox      repository.OutboxRepository
+	webhooks    repository.WebhookRepository
+	client      *http.Client
+	logger      *slog.Logger
+	interval    time.Duration
+	maxAttempts int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+	batchSize   int
+	now         func() time.Time
+}
+
+type Option func(*Dispatcher)
+
+// WithInterval sets how often the outbox is polled.
+func WithInterval(interval time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.interval = interval
+	}
+}
+
+// WithMaxAttempts sets after how many failed attempts a delivery is dead.
+func WithMaxAttempts(attempts int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = attempts
+	}
+}
+
+// WithBackoff sets the wait after the first failure, doubled after every
+// further one up to max.
+func WithBackoff(base, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.backoffBase = base
+		d.backoffMax = max
+	}
+}
+
+// WithTimeout bounds each request to a receiver.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.client.Timeout = timeout
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(d *Dispatcher) {
+		d.logger = logger
+	}
+}
+
+func NewDispatcher(outbox repository.OutboxRepository, webhooks repository.WebhookRepository, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		outbox:      outbox,
+		webhooks:    webhooks,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		logger:      slog.Default(),
+		interval:    5 * time.Second,
+		maxAttempts: 10,
+		backoffBase: 30 * time.Second,
+		backoffMax:  6 * time.Hour,
+		batchSize:   20,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run dispatches every interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.logger.InfoContext(ctx, "webhook dispatcher started", "interval", d.interval)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if err := d.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			d.logger.ErrorContext(ctx, "webhook dispatch failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce queues the deliveries of new events and attempts the due ones.
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	if err := d.queue(ctx); err != nil {
+		return err
+	}
+	return d.deliverDue(ctx)
+}
+
+func (d *Dispatcher) queue(ctx context.Context) error {
+	events, err := d.outbox.ListUndispatched(ctx, d.batchSize)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	subs, err := d.webhooks.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	now := d.now()
+	for _, event := range events {
+		for _, sub := range subs {
+			if !sub.Subscribes(event.Type) {
+				continue
+			}
+			err := d.webhooks.EnqueueDelivery(ctx, &domain.WebhookDelivery{
+				ID:             uuid.NewString(),
+				EventID:        event.ID,
+				SubscriptionID: sub.ID,
+				Status:         domain.DeliveryPending,
+				NextAttemptAt:  now,
+				CreatedAt:      now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := d.outbox.MarkDispatched(ctx, event.ID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) error {
+	now := d.now()
+	// Deliveries are attempted one after the other, so the lease has to
+	// outlast a batch of receivers that all time out.
+	lease := time.Duration(d.batchSize)*d.client.Timeout + time.Minute
+	deliveries, err := d.webhooks.ClaimDue(ctx, now, now.Add(lease), d.batchSize)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		if err := d.attempt(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt sends one delivery and records the outcome. Only failures to
+// reach the store are returned; a receiver failing is the delivery's state.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	sub, err := d.webhooks.FindSubscription(ctx, delivery.SubscriptionID)
+	if errors.Is(err, domain.ErrWebhookNotFound) {
+		// Deleted while the delivery was claimed; its deliveries are gone too.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	event, err := d.outbox.FindByID(ctx, delivery.EventID)
+	if err != nil {
+		return err
+	}
+
+	sendErr := d.send(ctx, sub, event)
+	now := d.now()
+	delivery.Attempts++
+	switch {
+	case sendErr == nil:
+		delivery.Status = domain.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		d.logger.DebugContext(ctx, "webhook delivered", "subscription_id", sub.ID, "event_id", event.ID, "event_type", event.Type)
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = domain.DeliveryDead
+		delivery.LastError = truncate(sendErr.Error())
+		d.logger.WarnContext(ctx, "webhook delivery dead-lettered", "subscription_id", sub.ID, "event_id", event.ID,
+			"event_type", event.Type, "attempts", delivery.Attempts, "error", sendErr)
+	default:
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+		delivery.LastError = truncate(sendErr.Error())
+		d.logger.InfoContext(ctx, "webhook delivery failed, will retry", "subscription_id", sub.ID, "event_id", event.ID,
+			"attempts", delivery.Attempts, "next_attempt_at", delivery.NextAttemptAt, "error", sendErr)
+	}
+	return d.webhooks.UpdateDelivery(ctx, delivery)
+}
+
+func (d *Dispatcher) send(ctx context.Context, sub *domain.WebhookSubscription, event *domain.Event) (err error) {
+	ctx, span := tracer.Start(ctx, "Dispatcher.Send", tracing.WithWebhook(sub.ID, event.Type))
+	defer func() { tracing.End(span, err) }()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "auth-service-webhooks")
+	req.Header.Set(HeaderID, event.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, event.ID, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return fmt.Errorf("receiver returned %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// backoff doubles the wait after every failed attempt, up to backoffMax.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.backoffBase
+	for i := 1; i < attempts && wait < d.backoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, d.backoffMax)
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorLength {
+		return s[:maxErrorLength]
+	}
+	return s
+}
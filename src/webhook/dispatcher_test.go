@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"auth-service/src/domain"
+	"auth-service/src/repository"
+	"auth-service/src/repository/memory"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "whsec_test-secret"
+
+// receiver records the requests it gets and answers with the next status
+// in statuses, repeating the last one.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	headers  []http.Header
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, *httptest.Server) {
+	t.Helper()
+	rec := &receiver{statuses: statuses}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.headers = append(rec.headers, r.Header.Clone())
+		rec.bodies = append(rec.bodies, body)
+		status := rec.statuses[min(len(rec.bodies), len(rec.statuses))-1]
+		w.WriteHeader(status)
+		w.Write([]byte("receiver says hi"))
+	}))
+	t.Cleanup(srv.Close)
+	return rec, srv
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies)
+}
+
+type fixture struct {
+	ctx      context.Context
+	outbox   repository.OutboxRepository
+	webhooks repository.WebhookRepository
+	clock    time.Time
+}
+
+func newFixture() *fixture {
+	return &fixture{
+		ctx:      context.Background(),
+		outbox:   memory.NewOutbox(),
+		webhooks: memory.NewWebhook(),
+		clock:    time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func (f *fixture) dispatcher(opts ...Option) *Dispatcher {
+	d := NewDispatcher(f.outbox, f.webhooks, opts...)
+	d.now = func() time.Time { return f.clock }
+	return d
+}
+
+func (f *fixture) subscribe(t *testing.T, url string, events ...string) *domain.WebhookSubscription {
+	t.Helper()
+	sub := &domain.WebhookSubscription{ID: "sub-" + url, URL: url, Events: events, Secret: testSecret, CreatedAt: f.clock}
+	require.NoError(t, f.webhooks.CreateSubscription(f.ctx, sub))
+	return sub
+}
+
+func (f *fixture) publish(t *testing.T, eventType string) *domain.Event {
+	t.Helper()
+	event, err := domain.NewUserEvent("evt-1", eventType, &domain.User{ID: "user-1", Email: "ada@example.com", Name: "Ada"}, f.clock)
+	require.NoError(t, err)
+	require.NoError(t, f.outbox.Append(f.ctx, event))
+	return event
+}
+
+func TestDispatcher_DeliversSignedEventsToSubscribers(t *testing.T) {
+	// Arrange
+	f := newFixture()
+	orders, ordersSrv := newReceiver(t, http.StatusOK)
+	marketing, marketingSrv := newReceiver(t, http.StatusOK)
+	sub := f.subscribe(t, ordersSrv.URL, domain.EventUserRegistered)
+	f.subscribe(t, marketingSrv.URL, domain.EventPasswordChanged)
+	event := f.publish(t, domain.EventUserRegistered)
+
+	// Act
+	err := f.dispatcher().RunOnce(f.ctx)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, 1, orders.count())
+	assert.Zero(t, marketing.count())
+	assert.NoError(t, Verify(testSecret, orders.headers[0], orders.bodies[0], 5*time.Minute, f.clock))
+	assert.Equal(t, event.ID, orders.headers[0].Get(HeaderID))
+
+	var body struct {
+		ID   string               `json:"id"`
+		Type string               `json:"type"`
+		Data domain.UserEventData `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(orders.bodies[0], &body))
+	assert.Equal(t, event.ID, body.ID)
+	assert.Equal(t, domain.EventUserRegistered, body.Type)
+	assert.Equal(t, "user-1", body.Data.UserID)
+	assert.Equal(t, "ada@example.com", body.Data.Email)
+
+	deliveries, err := f.webhooks.ListDeliveries(f.ctx, sub.ID, "", 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, domain.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	undispatched, err := f.outbox.ListUndispatched(f.ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, undispatched)
+}
+
+func TestDispatcher_RetriesWithBackoffUntilDelivered(t *testing.T) {
+	// Arrange
+	f := newFixture()
+	rec, srv := newReceiver(t, http.StatusServiceUnavailable, http.StatusOK)
+	sub := f.subscribe(t, srv.URL, domain.EventPasswordChanged)
+	f.publish(t, domain.EventPasswordChanged)
+	d := f.dispatcher(WithBackoff(time.Minute, time.Hour))
+
+	// Act
+	firstErr := d.RunOnce(f.ctx)
+	f.clock = f.clock.Add(59 * time.Second)
+	earlyErr := d.RunOnce(f.ctx)
+	attemptsBeforeBackoff := rec.count()
+	f.clock = f.clock.Add(time.Second)
+	retryErr := d.RunOnce(f.ctx)
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, earlyErr)
+	require.NoError(t, retryErr)
+	assert.Equal(t, 1, attemptsBeforeBackoff)
+	assert.Equal(t, 2, rec.count())
+	deliveries, err := f.webhooks.ListDeliveries(f.ctx, sub.ID, "", 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, domain.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Empty(t, deliveries[0].LastError)
+}
+
+func TestDispatcher_DeadLettersAfterMaxAttempts(t *testing.T) {
+	// Arrange
+	f := newFixture()
+	rec, srv := newReceiver(t, http.StatusInternalServerError)
+	sub := f.subscribe(t, srv.URL, domain.EventUserDeleted)
+	f.publish(t, domain.EventUserDeleted)
+	d := f.dispatcher(WithMaxAttempts(3), WithBackoff(time.Minute, 90*time.Second))
+
+	// Act
+	for range 5 {
+		require.NoError(t, d.RunOnce(f.ctx))
+		f.clock = f.clock.Add(90 * time.Second)
+	}
+
+	// Assert
+	assert.Equal(t, 3, rec.count())
+	dead, err := f.webhooks.ListDeliveries(f.ctx, sub.ID, domain.DeliveryDead, 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 3, dead[0].Attempts)
+	assert.Contains(t, dead[0].LastError, "receiver returned 500: receiver says hi")
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := NewDispatcher(nil, nil, WithBackoff(30*time.Second, 5*time.Minute))
+
+	assert.Equal(t, 30*time.Second, d.backoff(1))
+	assert.Equal(t, time.Minute, d.backoff(2))
+	assert.Equal(t, 4*time.Minute, d.backoff(4))
+	assert.Equal(t, 5*time.Minute, d.backoff(5))
+	assert.Equal(t, 5*time.Minute, d.backoff(40))
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"id":"evt-1"}`)
+	signed := func(secret string, at time.Time) http.Header {
+		h := http.Header{}
+		h.Set(HeaderID, "evt-1")
+		h.Set(HeaderTimestamp, "1700000000")
+		h.Set(HeaderSignature, Sign(secret, "evt-1", at.Unix(), body))
+		return h
+	}
+
+	assert.NoError(t, Verify(testSecret, signed(testSecret, now), body, time.Minute, now))
+	assert.ErrorIs(t, Verify("whsec_other", signed(testSecret, now), body, time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, signed(testSecret, now), []byte(`{"id":"evt-2"}`), time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, signed(testSecret, now), body, time.Minute, now.Add(2*time.Minute)), ErrTimestampSkew)
+	assert.ErrorIs(t, Verify(testSecret, http.Header{}, body, time.Minute, now), ErrMissingHeaders)
+}